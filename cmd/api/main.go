@@ -14,6 +14,7 @@ import (
 	"github.com/Flack74/go-auth-system/internal/databases"
 	"github.com/Flack74/go-auth-system/internal/handlers"
 	"github.com/Flack74/go-auth-system/internal/middleware"
+	"github.com/Flack74/go-auth-system/internal/models"
 	"github.com/Flack74/go-auth-system/internal/repository"
 	"github.com/Flack74/go-auth-system/internal/services"
 	"github.com/Flack74/go-auth-system/internal/utils"
@@ -49,17 +50,64 @@ func main() {
 
 	// Initialize sqlc repositories
 	userRepo := repository.NewSqlcUserRepository(db)
+	backupCodeRepo := repository.NewSqlcBackupCodeRepository(db)
+	sessionRepo := repository.NewSqlcSessionRepository(db)
+	oauthClientRepo := repository.NewSqlcOAuthClientRepository(db)
+	roleRepo := repository.NewSqlcRoleRepository(db)
+	auditRepo := repository.NewSqlcAuditRepository(db, []byte(cfg.AuditChainKey))
+	webhookRepo := repository.NewSqlcWebhookRepository(db)
+	outboxRepo := repository.NewSqlcOutboxRepository(db)
+	oneTimeTokenRepo := repository.NewSqlcOneTimeTokenRepository(db)
 
 	// Initialize services
-	tokenService := services.NewTokenService(cfg, redisClient)
-	emailService := services.NewEmailService(cfg)
-	authService := services.NewAuthService(userRepo, tokenService, emailService, cfg)
+	keySet, err := services.NewKeySet(cfg)
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+	tokenService := services.NewTokenService(cfg, keySet, redisClient, userRepo)
+	emailTransport, err := services.NewEmailTransport(cfg)
+	if err != nil {
+		log.Fatal("Failed to set up email transport:", err)
+	}
+	emailTemplates, err := services.NewEmailTemplates(cfg.EmailTemplateDir, cfg.EmailDefaultLocale)
+	if err != nil {
+		log.Fatal("Failed to load email templates:", err)
+	}
+	emailService := services.NewEmailService(cfg, emailTransport, emailTemplates)
+	totpService := services.NewTOTPService(userRepo, backupCodeRepo, redisClient, cfg)
+	sessionService := services.NewSessionService(sessionRepo, tokenService, redisClient, cfg)
+	rbacService := services.NewRBACService(roleRepo, redisClient)
+	webhookService := services.NewWebhookService(webhookRepo)
+	authService := services.NewAuthService(userRepo, oneTimeTokenRepo, tokenService, totpService, sessionService, rbacService, auditRepo, outboxRepo, services.NewRedisThrottle(redisClient), cfg)
+	clientRegistry := services.NewClientRegistry(oauthClientRepo)
+	oidcService := services.NewOIDCService(clientRegistry, userRepo, authService, tokenService, redisClient, cfg)
+	adminService := services.NewAdminService(userRepo, authService, rbacService)
+	auditService := services.NewAuditService(auditRepo, []byte(cfg.AuditChainKey))
+	accountService := services.NewAccountService(userRepo, sessionRepo, auditRepo, cfg)
+
+	// Keep logged auth and security events in the audit trail
+	auditService.Start()
+	utils.SetEventSink(auditService)
+
+	// Deliver queued webhooks and outbox messages in the background
+	webhookService.Start()
+	outboxService := services.NewOutboxService(outboxRepo, emailService, webhookService)
+	outboxService.Start()
+
+	// Purge deleted accounts once their grace period ends
+	accountService.Start()
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService, cfg)
+	twoFactorHandler := handlers.NewTwoFactorHandler(totpService, authService)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	oauthHandler := handlers.NewOAuthHandler(oidcService, cfg)
+	wellKnownHandler := handlers.NewWellKnownHandler(tokenService, oidcService)
+	adminHandler := handlers.NewAdminHandler(adminService, rbacService, auditService, webhookService)
+	accountHandler := handlers.NewAccountHandler(authService, accountService)
 
 	// Setup routes
-	router := setupRouter(cfg, authHandler, tokenService, redisClient, db)
+	router := setupRouter(cfg, authHandler, twoFactorHandler, sessionHandler, oauthHandler, wellKnownHandler, adminHandler, accountHandler, tokenService, sessionService, rbacService, redisClient, db)
 
 	// Start server
 	srv := &http.Server{
@@ -90,10 +138,16 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	accountService.Stop()
+	outboxService.Stop()
+	webhookService.Stop()
+	utils.SetEventSink(nil)
+	auditService.Stop()
+
 	log.Println("Server exited")
 }
 
-func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, tokenService *services.TokenService, redisClient *redis.Client, db *sql.DB) *gin.Engine {
+func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, twoFactorHandler *handlers.TwoFactorHandler, sessionHandler *handlers.SessionHandler, oauthHandler *handlers.OAuthHandler, wellKnownHandler *handlers.WellKnownHandler, adminHandler *handlers.AdminHandler, accountHandler *handlers.AccountHandler, tokenService *services.TokenService, sessionService *services.SessionService, rbacService *services.RBACService, redisClient *redis.Client, db *sql.DB) *gin.Engine {
 	router := gin.Default()
 
 	// Initialize logger
@@ -114,7 +168,7 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, tokenSer
 		router.Use(func(c *gin.Context) {
 			// Skip CSRF for public auth routes only
 			path := c.Request.URL.Path
-			if path == "/auth/login" || path == "/auth/register" || path == "/auth/password/forgot" || path == "/auth/password/reset" || path == "/auth/verify" {
+			if path == "/auth/login" || path == "/auth/login/mfa" || path == "/auth/register" || path == "/auth/password/forgot" || path == "/auth/password/reset" || path == "/auth/verify" || path == "/auth/verify/resend" || path == "/auth/account/restore" || path == "/oauth/token" || path == "/oauth/userinfo" {
 				c.Next()
 				return
 			}
@@ -135,6 +189,23 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, tokenSer
 	router.GET("/health/ready", healthHandler.ReadinessCheck)
 	router.GET("/health/live", healthHandler.LivenessCheck)
 
+	// Public keys for downstream token verification
+	router.GET("/.well-known/jwks.json", wellKnownHandler.JWKS)
+
+	// OAuth token endpoint, plus the OpenID Connect provider when enabled
+	oauth := router.Group("/oauth")
+	{
+		oauth.Use(middleware.RateLimit(redisClient, cfg.RateLimitRequests, cfg.RateLimitWindow))
+
+		oauth.POST("/token", oauthHandler.Token)
+		if cfg.OIDCEnabled {
+			router.GET("/.well-known/openid-configuration", wellKnownHandler.OpenIDConfiguration)
+			oauth.GET("/authorize", middleware.OptionalAuth(tokenService, sessionService), oauthHandler.Authorize)
+			oauth.GET("/userinfo", middleware.PrincipalAuth(tokenService, rbacService), middleware.RequireScope("openid"), oauthHandler.UserInfo)
+			oauth.POST("/userinfo", middleware.PrincipalAuth(tokenService, rbacService), middleware.RequireScope("openid"), oauthHandler.UserInfo)
+		}
+	}
+
 	// Auth routes
 	auth := router.Group("/auth")
 	{
@@ -143,20 +214,86 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, tokenSer
 
 		auth.POST("/register", authHandler.Register)
 		auth.POST("/login", authHandler.Login)
+		auth.POST("/login/mfa", authHandler.LoginMFA)
 		auth.POST("/refresh", authHandler.Refresh)
-		auth.POST("/logout", middleware.Auth(tokenService), authHandler.Logout)
+		auth.POST("/logout", middleware.AuthOrSession(tokenService, sessionService, rbacService, cfg.SessionTimeout), authHandler.Logout)
 		auth.GET("/verify", authHandler.VerifyEmail)
+		auth.POST("/verify/resend", authHandler.ResendVerification)
 		auth.POST("/password/forgot", authHandler.ForgotPassword)
 		auth.POST("/password/reset", authHandler.ResetPassword)
+		auth.GET("/email/confirm", authHandler.ConfirmEmailChange)
+		auth.POST("/account/restore", accountHandler.Restore)
 	}
 
 	// Protected routes example
 	protected := router.Group("/api")
-	protected.Use(middleware.Auth(tokenService))
+	protected.Use(middleware.AuthOrSession(tokenService, sessionService, rbacService, cfg.SessionTimeout))
 	{
 		protected.GET("/profile", authHandler.GetProfile)
-		protected.POST("/2fa/toggle", authHandler.Toggle2FA)
+		protected.POST("/password", authHandler.ChangePassword)
+		protected.POST("/email", authHandler.ChangeEmail)
+		protected.POST("/2fa/setup", twoFactorHandler.Setup)
+		protected.POST("/2fa/enable", twoFactorHandler.Enable)
+		protected.POST("/2fa/disable", twoFactorHandler.Disable)
+		protected.GET("/2fa/backup-codes", twoFactorHandler.BackupCodesStatus)
+		protected.POST("/2fa/backup-codes", twoFactorHandler.RegenerateBackupCodes)
+		protected.GET("/sessions", sessionHandler.List)
+		protected.DELETE("/sessions/:id", sessionHandler.Revoke)
+		protected.POST("/sessions/revoke-others", sessionHandler.RevokeOthers)
 		protected.GET("/activity-log", authHandler.GetActivityLog)
+		protected.DELETE("/account", accountHandler.Delete)
+		protected.GET("/account/export", accountHandler.Export)
+	}
+
+	// Read-only user directory for administrators and for machine clients
+	// granted the users:read scope
+	directory := router.Group("/api/directory")
+	directory.Use(middleware.PrincipalAuth(tokenService, rbacService))
+	directory.Use(middleware.RequireScope("users:read"), middleware.RequireUserPermission(models.PermissionAdminAccess))
+	{
+		directory.GET("/users", adminHandler.ListUsers)
+		directory.GET("/users/:id", adminHandler.GetUser)
+	}
+
+	// Administrator user management
+	admin := protected.Group("/admin")
+	admin.Use(middleware.AdminOnly())
+	{
+		admin.GET("/users", adminHandler.ListUsers)
+		admin.GET("/users/:id", adminHandler.GetUser)
+		admin.POST("/users/:id/unlock", adminHandler.UnlockUser)
+		admin.POST("/users/:id/verify-email", adminHandler.VerifyUserEmail)
+		admin.POST("/users/:id/reset-password", adminHandler.ForcePasswordReset)
+		admin.POST("/users/:id/disable", adminHandler.DisableUser)
+		admin.POST("/users/:id/enable", adminHandler.EnableUser)
+		admin.DELETE("/users/:id", adminHandler.DeleteUser)
+		admin.PUT("/users/:id/roles", adminHandler.SetRoles)
+		admin.POST("/users/:id/roles", adminHandler.GrantRole)
+		admin.DELETE("/users/:id/roles/:role", adminHandler.RevokeRole)
+
+		admin.GET("/roles", adminHandler.ListRoles)
+		admin.POST("/roles", adminHandler.CreateRole)
+		admin.GET("/roles/:id", adminHandler.GetRole)
+		admin.PUT("/roles/:id", adminHandler.UpdateRole)
+		admin.DELETE("/roles/:id", adminHandler.DeleteRole)
+		admin.GET("/roles/:id/users", adminHandler.ListRoleUsers)
+		admin.POST("/roles/:id/permissions", adminHandler.AttachPermission)
+		admin.DELETE("/roles/:id/permissions/:permission", adminHandler.DetachPermission)
+		admin.GET("/permissions", adminHandler.ListPermissions)
+		admin.POST("/permissions", adminHandler.CreatePermission)
+
+		admin.GET("/audit/verify", adminHandler.VerifyAuditChain)
+		admin.GET("/audit/export", adminHandler.ExportAudit)
+
+		admin.GET("/webhooks", adminHandler.ListWebhooks)
+		admin.POST("/webhooks", adminHandler.CreateWebhook)
+		admin.GET("/webhooks/deliveries", adminHandler.ListWebhookDeliveries)
+		admin.GET("/webhooks/deliveries/:id", adminHandler.GetWebhookDelivery)
+		admin.POST("/webhooks/deliveries/:id/replay", adminHandler.ReplayWebhookDelivery)
+		admin.GET("/webhooks/:id", adminHandler.GetWebhook)
+		admin.PUT("/webhooks/:id", adminHandler.UpdateWebhook)
+		admin.DELETE("/webhooks/:id", adminHandler.DeleteWebhook)
+		admin.POST("/webhooks/:id/replay", adminHandler.ReplayWebhookFailures)
 	}
 
 	return router
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSetupRouter_CSRFExemptsPublicAuthRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Nothing listens here: the rate limiter fails closed with 429, which
+	// only a request CSRF let through can reach
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer redisClient.Close()
+
+	cfg := &config.Config{CSRFProtection: true, RateLimitRequests: 10, RateLimitWindow: time.Minute}
+	router := setupRouter(cfg, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, redisClient, nil)
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// No endpoint hands out CSRF tokens, so public routes must skip the check
+	for _, path := range []string{"/auth/login", "/auth/verify/resend", "/auth/account/restore"} {
+		if w := post(path); w.Code == http.StatusForbidden {
+			t.Fatalf("expected %s to skip CSRF, got %d %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	if w := post("/auth/refresh"); w.Code != http.StatusForbidden {
+		t.Fatalf("expected /auth/refresh to require a CSRF token, got %d", w.Code)
+	}
+}
@@ -0,0 +1,40 @@
+// Command auditchain re-hashes the audit trail under AUDIT_CHAIN_KEY. Run it
+// with the API stopped: once when first setting the key, and again when
+// rotating it, with AUDIT_CHAIN_OLD_KEY holding the key being replaced.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/databases"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	godotenv.Load()
+	cfg := config.Load()
+	if cfg.AuditChainKey == "" {
+		log.Fatal("AUDIT_CHAIN_KEY must be set")
+	}
+
+	db, err := databases.NewPostgresDB(cfg)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	auditRepo := repository.NewSqlcAuditRepository(db, []byte(cfg.AuditChainKey))
+	auditService := services.NewAuditService(auditRepo, []byte(cfg.AuditChainKey))
+
+	rekeyed, err := auditService.RekeyChain([]byte(os.Getenv("AUDIT_CHAIN_OLD_KEY")))
+	if err != nil {
+		log.Fatal("Failed to rekey the audit chain:", err)
+	}
+
+	fmt.Printf("Re-hashed %d audit records under AUDIT_CHAIN_KEY\n", rekeyed)
+}
@@ -0,0 +1,30 @@
+// Command jwtkeys rotates the JWT signing key. The new key signs tokens from
+// the next restart on; older keys stay in the directory, and in the JWKS,
+// until they are deleted once every token they signed has expired.
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	godotenv.Load()
+	cfg := config.Load()
+
+	keySet, err := services.LoadKeySet(cfg.JWTKeyDir, cfg.JWTSigningAlg)
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+
+	key, err := keySet.Rotate()
+	if err != nil {
+		log.Fatal("Failed to rotate signing key:", err)
+	}
+
+	fmt.Printf("New %s signing key %s written to %s\n", key.Method.Alg(), key.ID, cfg.JWTKeyDir)
+}
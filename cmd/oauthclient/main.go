@@ -0,0 +1,64 @@
+// Command oauthclient registers an application with the OpenID Connect
+// provider and prints its credentials. The client secret is shown only once.
+//
+//	go run ./cmd/oauthclient -name "Billing" -redirect https://billing.example.com/callback
+//	go run ./cmd/oauthclient -name "Report job" -machine -scopes reports:read,users:read
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/databases"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	name := flag.String("name", "", "human readable client name")
+	redirects := flag.String("redirect", "", "comma-separated list of allowed redirect URIs")
+	public := flag.Bool("public", false, "register a public client (no secret, PKCE only)")
+	machine := flag.Bool("machine", false, "register a machine client using the client_credentials grant")
+	scopes := flag.String("scopes", "", "comma-separated list of scopes a machine client may request")
+	flag.Parse()
+
+	reg := models.OAuthClientRegistration{Name: *name, Public: *public}
+	if *machine {
+		reg.GrantTypes = []string{"client_credentials"}
+		if *scopes != "" {
+			reg.Scopes = strings.Split(*scopes, ",")
+		}
+	} else if *redirects != "" {
+		reg.RedirectURIs = strings.Split(*redirects, ",")
+	}
+
+	if *name == "" || (!*machine && *redirects == "") {
+		flag.Usage()
+		log.Fatal("-name and either -redirect or -machine are required")
+	}
+
+	godotenv.Load()
+	cfg := config.Load()
+
+	db, err := databases.NewPostgresDB(cfg)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	registry := services.NewClientRegistry(repository.NewSqlcOAuthClientRepository(db))
+	client, secret, err := registry.Register(reg)
+	if err != nil {
+		log.Fatal("Failed to register client:", err)
+	}
+
+	fmt.Printf("client_id:     %s\n", client.ClientID)
+	if secret != "" {
+		fmt.Printf("client_secret: %s\n", secret)
+	}
+}
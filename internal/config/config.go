@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -29,9 +30,12 @@ type Config struct {
 	RedisDB           int
 
 	// JWT
-	JWTSecret        string
-	JWTAccessExpiry  time.Duration
-	JWTRefreshExpiry time.Duration
+	JWTSecret         string
+	JWTAccessExpiry   time.Duration
+	JWTRefreshExpiry  time.Duration
+	RefreshReuseGrace time.Duration // window in which a just-rotated refresh token is not treated as reuse
+	JWTSigningAlg     string        // HS256, RS256, ES256 or EdDSA
+	JWTKeyDir         string        // PEM keyset directory for asymmetric algorithms
 
 	// Email
 	SMTPHost     string
@@ -42,6 +46,12 @@ type Config struct {
 	BaseURL      string
 	FrontendURL  string
 
+	EmailTransport     string // smtp, file or log
+	EmailCaptureDir    string // where the file transport writes messages
+	EmailTemplateDir   string // optional overrides for the built-in templates
+	EmailDefaultLocale string
+	EmailVerifyExpiry  time.Duration // lifetime of email verification links
+
 	// Security
 	BcryptCost        int
 	RateLimitRequests int
@@ -52,6 +62,18 @@ type Config struct {
 	PasswordComplexity     bool
 	CSRFProtection         bool
 	RequestTimeout         time.Duration
+	AccountDeletionGrace   time.Duration // how long a deleted account can be restored before it is purged
+
+	// Audit trail
+	AuditChainKey string // keys the audit trail's hash chain; keep it out of the database
+
+	// Two-factor authentication
+	TOTPIssuer         string
+	MFAChallengeExpiry time.Duration
+
+	// OpenID Connect provider
+	OIDCEnabled bool // needs an asymmetric JWT_SIGNING_ALG so relying parties can verify ID tokens
+	OIDCIssuer  string
 }
 
 func Load() *Config {
@@ -74,9 +96,12 @@ func Load() *Config {
 		RedisPassword:     getEnv("REDIS_PASSWORD", ""),
 		RedisDB:           getEnvAsInt("REDIS_DB", 0),
 
-		JWTSecret:        getEnv("JWT_SECRET", "your-secret-key"),
-		JWTAccessExpiry:  getEnvAsDuration("JWT_ACCESS_EXPIRY", "15m"),
-		JWTRefreshExpiry: getEnvAsDuration("JWT_REFRESH_EXPIRY", "7d"),
+		JWTSecret:         getEnv("JWT_SECRET", "your-secret-key"),
+		JWTAccessExpiry:   getEnvAsDuration("JWT_ACCESS_EXPIRY", "15m"),
+		JWTRefreshExpiry:  getEnvAsDuration("JWT_REFRESH_EXPIRY", "7d"),
+		RefreshReuseGrace: getEnvAsDuration("REFRESH_REUSE_GRACE", "10s"),
+		JWTSigningAlg:     getEnv("JWT_SIGNING_ALG", "HS256"),
+		JWTKeyDir:         getEnv("JWT_KEY_DIR", "keys"),
 
 		SMTPHost:     getEnv("SMTP_HOST", "smtp.gmail.com"),
 		SMTPPort:     getEnvAsInt("SMTP_PORT", 587),
@@ -86,6 +111,12 @@ func Load() *Config {
 		BaseURL:      getEnv("BASE_URL", "http://localhost:8080"),
 		FrontendURL:  getEnv("FRONTEND_URL", "http://localhost:3000"),
 
+		EmailTransport:     getEnv("EMAIL_TRANSPORT", "smtp"),
+		EmailCaptureDir:    getEnv("EMAIL_CAPTURE_DIR", "tmp/mail"),
+		EmailTemplateDir:   getEnv("EMAIL_TEMPLATE_DIR", ""),
+		EmailDefaultLocale: getEnv("EMAIL_DEFAULT_LOCALE", "en"),
+		EmailVerifyExpiry:  getEnvAsDuration("EMAIL_VERIFY_EXPIRY", "24h"),
+
 		BcryptCost:             getEnvAsInt("BCRYPT_COST", 12),
 		RateLimitRequests:      getEnvAsInt("RATE_LIMIT_REQUESTS", 10),
 		RateLimitWindow:        getEnvAsDuration("RATE_LIMIT_WINDOW", "1m"),
@@ -95,6 +126,15 @@ func Load() *Config {
 		PasswordComplexity:     getEnvAsBool("PASSWORD_COMPLEXITY", true),
 		CSRFProtection:         getEnvAsBool("CSRF_PROTECTION", true),
 		RequestTimeout:         getEnvAsDuration("REQUEST_TIMEOUT", "30s"),
+		AccountDeletionGrace:   getEnvAsDuration("ACCOUNT_DELETION_GRACE", "720h"),
+
+		AuditChainKey: getEnv("AUDIT_CHAIN_KEY", ""),
+
+		TOTPIssuer:         getEnv("TOTP_ISSUER", "Go Auth System"),
+		MFAChallengeExpiry: getEnvAsDuration("MFA_CHALLENGE_EXPIRY", "5m"),
+
+		OIDCEnabled: getEnvAsBool("OIDC_ENABLED", false),
+		OIDCIssuer:  getEnv("OIDC_ISSUER", getEnv("BASE_URL", "http://localhost:8080")),
 	}
 }
 
@@ -132,14 +172,35 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 }
 
 func (c *Config) Validate() error {
-	// JWT Secret validation
-	if c.JWTSecret == "your-secret-key" || c.JWTSecret == "" {
-		return errors.New("JWT_SECRET must be set and changed from default")
+	// JWT Secret validation (only used by the shared-secret HS256 mode)
+	switch strings.ToUpper(c.JWTSigningAlg) {
+	case "HS256":
+		if c.JWTSecret == "your-secret-key" || c.JWTSecret == "" {
+			return errors.New("JWT_SECRET must be set and changed from default")
+		}
+		if len(c.JWTSecret) < 32 {
+			return errors.New("JWT_SECRET must be at least 32 characters for security")
+		}
+	case "RS256", "ES256", "EDDSA":
+		if c.JWTKeyDir == "" {
+			return errors.New("JWT_KEY_DIR must be set for asymmetric signing")
+		}
+	default:
+		return errors.New("JWT_SIGNING_ALG must be one of HS256, RS256, ES256, EdDSA")
 	}
-	if len(c.JWTSecret) < 32 {
-		return errors.New("JWT_SECRET must be at least 32 characters for security")
+
+	// ID tokens signed with the shared secret could only be checked by parties
+	// holding it, who could then forge access tokens as well
+	if c.OIDCEnabled && strings.ToUpper(c.JWTSigningAlg) == "HS256" {
+		return errors.New("OIDC_ENABLED requires JWT_SIGNING_ALG to be RS256, ES256 or EdDSA")
 	}
 	
+	// Without a key, anyone who can write the database can rewrite the
+	// audit trail and recompute its hashes
+	if c.AuditChainKey != "" && len(c.AuditChainKey) < 32 {
+		return errors.New("AUDIT_CHAIN_KEY must be at least 32 characters")
+	}
+
 	// Bcrypt cost validation
 	if c.BcryptCost < 10 {
 		return errors.New("BCRYPT_COST must be at least 10")
@@ -153,6 +214,13 @@ func (c *Config) Validate() error {
 		return errors.New("RATE_LIMIT_REQUESTS must be positive")
 	}
 	
+	// Email transport validation
+	switch c.EmailTransport {
+	case "smtp", "file", "log":
+	default:
+		return errors.New("EMAIL_TRANSPORT must be one of smtp, file or log")
+	}
+
 	// Production environment checks
 	if c.Env == "production" {
 		if c.NeonDatabaseURL == "" {
@@ -161,6 +229,13 @@ func (c *Config) Validate() error {
 		if c.UpstashRedisURL == "" || c.UpstashRedisToken == "" {
 			return errors.New("Upstash Redis credentials must be set in production")
 		}
+		if c.AuditChainKey == "" {
+			return errors.New("AUDIT_CHAIN_KEY must be set in production")
+		}
+		// Nothing is delivered and the log is read far more widely than a mailbox
+		if c.EmailTransport == "log" {
+			return errors.New("EMAIL_TRANSPORT=log must not be used in production")
+		}
 		// CSRF is optional for JWT-based APIs
 		if c.FrontendURL == "" || c.FrontendURL == "http://localhost:3000" {
 			return errors.New("FRONTEND_URL must be set to production URL (current: " + c.FrontendURL + ")")
@@ -24,40 +24,467 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.addRolePermissionStmt, err = db.PrepareContext(ctx, addRolePermission); err != nil {
+		return nil, fmt.Errorf("error preparing query AddRolePermission: %w", err)
+	}
+	if q.addUserRoleStmt, err = db.PrepareContext(ctx, addUserRole); err != nil {
+		return nil, fmt.Errorf("error preparing query AddUserRole: %w", err)
+	}
+	if q.bumpRoleHolderTokenVersionsStmt, err = db.PrepareContext(ctx, bumpRoleHolderTokenVersions); err != nil {
+		return nil, fmt.Errorf("error preparing query BumpRoleHolderTokenVersions: %w", err)
+	}
+	if q.bumpUserTokenVersionStmt, err = db.PrepareContext(ctx, bumpUserTokenVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query BumpUserTokenVersion: %w", err)
+	}
+	if q.changeUserEmailStmt, err = db.PrepareContext(ctx, changeUserEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query ChangeUserEmail: %w", err)
+	}
+	if q.claimOutboxMessagesStmt, err = db.PrepareContext(ctx, claimOutboxMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimOutboxMessages: %w", err)
+	}
+	if q.claimWebhookDeliveriesStmt, err = db.PrepareContext(ctx, claimWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimWebhookDeliveries: %w", err)
+	}
+	if q.clearUserRolesStmt, err = db.PrepareContext(ctx, clearUserRoles); err != nil {
+		return nil, fmt.Errorf("error preparing query ClearUserRoles: %w", err)
+	}
+	if q.countActiveUsersWithRolesStmt, err = db.PrepareContext(ctx, countActiveUsersWithRoles); err != nil {
+		return nil, fmt.Errorf("error preparing query CountActiveUsersWithRoles: %w", err)
+	}
+	if q.countAuditEventsByUserStmt, err = db.PrepareContext(ctx, countAuditEventsByUser); err != nil {
+		return nil, fmt.Errorf("error preparing query CountAuditEventsByUser: %w", err)
+	}
+	if q.countUnusedBackupCodesStmt, err = db.PrepareContext(ctx, countUnusedBackupCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query CountUnusedBackupCodes: %w", err)
+	}
+	if q.countUsersStmt, err = db.PrepareContext(ctx, countUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query CountUsers: %w", err)
+	}
+	if q.countWebhookDeliveriesStmt, err = db.PrepareContext(ctx, countWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query CountWebhookDeliveries: %w", err)
+	}
+	if q.createAuditEventStmt, err = db.PrepareContext(ctx, createAuditEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAuditEvent: %w", err)
+	}
+	if q.createBackupCodeStmt, err = db.PrepareContext(ctx, createBackupCode); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateBackupCode: %w", err)
+	}
+	if q.createOAuthClientStmt, err = db.PrepareContext(ctx, createOAuthClient); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateOAuthClient: %w", err)
+	}
+	if q.createOneTimeTokenStmt, err = db.PrepareContext(ctx, createOneTimeToken); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateOneTimeToken: %w", err)
+	}
+	if q.createOutboxMessageStmt, err = db.PrepareContext(ctx, createOutboxMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateOutboxMessage: %w", err)
+	}
+	if q.createPermissionStmt, err = db.PrepareContext(ctx, createPermission); err != nil {
+		return nil, fmt.Errorf("error preparing query CreatePermission: %w", err)
+	}
+	if q.createRoleStmt, err = db.PrepareContext(ctx, createRole); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateRole: %w", err)
+	}
+	if q.createSessionStmt, err = db.PrepareContext(ctx, createSession); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSession: %w", err)
+	}
 	if q.createUserStmt, err = db.PrepareContext(ctx, createUser); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateUser: %w", err)
 	}
+	if q.createWebhookSubscriptionStmt, err = db.PrepareContext(ctx, createWebhookSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateWebhookSubscription: %w", err)
+	}
+	if q.deactivateSessionStmt, err = db.PrepareContext(ctx, deactivateSession); err != nil {
+		return nil, fmt.Errorf("error preparing query DeactivateSession: %w", err)
+	}
+	if q.deleteBackupCodesByUserStmt, err = db.PrepareContext(ctx, deleteBackupCodesByUser); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteBackupCodesByUser: %w", err)
+	}
+	if q.deleteOutboxMessagesForUserStmt, err = db.PrepareContext(ctx, deleteOutboxMessagesForUser); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOutboxMessagesForUser: %w", err)
+	}
+	if q.deleteRoleStmt, err = db.PrepareContext(ctx, deleteRole); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteRole: %w", err)
+	}
+	if q.deleteUserStmt, err = db.PrepareContext(ctx, deleteUser); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUser: %w", err)
+	}
+	if q.deleteWebhookSubscriptionStmt, err = db.PrepareContext(ctx, deleteWebhookSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebhookSubscription: %w", err)
+	}
+	if q.disableTOTPStmt, err = db.PrepareContext(ctx, disableTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query DisableTOTP: %w", err)
+	}
+	if q.enableTOTPStmt, err = db.PrepareContext(ctx, enableTOTP); err != nil {
+		return nil, fmt.Errorf("error preparing query EnableTOTP: %w", err)
+	}
+	if q.enqueueWebhookDeliveriesStmt, err = db.PrepareContext(ctx, enqueueWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query EnqueueWebhookDeliveries: %w", err)
+	}
+	if q.getLastAuditHashStmt, err = db.PrepareContext(ctx, getLastAuditHash); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLastAuditHash: %w", err)
+	}
+	if q.getLiveOneTimeTokenStmt, err = db.PrepareContext(ctx, getLiveOneTimeToken); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLiveOneTimeToken: %w", err)
+	}
+	if q.getOAuthClientByClientIDStmt, err = db.PrepareContext(ctx, getOAuthClientByClientID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetOAuthClientByClientID: %w", err)
+	}
+	if q.getPermissionByNameStmt, err = db.PrepareContext(ctx, getPermissionByName); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPermissionByName: %w", err)
+	}
+	if q.getRoleByIDStmt, err = db.PrepareContext(ctx, getRoleByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetRoleByID: %w", err)
+	}
+	if q.getRoleByNameStmt, err = db.PrepareContext(ctx, getRoleByName); err != nil {
+		return nil, fmt.Errorf("error preparing query GetRoleByName: %w", err)
+	}
+	if q.getSessionByIDStmt, err = db.PrepareContext(ctx, getSessionByID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSessionByID: %w", err)
+	}
 	if q.getUserByEmailStmt, err = db.PrepareContext(ctx, getUserByEmail); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUserByEmail: %w", err)
 	}
 	if q.getUserByIDStmt, err = db.PrepareContext(ctx, getUserByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUserByID: %w", err)
 	}
-	if q.getUserByPasswordResetTokenStmt, err = db.PrepareContext(ctx, getUserByPasswordResetToken); err != nil {
-		return nil, fmt.Errorf("error preparing query GetUserByPasswordResetToken: %w", err)
+	if q.getUserTokenVersionStmt, err = db.PrepareContext(ctx, getUserTokenVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserTokenVersion: %w", err)
+	}
+	if q.getWebhookDeliveryStmt, err = db.PrepareContext(ctx, getWebhookDelivery); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhookDelivery: %w", err)
+	}
+	if q.getWebhookSubscriptionStmt, err = db.PrepareContext(ctx, getWebhookSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhookSubscription: %w", err)
+	}
+	if q.hasSessionForDeviceStmt, err = db.PrepareContext(ctx, hasSessionForDevice); err != nil {
+		return nil, fmt.Errorf("error preparing query HasSessionForDevice: %w", err)
 	}
 	if q.incrementFailedLoginAttemptsStmt, err = db.PrepareContext(ctx, incrementFailedLoginAttempts); err != nil {
 		return nil, fmt.Errorf("error preparing query IncrementFailedLoginAttempts: %w", err)
 	}
+	if q.listActiveSessionsByUserStmt, err = db.PrepareContext(ctx, listActiveSessionsByUser); err != nil {
+		return nil, fmt.Errorf("error preparing query ListActiveSessionsByUser: %w", err)
+	}
+	if q.listAuditEventsAfterStmt, err = db.PrepareContext(ctx, listAuditEventsAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAuditEventsAfter: %w", err)
+	}
+	if q.listAuditEventsByUserStmt, err = db.PrepareContext(ctx, listAuditEventsByUser); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAuditEventsByUser: %w", err)
+	}
+	if q.listAuditEventsInRangeStmt, err = db.PrepareContext(ctx, listAuditEventsInRange); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAuditEventsInRange: %w", err)
+	}
+	if q.listPermissionsStmt, err = db.PrepareContext(ctx, listPermissions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListPermissions: %w", err)
+	}
+	if q.listPermissionsByRoleStmt, err = db.PrepareContext(ctx, listPermissionsByRole); err != nil {
+		return nil, fmt.Errorf("error preparing query ListPermissionsByRole: %w", err)
+	}
+	if q.listRolesStmt, err = db.PrepareContext(ctx, listRoles); err != nil {
+		return nil, fmt.Errorf("error preparing query ListRoles: %w", err)
+	}
+	if q.listSessionsByUserStmt, err = db.PrepareContext(ctx, listSessionsByUser); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSessionsByUser: %w", err)
+	}
+	if q.listUserRolesStmt, err = db.PrepareContext(ctx, listUserRoles); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUserRoles: %w", err)
+	}
+	if q.listUsersDueForPurgeStmt, err = db.PrepareContext(ctx, listUsersDueForPurge); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUsersDueForPurge: %w", err)
+	}
+	if q.listWebhookDeliveriesStmt, err = db.PrepareContext(ctx, listWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query ListWebhookDeliveries: %w", err)
+	}
+	if q.listWebhookSubscriptionsStmt, err = db.PrepareContext(ctx, listWebhookSubscriptions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListWebhookSubscriptions: %w", err)
+	}
+	if q.lockAuditChainStmt, err = db.PrepareContext(ctx, lockAuditChain); err != nil {
+		return nil, fmt.Errorf("error preparing query LockAuditChain: %w", err)
+	}
+	if q.lockRoleByNameStmt, err = db.PrepareContext(ctx, lockRoleByName); err != nil {
+		return nil, fmt.Errorf("error preparing query LockRoleByName: %w", err)
+	}
+	if q.markEmailVerifiedStmt, err = db.PrepareContext(ctx, markEmailVerified); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkEmailVerified: %w", err)
+	}
+	if q.markOutboxMessageFailedStmt, err = db.PrepareContext(ctx, markOutboxMessageFailed); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkOutboxMessageFailed: %w", err)
+	}
+	if q.markOutboxMessageSentStmt, err = db.PrepareContext(ctx, markOutboxMessageSent); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkOutboxMessageSent: %w", err)
+	}
+	if q.markWebhookDeliveredStmt, err = db.PrepareContext(ctx, markWebhookDelivered); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkWebhookDelivered: %w", err)
+	}
+	if q.markWebhookFailedStmt, err = db.PrepareContext(ctx, markWebhookFailed); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkWebhookFailed: %w", err)
+	}
+	if q.purgeUserStmt, err = db.PrepareContext(ctx, purgeUser); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeUser: %w", err)
+	}
+	if q.redactAuditEventsByUserStmt, err = db.PrepareContext(ctx, redactAuditEventsByUser); err != nil {
+		return nil, fmt.Errorf("error preparing query RedactAuditEventsByUser: %w", err)
+	}
+	if q.removeRolePermissionStmt, err = db.PrepareContext(ctx, removeRolePermission); err != nil {
+		return nil, fmt.Errorf("error preparing query RemoveRolePermission: %w", err)
+	}
+	if q.removeUserRoleStmt, err = db.PrepareContext(ctx, removeUserRole); err != nil {
+		return nil, fmt.Errorf("error preparing query RemoveUserRole: %w", err)
+	}
+	if q.replayDeadWebhookDeliveriesStmt, err = db.PrepareContext(ctx, replayDeadWebhookDeliveries); err != nil {
+		return nil, fmt.Errorf("error preparing query ReplayDeadWebhookDeliveries: %w", err)
+	}
+	if q.replayWebhookDeliveryStmt, err = db.PrepareContext(ctx, replayWebhookDelivery); err != nil {
+		return nil, fmt.Errorf("error preparing query ReplayWebhookDelivery: %w", err)
+	}
 	if q.resetFailedLoginAttemptsStmt, err = db.PrepareContext(ctx, resetFailedLoginAttempts); err != nil {
 		return nil, fmt.Errorf("error preparing query ResetFailedLoginAttempts: %w", err)
 	}
+	if q.restoreUserStmt, err = db.PrepareContext(ctx, restoreUser); err != nil {
+		return nil, fmt.Errorf("error preparing query RestoreUser: %w", err)
+	}
+	if q.revokeOneTimeTokensStmt, err = db.PrepareContext(ctx, revokeOneTimeTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeOneTimeTokens: %w", err)
+	}
+	if q.searchUsersStmt, err = db.PrepareContext(ctx, searchUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchUsers: %w", err)
+	}
+	if q.setTOTPSecretStmt, err = db.PrepareContext(ctx, setTOTPSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query SetTOTPSecret: %w", err)
+	}
+	if q.setUserDisabledStmt, err = db.PrepareContext(ctx, setUserDisabled); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserDisabled: %w", err)
+	}
+	if q.softDeleteUserStmt, err = db.PrepareContext(ctx, softDeleteUser); err != nil {
+		return nil, fmt.Errorf("error preparing query SoftDeleteUser: %w", err)
+	}
+	if q.touchSessionStmt, err = db.PrepareContext(ctx, touchSession); err != nil {
+		return nil, fmt.Errorf("error preparing query TouchSession: %w", err)
+	}
+	if q.unlockUserStmt, err = db.PrepareContext(ctx, unlockUser); err != nil {
+		return nil, fmt.Errorf("error preparing query UnlockUser: %w", err)
+	}
+	if q.updateAuditEventHashStmt, err = db.PrepareContext(ctx, updateAuditEventHash); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateAuditEventHash: %w", err)
+	}
+	if q.updateRoleStmt, err = db.PrepareContext(ctx, updateRole); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateRole: %w", err)
+	}
 	if q.updateUserStmt, err = db.PrepareContext(ctx, updateUser); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateUser: %w", err)
 	}
-	if q.verifyEmailStmt, err = db.PrepareContext(ctx, verifyEmail); err != nil {
-		return nil, fmt.Errorf("error preparing query VerifyEmail: %w", err)
+	if q.updateWebhookSubscriptionStmt, err = db.PrepareContext(ctx, updateWebhookSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateWebhookSubscription: %w", err)
+	}
+	if q.useBackupCodeStmt, err = db.PrepareContext(ctx, useBackupCode); err != nil {
+		return nil, fmt.Errorf("error preparing query UseBackupCode: %w", err)
+	}
+	if q.useOneTimeTokenStmt, err = db.PrepareContext(ctx, useOneTimeToken); err != nil {
+		return nil, fmt.Errorf("error preparing query UseOneTimeToken: %w", err)
+	}
+	if q.userExistsStmt, err = db.PrepareContext(ctx, userExists); err != nil {
+		return nil, fmt.Errorf("error preparing query UserExists: %w", err)
 	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.addRolePermissionStmt != nil {
+		if cerr := q.addRolePermissionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addRolePermissionStmt: %w", cerr)
+		}
+	}
+	if q.addUserRoleStmt != nil {
+		if cerr := q.addUserRoleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addUserRoleStmt: %w", cerr)
+		}
+	}
+	if q.bumpRoleHolderTokenVersionsStmt != nil {
+		if cerr := q.bumpRoleHolderTokenVersionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing bumpRoleHolderTokenVersionsStmt: %w", cerr)
+		}
+	}
+	if q.bumpUserTokenVersionStmt != nil {
+		if cerr := q.bumpUserTokenVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing bumpUserTokenVersionStmt: %w", cerr)
+		}
+	}
+	if q.changeUserEmailStmt != nil {
+		if cerr := q.changeUserEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing changeUserEmailStmt: %w", cerr)
+		}
+	}
+	if q.claimOutboxMessagesStmt != nil {
+		if cerr := q.claimOutboxMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimOutboxMessagesStmt: %w", cerr)
+		}
+	}
+	if q.claimWebhookDeliveriesStmt != nil {
+		if cerr := q.claimWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.clearUserRolesStmt != nil {
+		if cerr := q.clearUserRolesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing clearUserRolesStmt: %w", cerr)
+		}
+	}
+	if q.countActiveUsersWithRolesStmt != nil {
+		if cerr := q.countActiveUsersWithRolesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countActiveUsersWithRolesStmt: %w", cerr)
+		}
+	}
+	if q.countAuditEventsByUserStmt != nil {
+		if cerr := q.countAuditEventsByUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countAuditEventsByUserStmt: %w", cerr)
+		}
+	}
+	if q.countUnusedBackupCodesStmt != nil {
+		if cerr := q.countUnusedBackupCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countUnusedBackupCodesStmt: %w", cerr)
+		}
+	}
+	if q.countUsersStmt != nil {
+		if cerr := q.countUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countUsersStmt: %w", cerr)
+		}
+	}
+	if q.countWebhookDeliveriesStmt != nil {
+		if cerr := q.countWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.createAuditEventStmt != nil {
+		if cerr := q.createAuditEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAuditEventStmt: %w", cerr)
+		}
+	}
+	if q.createBackupCodeStmt != nil {
+		if cerr := q.createBackupCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createBackupCodeStmt: %w", cerr)
+		}
+	}
+	if q.createOAuthClientStmt != nil {
+		if cerr := q.createOAuthClientStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createOAuthClientStmt: %w", cerr)
+		}
+	}
+	if q.createOneTimeTokenStmt != nil {
+		if cerr := q.createOneTimeTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createOneTimeTokenStmt: %w", cerr)
+		}
+	}
+	if q.createOutboxMessageStmt != nil {
+		if cerr := q.createOutboxMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createOutboxMessageStmt: %w", cerr)
+		}
+	}
+	if q.createPermissionStmt != nil {
+		if cerr := q.createPermissionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createPermissionStmt: %w", cerr)
+		}
+	}
+	if q.createRoleStmt != nil {
+		if cerr := q.createRoleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createRoleStmt: %w", cerr)
+		}
+	}
+	if q.createSessionStmt != nil {
+		if cerr := q.createSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSessionStmt: %w", cerr)
+		}
+	}
 	if q.createUserStmt != nil {
 		if cerr := q.createUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createUserStmt: %w", cerr)
 		}
 	}
+	if q.createWebhookSubscriptionStmt != nil {
+		if cerr := q.createWebhookSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createWebhookSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.deactivateSessionStmt != nil {
+		if cerr := q.deactivateSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deactivateSessionStmt: %w", cerr)
+		}
+	}
+	if q.deleteBackupCodesByUserStmt != nil {
+		if cerr := q.deleteBackupCodesByUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteBackupCodesByUserStmt: %w", cerr)
+		}
+	}
+	if q.deleteOutboxMessagesForUserStmt != nil {
+		if cerr := q.deleteOutboxMessagesForUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOutboxMessagesForUserStmt: %w", cerr)
+		}
+	}
+	if q.deleteRoleStmt != nil {
+		if cerr := q.deleteRoleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteRoleStmt: %w", cerr)
+		}
+	}
+	if q.deleteUserStmt != nil {
+		if cerr := q.deleteUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUserStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebhookSubscriptionStmt != nil {
+		if cerr := q.deleteWebhookSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebhookSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.disableTOTPStmt != nil {
+		if cerr := q.disableTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing disableTOTPStmt: %w", cerr)
+		}
+	}
+	if q.enableTOTPStmt != nil {
+		if cerr := q.enableTOTPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing enableTOTPStmt: %w", cerr)
+		}
+	}
+	if q.enqueueWebhookDeliveriesStmt != nil {
+		if cerr := q.enqueueWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing enqueueWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.getLastAuditHashStmt != nil {
+		if cerr := q.getLastAuditHashStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLastAuditHashStmt: %w", cerr)
+		}
+	}
+	if q.getLiveOneTimeTokenStmt != nil {
+		if cerr := q.getLiveOneTimeTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLiveOneTimeTokenStmt: %w", cerr)
+		}
+	}
+	if q.getOAuthClientByClientIDStmt != nil {
+		if cerr := q.getOAuthClientByClientIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getOAuthClientByClientIDStmt: %w", cerr)
+		}
+	}
+	if q.getPermissionByNameStmt != nil {
+		if cerr := q.getPermissionByNameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPermissionByNameStmt: %w", cerr)
+		}
+	}
+	if q.getRoleByIDStmt != nil {
+		if cerr := q.getRoleByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getRoleByIDStmt: %w", cerr)
+		}
+	}
+	if q.getRoleByNameStmt != nil {
+		if cerr := q.getRoleByNameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getRoleByNameStmt: %w", cerr)
+		}
+	}
+	if q.getSessionByIDStmt != nil {
+		if cerr := q.getSessionByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSessionByIDStmt: %w", cerr)
+		}
+	}
 	if q.getUserByEmailStmt != nil {
 		if cerr := q.getUserByEmailStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getUserByEmailStmt: %w", cerr)
@@ -68,9 +495,24 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getUserByIDStmt: %w", cerr)
 		}
 	}
-	if q.getUserByPasswordResetTokenStmt != nil {
-		if cerr := q.getUserByPasswordResetTokenStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing getUserByPasswordResetTokenStmt: %w", cerr)
+	if q.getUserTokenVersionStmt != nil {
+		if cerr := q.getUserTokenVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserTokenVersionStmt: %w", cerr)
+		}
+	}
+	if q.getWebhookDeliveryStmt != nil {
+		if cerr := q.getWebhookDeliveryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhookDeliveryStmt: %w", cerr)
+		}
+	}
+	if q.getWebhookSubscriptionStmt != nil {
+		if cerr := q.getWebhookSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhookSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.hasSessionForDeviceStmt != nil {
+		if cerr := q.hasSessionForDeviceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing hasSessionForDeviceStmt: %w", cerr)
 		}
 	}
 	if q.incrementFailedLoginAttemptsStmt != nil {
@@ -78,19 +520,209 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing incrementFailedLoginAttemptsStmt: %w", cerr)
 		}
 	}
+	if q.listActiveSessionsByUserStmt != nil {
+		if cerr := q.listActiveSessionsByUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listActiveSessionsByUserStmt: %w", cerr)
+		}
+	}
+	if q.listAuditEventsAfterStmt != nil {
+		if cerr := q.listAuditEventsAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAuditEventsAfterStmt: %w", cerr)
+		}
+	}
+	if q.listAuditEventsByUserStmt != nil {
+		if cerr := q.listAuditEventsByUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAuditEventsByUserStmt: %w", cerr)
+		}
+	}
+	if q.listAuditEventsInRangeStmt != nil {
+		if cerr := q.listAuditEventsInRangeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAuditEventsInRangeStmt: %w", cerr)
+		}
+	}
+	if q.listPermissionsStmt != nil {
+		if cerr := q.listPermissionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listPermissionsStmt: %w", cerr)
+		}
+	}
+	if q.listPermissionsByRoleStmt != nil {
+		if cerr := q.listPermissionsByRoleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listPermissionsByRoleStmt: %w", cerr)
+		}
+	}
+	if q.listRolesStmt != nil {
+		if cerr := q.listRolesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listRolesStmt: %w", cerr)
+		}
+	}
+	if q.listSessionsByUserStmt != nil {
+		if cerr := q.listSessionsByUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSessionsByUserStmt: %w", cerr)
+		}
+	}
+	if q.listUserRolesStmt != nil {
+		if cerr := q.listUserRolesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUserRolesStmt: %w", cerr)
+		}
+	}
+	if q.listUsersDueForPurgeStmt != nil {
+		if cerr := q.listUsersDueForPurgeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUsersDueForPurgeStmt: %w", cerr)
+		}
+	}
+	if q.listWebhookDeliveriesStmt != nil {
+		if cerr := q.listWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.listWebhookSubscriptionsStmt != nil {
+		if cerr := q.listWebhookSubscriptionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listWebhookSubscriptionsStmt: %w", cerr)
+		}
+	}
+	if q.lockAuditChainStmt != nil {
+		if cerr := q.lockAuditChainStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockAuditChainStmt: %w", cerr)
+		}
+	}
+	if q.lockRoleByNameStmt != nil {
+		if cerr := q.lockRoleByNameStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockRoleByNameStmt: %w", cerr)
+		}
+	}
+	if q.markEmailVerifiedStmt != nil {
+		if cerr := q.markEmailVerifiedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markEmailVerifiedStmt: %w", cerr)
+		}
+	}
+	if q.markOutboxMessageFailedStmt != nil {
+		if cerr := q.markOutboxMessageFailedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markOutboxMessageFailedStmt: %w", cerr)
+		}
+	}
+	if q.markOutboxMessageSentStmt != nil {
+		if cerr := q.markOutboxMessageSentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markOutboxMessageSentStmt: %w", cerr)
+		}
+	}
+	if q.markWebhookDeliveredStmt != nil {
+		if cerr := q.markWebhookDeliveredStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markWebhookDeliveredStmt: %w", cerr)
+		}
+	}
+	if q.markWebhookFailedStmt != nil {
+		if cerr := q.markWebhookFailedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markWebhookFailedStmt: %w", cerr)
+		}
+	}
+	if q.purgeUserStmt != nil {
+		if cerr := q.purgeUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeUserStmt: %w", cerr)
+		}
+	}
+	if q.redactAuditEventsByUserStmt != nil {
+		if cerr := q.redactAuditEventsByUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing redactAuditEventsByUserStmt: %w", cerr)
+		}
+	}
+	if q.removeRolePermissionStmt != nil {
+		if cerr := q.removeRolePermissionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing removeRolePermissionStmt: %w", cerr)
+		}
+	}
+	if q.removeUserRoleStmt != nil {
+		if cerr := q.removeUserRoleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing removeUserRoleStmt: %w", cerr)
+		}
+	}
+	if q.replayDeadWebhookDeliveriesStmt != nil {
+		if cerr := q.replayDeadWebhookDeliveriesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing replayDeadWebhookDeliveriesStmt: %w", cerr)
+		}
+	}
+	if q.replayWebhookDeliveryStmt != nil {
+		if cerr := q.replayWebhookDeliveryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing replayWebhookDeliveryStmt: %w", cerr)
+		}
+	}
 	if q.resetFailedLoginAttemptsStmt != nil {
 		if cerr := q.resetFailedLoginAttemptsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing resetFailedLoginAttemptsStmt: %w", cerr)
 		}
 	}
+	if q.restoreUserStmt != nil {
+		if cerr := q.restoreUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing restoreUserStmt: %w", cerr)
+		}
+	}
+	if q.revokeOneTimeTokensStmt != nil {
+		if cerr := q.revokeOneTimeTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeOneTimeTokensStmt: %w", cerr)
+		}
+	}
+	if q.searchUsersStmt != nil {
+		if cerr := q.searchUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchUsersStmt: %w", cerr)
+		}
+	}
+	if q.setTOTPSecretStmt != nil {
+		if cerr := q.setTOTPSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setTOTPSecretStmt: %w", cerr)
+		}
+	}
+	if q.setUserDisabledStmt != nil {
+		if cerr := q.setUserDisabledStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserDisabledStmt: %w", cerr)
+		}
+	}
+	if q.softDeleteUserStmt != nil {
+		if cerr := q.softDeleteUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing softDeleteUserStmt: %w", cerr)
+		}
+	}
+	if q.touchSessionStmt != nil {
+		if cerr := q.touchSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing touchSessionStmt: %w", cerr)
+		}
+	}
+	if q.unlockUserStmt != nil {
+		if cerr := q.unlockUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing unlockUserStmt: %w", cerr)
+		}
+	}
+	if q.updateAuditEventHashStmt != nil {
+		if cerr := q.updateAuditEventHashStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateAuditEventHashStmt: %w", cerr)
+		}
+	}
+	if q.updateRoleStmt != nil {
+		if cerr := q.updateRoleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateRoleStmt: %w", cerr)
+		}
+	}
 	if q.updateUserStmt != nil {
 		if cerr := q.updateUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateUserStmt: %w", cerr)
 		}
 	}
-	if q.verifyEmailStmt != nil {
-		if cerr := q.verifyEmailStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing verifyEmailStmt: %w", cerr)
+	if q.updateWebhookSubscriptionStmt != nil {
+		if cerr := q.updateWebhookSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateWebhookSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.useBackupCodeStmt != nil {
+		if cerr := q.useBackupCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useBackupCodeStmt: %w", cerr)
+		}
+	}
+	if q.useOneTimeTokenStmt != nil {
+		if cerr := q.useOneTimeTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing useOneTimeTokenStmt: %w", cerr)
+		}
+	}
+	if q.userExistsStmt != nil {
+		if cerr := q.userExistsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing userExistsStmt: %w", cerr)
 		}
 	}
 	return err
@@ -132,27 +764,185 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 type Queries struct {
 	db                               DBTX
 	tx                               *sql.Tx
+	addRolePermissionStmt            *sql.Stmt
+	addUserRoleStmt                  *sql.Stmt
+	bumpRoleHolderTokenVersionsStmt  *sql.Stmt
+	bumpUserTokenVersionStmt         *sql.Stmt
+	changeUserEmailStmt              *sql.Stmt
+	claimOutboxMessagesStmt          *sql.Stmt
+	claimWebhookDeliveriesStmt       *sql.Stmt
+	clearUserRolesStmt               *sql.Stmt
+	countActiveUsersWithRolesStmt    *sql.Stmt
+	countAuditEventsByUserStmt       *sql.Stmt
+	countUnusedBackupCodesStmt       *sql.Stmt
+	countUsersStmt                   *sql.Stmt
+	countWebhookDeliveriesStmt       *sql.Stmt
+	createAuditEventStmt             *sql.Stmt
+	createBackupCodeStmt             *sql.Stmt
+	createOAuthClientStmt            *sql.Stmt
+	createOneTimeTokenStmt           *sql.Stmt
+	createOutboxMessageStmt          *sql.Stmt
+	createPermissionStmt             *sql.Stmt
+	createRoleStmt                   *sql.Stmt
+	createSessionStmt                *sql.Stmt
 	createUserStmt                   *sql.Stmt
+	createWebhookSubscriptionStmt    *sql.Stmt
+	deactivateSessionStmt            *sql.Stmt
+	deleteBackupCodesByUserStmt      *sql.Stmt
+	deleteOutboxMessagesForUserStmt  *sql.Stmt
+	deleteRoleStmt                   *sql.Stmt
+	deleteUserStmt                   *sql.Stmt
+	deleteWebhookSubscriptionStmt    *sql.Stmt
+	disableTOTPStmt                  *sql.Stmt
+	enableTOTPStmt                   *sql.Stmt
+	enqueueWebhookDeliveriesStmt     *sql.Stmt
+	getLastAuditHashStmt             *sql.Stmt
+	getLiveOneTimeTokenStmt          *sql.Stmt
+	getOAuthClientByClientIDStmt     *sql.Stmt
+	getPermissionByNameStmt          *sql.Stmt
+	getRoleByIDStmt                  *sql.Stmt
+	getRoleByNameStmt                *sql.Stmt
+	getSessionByIDStmt               *sql.Stmt
 	getUserByEmailStmt               *sql.Stmt
 	getUserByIDStmt                  *sql.Stmt
-	getUserByPasswordResetTokenStmt  *sql.Stmt
+	getUserTokenVersionStmt          *sql.Stmt
+	getWebhookDeliveryStmt           *sql.Stmt
+	getWebhookSubscriptionStmt       *sql.Stmt
+	hasSessionForDeviceStmt          *sql.Stmt
 	incrementFailedLoginAttemptsStmt *sql.Stmt
+	listActiveSessionsByUserStmt     *sql.Stmt
+	listAuditEventsAfterStmt         *sql.Stmt
+	listAuditEventsByUserStmt        *sql.Stmt
+	listAuditEventsInRangeStmt       *sql.Stmt
+	listPermissionsStmt              *sql.Stmt
+	listPermissionsByRoleStmt        *sql.Stmt
+	listRolesStmt                    *sql.Stmt
+	listSessionsByUserStmt           *sql.Stmt
+	listUserRolesStmt                *sql.Stmt
+	listUsersDueForPurgeStmt         *sql.Stmt
+	listWebhookDeliveriesStmt        *sql.Stmt
+	listWebhookSubscriptionsStmt     *sql.Stmt
+	lockAuditChainStmt               *sql.Stmt
+	lockRoleByNameStmt               *sql.Stmt
+	markEmailVerifiedStmt            *sql.Stmt
+	markOutboxMessageFailedStmt      *sql.Stmt
+	markOutboxMessageSentStmt        *sql.Stmt
+	markWebhookDeliveredStmt         *sql.Stmt
+	markWebhookFailedStmt            *sql.Stmt
+	purgeUserStmt                    *sql.Stmt
+	redactAuditEventsByUserStmt      *sql.Stmt
+	removeRolePermissionStmt         *sql.Stmt
+	removeUserRoleStmt               *sql.Stmt
+	replayDeadWebhookDeliveriesStmt  *sql.Stmt
+	replayWebhookDeliveryStmt        *sql.Stmt
 	resetFailedLoginAttemptsStmt     *sql.Stmt
+	restoreUserStmt                  *sql.Stmt
+	revokeOneTimeTokensStmt          *sql.Stmt
+	searchUsersStmt                  *sql.Stmt
+	setTOTPSecretStmt                *sql.Stmt
+	setUserDisabledStmt              *sql.Stmt
+	softDeleteUserStmt               *sql.Stmt
+	touchSessionStmt                 *sql.Stmt
+	unlockUserStmt                   *sql.Stmt
+	updateAuditEventHashStmt         *sql.Stmt
+	updateRoleStmt                   *sql.Stmt
 	updateUserStmt                   *sql.Stmt
-	verifyEmailStmt                  *sql.Stmt
+	updateWebhookSubscriptionStmt    *sql.Stmt
+	useBackupCodeStmt                *sql.Stmt
+	useOneTimeTokenStmt              *sql.Stmt
+	userExistsStmt                   *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
 		db:                               tx,
 		tx:                               tx,
+		addRolePermissionStmt:            q.addRolePermissionStmt,
+		addUserRoleStmt:                  q.addUserRoleStmt,
+		bumpRoleHolderTokenVersionsStmt:  q.bumpRoleHolderTokenVersionsStmt,
+		bumpUserTokenVersionStmt:         q.bumpUserTokenVersionStmt,
+		changeUserEmailStmt:              q.changeUserEmailStmt,
+		claimOutboxMessagesStmt:          q.claimOutboxMessagesStmt,
+		claimWebhookDeliveriesStmt:       q.claimWebhookDeliveriesStmt,
+		clearUserRolesStmt:               q.clearUserRolesStmt,
+		countActiveUsersWithRolesStmt:    q.countActiveUsersWithRolesStmt,
+		countAuditEventsByUserStmt:       q.countAuditEventsByUserStmt,
+		countUnusedBackupCodesStmt:       q.countUnusedBackupCodesStmt,
+		countUsersStmt:                   q.countUsersStmt,
+		countWebhookDeliveriesStmt:       q.countWebhookDeliveriesStmt,
+		createAuditEventStmt:             q.createAuditEventStmt,
+		createBackupCodeStmt:             q.createBackupCodeStmt,
+		createOAuthClientStmt:            q.createOAuthClientStmt,
+		createOneTimeTokenStmt:           q.createOneTimeTokenStmt,
+		createOutboxMessageStmt:          q.createOutboxMessageStmt,
+		createPermissionStmt:             q.createPermissionStmt,
+		createRoleStmt:                   q.createRoleStmt,
+		createSessionStmt:                q.createSessionStmt,
 		createUserStmt:                   q.createUserStmt,
+		createWebhookSubscriptionStmt:    q.createWebhookSubscriptionStmt,
+		deactivateSessionStmt:            q.deactivateSessionStmt,
+		deleteBackupCodesByUserStmt:      q.deleteBackupCodesByUserStmt,
+		deleteOutboxMessagesForUserStmt:  q.deleteOutboxMessagesForUserStmt,
+		deleteRoleStmt:                   q.deleteRoleStmt,
+		deleteUserStmt:                   q.deleteUserStmt,
+		deleteWebhookSubscriptionStmt:    q.deleteWebhookSubscriptionStmt,
+		disableTOTPStmt:                  q.disableTOTPStmt,
+		enableTOTPStmt:                   q.enableTOTPStmt,
+		enqueueWebhookDeliveriesStmt:     q.enqueueWebhookDeliveriesStmt,
+		getLastAuditHashStmt:             q.getLastAuditHashStmt,
+		getLiveOneTimeTokenStmt:          q.getLiveOneTimeTokenStmt,
+		getOAuthClientByClientIDStmt:     q.getOAuthClientByClientIDStmt,
+		getPermissionByNameStmt:          q.getPermissionByNameStmt,
+		getRoleByIDStmt:                  q.getRoleByIDStmt,
+		getRoleByNameStmt:                q.getRoleByNameStmt,
+		getSessionByIDStmt:               q.getSessionByIDStmt,
 		getUserByEmailStmt:               q.getUserByEmailStmt,
 		getUserByIDStmt:                  q.getUserByIDStmt,
-		getUserByPasswordResetTokenStmt:  q.getUserByPasswordResetTokenStmt,
+		getUserTokenVersionStmt:          q.getUserTokenVersionStmt,
+		getWebhookDeliveryStmt:           q.getWebhookDeliveryStmt,
+		getWebhookSubscriptionStmt:       q.getWebhookSubscriptionStmt,
+		hasSessionForDeviceStmt:          q.hasSessionForDeviceStmt,
 		incrementFailedLoginAttemptsStmt: q.incrementFailedLoginAttemptsStmt,
+		listActiveSessionsByUserStmt:     q.listActiveSessionsByUserStmt,
+		listAuditEventsAfterStmt:         q.listAuditEventsAfterStmt,
+		listAuditEventsByUserStmt:        q.listAuditEventsByUserStmt,
+		listAuditEventsInRangeStmt:       q.listAuditEventsInRangeStmt,
+		listPermissionsStmt:              q.listPermissionsStmt,
+		listPermissionsByRoleStmt:        q.listPermissionsByRoleStmt,
+		listRolesStmt:                    q.listRolesStmt,
+		listSessionsByUserStmt:           q.listSessionsByUserStmt,
+		listUserRolesStmt:                q.listUserRolesStmt,
+		listUsersDueForPurgeStmt:         q.listUsersDueForPurgeStmt,
+		listWebhookDeliveriesStmt:        q.listWebhookDeliveriesStmt,
+		listWebhookSubscriptionsStmt:     q.listWebhookSubscriptionsStmt,
+		lockAuditChainStmt:               q.lockAuditChainStmt,
+		lockRoleByNameStmt:               q.lockRoleByNameStmt,
+		markEmailVerifiedStmt:            q.markEmailVerifiedStmt,
+		markOutboxMessageFailedStmt:      q.markOutboxMessageFailedStmt,
+		markOutboxMessageSentStmt:        q.markOutboxMessageSentStmt,
+		markWebhookDeliveredStmt:         q.markWebhookDeliveredStmt,
+		markWebhookFailedStmt:            q.markWebhookFailedStmt,
+		purgeUserStmt:                    q.purgeUserStmt,
+		redactAuditEventsByUserStmt:      q.redactAuditEventsByUserStmt,
+		removeRolePermissionStmt:         q.removeRolePermissionStmt,
+		removeUserRoleStmt:               q.removeUserRoleStmt,
+		replayDeadWebhookDeliveriesStmt:  q.replayDeadWebhookDeliveriesStmt,
+		replayWebhookDeliveryStmt:        q.replayWebhookDeliveryStmt,
 		resetFailedLoginAttemptsStmt:     q.resetFailedLoginAttemptsStmt,
+		restoreUserStmt:                  q.restoreUserStmt,
+		revokeOneTimeTokensStmt:          q.revokeOneTimeTokensStmt,
+		searchUsersStmt:                  q.searchUsersStmt,
+		setTOTPSecretStmt:                q.setTOTPSecretStmt,
+		setUserDisabledStmt:              q.setUserDisabledStmt,
+		softDeleteUserStmt:               q.softDeleteUserStmt,
+		touchSessionStmt:                 q.touchSessionStmt,
+		unlockUserStmt:                   q.unlockUserStmt,
+		updateAuditEventHashStmt:         q.updateAuditEventHashStmt,
+		updateRoleStmt:                   q.updateRoleStmt,
 		updateUserStmt:                   q.updateUserStmt,
-		verifyEmailStmt:                  q.verifyEmailStmt,
+		updateWebhookSubscriptionStmt:    q.updateWebhookSubscriptionStmt,
+		useBackupCodeStmt:                q.useBackupCodeStmt,
+		useOneTimeTokenStmt:              q.useOneTimeTokenStmt,
+		userExistsStmt:                   q.userExistsStmt,
 	}
 }
@@ -6,20 +6,156 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+type AuditEvent struct {
+	ID            int64           `json:"id"`
+	UserID        uuid.NullUUID   `json:"user_id"`
+	EventType     string          `json:"event_type"`
+	IpAddress     sql.NullString  `json:"ip_address"`
+	UserAgent     sql.NullString  `json:"user_agent"`
+	CorrelationID sql.NullString  `json:"correlation_id"`
+	Metadata      json.RawMessage `json:"metadata"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Source        string          `json:"source"`
+	PrevHash      sql.NullString  `json:"prev_hash"`
+	Hash          sql.NullString  `json:"hash"`
+	RedactedAt    sql.NullTime    `json:"redacted_at"`
+	PiiNonce      sql.NullString  `json:"pii_nonce"`
+	PiiHash       sql.NullString  `json:"pii_hash"`
+}
+
+type BackupCode struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	Used      sql.NullBool `json:"used"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
+type OauthClient struct {
+	ID               uuid.UUID      `json:"id"`
+	ClientID         string         `json:"client_id"`
+	ClientSecretHash sql.NullString `json:"client_secret_hash"`
+	Name             string         `json:"name"`
+	RedirectUris     []string       `json:"redirect_uris"`
+	IsActive         bool           `json:"is_active"`
+	CreatedAt        sql.NullTime   `json:"created_at"`
+	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	Scopes           []string       `json:"scopes"`
+	GrantTypes       []string       `json:"grant_types"`
+}
+
+type OneTimeToken struct {
+	ID        uuid.UUID       `json:"id"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Purpose   string          `json:"purpose"`
+	TokenHash string          `json:"token_hash"`
+	Data      json.RawMessage `json:"data"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	UsedAt    sql.NullTime    `json:"used_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type OutboxMessage struct {
+	ID            uuid.UUID       `json:"id"`
+	Kind          string          `json:"kind"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     sql.NullString  `json:"last_error"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ProcessedAt   sql.NullTime    `json:"processed_at"`
+}
+
+type Permission struct {
+	ID          uuid.UUID      `json:"id"`
+	Name        string         `json:"name"`
+	Resource    string         `json:"resource"`
+	Action      string         `json:"action"`
+	Description sql.NullString `json:"description"`
+	CreatedAt   sql.NullTime   `json:"created_at"`
+}
+
+type Role struct {
+	ID           uuid.UUID      `json:"id"`
+	Name         string         `json:"name"`
+	Description  sql.NullString `json:"description"`
+	CreatedAt    sql.NullTime   `json:"created_at"`
+	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	InheritsFrom uuid.NullUUID  `json:"inherits_from"`
+}
+
+type RolePermission struct {
+	RoleID       uuid.UUID `json:"role_id"`
+	PermissionID uuid.UUID `json:"permission_id"`
+}
+
+type Session struct {
+	ID        uuid.UUID      `json:"id"`
+	UserID    uuid.UUID      `json:"user_id"`
+	DeviceID  string         `json:"device_id"`
+	UserAgent sql.NullString `json:"user_agent"`
+	IpAddress sql.NullString `json:"ip_address"`
+	Location  sql.NullString `json:"location"`
+	IsActive  sql.NullBool   `json:"is_active"`
+	LastSeen  sql.NullTime   `json:"last_seen"`
+	CreatedAt sql.NullTime   `json:"created_at"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	TokenHash sql.NullString `json:"token_hash"`
+}
+
 type User struct {
 	ID                  uuid.UUID      `json:"id"`
 	Email               string         `json:"email"`
 	Password            string         `json:"password"`
 	EmailVerified       sql.NullBool   `json:"email_verified"`
-	EmailVerifyToken    sql.NullString `json:"email_verify_token"`
-	PasswordResetToken  sql.NullString `json:"password_reset_token"`
-	PasswordResetExpiry sql.NullTime   `json:"password_reset_expiry"`
 	FailedLoginAttempts sql.NullInt32  `json:"failed_login_attempts"`
 	LockedUntil         sql.NullTime   `json:"locked_until"`
 	CreatedAt           sql.NullTime   `json:"created_at"`
 	UpdatedAt           sql.NullTime   `json:"updated_at"`
+	TotpSecret          sql.NullString `json:"totp_secret"`
+	TotpEnabled         sql.NullBool   `json:"totp_enabled"`
+	TotpVerifiedAt      sql.NullTime   `json:"totp_verified_at"`
+	DisabledAt          sql.NullTime   `json:"disabled_at"`
+	DeletedAt           sql.NullTime   `json:"deleted_at"`
+	TokenVersion        int32          `json:"token_version"`
+}
+
+type UserRole struct {
+	UserID    uuid.UUID    `json:"user_id"`
+	RoleID    uuid.UUID    `json:"role_id"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode sql.NullInt32   `json:"last_status_code"`
+	LastError      sql.NullString  `json:"last_error"`
+	DeliveredAt    sql.NullTime    `json:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+type WebhookSubscription struct {
+	ID        uuid.UUID `json:"id"`
+	Url       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	Events    []string  `json:"events"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
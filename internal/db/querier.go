@@ -12,14 +12,93 @@ import (
 )
 
 type Querier interface {
+	AddRolePermission(ctx context.Context, arg AddRolePermissionParams) error
+	AddUserRole(ctx context.Context, arg AddUserRoleParams) error
+	BumpRoleHolderTokenVersions(ctx context.Context, roleIds []uuid.UUID) error
+	BumpUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error)
+	ChangeUserEmail(ctx context.Context, arg ChangeUserEmailParams) (int64, error)
+	ClaimOutboxMessages(ctx context.Context, arg ClaimOutboxMessagesParams) ([]OutboxMessage, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ClearUserRoles(ctx context.Context, userID uuid.UUID) error
+	CountActiveUsersWithRoles(ctx context.Context, roleIds []uuid.UUID) (int64, error)
+	CountAuditEventsByUser(ctx context.Context, userID uuid.NullUUID) (int64, error)
+	CountUnusedBackupCodes(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountUsers(ctx context.Context, arg CountUsersParams) (int64, error)
+	CountWebhookDeliveries(ctx context.Context, arg CountWebhookDeliveriesParams) (int64, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (int64, error)
+	CreateBackupCode(ctx context.Context, arg CreateBackupCodeParams) error
+	CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error)
+	CreateOneTimeToken(ctx context.Context, arg CreateOneTimeTokenParams) error
+	CreateOutboxMessage(ctx context.Context, arg CreateOutboxMessageParams) error
+	CreatePermission(ctx context.Context, arg CreatePermissionParams) (Permission, error)
+	CreateRole(ctx context.Context, arg CreateRoleParams) (Role, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
+	DeactivateSession(ctx context.Context, id uuid.UUID) error
+	DeleteBackupCodesByUser(ctx context.Context, userID uuid.UUID) error
+	DeleteOutboxMessagesForUser(ctx context.Context, arg DeleteOutboxMessagesForUserParams) error
+	DeleteRole(ctx context.Context, id uuid.UUID) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeleteWebhookSubscription(ctx context.Context, id uuid.UUID) (int64, error)
+	DisableTOTP(ctx context.Context, arg DisableTOTPParams) error
+	EnableTOTP(ctx context.Context, arg EnableTOTPParams) error
+	EnqueueWebhookDeliveries(ctx context.Context, arg EnqueueWebhookDeliveriesParams) (int64, error)
+	GetLastAuditHash(ctx context.Context) (sql.NullString, error)
+	GetLiveOneTimeToken(ctx context.Context, arg GetLiveOneTimeTokenParams) (OneTimeToken, error)
+	GetOAuthClientByClientID(ctx context.Context, clientID string) (OauthClient, error)
+	GetPermissionByName(ctx context.Context, name string) (Permission, error)
+	GetRoleByID(ctx context.Context, id uuid.UUID) (Role, error)
+	GetRoleByName(ctx context.Context, name string) (Role, error)
+	GetSessionByID(ctx context.Context, id uuid.UUID) (Session, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
-	GetUserByPasswordResetToken(ctx context.Context, passwordResetToken sql.NullString) (User, error)
-	IncrementFailedLoginAttempts(ctx context.Context, arg IncrementFailedLoginAttemptsParams) error
+	GetUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error)
+	GetWebhookDelivery(ctx context.Context, id uuid.UUID) (WebhookDelivery, error)
+	GetWebhookSubscription(ctx context.Context, id uuid.UUID) (WebhookSubscription, error)
+	HasSessionForDevice(ctx context.Context, arg HasSessionForDeviceParams) (bool, error)
+	IncrementFailedLoginAttempts(ctx context.Context, arg IncrementFailedLoginAttemptsParams) (IncrementFailedLoginAttemptsRow, error)
+	ListActiveSessionsByUser(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	ListAuditEventsAfter(ctx context.Context, arg ListAuditEventsAfterParams) ([]AuditEvent, error)
+	ListAuditEventsByUser(ctx context.Context, arg ListAuditEventsByUserParams) ([]AuditEvent, error)
+	ListAuditEventsInRange(ctx context.Context, arg ListAuditEventsInRangeParams) ([]AuditEvent, error)
+	ListPermissions(ctx context.Context) ([]Permission, error)
+	ListPermissionsByRole(ctx context.Context, roleID uuid.UUID) ([]Permission, error)
+	ListRoles(ctx context.Context) ([]Role, error)
+	ListSessionsByUser(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	ListUserRoles(ctx context.Context, userID uuid.UUID) ([]Role, error)
+	ListUsersDueForPurge(ctx context.Context, arg ListUsersDueForPurgeParams) ([]uuid.UUID, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	LockAuditChain(ctx context.Context) error
+	LockRoleByName(ctx context.Context, name string) error
+	MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) error
+	MarkOutboxMessageFailed(ctx context.Context, arg MarkOutboxMessageFailedParams) error
+	MarkOutboxMessageSent(ctx context.Context, id uuid.UUID) error
+	MarkWebhookDelivered(ctx context.Context, arg MarkWebhookDeliveredParams) error
+	MarkWebhookFailed(ctx context.Context, arg MarkWebhookFailedParams) error
+	PurgeUser(ctx context.Context, arg PurgeUserParams) (string, error)
+	RedactAuditEventsByUser(ctx context.Context, arg RedactAuditEventsByUserParams) (int64, error)
+	RemoveRolePermission(ctx context.Context, arg RemoveRolePermissionParams) error
+	RemoveUserRole(ctx context.Context, arg RemoveUserRoleParams) error
+	ReplayDeadWebhookDeliveries(ctx context.Context, subscriptionID uuid.UUID) (int64, error)
+	ReplayWebhookDelivery(ctx context.Context, id uuid.UUID) (int64, error)
 	ResetFailedLoginAttempts(ctx context.Context, arg ResetFailedLoginAttemptsParams) error
+	RestoreUser(ctx context.Context, arg RestoreUserParams) error
+	RevokeOneTimeTokens(ctx context.Context, arg RevokeOneTimeTokensParams) error
+	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error)
+	SetTOTPSecret(ctx context.Context, arg SetTOTPSecretParams) error
+	SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) error
+	SoftDeleteUser(ctx context.Context, arg SoftDeleteUserParams) error
+	TouchSession(ctx context.Context, arg TouchSessionParams) error
+	UnlockUser(ctx context.Context, arg UnlockUserParams) error
+	UpdateAuditEventHash(ctx context.Context, arg UpdateAuditEventHashParams) error
+	UpdateRole(ctx context.Context, arg UpdateRoleParams) (Role, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) error
-	VerifyEmail(ctx context.Context, arg VerifyEmailParams) error
+	UpdateWebhookSubscription(ctx context.Context, arg UpdateWebhookSubscriptionParams) (WebhookSubscription, error)
+	UseBackupCode(ctx context.Context, arg UseBackupCodeParams) (int64, error)
+	UseOneTimeToken(ctx context.Context, id uuid.UUID) (int64, error)
+	UserExists(ctx context.Context, id uuid.UUID) (bool, error)
 }
 
 var _ Querier = (*Queries)(nil)
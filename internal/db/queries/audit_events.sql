@@ -0,0 +1,42 @@
+-- name: CreateAuditEvent :one
+INSERT INTO audit_events (user_id, source, event_type, ip_address, user_agent, correlation_id, metadata, created_at, prev_hash, hash, pii_nonce, pii_hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING id;
+
+-- name: LockAuditChain :exec
+SELECT pg_advisory_xact_lock(hashtext('audit_events'));
+
+-- name: GetLastAuditHash :one
+SELECT hash FROM audit_events
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: ListAuditEventsByUser :many
+SELECT * FROM audit_events
+WHERE user_id = $1 AND source = 'account'
+ORDER BY created_at DESC, id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountAuditEventsByUser :one
+SELECT COUNT(*) FROM audit_events WHERE user_id = $1 AND source = 'account';
+
+-- name: ListAuditEventsAfter :many
+SELECT * FROM audit_events
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: ListAuditEventsInRange :many
+SELECT * FROM audit_events
+WHERE created_at >= $1 AND created_at < $2 AND id > $3
+ORDER BY id
+LIMIT $4;
+
+-- name: UpdateAuditEventHash :exec
+UPDATE audit_events SET prev_hash = $2, hash = $3 WHERE id = $1;
+
+-- name: RedactAuditEventsByUser :execrows
+UPDATE audit_events
+SET ip_address = NULL, user_agent = NULL, metadata = '{}', pii_nonce = NULL, redacted_at = sqlc.arg('redacted_at')
+WHERE (user_id = sqlc.arg('user_id') OR (user_id IS NULL AND metadata->>'email_hash' = sqlc.arg('email_hash')::text))
+  AND redacted_at IS NULL;
@@ -0,0 +1,15 @@
+-- name: CreateBackupCode :exec
+INSERT INTO backup_codes (user_id, code_hash)
+VALUES ($1, $2);
+
+-- name: DeleteBackupCodesByUser :exec
+DELETE FROM backup_codes WHERE user_id = $1;
+
+-- name: UseBackupCode :execrows
+UPDATE backup_codes
+SET used = true, used_at = $3
+WHERE user_id = $1 AND code_hash = $2 AND used = false;
+
+-- name: CountUnusedBackupCodes :one
+SELECT COUNT(*) FROM backup_codes
+WHERE user_id = $1 AND used = false;
@@ -0,0 +1,7 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris, scopes, grant_types)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetOAuthClientByClientID :one
+SELECT * FROM oauth_clients WHERE client_id = $1 AND is_active = true;
@@ -0,0 +1,17 @@
+-- name: CreateOneTimeToken :exec
+INSERT INTO one_time_tokens (user_id, purpose, token_hash, data, expires_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetLiveOneTimeToken :one
+SELECT * FROM one_time_tokens
+WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW();
+
+-- name: RevokeOneTimeTokens :exec
+UPDATE one_time_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+
+-- name: UseOneTimeToken :execrows
+UPDATE one_time_tokens
+SET used_at = NOW()
+WHERE id = $1 AND used_at IS NULL AND expires_at > NOW();
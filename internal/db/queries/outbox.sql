@@ -0,0 +1,31 @@
+-- name: CreateOutboxMessage :exec
+INSERT INTO outbox_messages (kind, payload)
+VALUES ($1, $2);
+
+-- name: ClaimOutboxMessages :many
+UPDATE outbox_messages
+SET next_attempt_at = sqlc.arg('lease_until')
+WHERE id IN (
+    SELECT m.id FROM outbox_messages m
+    WHERE m.status = 'pending' AND m.next_attempt_at <= NOW()
+    ORDER BY m.next_attempt_at
+    LIMIT sqlc.arg('batch_size')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxMessageSent :exec
+UPDATE outbox_messages
+SET status = 'sent', attempts = attempts + 1, payload = '{}', last_error = NULL, processed_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteOutboxMessagesForUser :exec
+DELETE FROM outbox_messages
+WHERE status <> 'sent'
+  AND (payload->>'to' = sqlc.arg('email')::text OR payload->'data'->>'user_id' = sqlc.arg('user_id')::text);
+
+-- name: MarkOutboxMessageFailed :exec
+UPDATE outbox_messages
+SET status = $2, attempts = attempts + 1, next_attempt_at = $3, last_error = $4,
+    payload = CASE WHEN $2 = 'dead' THEN payload - 'token' ELSE payload END
+WHERE id = $1;
@@ -0,0 +1,80 @@
+-- name: GetRoleByID :one
+SELECT * FROM roles WHERE id = $1;
+
+-- name: GetRoleByName :one
+SELECT * FROM roles WHERE name = $1;
+
+-- name: ListRoles :many
+SELECT * FROM roles ORDER BY name;
+
+-- name: ListPermissionsByRole :many
+SELECT p.* FROM permissions p
+JOIN role_permissions rp ON rp.permission_id = p.id
+WHERE rp.role_id = $1
+ORDER BY p.name;
+
+-- name: CreateRole :one
+INSERT INTO roles (name, description, inherits_from)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: UpdateRole :one
+UPDATE roles
+SET name = $2, description = $3, inherits_from = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteRole :exec
+DELETE FROM roles WHERE id = $1;
+
+-- name: ListPermissions :many
+SELECT * FROM permissions ORDER BY name;
+
+-- name: GetPermissionByName :one
+SELECT * FROM permissions WHERE name = $1;
+
+-- name: CreatePermission :one
+INSERT INTO permissions (name, resource, action, description)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: AddRolePermission :exec
+INSERT INTO role_permissions (role_id, permission_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveRolePermission :exec
+DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2;
+
+-- name: ListUserRoles :many
+SELECT r.* FROM roles r
+JOIN user_roles ur ON ur.role_id = r.id
+WHERE ur.user_id = $1
+ORDER BY r.name;
+
+-- name: AddUserRole :exec
+INSERT INTO user_roles (user_id, role_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveUserRole :exec
+DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2;
+
+-- name: ClearUserRoles :exec
+DELETE FROM user_roles WHERE user_id = $1;
+
+-- name: CountActiveUsersWithRoles :one
+SELECT COUNT(DISTINCT u.id) FROM users u
+JOIN user_roles ur ON ur.user_id = u.id
+WHERE ur.role_id = ANY(sqlc.arg('role_ids')::uuid[]) AND u.disabled_at IS NULL AND u.deleted_at IS NULL;
+
+-- name: BumpRoleHolderTokenVersions :exec
+UPDATE users
+SET token_version = token_version + 1
+WHERE id IN (SELECT user_id FROM user_roles WHERE role_id = ANY(sqlc.arg('role_ids')::uuid[]));
+
+-- name: LockRoleByName :exec
+SELECT id FROM roles WHERE name = $1 FOR UPDATE;
+
+-- name: UserExists :one
+SELECT EXISTS(SELECT 1 FROM users WHERE id = $1);
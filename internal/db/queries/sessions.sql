@@ -0,0 +1,31 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, device_id, user_agent, ip_address, token_hash, last_seen, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetSessionByID :one
+SELECT * FROM sessions WHERE id = $1;
+
+-- name: HasSessionForDevice :one
+SELECT EXISTS (
+    SELECT 1 FROM sessions
+    WHERE user_id = $1 AND device_id = $2
+) AS seen;
+
+-- name: ListActiveSessionsByUser :many
+SELECT * FROM sessions
+WHERE user_id = $1 AND is_active = true AND expires_at > NOW()
+ORDER BY last_seen DESC;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_seen = $2, expires_at = $3
+WHERE id = $1 AND is_active = true;
+
+-- name: DeactivateSession :exec
+UPDATE sessions SET is_active = false WHERE id = $1;
+
+-- name: ListSessionsByUser :many
+SELECT * FROM sessions
+WHERE user_id = $1
+ORDER BY created_at DESC;
@@ -1,6 +1,6 @@
 -- name: CreateUser :one
-INSERT INTO users (id, email, password, email_verify_token, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO users (id, email, password, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetUserByEmail :one
@@ -11,17 +11,11 @@ SELECT * FROM users WHERE id = $1;
 
 -- name: UpdateUser :exec
 UPDATE users 
-SET email = $2, password = $3, email_verified = $4, email_verify_token = $5,
-    password_reset_token = $6, password_reset_expiry = $7,
-    failed_login_attempts = $8, locked_until = $9, updated_at = $10
+SET email = $2, password = $3, email_verified = $4,
+    failed_login_attempts = $5, locked_until = $6, updated_at = $7
 WHERE id = $1;
 
--- name: VerifyEmail :exec
-UPDATE users 
-SET email_verified = true, email_verify_token = NULL, updated_at = $2
-WHERE email_verify_token = $1 AND email_verified = false;
-
--- name: IncrementFailedLoginAttempts :exec
+-- name: IncrementFailedLoginAttempts :one
 UPDATE users
 SET failed_login_attempts = failed_login_attempts + 1,
     locked_until = CASE
@@ -29,12 +23,109 @@ SET failed_login_attempts = failed_login_attempts + 1,
         ELSE locked_until
     END,
     updated_at = $3
-WHERE email = $1;
+WHERE email = $1
+RETURNING failed_login_attempts, locked_until;
 
 -- name: ResetFailedLoginAttempts :exec
 UPDATE users
 SET failed_login_attempts = 0, locked_until = NULL, updated_at = $2
 WHERE email = $1;
 
--- name: GetUserByPasswordResetToken :one
-SELECT * FROM users WHERE password_reset_token = $1;
\ No newline at end of file
+-- name: SetTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, totp_enabled = false, totp_verified_at = NULL, updated_at = $3
+WHERE id = $1;
+
+-- name: EnableTOTP :exec
+UPDATE users
+SET totp_enabled = true, totp_verified_at = $2, updated_at = $3
+WHERE id = $1 AND totp_secret IS NOT NULL;
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled = false, totp_verified_at = NULL, updated_at = $2
+WHERE id = $1;
+
+-- name: SearchUsers :many
+SELECT u.*, ARRAY(
+    SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+    WHERE ur.user_id = u.id ORDER BY r.name
+)::text[] AS role_names
+FROM users u
+WHERE (sqlc.narg('email')::text IS NULL OR u.email ILIKE '%' || sqlc.narg('email')::text || '%')
+  AND (sqlc.narg('role')::text IS NULL
+       OR EXISTS (SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+                  WHERE ur.user_id = u.id AND r.name = sqlc.narg('role')::text)
+       OR (sqlc.narg('role')::text = 'user' AND NOT EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id)))
+  AND (sqlc.narg('verified')::bool IS NULL OR u.email_verified = sqlc.narg('verified')::bool)
+  AND (sqlc.narg('locked')::bool IS NULL OR (u.locked_until IS NOT NULL AND u.locked_until > NOW()) = sqlc.narg('locked')::bool)
+  AND (sqlc.narg('disabled')::bool IS NULL OR (u.disabled_at IS NOT NULL) = sqlc.narg('disabled')::bool)
+ORDER BY u.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountUsers :one
+SELECT COUNT(*)
+FROM users u
+WHERE (sqlc.narg('email')::text IS NULL OR u.email ILIKE '%' || sqlc.narg('email')::text || '%')
+  AND (sqlc.narg('role')::text IS NULL
+       OR EXISTS (SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+                  WHERE ur.user_id = u.id AND r.name = sqlc.narg('role')::text)
+       OR (sqlc.narg('role')::text = 'user' AND NOT EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id)))
+  AND (sqlc.narg('verified')::bool IS NULL OR u.email_verified = sqlc.narg('verified')::bool)
+  AND (sqlc.narg('locked')::bool IS NULL OR (u.locked_until IS NOT NULL AND u.locked_until > NOW()) = sqlc.narg('locked')::bool)
+  AND (sqlc.narg('disabled')::bool IS NULL OR (u.disabled_at IS NOT NULL) = sqlc.narg('disabled')::bool);
+
+-- name: UnlockUser :exec
+UPDATE users
+SET failed_login_attempts = 0, locked_until = NULL, updated_at = $2
+WHERE id = $1;
+
+-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified = true, updated_at = $2
+WHERE id = $1;
+
+-- name: ChangeUserEmail :execrows
+UPDATE users
+SET email = $2, email_verified = true, updated_at = $3
+WHERE id = $1 AND NOT EXISTS (
+    SELECT 1 FROM users other WHERE other.email = $2 AND other.id <> $1
+);
+
+-- name: SetUserDisabled :exec
+UPDATE users
+SET disabled_at = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
+
+-- name: SoftDeleteUser :exec
+UPDATE users
+SET deleted_at = $2, updated_at = $2
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreUser :exec
+UPDATE users
+SET deleted_at = NULL, updated_at = $2
+WHERE id = $1;
+
+-- name: ListUsersDueForPurge :many
+SELECT id FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at <= $1
+ORDER BY deleted_at
+LIMIT $2;
+
+-- name: PurgeUser :one
+DELETE FROM users
+WHERE id = $1 AND deleted_at IS NOT NULL AND deleted_at <= $2
+RETURNING email;
+
+-- name: GetUserTokenVersion :one
+SELECT token_version FROM users WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: BumpUserTokenVersion :one
+UPDATE users
+SET token_version = token_version + 1
+WHERE id = $1
+RETURNING token_version;
@@ -10,21 +10,91 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const bumpUserTokenVersion = `-- name: BumpUserTokenVersion :one
+UPDATE users
+SET token_version = token_version + 1
+WHERE id = $1
+RETURNING token_version
+`
+
+func (q *Queries) BumpUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.queryRow(ctx, q.bumpUserTokenVersionStmt, bumpUserTokenVersion, id)
+	var token_version int32
+	err := row.Scan(&token_version)
+	return token_version, err
+}
+
+const changeUserEmail = `-- name: ChangeUserEmail :execrows
+UPDATE users
+SET email = $2, email_verified = true, updated_at = $3
+WHERE id = $1 AND NOT EXISTS (
+    SELECT 1 FROM users other WHERE other.email = $2 AND other.id <> $1
+)
+`
+
+type ChangeUserEmailParams struct {
+	ID        uuid.UUID    `json:"id"`
+	Email     string       `json:"email"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+}
+
+func (q *Queries) ChangeUserEmail(ctx context.Context, arg ChangeUserEmailParams) (int64, error) {
+	result, err := q.exec(ctx, q.changeUserEmailStmt, changeUserEmail, arg.ID, arg.Email, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countUsers = `-- name: CountUsers :one
+SELECT COUNT(*)
+FROM users u
+WHERE ($1::text IS NULL OR u.email ILIKE '%' || $1::text || '%')
+  AND ($2::text IS NULL
+       OR EXISTS (SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+                  WHERE ur.user_id = u.id AND r.name = $2::text)
+       OR ($2::text = 'user' AND NOT EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id)))
+  AND ($3::bool IS NULL OR u.email_verified = $3::bool)
+  AND ($4::bool IS NULL OR (u.locked_until IS NOT NULL AND u.locked_until > NOW()) = $4::bool)
+  AND ($5::bool IS NULL OR (u.disabled_at IS NOT NULL) = $5::bool)
+`
+
+type CountUsersParams struct {
+	Email    sql.NullString `json:"email"`
+	Role     sql.NullString `json:"role"`
+	Verified sql.NullBool   `json:"verified"`
+	Locked   sql.NullBool   `json:"locked"`
+	Disabled sql.NullBool   `json:"disabled"`
+}
+
+func (q *Queries) CountUsers(ctx context.Context, arg CountUsersParams) (int64, error) {
+	row := q.queryRow(ctx, q.countUsersStmt, countUsers,
+		arg.Email,
+		arg.Role,
+		arg.Verified,
+		arg.Locked,
+		arg.Disabled,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, email, password, email_verify_token, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, email, password, email_verified, email_verify_token, password_reset_token, password_reset_expiry, failed_login_attempts, locked_until, created_at, updated_at
+INSERT INTO users (id, email, password, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, email, password, email_verified, failed_login_attempts, locked_until, created_at, updated_at, totp_secret, totp_enabled, totp_verified_at, disabled_at, deleted_at, token_version
 `
 
 type CreateUserParams struct {
-	ID               uuid.UUID      `json:"id"`
-	Email            string         `json:"email"`
-	Password         string         `json:"password"`
-	EmailVerifyToken sql.NullString `json:"email_verify_token"`
-	CreatedAt        sql.NullTime   `json:"created_at"`
-	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	ID        uuid.UUID    `json:"id"`
+	Email     string       `json:"email"`
+	Password  string       `json:"password"`
+	CreatedAt sql.NullTime `json:"created_at"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -32,7 +102,6 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.ID,
 		arg.Email,
 		arg.Password,
-		arg.EmailVerifyToken,
 		arg.CreatedAt,
 		arg.UpdatedAt,
 	)
@@ -42,19 +111,64 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.Password,
 		&i.EmailVerified,
-		&i.EmailVerifyToken,
-		&i.PasswordResetToken,
-		&i.PasswordResetExpiry,
 		&i.FailedLoginAttempts,
 		&i.LockedUntil,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpVerifiedAt,
+		&i.DisabledAt,
+		&i.DeletedAt,
+		&i.TokenVersion,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.exec(ctx, q.deleteUserStmt, deleteUser, id)
+	return err
+}
+
+const disableTOTP = `-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled = false, totp_verified_at = NULL, updated_at = $2
+WHERE id = $1
+`
+
+type DisableTOTPParams struct {
+	ID        uuid.UUID    `json:"id"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+}
+
+func (q *Queries) DisableTOTP(ctx context.Context, arg DisableTOTPParams) error {
+	_, err := q.exec(ctx, q.disableTOTPStmt, disableTOTP, arg.ID, arg.UpdatedAt)
+	return err
+}
+
+const enableTOTP = `-- name: EnableTOTP :exec
+UPDATE users
+SET totp_enabled = true, totp_verified_at = $2, updated_at = $3
+WHERE id = $1 AND totp_secret IS NOT NULL
+`
+
+type EnableTOTPParams struct {
+	ID             uuid.UUID    `json:"id"`
+	TotpVerifiedAt sql.NullTime `json:"totp_verified_at"`
+	UpdatedAt      sql.NullTime `json:"updated_at"`
+}
+
+func (q *Queries) EnableTOTP(ctx context.Context, arg EnableTOTPParams) error {
+	_, err := q.exec(ctx, q.enableTOTPStmt, enableTOTP, arg.ID, arg.TotpVerifiedAt, arg.UpdatedAt)
+	return err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password, email_verified, email_verify_token, password_reset_token, password_reset_expiry, failed_login_attempts, locked_until, created_at, updated_at FROM users WHERE email = $1
+SELECT id, email, password, email_verified, failed_login_attempts, locked_until, created_at, updated_at, totp_secret, totp_enabled, totp_verified_at, disabled_at, deleted_at, token_version FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -65,19 +179,22 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.Password,
 		&i.EmailVerified,
-		&i.EmailVerifyToken,
-		&i.PasswordResetToken,
-		&i.PasswordResetExpiry,
 		&i.FailedLoginAttempts,
 		&i.LockedUntil,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpVerifiedAt,
+		&i.DisabledAt,
+		&i.DeletedAt,
+		&i.TokenVersion,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, password, email_verified, email_verify_token, password_reset_token, password_reset_expiry, failed_login_attempts, locked_until, created_at, updated_at FROM users WHERE id = $1
+SELECT id, email, password, email_verified, failed_login_attempts, locked_until, created_at, updated_at, totp_secret, totp_enabled, totp_verified_at, disabled_at, deleted_at, token_version FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -88,41 +205,32 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.Password,
 		&i.EmailVerified,
-		&i.EmailVerifyToken,
-		&i.PasswordResetToken,
-		&i.PasswordResetExpiry,
 		&i.FailedLoginAttempts,
 		&i.LockedUntil,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpVerifiedAt,
+		&i.DisabledAt,
+		&i.DeletedAt,
+		&i.TokenVersion,
 	)
 	return i, err
 }
 
-const getUserByPasswordResetToken = `-- name: GetUserByPasswordResetToken :one
-SELECT id, email, password, email_verified, email_verify_token, password_reset_token, password_reset_expiry, failed_login_attempts, locked_until, created_at, updated_at FROM users WHERE password_reset_token = $1
+const getUserTokenVersion = `-- name: GetUserTokenVersion :one
+SELECT token_version FROM users WHERE id = $1 AND deleted_at IS NULL
 `
 
-func (q *Queries) GetUserByPasswordResetToken(ctx context.Context, passwordResetToken sql.NullString) (User, error) {
-	row := q.queryRow(ctx, q.getUserByPasswordResetTokenStmt, getUserByPasswordResetToken, passwordResetToken)
-	var i User
-	err := row.Scan(
-		&i.ID,
-		&i.Email,
-		&i.Password,
-		&i.EmailVerified,
-		&i.EmailVerifyToken,
-		&i.PasswordResetToken,
-		&i.PasswordResetExpiry,
-		&i.FailedLoginAttempts,
-		&i.LockedUntil,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+func (q *Queries) GetUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.queryRow(ctx, q.getUserTokenVersionStmt, getUserTokenVersion, id)
+	var token_version int32
+	err := row.Scan(&token_version)
+	return token_version, err
 }
 
-const incrementFailedLoginAttempts = `-- name: IncrementFailedLoginAttempts :exec
+const incrementFailedLoginAttempts = `-- name: IncrementFailedLoginAttempts :one
 UPDATE users
 SET failed_login_attempts = failed_login_attempts + 1,
     locked_until = CASE
@@ -131,6 +239,7 @@ SET failed_login_attempts = failed_login_attempts + 1,
     END,
     updated_at = $3
 WHERE email = $1
+RETURNING failed_login_attempts, locked_until
 `
 
 type IncrementFailedLoginAttemptsParams struct {
@@ -139,11 +248,87 @@ type IncrementFailedLoginAttemptsParams struct {
 	UpdatedAt   sql.NullTime `json:"updated_at"`
 }
 
-func (q *Queries) IncrementFailedLoginAttempts(ctx context.Context, arg IncrementFailedLoginAttemptsParams) error {
-	_, err := q.exec(ctx, q.incrementFailedLoginAttemptsStmt, incrementFailedLoginAttempts, arg.Email, arg.LockedUntil, arg.UpdatedAt)
+type IncrementFailedLoginAttemptsRow struct {
+	FailedLoginAttempts sql.NullInt32 `json:"failed_login_attempts"`
+	LockedUntil         sql.NullTime  `json:"locked_until"`
+}
+
+func (q *Queries) IncrementFailedLoginAttempts(ctx context.Context, arg IncrementFailedLoginAttemptsParams) (IncrementFailedLoginAttemptsRow, error) {
+	row := q.queryRow(ctx, q.incrementFailedLoginAttemptsStmt, incrementFailedLoginAttempts, arg.Email, arg.LockedUntil, arg.UpdatedAt)
+	var i IncrementFailedLoginAttemptsRow
+	err := row.Scan(&i.FailedLoginAttempts, &i.LockedUntil)
+	return i, err
+}
+
+const listUsersDueForPurge = `-- name: ListUsersDueForPurge :many
+SELECT id FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at <= $1
+ORDER BY deleted_at
+LIMIT $2
+`
+
+type ListUsersDueForPurgeParams struct {
+	DeletedAt sql.NullTime `json:"deleted_at"`
+	Limit     int32        `json:"limit"`
+}
+
+func (q *Queries) ListUsersDueForPurge(ctx context.Context, arg ListUsersDueForPurgeParams) ([]uuid.UUID, error) {
+	rows, err := q.query(ctx, q.listUsersDueForPurgeStmt, listUsersDueForPurge, arg.DeletedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :exec
+UPDATE users
+SET email_verified = true, updated_at = $2
+WHERE id = $1
+`
+
+type MarkEmailVerifiedParams struct {
+	ID        uuid.UUID    `json:"id"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+}
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) error {
+	_, err := q.exec(ctx, q.markEmailVerifiedStmt, markEmailVerified, arg.ID, arg.UpdatedAt)
 	return err
 }
 
+const purgeUser = `-- name: PurgeUser :one
+DELETE FROM users
+WHERE id = $1 AND deleted_at IS NOT NULL AND deleted_at <= $2
+RETURNING email
+`
+
+type PurgeUserParams struct {
+	ID        uuid.UUID    `json:"id"`
+	DeletedAt sql.NullTime `json:"deleted_at"`
+}
+
+func (q *Queries) PurgeUser(ctx context.Context, arg PurgeUserParams) (string, error) {
+	row := q.queryRow(ctx, q.purgeUserStmt, purgeUser, arg.ID, arg.DeletedAt)
+	var email string
+	err := row.Scan(&email)
+	return email, err
+}
+
 const resetFailedLoginAttempts = `-- name: ResetFailedLoginAttempts :exec
 UPDATE users
 SET failed_login_attempts = 0, locked_until = NULL, updated_at = $2
@@ -160,55 +345,207 @@ func (q *Queries) ResetFailedLoginAttempts(ctx context.Context, arg ResetFailedL
 	return err
 }
 
-const updateUser = `-- name: UpdateUser :exec
-UPDATE users 
-SET email = $2, password = $3, email_verified = $4, email_verify_token = $5,
-    password_reset_token = $6, password_reset_expiry = $7,
-    failed_login_attempts = $8, locked_until = $9, updated_at = $10
+const restoreUser = `-- name: RestoreUser :exec
+UPDATE users
+SET deleted_at = NULL, updated_at = $2
 WHERE id = $1
 `
 
-type UpdateUserParams struct {
+type RestoreUserParams struct {
+	ID        uuid.UUID    `json:"id"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+}
+
+func (q *Queries) RestoreUser(ctx context.Context, arg RestoreUserParams) error {
+	_, err := q.exec(ctx, q.restoreUserStmt, restoreUser, arg.ID, arg.UpdatedAt)
+	return err
+}
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT u.id, u.email, u.password, u.email_verified, u.failed_login_attempts, u.locked_until, u.created_at, u.updated_at, u.totp_secret, u.totp_enabled, u.totp_verified_at, u.disabled_at, u.deleted_at, u.token_version, ARRAY(
+    SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+    WHERE ur.user_id = u.id ORDER BY r.name
+)::text[] AS role_names
+FROM users u
+WHERE ($1::text IS NULL OR u.email ILIKE '%' || $1::text || '%')
+  AND ($2::text IS NULL
+       OR EXISTS (SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+                  WHERE ur.user_id = u.id AND r.name = $2::text)
+       OR ($2::text = 'user' AND NOT EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id)))
+  AND ($3::bool IS NULL OR u.email_verified = $3::bool)
+  AND ($4::bool IS NULL OR (u.locked_until IS NOT NULL AND u.locked_until > NOW()) = $4::bool)
+  AND ($5::bool IS NULL OR (u.disabled_at IS NOT NULL) = $5::bool)
+ORDER BY u.created_at DESC
+LIMIT $6 OFFSET $7
+`
+
+type SearchUsersParams struct {
+	Email    sql.NullString `json:"email"`
+	Role     sql.NullString `json:"role"`
+	Verified sql.NullBool   `json:"verified"`
+	Locked   sql.NullBool   `json:"locked"`
+	Disabled sql.NullBool   `json:"disabled"`
+	Limit    int32          `json:"limit"`
+	Offset   int32          `json:"offset"`
+}
+
+type SearchUsersRow struct {
 	ID                  uuid.UUID      `json:"id"`
 	Email               string         `json:"email"`
 	Password            string         `json:"password"`
 	EmailVerified       sql.NullBool   `json:"email_verified"`
-	EmailVerifyToken    sql.NullString `json:"email_verify_token"`
-	PasswordResetToken  sql.NullString `json:"password_reset_token"`
-	PasswordResetExpiry sql.NullTime   `json:"password_reset_expiry"`
 	FailedLoginAttempts sql.NullInt32  `json:"failed_login_attempts"`
 	LockedUntil         sql.NullTime   `json:"locked_until"`
+	CreatedAt           sql.NullTime   `json:"created_at"`
 	UpdatedAt           sql.NullTime   `json:"updated_at"`
+	TotpSecret          sql.NullString `json:"totp_secret"`
+	TotpEnabled         sql.NullBool   `json:"totp_enabled"`
+	TotpVerifiedAt      sql.NullTime   `json:"totp_verified_at"`
+	DisabledAt          sql.NullTime   `json:"disabled_at"`
+	DeletedAt           sql.NullTime   `json:"deleted_at"`
+	TokenVersion        int32          `json:"token_version"`
+	RoleNames           []string       `json:"role_names"`
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
-	_, err := q.exec(ctx, q.updateUserStmt, updateUser,
-		arg.ID,
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	rows, err := q.query(ctx, q.searchUsersStmt, searchUsers,
 		arg.Email,
-		arg.Password,
-		arg.EmailVerified,
-		arg.EmailVerifyToken,
-		arg.PasswordResetToken,
-		arg.PasswordResetExpiry,
-		arg.FailedLoginAttempts,
-		arg.LockedUntil,
-		arg.UpdatedAt,
+		arg.Role,
+		arg.Verified,
+		arg.Locked,
+		arg.Disabled,
+		arg.Limit,
+		arg.Offset,
 	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchUsersRow
+	for rows.Next() {
+		var i SearchUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Password,
+			&i.EmailVerified,
+			&i.FailedLoginAttempts,
+			&i.LockedUntil,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.TotpSecret,
+			&i.TotpEnabled,
+			&i.TotpVerifiedAt,
+			&i.DisabledAt,
+			&i.DeletedAt,
+			&i.TokenVersion,
+			pq.Array(&i.RoleNames),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setTOTPSecret = `-- name: SetTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, totp_enabled = false, totp_verified_at = NULL, updated_at = $3
+WHERE id = $1
+`
+
+type SetTOTPSecretParams struct {
+	ID         uuid.UUID      `json:"id"`
+	TotpSecret sql.NullString `json:"totp_secret"`
+	UpdatedAt  sql.NullTime   `json:"updated_at"`
+}
+
+func (q *Queries) SetTOTPSecret(ctx context.Context, arg SetTOTPSecretParams) error {
+	_, err := q.exec(ctx, q.setTOTPSecretStmt, setTOTPSecret, arg.ID, arg.TotpSecret, arg.UpdatedAt)
+	return err
+}
+
+const setUserDisabled = `-- name: SetUserDisabled :exec
+UPDATE users
+SET disabled_at = $2, updated_at = $3
+WHERE id = $1
+`
+
+type SetUserDisabledParams struct {
+	ID         uuid.UUID    `json:"id"`
+	DisabledAt sql.NullTime `json:"disabled_at"`
+	UpdatedAt  sql.NullTime `json:"updated_at"`
+}
+
+func (q *Queries) SetUserDisabled(ctx context.Context, arg SetUserDisabledParams) error {
+	_, err := q.exec(ctx, q.setUserDisabledStmt, setUserDisabled, arg.ID, arg.DisabledAt, arg.UpdatedAt)
+	return err
+}
+
+const softDeleteUser = `-- name: SoftDeleteUser :exec
+UPDATE users
+SET deleted_at = $2, updated_at = $2
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type SoftDeleteUserParams struct {
+	ID        uuid.UUID    `json:"id"`
+	DeletedAt sql.NullTime `json:"deleted_at"`
+}
+
+func (q *Queries) SoftDeleteUser(ctx context.Context, arg SoftDeleteUserParams) error {
+	_, err := q.exec(ctx, q.softDeleteUserStmt, softDeleteUser, arg.ID, arg.DeletedAt)
 	return err
 }
 
-const verifyEmail = `-- name: VerifyEmail :exec
+const unlockUser = `-- name: UnlockUser :exec
+UPDATE users
+SET failed_login_attempts = 0, locked_until = NULL, updated_at = $2
+WHERE id = $1
+`
+
+type UnlockUserParams struct {
+	ID        uuid.UUID    `json:"id"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+}
+
+func (q *Queries) UnlockUser(ctx context.Context, arg UnlockUserParams) error {
+	_, err := q.exec(ctx, q.unlockUserStmt, unlockUser, arg.ID, arg.UpdatedAt)
+	return err
+}
+
+const updateUser = `-- name: UpdateUser :exec
 UPDATE users 
-SET email_verified = true, email_verify_token = NULL, updated_at = $2
-WHERE email_verify_token = $1 AND email_verified = false
+SET email = $2, password = $3, email_verified = $4,
+    failed_login_attempts = $5, locked_until = $6, updated_at = $7
+WHERE id = $1
 `
 
-type VerifyEmailParams struct {
-	EmailVerifyToken sql.NullString `json:"email_verify_token"`
-	UpdatedAt        sql.NullTime   `json:"updated_at"`
+type UpdateUserParams struct {
+	ID                  uuid.UUID     `json:"id"`
+	Email               string        `json:"email"`
+	Password            string        `json:"password"`
+	EmailVerified       sql.NullBool  `json:"email_verified"`
+	FailedLoginAttempts sql.NullInt32 `json:"failed_login_attempts"`
+	LockedUntil         sql.NullTime  `json:"locked_until"`
+	UpdatedAt           sql.NullTime  `json:"updated_at"`
 }
 
-func (q *Queries) VerifyEmail(ctx context.Context, arg VerifyEmailParams) error {
-	_, err := q.exec(ctx, q.verifyEmailStmt, verifyEmail, arg.EmailVerifyToken, arg.UpdatedAt)
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
+	_, err := q.exec(ctx, q.updateUserStmt, updateUser,
+		arg.ID,
+		arg.Email,
+		arg.Password,
+		arg.EmailVerified,
+		arg.FailedLoginAttempts,
+		arg.LockedUntil,
+		arg.UpdatedAt,
+	)
 	return err
 }
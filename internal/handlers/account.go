@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AccountHandler struct {
+	authService    *services.AuthService
+	accountService *services.AccountService
+	logger         *utils.Logger
+}
+
+func NewAccountHandler(authService *services.AuthService, accountService *services.AccountService) *AccountHandler {
+	return &AccountHandler{
+		authService:    authService,
+		accountService: accountService,
+		logger:         utils.NewLogger(),
+	}
+}
+
+// Delete schedules the account for deletion and signs out every device.
+// Restoring the account before purge_after cancels it.
+func (h *AccountHandler) Delete(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	purgeAt, err := h.authService.DeleteAccount(userID.(uuid.UUID), req.Password, req.Code, clientInfo(c))
+	if err != nil {
+		switch err {
+		case services.ErrInvalidCredentials:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		case services.ErrInvalidTOTPCode:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+		case services.ErrTooManyRequests:
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case services.ErrLastAdmin:
+			c.JSON(http.StatusConflict, gin.H{"error": "At least one active administrator must remain"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		}
+		return
+	}
+
+	c.SetCookie("access_token", "", -1, "/", "", true, true)
+	c.SetCookie("refresh_token", "", -1, "/", "", true, true)
+	c.SetCookie(services.SessionCookieName, "", -1, "/", "", true, true)
+
+	c.JSON(http.StatusAccepted, models.DeleteAccountResponse{
+		Message:    "Account scheduled for deletion, restore it before then to cancel",
+		PurgeAfter: purgeAt.UTC(),
+	})
+}
+
+// Restore cancels a pending deletion. It is public, since a deleted account
+// can't sign in, and takes the same credentials as a login.
+func (h *AccountHandler) Restore(c *gin.Context) {
+	var req models.RestoreAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.RestoreAccount(&req, clientInfo(c)); err != nil {
+		switch err {
+		case services.ErrInvalidCredentials:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		case services.ErrInvalidTOTPCode:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+		case services.ErrAccountLocked:
+			c.JSON(http.StatusLocked, gin.H{"error": "Account locked due to too many failed attempts"})
+		case services.ErrAccountDisabled:
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore account"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account restored, you can sign in again"})
+}
+
+// Export downloads everything stored about the user as a JSON archive
+func (h *AccountHandler) Export(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	export, err := h.accountService.ExportAccount(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account data"})
+		return
+	}
+
+	filename := fmt.Sprintf("account-%s.json", export.ExportedAt.Format("20060102T150405Z"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, export)
+}
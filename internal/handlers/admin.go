@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminHandler serves user management for administrators. Every route is
+// mounted behind middleware.AdminOnly, so userID is always the acting admin,
+// except the read-only directory routes that machine clients may also call.
+type AdminHandler struct {
+	adminService   *services.AdminService
+	rbacService    *services.RBACService
+	auditService   *services.AuditService
+	webhookService *services.WebhookService
+	logger         *utils.Logger
+}
+
+func NewAdminHandler(adminService *services.AdminService, rbacService *services.RBACService, auditService *services.AuditService, webhookService *services.WebhookService) *AdminHandler {
+	return &AdminHandler{
+		adminService:   adminService,
+		rbacService:    rbacService,
+		auditService:   auditService,
+		webhookService: webhookService,
+		logger:         utils.NewLogger(),
+	}
+}
+
+// audit records an administrative action against a user
+func (h *AdminHandler) audit(c *gin.Context, action string, targetID uuid.UUID, extra map[string]interface{}) {
+	if extra == nil {
+		extra = map[string]interface{}{}
+	}
+	extra["target_user_id"] = targetID
+	h.auditAction(c, action, extra)
+}
+
+// auditAction records an administrative action
+func (h *AdminHandler) auditAction(c *gin.Context, action string, extra map[string]interface{}) {
+	adminID, _ := c.Get("userID")
+	fields := map[string]interface{}{
+		"admin_id": adminID,
+		"ip":       c.ClientIP(),
+	}
+	for k, v := range extra {
+		fields[k] = v
+	}
+	h.logger.LogSecurityEvent(c.Request.Context(), "admin_"+action, fields)
+}
+
+// targetUser parses the :id path parameter and the acting admin's ID
+func targetUser(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return adminID.(uuid.UUID), userID, true
+}
+
+// userIDParam parses the :id path parameter
+func userIDParam(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// adminError writes the response for errors shared by the admin actions
+func adminError(c *gin.Context, err error, fallback string) {
+	switch err {
+	case services.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case services.ErrRoleNotFound:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+	case services.ErrCannotModifySelf:
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot perform this action on your own account"})
+	case services.ErrLastAdmin:
+		c.JSON(http.StatusConflict, gin.H{"error": "At least one active administrator must remain"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	var filter models.UserFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.adminService.SearchUsers(&filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetUser is also served on the directory routes, so it doesn't need an admin ID
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.adminService.GetUser(userID)
+	if err != nil {
+		adminError(c, err, "Failed to load user")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	_, userID, ok := targetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.UnlockUser(userID); err != nil {
+		adminError(c, err, "Failed to unlock user")
+		return
+	}
+
+	h.audit(c, "user_unlocked", userID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
+
+func (h *AdminHandler) VerifyUserEmail(c *gin.Context) {
+	_, userID, ok := targetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.VerifyUserEmail(userID); err != nil {
+		adminError(c, err, "Failed to verify email")
+		return
+	}
+
+	h.audit(c, "email_verified", userID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Email marked as verified"})
+}
+
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	_, userID, ok := targetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.ForcePasswordReset(userID); err != nil {
+		adminError(c, err, "Failed to reset password")
+		return
+	}
+
+	h.audit(c, "password_reset_forced", userID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset email sent and all sessions revoked"})
+}
+
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	h.setDisabled(c, true)
+}
+
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	h.setDisabled(c, false)
+}
+
+func (h *AdminHandler) setDisabled(c *gin.Context, disabled bool) {
+	adminID, userID, ok := targetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.SetUserDisabled(adminID, userID, disabled); err != nil {
+		adminError(c, err, "Failed to update account")
+		return
+	}
+
+	if disabled {
+		h.audit(c, "user_disabled", userID, nil)
+		c.JSON(http.StatusOK, gin.H{"message": "Account disabled"})
+		return
+	}
+	h.audit(c, "user_enabled", userID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Account enabled"})
+}
+
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	adminID, userID, ok := targetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.DeleteUser(adminID, userID); err != nil {
+		adminError(c, err, "Failed to delete user")
+		return
+	}
+
+	h.audit(c, "user_deleted", userID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// SetRoles replaces every role the user holds
+func (h *AdminHandler) SetRoles(c *gin.Context) {
+	adminID, userID, ok := targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.SetRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.adminService.SetRoles(adminID, userID, req.Roles); err != nil {
+		adminError(c, err, "Failed to set roles")
+		return
+	}
+
+	h.audit(c, "roles_set", userID, map[string]interface{}{"roles": req.Roles})
+	c.JSON(http.StatusOK, gin.H{"message": "Roles updated"})
+}
+
+func (h *AdminHandler) GrantRole(c *gin.Context) {
+	adminID, userID, ok := targetUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.adminService.GrantRole(adminID, userID, req.Role); err != nil {
+		adminError(c, err, "Failed to grant role")
+		return
+	}
+
+	h.audit(c, "role_granted", userID, map[string]interface{}{"role": req.Role})
+	c.JSON(http.StatusOK, gin.H{"message": "Role granted"})
+}
+
+func (h *AdminHandler) RevokeRole(c *gin.Context) {
+	adminID, userID, ok := targetUser(c)
+	if !ok {
+		return
+	}
+
+	role := c.Param("role")
+	if err := h.adminService.RevokeRole(adminID, userID, role); err != nil {
+		adminError(c, err, "Failed to revoke role")
+		return
+	}
+
+	h.audit(c, "role_revoked", userID, map[string]interface{}{"role": role})
+	c.JSON(http.StatusOK, gin.H{"message": "Role revoked"})
+}
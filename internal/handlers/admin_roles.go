@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// roleError writes the response for role and permission management errors
+func roleError(c *gin.Context, err error, fallback string) {
+	switch err {
+	case services.ErrRoleNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+	case services.ErrPermissionNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Permission not found"})
+	case services.ErrRoleExists, services.ErrPermissionExists:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case services.ErrBuiltinRole:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case services.ErrInvalidRoleName, services.ErrInvalidPermissionName, services.ErrInvalidInheritance:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// roleID parses the :id path parameter
+func roleID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *AdminHandler) ListRoles(c *gin.Context) {
+	roles, err := h.rbacService.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+func (h *AdminHandler) GetRole(c *gin.Context) {
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	role, err := h.rbacService.GetRole(id)
+	if err != nil {
+		roleError(c, err, "Failed to load role")
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+func (h *AdminHandler) CreateRole(c *gin.Context) {
+	var req models.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.rbacService.CreateRole(&req)
+	if err != nil {
+		roleError(c, err, "Failed to create role")
+		return
+	}
+
+	h.auditAction(c, "role_created", map[string]interface{}{"role_id": role.ID, "role": role.Name})
+	c.JSON(http.StatusCreated, role)
+}
+
+func (h *AdminHandler) UpdateRole(c *gin.Context) {
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.rbacService.UpdateRole(id, &req)
+	if err != nil {
+		roleError(c, err, "Failed to update role")
+		return
+	}
+
+	h.auditAction(c, "role_updated", map[string]interface{}{"role_id": role.ID, "role": role.Name})
+	c.JSON(http.StatusOK, role)
+}
+
+func (h *AdminHandler) DeleteRole(c *gin.Context) {
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.rbacService.DeleteRole(id); err != nil {
+		roleError(c, err, "Failed to delete role")
+		return
+	}
+
+	h.auditAction(c, "role_deleted", map[string]interface{}{"role_id": id})
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
+
+func (h *AdminHandler) AttachPermission(c *gin.Context) {
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	var req models.RolePermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.rbacService.AttachPermission(id, req.Permission); err != nil {
+		roleError(c, err, "Failed to attach permission")
+		return
+	}
+
+	h.auditAction(c, "permission_attached", map[string]interface{}{"role_id": id, "permission": req.Permission})
+	c.JSON(http.StatusOK, gin.H{"message": "Permission attached"})
+}
+
+func (h *AdminHandler) DetachPermission(c *gin.Context) {
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	permission := c.Param("permission")
+	if err := h.rbacService.DetachPermission(id, permission); err != nil {
+		roleError(c, err, "Failed to detach permission")
+		return
+	}
+
+	h.auditAction(c, "permission_detached", map[string]interface{}{"role_id": id, "permission": permission})
+	c.JSON(http.StatusOK, gin.H{"message": "Permission detached"})
+}
+
+// ListRoleUsers pages through the users holding a role
+func (h *AdminHandler) ListRoleUsers(c *gin.Context) {
+	id, ok := roleID(c)
+	if !ok {
+		return
+	}
+
+	var filter models.UserFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.rbacService.GetRole(id)
+	if err != nil {
+		roleError(c, err, "Failed to load role")
+		return
+	}
+
+	filter.Role = role.Name
+	response, err := h.adminService.SearchUsers(&filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *AdminHandler) ListPermissions(c *gin.Context) {
+	permissions, err := h.rbacService.ListPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"permissions": permissions})
+}
+
+func (h *AdminHandler) CreatePermission(c *gin.Context) {
+	var req models.CreatePermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	permission, err := h.rbacService.CreatePermission(&req)
+	if err != nil {
+		roleError(c, err, "Failed to create permission")
+		return
+	}
+
+	h.auditAction(c, "permission_created", map[string]interface{}{"permission": permission.Name})
+	c.JSON(http.StatusCreated, permission)
+}
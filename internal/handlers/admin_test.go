@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/middleware"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// stubDirectoryRepo serves a fixed user list; any other call panics
+type stubDirectoryRepo struct {
+	repository.UserRepositoryInterface
+	users []models.AdminUserView
+}
+
+func (r *stubDirectoryRepo) Search(filter *models.UserFilter) ([]models.AdminUserView, error) {
+	return r.users, nil
+}
+
+func (r *stubDirectoryRepo) Count(filter *models.UserFilter) (int64, error) {
+	return int64(len(r.users)), nil
+}
+
+// startEmptyRedis answers EXISTS with 0 and refuses every other command, which
+// is all token validation needs from a Redis server with nothing revoked
+func startEmptyRedis(t *testing.T) *redis.Client {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveEmptyRedis(conn)
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), Protocol: 2, DisableIndentity: true})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func serveEmptyRedis(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readRESPArray(reader)
+		if err != nil {
+			return
+		}
+		reply := "-ERR unknown command\r\n"
+		if len(args) > 0 && strings.EqualFold(args[0], "EXISTS") {
+			reply = ":0\r\n"
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func readRESPArray(reader *bufio.Reader) ([]string, error) {
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(header, "*") {
+		return nil, fmt.Errorf("unexpected %q", header)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, strings.TrimSuffix(arg, "\r\n"))
+	}
+	return args, nil
+}
+
+func TestAdminHandler_ListUsers_ClientScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{JWTAccessExpiry: time.Minute}
+	tokenService := services.NewTokenService(cfg, services.NewHMACKeySet(strings.Repeat("k", 32)), startEmptyRedis(t), nil)
+	repo := &stubDirectoryRepo{users: []models.AdminUserView{{ID: uuid.New(), Email: "user@example.com"}}}
+	handler := NewAdminHandler(services.NewAdminService(repo, nil, nil), nil, nil, nil)
+
+	// Mounted as in setupRouter; client principals never reach the RBAC service
+	router := gin.New()
+	directory := router.Group("/api/directory")
+	directory.Use(middleware.PrincipalAuth(tokenService, nil))
+	directory.Use(middleware.RequireScope("users:read"), middleware.RequireUserPermission(models.PermissionAdminAccess))
+	directory.GET("/users", handler.ListUsers)
+
+	request := func(scopes ...string) *httptest.ResponseRecorder {
+		token, err := tokenService.GenerateClientToken("report-job", scopes)
+		if err != nil {
+			t.Fatalf("GenerateClientToken: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/directory/users", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("users:read")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.UserListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if response.Total != 1 || len(response.Users) != 1 || response.Users[0].Email != "user@example.com" {
+		t.Fatalf("unexpected response %+v", response)
+	}
+
+	w = request("reports:read")
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Header().Get("WWW-Authenticate"), "insufficient_scope") {
+		t.Fatalf("expected insufficient_scope, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// webhookError writes the response for webhook management errors
+func webhookError(c *gin.Context, err error, fallback string) {
+	switch err {
+	case services.ErrWebhookNotFound, services.ErrWebhookDeliveryMissing:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case services.ErrWebhookNotReplayable:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case services.ErrInvalidWebhookURL, services.ErrUnknownWebhookEvent, services.ErrInvalidDeliveryFilter:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// pathID parses the :id path parameter
+func pathID(c *gin.Context, invalid string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *AdminHandler) ListWebhooks(c *gin.Context) {
+	subscriptions, err := h.webhookService.ListSubscriptions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": subscriptions, "events": models.WebhookEvents})
+}
+
+func (h *AdminHandler) GetWebhook(c *gin.Context) {
+	id, ok := pathID(c, "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	subscription, err := h.webhookService.GetSubscription(id)
+	if err != nil {
+		webhookError(c, err, "Failed to load webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// CreateWebhook registers an endpoint. The response carries the signing
+// secret, which is not shown again.
+func (h *AdminHandler) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription, err := h.webhookService.CreateSubscription(&req)
+	if err != nil {
+		webhookError(c, err, "Failed to create webhook")
+		return
+	}
+
+	h.auditAction(c, "webhook_created", map[string]interface{}{
+		"webhook_id": subscription.ID,
+		"url":        subscription.URL,
+		"events":     subscription.Events,
+	})
+	c.JSON(http.StatusCreated, subscription)
+}
+
+func (h *AdminHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := pathID(c, "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription, err := h.webhookService.UpdateSubscription(id, &req)
+	if err != nil {
+		webhookError(c, err, "Failed to update webhook")
+		return
+	}
+
+	h.auditAction(c, "webhook_updated", map[string]interface{}{
+		"webhook_id": subscription.ID,
+		"url":        subscription.URL,
+		"events":     subscription.Events,
+		"is_active":  subscription.IsActive,
+	})
+	c.JSON(http.StatusOK, subscription)
+}
+
+func (h *AdminHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := pathID(c, "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(id); err != nil {
+		webhookError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	h.auditAction(c, "webhook_deleted", map[string]interface{}{"webhook_id": id})
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// ReplayWebhookFailures requeues every dead delivery for a subscription
+func (h *AdminHandler) ReplayWebhookFailures(c *gin.Context) {
+	id, ok := pathID(c, "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	replayed, err := h.webhookService.ReplayDeadDeliveries(id)
+	if err != nil {
+		webhookError(c, err, "Failed to replay deliveries")
+		return
+	}
+
+	h.auditAction(c, "webhook_replayed", map[string]interface{}{
+		"webhook_id": id,
+		"replayed":   replayed,
+	})
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed})
+}
+
+func (h *AdminHandler) ListWebhookDeliveries(c *gin.Context) {
+	var filter models.WebhookDeliveryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.webhookService.ListDeliveries(&filter)
+	if err != nil {
+		webhookError(c, err, "Failed to load deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *AdminHandler) GetWebhookDelivery(c *gin.Context) {
+	id, ok := pathID(c, "Invalid delivery ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.GetDelivery(id)
+	if err != nil {
+		webhookError(c, err, "Failed to load delivery")
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// ReplayWebhookDelivery requeues one dead delivery
+func (h *AdminHandler) ReplayWebhookDelivery(c *gin.Context) {
+	id, ok := pathID(c, "Invalid delivery ID")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.ReplayDelivery(id); err != nil {
+		webhookError(c, err, "Failed to replay delivery")
+		return
+	}
+
+	h.auditAction(c, "webhook_delivery_replayed", map[string]interface{}{"delivery_id": id})
+	c.JSON(http.StatusOK, gin.H{"message": "Delivery queued"})
+}
@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/Flack74/go-auth-system/internal/config"
 	"github.com/Flack74/go-auth-system/internal/models"
@@ -10,6 +9,7 @@ import (
 	"github.com/Flack74/go-auth-system/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"golang.org/x/text/language"
 )
 
 type AuthHandler struct {
@@ -37,7 +37,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.Register(&req)
+	response, err := h.authService.Register(&req, clientInfo(c))
 	if err != nil {
 		h.logger.LogAuthEvent(c.Request.Context(), "register", req.Email, false)
 		switch err {
@@ -75,9 +75,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Check if client prefers session-based auth
-	useSession := c.GetHeader("X-Auth-Type") == "session"
-	
-	response, err := h.authService.Login(&req)
+	client := clientInfo(c)
+
+	response, err := h.authService.Login(&req, client)
 	if err != nil {
 		h.logger.LogAuthEvent(c.Request.Context(), "login", req.Email, false)
 		switch err {
@@ -91,18 +91,66 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			c.JSON(http.StatusLocked, gin.H{"error": "Account locked due to too many failed attempts"})
 		case services.ErrEmailNotVerified:
 			c.JSON(http.StatusForbidden, gin.H{"error": "Email not verified"})
+		case services.ErrAccountDisabled:
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+		case services.ErrAccountDeleted:
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account scheduled for deletion, restore it to sign in"})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Login failed"})
 		}
 		return
 	}
 
+	if response.MFARequired {
+		// Password accepted, second factor still outstanding: no cookies yet
+		h.logger.LogAuthEvent(c.Request.Context(), "login_mfa_required", req.Email, true)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
 	h.logger.LogAuthEvent(c.Request.Context(), "login", req.Email, true)
-	
-	if useSession {
+	h.writeLoginResponse(c, response)
+}
+
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req models.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authService.LoginMFA(&req, clientInfo(c))
+	if err != nil {
+		switch err {
+		case services.ErrInvalidMFAChallenge:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA challenge"})
+		case services.ErrInvalidTOTPCode:
+			h.logger.LogSecurityEvent(c.Request.Context(), "mfa_login_invalid_code", map[string]interface{}{
+				"ip": c.ClientIP(),
+			})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		case services.ErrAccountLocked:
+			c.JSON(http.StatusLocked, gin.H{"error": "Account locked due to too many failed attempts"})
+		case services.ErrAccountDisabled:
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+		case services.ErrAccountDeleted:
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account scheduled for deletion, restore it to sign in"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Login failed"})
+		}
+		return
+	}
+
+	h.logger.LogAuthEvent(c.Request.Context(), "login_mfa", response.User.Email, true)
+	h.writeLoginResponse(c, response)
+}
+
+// writeLoginResponse sets either the session cookie or the token cookies
+func (h *AuthHandler) writeLoginResponse(c *gin.Context, response *models.AuthResponse) {
+	if response.SessionToken != "" {
 		// Session-based auth: set session cookie only
 		c.SetSameSite(http.SameSiteStrictMode)
-		c.SetCookie("session_id", "session-placeholder", 1800, "/", "", true, true) // 30min
+		c.SetCookie(services.SessionCookieName, response.SessionToken, int(h.config.SessionTimeout.Seconds()), "/", "", true, true)
 		c.JSON(http.StatusOK, gin.H{"message": "Login successful", "user": response.User})
 	} else {
 		// JWT-based auth: set token cookies
@@ -120,9 +168,16 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.RefreshToken(req.RefreshToken)
+	response, err := h.authService.RefreshToken(req.RefreshToken, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		switch err {
+		case services.ErrRefreshTokenReused:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected, please log in again"})
+		case services.ErrRefreshTokenRotated:
+			c.JSON(http.StatusConflict, gin.H{"error": "Refresh token already rotated, retry with the latest token"})
+		default:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		}
 		return
 	}
 
@@ -136,11 +191,10 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		return
 	}
 
-	token, exists := c.Get("token")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token not found"})
-		return
-	}
+	// Cookie sessions carry no bearer token; JWTs carry both
+	token := c.GetString("token")
+	sessionID, _ := c.Get("sessionID")
+	currentSession, _ := sessionID.(uuid.UUID)
 
 	// Get refresh token from cookie or request body
 	refreshToken, _ := c.Cookie("refresh_token")
@@ -152,7 +206,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		refreshToken = req.RefreshToken
 	}
 
-	err := h.authService.Logout(userID.(uuid.UUID), token.(string))
+	err := h.authService.Logout(userID.(uuid.UUID), token, currentSession, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Logout failed"})
 		return
@@ -161,6 +215,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	// Clear cookies
 	c.SetCookie("access_token", "", -1, "/", "", true, true)
 	c.SetCookie("refresh_token", "", -1, "/", "", true, true)
+	c.SetCookie(services.SessionCookieName, "", -1, "/", "", true, true)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
@@ -172,7 +227,7 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 		return
 	}
 
-	err := h.authService.VerifyEmail(token)
+	err := h.authService.VerifyEmail(token, clientInfo(c))
 	if err != nil {
 		c.Redirect(http.StatusFound, h.config.FrontendURL+"/verify-email?token="+token+"&status=error")
 		return
@@ -181,6 +236,28 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	c.Redirect(http.StatusFound, h.config.FrontendURL+"/verify-email?token="+token+"&status=success")
 }
 
+// ResendVerification sends a new verification link. The response doesn't
+// say whether the address belongs to an account.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req models.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.authService.ResendVerification(req.Email, clientInfo(c))
+	if err == services.ErrTooManyRequests {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the address needs verifying, a new link has been sent"})
+}
+
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	var req models.ForgotPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -188,7 +265,7 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 		return
 	}
 
-	err := h.authService.ForgotPassword(req.Email)
+	err := h.authService.ForgotPassword(req.Email, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reset email"})
 		return
@@ -204,55 +281,137 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	err := h.authService.ResetPassword(req.Token, req.Password)
+	err := h.authService.ResetPassword(req.Token, req.Password, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		switch {
+		case err == services.ErrInvalidToken:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+		case isPasswordPolicyError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
 }
 
-func (h *AuthHandler) GetProfile(c *gin.Context) {
+// ChangePassword sets a new password for the signed-in user. Every device is
+// signed out, so the client has to log in again.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	user, err := h.authService.GetUserByID(userID.(uuid.UUID))
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.authService.ChangePassword(userID.(uuid.UUID), req.CurrentPassword, req.NewPassword, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		switch {
+		case err == services.ErrInvalidCredentials:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		case err == services.ErrTooManyRequests:
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case err == services.ErrPasswordUnchanged, isPasswordPolicyError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	c.SetCookie("access_token", "", -1, "/", "", true, true)
+	c.SetCookie("refresh_token", "", -1, "/", "", true, true)
+	c.SetCookie(services.SessionCookieName, "", -1, "/", "", true, true)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed, please sign in again"})
 }
 
-func (h *AuthHandler) Toggle2FA(c *gin.Context) {
+// ChangeEmail sends a confirmation link to the new address. The account
+// keeps its current address until the link is opened.
+func (h *AuthHandler) ChangeEmail(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	var req struct {
-		Enabled bool `json:"enabled"`
-	}
+	var req models.ChangeEmailRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	h.logger.LogSecurityEvent(c.Request.Context(), "2fa_toggle", map[string]interface{}{
-		"user_id": userID,
-		"enabled": req.Enabled,
-	})
+	err := h.authService.RequestEmailChange(userID.(uuid.UUID), req.Email, req.Password, clientInfo(c))
+	if err != nil {
+		switch err {
+		case services.ErrInvalidCredentials:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		case services.ErrTooManyRequests:
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case services.ErrEmailUnchanged:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case services.ErrEmailInUse:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation email"})
+		}
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "2FA settings updated",
-		"enabled": req.Enabled,
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "A confirmation link has been sent to the new address"})
+}
+
+// ConfirmEmailChange is the link emailed to the new address
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	err := h.authService.ConfirmEmailChange(token, clientInfo(c))
+	switch err {
+	case nil:
+		c.Redirect(http.StatusFound, h.config.FrontendURL+"/confirm-email?status=success")
+	case services.ErrEmailInUse:
+		c.Redirect(http.StatusFound, h.config.FrontendURL+"/confirm-email?status=in_use")
+	default:
+		c.Redirect(http.StatusFound, h.config.FrontendURL+"/confirm-email?status=error")
+	}
+}
+
+// isPasswordPolicyError reports whether err is one of the password
+// complexity errors from utils.ValidatePassword
+func isPasswordPolicyError(err error) bool {
+	switch err {
+	case utils.ErrPasswordTooShort, utils.ErrPasswordNoUpper, utils.ErrPasswordNoLower, utils.ErrPasswordNoDigit, utils.ErrPasswordNoSpecial:
+		return true
+	}
+	return false
+}
+
+func (h *AuthHandler) GetProfile(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	user, err := h.authService.GetUserByID(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
 func (h *AuthHandler) GetActivityLog(c *gin.Context) {
@@ -262,33 +421,49 @@ func (h *AuthHandler) GetActivityLog(c *gin.Context) {
 		return
 	}
 
-	activities := []gin.H{
-		{
-			"id":          1,
-			"type":        "login",
-			"description": "Successful login",
-			"timestamp":   time.Now().Format(time.RFC3339),
-			"ip":          c.ClientIP(),
-		},
-		{
-			"id":          2,
-			"type":        "password",
-			"description": "Password changed",
-			"timestamp":   time.Now().Add(-48 * time.Hour).Format(time.RFC3339),
-			"ip":          c.ClientIP(),
-		},
-		{
-			"id":          3,
-			"type":        "email",
-			"description": "Email verified",
-			"timestamp":   time.Now().Add(-120 * time.Hour).Format(time.RFC3339),
-			"ip":          c.ClientIP(),
-		},
+	var query models.ActivityLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authService.ActivityLog(userID.(uuid.UUID), &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load activity log"})
+		return
 	}
 
 	h.logger.LogBusinessEvent(c.Request.Context(), "activity_log_viewed", map[string]interface{}{
 		"user_id": userID,
 	})
 
-	c.JSON(http.StatusOK, gin.H{"activities": activities})
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, response)
+}
+
+// clientInfo collects the device metadata recorded with a login session and
+// in the audit trail
+func clientInfo(c *gin.Context) *models.ClientInfo {
+	deviceID := c.GetHeader("X-Device-ID")
+	if len(deviceID) > 255 {
+		deviceID = deviceID[:255]
+	}
+
+	return &models.ClientInfo{
+		DeviceID:      deviceID,
+		UserAgent:     c.Request.UserAgent(),
+		IPAddress:     c.ClientIP(),
+		CorrelationID: utils.GetCorrelationID(c.Request.Context()),
+		Locale:        preferredLocale(c.GetHeader("Accept-Language")),
+		UseSession:    c.GetHeader("X-Auth-Type") == "session",
+	}
+}
+
+// preferredLocale returns the client's first choice from an Accept-Language
+// header, or "" to use the default
+func preferredLocale(header string) string {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil || len(tags) == 0 || tags[0] == language.Und {
+		return ""
+	}
+	return tags[0].String()
+}
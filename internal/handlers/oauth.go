@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type OAuthHandler struct {
+	oidcService *services.OIDCService
+	logger      *utils.Logger
+	config      *config.Config
+}
+
+func NewOAuthHandler(oidcService *services.OIDCService, cfg *config.Config) *OAuthHandler {
+	return &OAuthHandler{
+		oidcService: oidcService,
+		logger:      utils.NewLogger(),
+		config:      cfg,
+	}
+}
+
+// oauthErrorCode maps service errors onto RFC 6749 error codes
+func oauthErrorCode(err error) (int, string) {
+	switch err {
+	case services.ErrInvalidClient:
+		return http.StatusUnauthorized, "invalid_client"
+	case services.ErrInvalidGrant:
+		return http.StatusBadRequest, "invalid_grant"
+	case services.ErrUnsupportedGrantType:
+		return http.StatusBadRequest, "unsupported_grant_type"
+	case services.ErrUnauthorizedClient:
+		return http.StatusBadRequest, "unauthorized_client"
+	case services.ErrUnsupportedResponseType:
+		return http.StatusBadRequest, "unsupported_response_type"
+	case services.ErrInvalidScope:
+		return http.StatusBadRequest, "invalid_scope"
+	case services.ErrPKCERequired, services.ErrInvalidRedirectURI:
+		return http.StatusBadRequest, "invalid_request"
+	}
+	return http.StatusInternalServerError, "server_error"
+}
+
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var req models.AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+
+	// Until the redirect URI is verified, errors go to the user agent, not the client
+	client, err := h.oidcService.ValidateAuthorizeRequest(&req)
+	if err != nil {
+		status, code := oauthErrorCode(err)
+		c.JSON(status, gin.H{"error": code, "error_description": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		// Send the browser to log in, then back here to finish the flow
+		returnTo := strings.TrimRight(h.config.OIDCIssuer, "/") + c.Request.URL.RequestURI()
+		c.Redirect(http.StatusFound, h.config.FrontendURL+"/login?return_to="+url.QueryEscape(returnTo))
+		return
+	}
+
+	redirect, _ := url.Parse(req.RedirectURI)
+	query := redirect.Query()
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+
+	code, err := h.oidcService.Authorize(client, &req, userID.(uuid.UUID))
+	if err != nil {
+		_, errorCode := oauthErrorCode(err)
+		query.Set("error", errorCode)
+		query.Set("error_description", err.Error())
+		redirect.RawQuery = query.Encode()
+		c.Redirect(http.StatusFound, redirect.String())
+		return
+	}
+
+	h.logger.LogSecurityEvent(c.Request.Context(), "oauth_authorized", map[string]interface{}{
+		"user_id":   userID,
+		"client_id": client.ClientID,
+		"ip":        c.ClientIP(),
+	})
+
+	query.Set("code", code)
+	redirect.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, redirect.String())
+}
+
+func (h *OAuthHandler) Token(c *gin.Context) {
+	// Token responses must never be cached (RFC 6749 section 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req models.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+
+	// client_secret_basic takes precedence over credentials in the body
+	if clientID, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID = clientID
+		req.ClientSecret = secret
+	}
+
+	response, err := h.oidcService.Token(&req)
+	if err != nil {
+		status, code := oauthErrorCode(err)
+		if code == "invalid_client" || code == "invalid_grant" || code == "unauthorized_client" {
+			h.logger.LogSecurityEvent(c.Request.Context(), "oauth_token_rejected", map[string]interface{}{
+				"client_id":  req.ClientID,
+				"grant_type": req.GrantType,
+				"error":      code,
+				"ip":         c.ClientIP(),
+			})
+		}
+		if status == http.StatusUnauthorized {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		c.JSON(status, gin.H{"error": code, "error_description": err.Error()})
+		return
+	}
+
+	if req.GrantType == "client_credentials" {
+		h.logger.LogSecurityEvent(c.Request.Context(), "oauth_client_token_issued", map[string]interface{}{
+			"client_id": req.ClientID,
+			"scope":     response.Scope,
+			"ip":        c.ClientIP(),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *OAuthHandler) UserInfo(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+
+	info, err := h.oidcService.UserInfo(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
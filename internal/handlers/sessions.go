@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SessionHandler struct {
+	sessionService *services.SessionService
+	logger         *utils.Logger
+}
+
+func NewSessionHandler(sessionService *services.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		logger:         utils.NewLogger(),
+	}
+}
+
+// currentSessionID returns the session the request authenticated with, if any
+func currentSessionID(c *gin.Context) uuid.UUID {
+	sessionID, _ := c.Get("sessionID")
+	id, _ := sessionID.(uuid.UUID)
+	return id
+}
+
+func (h *SessionHandler) List(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	sessions, err := h.sessionService.ListSessions(userID.(uuid.UUID), currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ActiveSessionsResponse{
+		Sessions: sessions,
+		Total:    len(sessions),
+	})
+}
+
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	err = h.sessionService.RevokeUserSession(userID.(uuid.UUID), sessionID)
+	if err != nil {
+		switch err {
+		case services.ErrSessionNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		}
+		return
+	}
+
+	h.logger.LogSecurityEvent(c.Request.Context(), "session_revoked", map[string]interface{}{
+		"user_id":    userID,
+		"session_id": sessionID,
+		"ip":         c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// RevokeOthers signs the user out of every device except the current one
+func (h *SessionHandler) RevokeOthers(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	current := currentSessionID(c)
+	if current == uuid.Nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Current session unknown, please log in again"})
+		return
+	}
+
+	revoked, err := h.sessionService.RevokeOtherSessions(userID.(uuid.UUID), current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	h.logger.LogSecurityEvent(c.Request.Context(), "sessions_revoked_others", map[string]interface{}{
+		"user_id": userID,
+		"revoked": revoked,
+		"ip":      c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Signed out of other sessions", "revoked": revoked})
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TwoFactorHandler struct {
+	totpService *services.TOTPService
+	authService *services.AuthService
+	logger      *utils.Logger
+}
+
+func NewTwoFactorHandler(totpService *services.TOTPService, authService *services.AuthService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		totpService: totpService,
+		authService: authService,
+		logger:      utils.NewLogger(),
+	}
+}
+
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	response, err := h.totpService.Setup(userID.(uuid.UUID))
+	if err != nil {
+		switch err {
+		case services.ErrTOTPAlreadyEnabled:
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication already enabled"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start 2FA setup"})
+		}
+		return
+	}
+
+	h.logger.LogSecurityEvent(c.Request.Context(), "2fa_setup_started", map[string]interface{}{
+		"user_id": userID,
+		"ip":      c.ClientIP(),
+	})
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.TOTPVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.totpService.Enable(userID.(uuid.UUID), req.Code)
+	if err != nil {
+		switch err {
+		case services.ErrTOTPAlreadyEnabled:
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication already enabled"})
+		case services.ErrTOTPNotSetup:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor setup has not been started"})
+		case services.ErrInvalidTOTPCode:
+			h.logger.LogSecurityEvent(c.Request.Context(), "2fa_enable_invalid_code", map[string]interface{}{
+				"user_id": userID,
+				"ip":      c.ClientIP(),
+			})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification code"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable 2FA"})
+		}
+		return
+	}
+
+	h.logger.LogSecurityEvent(c.Request.Context(), "2fa_enabled", map[string]interface{}{
+		"user_id": userID,
+		"ip":      c.ClientIP(),
+	})
+	h.authService.RecordEvent(userID.(uuid.UUID), models.Audit2FAEnabled, clientInfo(c), nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication enabled",
+		"enabled": true,
+	})
+}
+
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.TOTPDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.totpService.Disable(userID.(uuid.UUID), req.Password)
+	if err != nil {
+		switch err {
+		case services.ErrTOTPNotEnabled:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		case services.ErrInvalidCredentials:
+			h.logger.LogSecurityEvent(c.Request.Context(), "2fa_disable_invalid_password", map[string]interface{}{
+				"user_id": userID,
+				"ip":      c.ClientIP(),
+			})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable 2FA"})
+		}
+		return
+	}
+
+	h.logger.LogSecurityEvent(c.Request.Context(), "2fa_disabled", map[string]interface{}{
+		"user_id": userID,
+		"ip":      c.ClientIP(),
+	})
+	h.authService.RecordEvent(userID.(uuid.UUID), models.Audit2FADisabled, clientInfo(c), nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled",
+		"enabled": false,
+	})
+}
+
+func (h *TwoFactorHandler) BackupCodesStatus(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	remaining, err := h.totpService.BackupCodesRemaining(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load backup codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.BackupCodeStatusResponse{Remaining: remaining})
+}
+
+func (h *TwoFactorHandler) RegenerateBackupCodes(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.TOTPVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.totpService.RegenerateBackupCodes(userID.(uuid.UUID), req.Code)
+	if err != nil {
+		switch err {
+		case services.ErrTOTPNotEnabled:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		case services.ErrInvalidTOTPCode:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification code"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate backup codes"})
+		}
+		return
+	}
+
+	h.logger.LogSecurityEvent(c.Request.Context(), "2fa_backup_codes_regenerated", map[string]interface{}{
+		"user_id": userID,
+		"ip":      c.ClientIP(),
+	})
+	h.authService.RecordEvent(userID.(uuid.UUID), models.Audit2FABackupCodesRegenerated, clientInfo(c), nil)
+
+	c.JSON(http.StatusOK, models.BackupCodesResponse{BackupCodes: codes})
+}
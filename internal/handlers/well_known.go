@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Flack74/go-auth-system/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type WellKnownHandler struct {
+	tokenService *services.TokenService
+	oidcService  *services.OIDCService
+}
+
+func NewWellKnownHandler(tokenService *services.TokenService, oidcService *services.OIDCService) *WellKnownHandler {
+	return &WellKnownHandler{
+		tokenService: tokenService,
+		oidcService:  oidcService,
+	}
+}
+
+// OpenIDConfiguration serves the OIDC discovery document
+func (h *WellKnownHandler) OpenIDConfiguration(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.oidcService.Discovery())
+}
+
+// JWKS publishes the public keys that verify our tokens. In HS256 mode the
+// set is empty: the shared secret is never exposed.
+func (h *WellKnownHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokenService.JWKS())
+}
@@ -1,46 +1,219 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/Flack74/go-auth-system/internal/models"
 	"github.com/Flack74/go-auth-system/internal/services"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
-func Auth(tokenService *services.TokenService) gin.HandlerFunc {
+// bearerToken extracts the token from the Authorization header, aborting
+// the request when it is missing or malformed
+func bearerToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		c.Abort()
+		return "", false
+	}
+
+	// XSS protection: sanitize authorization header
+	if len(authHeader) > 1000 || strings.Contains(authHeader, "<") || strings.Contains(authHeader, ">") {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header"})
+		c.Abort()
+		return "", false
+	}
+
+	tokenParts := strings.Split(authHeader, " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		c.Abort()
+		return "", false
+	}
+
+	return tokenParts[1], true
+}
+
+// loadPermissions sets the userRoles and userPermissions keys read by the
+// RBAC middleware, aborting the request if they cannot be resolved
+func loadPermissions(c *gin.Context, rbacService *services.RBACService, userID uuid.UUID) bool {
+	effective, err := rbacService.UserPermissions(userID)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+		}
+		c.Abort()
+		return false
+	}
+
+	c.Set("userRoles", effective.Roles)
+	c.Set("userPermissions", effective.Permissions)
+	return true
+}
+
+func Auth(tokenService *services.TokenService, rbacService *services.RBACService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		token, ok := bearerToken(c)
+		if !ok {
+			return
+		}
+
+		claims, err := tokenService.ValidateAccessToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
-		
-		// XSS protection: sanitize authorization header
-		if len(authHeader) > 1000 || strings.Contains(authHeader, "<") || strings.Contains(authHeader, ">") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header"})
+
+		if !loadPermissions(c, rbacService, claims.UserID) {
+			return
+		}
+
+		c.Set("userID", claims.UserID)
+		c.Set("token", token)
+		if claims.SessionID != uuid.Nil {
+			c.Set("sessionID", claims.SessionID)
+		}
+		c.Next()
+	}
+}
+
+// PrincipalAuth is the variant of Auth for endpoints that serve both users
+// and OAuth clients. It sets "principal" for every request, plus the same
+// keys as Auth for users and "clientID" for tokens held by a client.
+// Combine with RequireScope.
+func PrincipalAuth(tokenService *services.TokenService, rbacService *services.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			return
+		}
+
+		claims, err := tokenService.ValidatePrincipalToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		principal := claims.Principal()
+		c.Set("principal", principal)
+		c.Set("token", token)
+		if principal.IsScoped() {
+			c.Set("clientID", principal.ClientID)
+		}
+		if !principal.IsClient() {
+			if !loadPermissions(c, rbacService, principal.UserID) {
+				return
+			}
+			c.Set("userID", principal.UserID)
+			if claims.SessionID != uuid.Nil {
+				c.Set("sessionID", claims.SessionID)
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireScope checks that a token held by an OAuth client, for itself or
+// for a user, was granted every listed scope. Users' own tokens are
+// authorised by their role instead and pass through.
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("principal")
+		principal, ok := value.(*models.Principal)
+		if !exists || !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			c.Abort()
+			return
+		}
+
+		if principal.IsScoped() {
+			for _, scope := range scopes {
+				if !principal.HasScope(scope) {
+					c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+					c.JSON(http.StatusForbidden, gin.H{"error": "insufficient_scope"})
+					c.Abort()
+					return
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// SessionAuth authenticates requests carrying a server-side session cookie
+func SessionAuth(sessionService *services.SessionService, rbacService *services.RBACService, sessionTimeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := c.Cookie(services.SessionCookieName)
+		if err != nil || token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session required"})
 			c.Abort()
 			return
 		}
 
-		token := tokenParts[1]
-		userID, err := tokenService.ValidateAccessToken(token)
+		userID, sessionID, err := sessionService.ValidateSession(token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired session"})
 			c.Abort()
 			return
 		}
 
+		if !loadPermissions(c, rbacService, userID) {
+			return
+		}
+
+		// Sliding expiry: keep the cookie lifetime in step with the server-side TTL
+		c.SetSameSite(http.SameSiteStrictMode)
+		c.SetCookie(services.SessionCookieName, token, int(sessionTimeout.Seconds()), "/", "", true, true)
+
 		c.Set("userID", userID)
-		c.Set("token", token)
+		c.Set("sessionID", sessionID)
+		c.Next()
+	}
+}
+
+// AuthOrSession accepts a bearer token, falling back to the session cookie
+// when no Authorization header is sent
+func AuthOrSession(tokenService *services.TokenService, sessionService *services.SessionService, rbacService *services.RBACService, sessionTimeout time.Duration) gin.HandlerFunc {
+	bearerAuth := Auth(tokenService, rbacService)
+	sessionAuth := SessionAuth(sessionService, rbacService, sessionTimeout)
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if _, err := c.Cookie(services.SessionCookieName); err == nil {
+				sessionAuth(c)
+				return
+			}
+		}
+		bearerAuth(c)
+	}
+}
+
+// OptionalAuth identifies the user from the session cookie or access token
+// cookie when present, but lets anonymous requests through. Used by browser
+// flows such as /oauth/authorize that redirect to login themselves.
+func OptionalAuth(tokenService *services.TokenService, sessionService *services.SessionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token, err := c.Cookie(services.SessionCookieName); err == nil && token != "" {
+			if userID, sessionID, err := sessionService.ValidateSession(token); err == nil {
+				c.Set("userID", userID)
+				c.Set("sessionID", sessionID)
+			}
+		} else if token, err := c.Cookie("access_token"); err == nil && token != "" {
+			if claims, err := tokenService.ValidateAccessToken(token); err == nil {
+				c.Set("userID", claims.UserID)
+				c.Set("sessionID", claims.SessionID)
+			}
+		}
 		c.Next()
 	}
-}
\ No newline at end of file
+}
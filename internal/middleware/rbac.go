@@ -30,41 +30,45 @@ func RequirePermission(permission string) gin.HandlerFunc {
 	}
 }
 
-// RequireRole middleware checks if user has required role
-func RequireRole(role string) gin.HandlerFunc {
+// RequireUserPermission is RequirePermission for routes behind PrincipalAuth.
+// Machine clients hold scopes rather than permissions and pass through, so
+// pair it with RequireScope.
+func RequireUserPermission(permission string) gin.HandlerFunc {
+	requirePermission := RequirePermission(permission)
 	return func(c *gin.Context) {
-		userRole, exists := c.Get("userRole")
-		if !exists {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-			c.Abort()
-			return
-		}
-
-		if userRole.(string) != role {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient role"})
-			c.Abort()
-			return
+		if value, exists := c.Get("principal"); exists {
+			if principal, ok := value.(*models.Principal); ok && principal.IsClient() {
+				c.Next()
+				return
+			}
 		}
-
-		c.Next()
+		requirePermission(c)
 	}
 }
 
-// RequireAnyRole middleware checks if user has any of the required roles
+// RequireRole middleware checks if user has required role, either directly
+// or through role inheritance
+func RequireRole(role string) gin.HandlerFunc {
+	return RequireAnyRole(role)
+}
+
+// RequireAnyRole middleware checks if user has any of the required roles,
+// either directly or through role inheritance
 func RequireAnyRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, exists := c.Get("userRole")
+		userRoles, exists := c.Get("userRoles")
 		if !exists {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 			c.Abort()
 			return
 		}
 
-		currentRole := userRole.(string)
-		for _, role := range roles {
-			if currentRole == role {
-				c.Next()
-				return
+		for _, current := range userRoles.([]string) {
+			for _, role := range roles {
+				if current == role {
+					c.Next()
+					return
+				}
 			}
 		}
 
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// DeleteAccountRequest confirms an account deletion. Code is the 2FA or
+// backup code, required when two-factor authentication is enabled.
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"`
+}
+
+// RestoreAccountRequest cancels a pending deletion with the credentials a
+// login would take. Code is required when two-factor authentication is enabled.
+type RestoreAccountRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"`
+}
+
+type DeleteAccountResponse struct {
+	Message    string    `json:"message"`
+	PurgeAfter time.Time `json:"purge_after"`
+}
+
+// AccountExport is everything stored about a user, as handed to them on request
+type AccountExport struct {
+	ExportedAt time.Time       `json:"exported_at"`
+	Profile    *User           `json:"profile"`
+	Sessions   []Session       `json:"sessions"`
+	Activity   []ActivityEntry `json:"activity"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserFilter narrows the admin user search; unset fields match everything
+type UserFilter struct {
+	Email    string `form:"email"`
+	Role     string `form:"role"`
+	Verified *bool  `form:"verified"`
+	Locked   *bool  `form:"locked"`
+	Disabled *bool  `form:"disabled"`
+	Page     int    `form:"page"`
+	PerPage  int    `form:"per_page"`
+}
+
+// AdminUserView is a user as administrators see it, including account state
+// hidden from the user-facing representation
+type AdminUserView struct {
+	ID                  uuid.UUID  `json:"id"`
+	Email               string     `json:"email"`
+	Roles               []string   `json:"roles"`
+	EmailVerified       bool       `json:"email_verified"`
+	TOTPEnabled         bool       `json:"totp_enabled"`
+	FailedLoginAttempts int        `json:"failed_login_attempts"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DeletedAt           *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+type UserListResponse struct {
+	Users   []AdminUserView `json:"users"`
+	Total   int64           `json:"total"`
+	Page    int             `json:"page"`
+	PerPage int             `json:"per_page"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// SetRolesRequest replaces every role a user holds; an empty list leaves
+// them with the default user role
+type SetRolesRequest struct {
+	Roles []string `json:"roles" binding:"required"`
+}
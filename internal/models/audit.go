@@ -0,0 +1,298 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit event types recorded in a user's security history
+const (
+	AuditRegister                  = "register"
+	AuditLoginSucceeded            = "login_succeeded"
+	AuditLoginFailed               = "login_failed"
+	AuditAccountLocked             = "account_locked"
+	AuditLogout                    = "logout"
+	AuditTokenRefreshed            = "token_refreshed"
+	AuditRefreshTokenReused        = "refresh_token_reused"
+	AuditPasswordResetRequested    = "password_reset_requested"
+	AuditPasswordResetCompleted    = "password_reset_completed"
+	AuditPasswordChanged           = "password_changed"
+	AuditEmailVerified             = "email_verified"
+	AuditVerificationResent        = "verification_resent"
+	AuditEmailChangeRequested      = "email_change_requested"
+	AuditEmailChanged              = "email_changed"
+	Audit2FAEnabled                = "2fa_enabled"
+	Audit2FADisabled               = "2fa_disabled"
+	Audit2FABackupCodesRegenerated = "2fa_backup_codes_regenerated"
+	AuditAccountDeletionRequested  = "account_deletion_requested"
+	AuditAccountRestored           = "account_restored"
+	AuditAccountPurged             = "account_purged"
+)
+
+// Audit event sources. Account events make up a user's activity log; the
+// auth and security sources hold events mirrored from the application log.
+const (
+	AuditSourceAccount  = "account"
+	AuditSourceAuth     = "auth"
+	AuditSourceSecurity = "security"
+)
+
+// AuditEvent is one entry in the audit trail. UserID is uuid.Nil when the
+// event cannot be tied to an account, e.g. a login attempt for an unknown email.
+// Hash covers the event and PrevHash, chaining each record to the one before.
+// The personal data, IPAddress, UserAgent and Metadata, is covered through
+// PIIHash, a digest salted with PIINonce; see CommitPII. RedactedAt is set
+// once the personal data and the nonce have been scrubbed.
+type AuditEvent struct {
+	ID            int64                  `json:"id"`
+	UserID        uuid.UUID              `json:"user_id"`
+	Source        string                 `json:"source"`
+	EventType     string                 `json:"event_type"`
+	IPAddress     string                 `json:"ip_address,omitempty"`
+	UserAgent     string                 `json:"user_agent,omitempty"`
+	CorrelationID string                 `json:"correlation_id,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	PrevHash      string                 `json:"prev_hash,omitempty"`
+	Hash          string                 `json:"hash,omitempty"`
+	PIINonce      string                 `json:"pii_nonce,omitempty"`
+	PIIHash       string                 `json:"pii_hash,omitempty"`
+	RedactedAt    *time.Time             `json:"redacted_at,omitempty"`
+}
+
+// CommitPII salts the event with a fresh nonce and sets PIIHash to the digest
+// of its personal data. Once the data and nonce are gone the digest can't be
+// used to test guesses at what they were.
+func (e *AuditEvent) CommitPII() error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	e.PIINonce = hex.EncodeToString(nonce)
+
+	digest, err := e.piiDigest()
+	if err != nil {
+		return err
+	}
+	e.PIIHash = digest
+	return nil
+}
+
+// PIIMatches reports whether the personal data still matches PIIHash. It
+// can't be checked for a redacted record, or one from before commitments.
+func (e *AuditEvent) PIIMatches() (bool, error) {
+	digest, err := e.piiDigest()
+	if err != nil {
+		return false, err
+	}
+	return digest == e.PIIHash, nil
+}
+
+// Scrubbed reports whether the personal data and the nonce are gone, as
+// redaction leaves them.
+func (e *AuditEvent) Scrubbed() bool {
+	return e.IPAddress == "" && e.UserAgent == "" && e.PIINonce == "" && len(e.Metadata) == 0
+}
+
+func (e *AuditEvent) piiDigest() (string, error) {
+	metadata, err := e.metadataJSON()
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(struct {
+		Nonce     string          `json:"nonce"`
+		IPAddress string          `json:"ip_address"`
+		UserAgent string          `json:"user_agent"`
+		Metadata  json.RawMessage `json:"metadata"`
+	}{
+		Nonce:     e.PIINonce,
+		IPAddress: e.IPAddress,
+		UserAgent: e.UserAgent,
+		Metadata:  metadata,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (e *AuditEvent) metadataJSON() (json.RawMessage, error) {
+	if len(e.Metadata) == 0 {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(e.Metadata)
+}
+
+// ComputeHash returns the chain hash of the event: an HMAC-SHA256 under key,
+// so that records cannot be rewritten by someone with only database access,
+// or a plain SHA-256 when key is empty. The timestamp is taken in UTC at
+// microsecond precision, which is what the database keeps. A record with a
+// PIIHash hashes that in place of its personal data, so the hash survives
+// redaction; older records hash the data itself.
+func (e *AuditEvent) ComputeHash(key []byte) (string, error) {
+	var payload []byte
+	var err error
+	if e.PIIHash != "" {
+		payload, err = json.Marshal(struct {
+			PrevHash      string `json:"prev_hash"`
+			UserID        string `json:"user_id"`
+			Source        string `json:"source"`
+			EventType     string `json:"event_type"`
+			CorrelationID string `json:"correlation_id"`
+			PIIHash       string `json:"pii_hash"`
+			CreatedAt     string `json:"created_at"`
+		}{
+			PrevHash:      e.PrevHash,
+			UserID:        e.UserID.String(),
+			Source:        e.Source,
+			EventType:     e.EventType,
+			CorrelationID: e.CorrelationID,
+			PIIHash:       e.PIIHash,
+			CreatedAt:     e.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		})
+	} else {
+		payload, err = e.legacyHashPayload()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if len(key) == 0 {
+		sum := sha256.Sum256(payload)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// legacyHashPayload is what records written before PII commitments hash
+func (e *AuditEvent) legacyHashPayload() ([]byte, error) {
+	metadata, err := e.metadataJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		PrevHash      string          `json:"prev_hash"`
+		UserID        string          `json:"user_id"`
+		Source        string          `json:"source"`
+		EventType     string          `json:"event_type"`
+		IPAddress     string          `json:"ip_address"`
+		UserAgent     string          `json:"user_agent"`
+		CorrelationID string          `json:"correlation_id"`
+		Metadata      json.RawMessage `json:"metadata"`
+		CreatedAt     string          `json:"created_at"`
+	}{
+		PrevHash:      e.PrevHash,
+		UserID:        e.UserID.String(),
+		Source:        e.Source,
+		EventType:     e.EventType,
+		IPAddress:     e.IPAddress,
+		UserAgent:     e.UserAgent,
+		CorrelationID: e.CorrelationID,
+		Metadata:      metadata,
+		CreatedAt:     e.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	})
+}
+
+// ActivityEntry is an audit event as shown to the account owner. Type is a
+// coarse category (login, logout, password, email, security) for display.
+type ActivityEntry struct {
+	ID          int64     `json:"id"`
+	Type        string    `json:"type"`
+	Event       string    `json:"event"`
+	Description string    `json:"description"`
+	Timestamp   time.Time `json:"timestamp"`
+	IP          string    `json:"ip,omitempty"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+}
+
+var activityDescriptions = map[string][2]string{
+	AuditRegister:                  {"security", "Account created"},
+	AuditLoginSucceeded:            {"login", "Successful login"},
+	AuditLoginFailed:               {"security", "Failed login attempt"},
+	AuditAccountLocked:             {"security", "Account locked after too many failed logins"},
+	AuditLogout:                    {"logout", "Signed out"},
+	AuditTokenRefreshed:            {"login", "Session refreshed"},
+	AuditRefreshTokenReused:        {"security", "Session revoked after a refresh token was reused"},
+	AuditPasswordResetRequested:    {"password", "Password reset requested"},
+	AuditPasswordResetCompleted:    {"password", "Password reset"},
+	AuditPasswordChanged:           {"password", "Password changed"},
+	AuditEmailVerified:             {"email", "Email verified"},
+	AuditVerificationResent:        {"email", "Verification email resent"},
+	AuditEmailChangeRequested:      {"email", "Email change requested"},
+	AuditEmailChanged:              {"email", "Email address changed"},
+	Audit2FAEnabled:                {"security", "Two-factor authentication enabled"},
+	Audit2FADisabled:               {"security", "Two-factor authentication disabled"},
+	Audit2FABackupCodesRegenerated: {"security", "Two-factor backup codes regenerated"},
+	AuditAccountDeletionRequested:  {"security", "Account deletion requested"},
+	AuditAccountRestored:           {"security", "Account deletion cancelled"},
+}
+
+// Activity converts the event for display to the account owner
+func (e *AuditEvent) Activity() ActivityEntry {
+	entry := ActivityEntry{
+		ID:          e.ID,
+		Type:        "security",
+		Event:       e.EventType,
+		Description: e.EventType,
+		Timestamp:   e.CreatedAt,
+		IP:          e.IPAddress,
+		UserAgent:   e.UserAgent,
+	}
+	if d, ok := activityDescriptions[e.EventType]; ok {
+		entry.Type, entry.Description = d[0], d[1]
+	}
+	return entry
+}
+
+type ActivityLogQuery struct {
+	Page    int `form:"page"`
+	PerPage int `form:"per_page"`
+}
+
+type ActivityLogResponse struct {
+	Activities []ActivityEntry `json:"activities"`
+	Total      int64           `json:"total"`
+	Page       int             `json:"page"`
+	PerPage    int             `json:"per_page"`
+}
+
+// AuditChainReport is the result of verifying the audit trail. Records
+// written before chaining was introduced are counted as Unchained. Redacted
+// records are checked against their hash like any other, but the scrubbed
+// personal data of those written before PII commitments can't be, so they
+// are counted as Redacted instead of Checked. A redacted record that still
+// holds personal data fails. BrokenAt is the ID of the first record that
+// fails verification.
+type AuditChainReport struct {
+	Valid     bool   `json:"valid"`
+	Checked   int64  `json:"checked"`
+	Unchained int64  `json:"unchained"`
+	Redacted  int64  `json:"redacted"`
+	HeadHash  string `json:"head_hash,omitempty"`
+	BrokenAt  int64  `json:"broken_at,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Audit export formats
+const (
+	AuditExportJSONL = "jsonl"
+	AuditExportCSV   = "csv"
+)
+
+// AuditExportQuery selects the events created in [From, To)
+type AuditExportQuery struct {
+	From   time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00" binding:"required"`
+	To     time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00" binding:"required"`
+	Format string    `form:"format"`
+}
@@ -13,9 +13,6 @@ type GormUser struct {
 	Email               string         `gorm:"unique;not null" json:"email"`
 	Password            string         `gorm:"not null" json:"-"`
 	EmailVerified       bool           `gorm:"default:false" json:"email_verified"`
-	EmailVerifyToken    *string        `gorm:"index" json:"-"`
-	PasswordResetToken  *string        `gorm:"index" json:"-"`
-	PasswordResetExpiry *time.Time     `json:"-"`
 	FailedLoginAttempts int            `gorm:"default:0" json:"-"`
 	LockedUntil         *time.Time     `gorm:"index" json:"-"`
 	CreatedAt           time.Time      `json:"created_at"`
@@ -48,21 +45,6 @@ func (u *GormUser) ToUser() *User {
 		UpdatedAt:           u.UpdatedAt,
 	}
 
-	if u.EmailVerifyToken != nil {
-		user.EmailVerifyToken.String = *u.EmailVerifyToken
-		user.EmailVerifyToken.Valid = true
-	}
-
-	if u.PasswordResetToken != nil {
-		user.PasswordResetToken.String = *u.PasswordResetToken
-		user.PasswordResetToken.Valid = true
-	}
-
-	if u.PasswordResetExpiry != nil {
-		user.PasswordResetExpiry.Time = *u.PasswordResetExpiry
-		user.PasswordResetExpiry.Valid = true
-	}
-
 	if u.LockedUntil != nil {
 		user.LockedUntil.Time = *u.LockedUntil
 		user.LockedUntil.Valid = true
@@ -81,18 +63,6 @@ func (u *GormUser) FromUser(user *User) {
 	u.CreatedAt = user.CreatedAt
 	u.UpdatedAt = user.UpdatedAt
 
-	if user.EmailVerifyToken.Valid {
-		u.EmailVerifyToken = &user.EmailVerifyToken.String
-	}
-
-	if user.PasswordResetToken.Valid {
-		u.PasswordResetToken = &user.PasswordResetToken.String
-	}
-
-	if user.PasswordResetExpiry.Valid {
-		u.PasswordResetExpiry = &user.PasswordResetExpiry.Time
-	}
-
 	if user.LockedUntil.Valid {
 		u.LockedUntil = &user.LockedUntil.Time
 	}
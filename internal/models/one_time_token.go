@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// One-time token purposes. A token only redeems for the purpose it was
+// issued for.
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposePasswordReset = "password_reset"
+	TokenPurposeEmailChange   = "email_change"
+)
+
+// OneTimeToken is a single-use token sent to the user by email. Only the
+// SHA-256 digest of the token is kept. Data carries purpose-specific values,
+// such as the new address for an email change.
+type OneTimeToken struct {
+	ID        uuid.UUID         `json:"id"`
+	UserID    uuid.UUID         `json:"user_id"`
+	Purpose   string            `json:"purpose"`
+	TokenHash string            `json:"-"`
+	Data      map[string]string `json:"data,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	UsedAt    *time.Time        `json:"used_at,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outbox message kinds
+const (
+	OutboxVerificationEmail      = "email.verification"
+	OutboxPasswordResetEmail     = "email.password_reset"
+	OutboxPasswordChangedEmail   = "email.password_changed"
+	OutboxEmailChangeEmail       = "email.email_change"
+	OutboxEmailChangeNoticeEmail = "email.email_change_notice"
+	OutboxNewDeviceEmail         = "email.new_device"
+	OutboxLockoutEmail           = "email.lockout"
+	OutboxAccountDeletionEmail   = "email.account_deletion"
+	OutboxWebhookEvent           = "webhook.event"
+)
+
+// Outbox message states. Dead messages have used up their retries.
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxDead    = "dead"
+)
+
+// OutboxMessage is a side effect recorded alongside the change that caused
+// it and carried out later by the outbox worker
+type OutboxMessage struct {
+	ID            uuid.UUID       `json:"id"`
+	Kind          string          `json:"kind"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// NewOutboxMessage encodes payload as a message of the given kind
+func NewOutboxMessage(kind string, payload interface{}) (OutboxMessage, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	return OutboxMessage{Kind: kind, Payload: encoded}, nil
+}
+
+// OutboxEmail is the payload of the email message kinds. Locale picks the
+// template language; the remaining fields are used by the kinds that need them.
+type OutboxEmail struct {
+	To        string    `json:"to"`
+	Locale    string    `json:"locale,omitempty"`
+	Token     string    `json:"token,omitempty"`
+	NewEmail  string    `json:"new_email,omitempty"` // address an email change moves to
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	At        time.Time `json:"at"`    // when the login or lockout happened
+	Until     time.Time `json:"until"` // end of a lockout, or when a deleted account is purged
+}
+
+// OutboxWebhook is the payload of OutboxWebhookEvent: a webhook event to
+// publish to its subscribers once the change it reports has committed
+type OutboxWebhook struct {
+	Event string                 `json:"event"`
+	Data  map[string]interface{} `json:"data"`
+}
@@ -6,12 +6,13 @@ import (
 )
 
 type Role struct {
-	ID          uuid.UUID    `json:"id"`
-	Name        string       `json:"name"`
-	Description string       `json:"description"`
-	Permissions []Permission `json:"permissions,omitempty"`
-	CreatedAt   time.Time    `json:"created_at"`
-	UpdatedAt   time.Time    `json:"updated_at"`
+	ID           uuid.UUID     `json:"id"`
+	Name         string        `json:"name"`
+	Description  string        `json:"description"`
+	InheritsFrom uuid.NullUUID `json:"inherits_from"`
+	Permissions  []Permission  `json:"permissions,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
 }
 
 type Permission struct {
@@ -23,6 +24,47 @@ type Permission struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// EffectivePermissions is what a user may do: the roles they hold plus every
+// role those inherit from, and the permissions granted to any of them
+type EffectivePermissions struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// HasRole reports whether role is in the expanded role set
+func (e *EffectivePermissions) HasRole(role string) bool {
+	for _, r := range e.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateRoleRequest and UpdateRoleRequest name the inherited role, if any.
+// An update that omits inherits keeps the current one; "" clears it.
+type CreateRoleRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Inherits    string `json:"inherits"`
+}
+
+type UpdateRoleRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description string  `json:"description"`
+	Inherits    *string `json:"inherits"`
+}
+
+type CreatePermissionRequest struct {
+	Resource    string `json:"resource" binding:"required"`
+	Action      string `json:"action" binding:"required"`
+	Description string `json:"description"`
+}
+
+type RolePermissionRequest struct {
+	Permission string `json:"permission" binding:"required"`
+}
+
 type UserRole struct {
 	UserID uuid.UUID `json:"user_id"`
 	RoleID uuid.UUID `json:"role_id"`
@@ -42,4 +84,10 @@ const (
 	RoleAdmin     = "admin"
 	RoleUser      = "user"
 	RoleModerator = "moderator"
-)
\ No newline at end of file
+)
+
+// IsBuiltinRole reports whether the role is referenced by code and so
+// cannot be renamed or deleted
+func IsBuiltinRole(name string) bool {
+	return name == RoleAdmin || name == RoleUser || name == RoleModerator
+}
\ No newline at end of file
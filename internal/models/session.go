@@ -16,6 +16,18 @@ type Session struct {
 	LastSeen  time.Time `json:"last_seen"`
 	CreatedAt time.Time `json:"created_at"`
 	ExpiresAt time.Time `json:"expires_at"`
+	TokenHash string    `json:"-"`
+	Current   bool      `json:"current"`
+}
+
+// ClientInfo describes the device a request originates from
+type ClientInfo struct {
+	DeviceID      string
+	UserAgent     string
+	IPAddress     string
+	CorrelationID string
+	Locale        string // preferred language from Accept-Language
+	UseSession    bool // client prefers a session cookie over a JWT pair
 }
 
 type ActiveSessionsResponse struct {
@@ -19,14 +19,28 @@ type TOTPVerifyRequest struct {
 	Code string `json:"code" binding:"required,len=6"`
 }
 
+// MFALoginRequest completes a login that returned mfa_required
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
 type TOTPDisableRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type BackupCodesResponse struct {
+	BackupCodes []string `json:"backup_codes"`
+}
+
+type BackupCodeStatusResponse struct {
+	Remaining int `json:"remaining"`
+}
+
 type BackupCode struct {
 	ID        uuid.UUID `json:"id"`
 	UserID    uuid.UUID `json:"user_id"`
-	Code      string    `json:"code"`
+	CodeHash  string    `json:"-"`
 	Used      bool      `json:"used"`
 	UsedAt    *time.Time `json:"used_at,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
@@ -12,15 +12,28 @@ type User struct {
 	Email               string         `json:"email"`
 	Password            string         `json:"-"`
 	EmailVerified       bool           `json:"email_verified"`
-	EmailVerifyToken    sql.NullString `json:"-"`
-	PasswordResetToken  sql.NullString `json:"-"`
-	PasswordResetExpiry sql.NullTime   `json:"-"`
 	FailedLoginAttempts int            `json:"-"`
 	LockedUntil         sql.NullTime   `json:"-"`
+	TOTPSecret          sql.NullString `json:"-"`
+	TOTPEnabled         bool           `json:"totp_enabled"`
+	TOTPVerifiedAt      sql.NullTime   `json:"-"`
+	DisabledAt          sql.NullTime   `json:"-"`
+	DeletedAt           sql.NullTime   `json:"-"`
 	CreatedAt           time.Time      `json:"created_at"`
 	UpdatedAt           time.Time      `json:"updated_at"`
 }
 
+// IsDisabled reports whether an administrator has disabled the account
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt.Valid
+}
+
+// IsDeleted reports whether the user has asked for the account to be deleted
+// and it's waiting out the grace period before being purged
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt.Valid
+}
+
 type CreateUserRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
@@ -32,13 +45,27 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	AccessToken  string `json:"access_token"`
+	AccessToken  string `json:"access_token,omitempty"`
 	RefreshToken string `json:"refresh_token,omitempty"`
-	User         *User  `json:"user"`
+	User         *User  `json:"user,omitempty"`
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
+	SessionToken string `json:"-"`
+	// Scope is set for tokens held by an OAuth client
+	Scope string `json:"scope,omitempty"`
 }
 
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
 
+// ChangeEmailRequest asks to move the account to a new address. The current
+// password is required so a hijacked session can't take over the account.
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
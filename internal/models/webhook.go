@@ -0,0 +1,96 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook event types delivered to subscribers
+const (
+	WebhookUserRegistered      = "user.registered"
+	WebhookUserEmailVerified   = "user.email_verified"
+	WebhookUserPasswordChanged = "user.password_changed"
+	WebhookUserLocked          = "user.locked"
+)
+
+// WebhookEvents lists every event a subscription can filter on
+var WebhookEvents = []string{
+	WebhookUserRegistered,
+	WebhookUserEmailVerified,
+	WebhookUserPasswordChanged,
+	WebhookUserLocked,
+}
+
+// Webhook delivery states. Dead deliveries have used up their retries and
+// stay put until an administrator replays them.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// WebhookSubscription is an endpoint that receives signed event payloads.
+// An empty Events list subscribes to every event. Secret is only returned
+// when the subscription is created.
+type WebhookSubscription struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one event queued for one subscription
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// WebhookPayload is the JSON body POSTed to subscribers. ID is shared by
+// every delivery of the same event so receivers can deduplicate.
+type WebhookPayload struct {
+	ID        uuid.UUID              `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events"`
+}
+
+type UpdateWebhookRequest struct {
+	URL      string   `json:"url" binding:"required"`
+	Events   []string `json:"events"`
+	IsActive *bool    `json:"is_active"`
+}
+
+// WebhookDeliveryFilter narrows the delivery list; unset fields match everything
+type WebhookDeliveryFilter struct {
+	Status         string `form:"status"`
+	SubscriptionID string `form:"subscription_id"`
+	Page           int    `form:"page"`
+	PerPage        int    `form:"per_page"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int64             `json:"total"`
+	Page       int               `json:"page"`
+	PerPage    int               `json:"per_page"`
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Flack74/go-auth-system/internal/db"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+)
+
+var ErrNoAdminLeft = errors.New("no enabled administrator would be left")
+
+// AdminGuard makes a write fail with ErrNoAdminLeft if, once applied, no
+// enabled user, not pending deletion, holds any of Roles: the roles that are
+// or inherit admin
+type AdminGuard struct {
+	Roles []uuid.UUID
+}
+
+// withAdminGuard runs change in one transaction. With a guard, it first
+// locks the admin role, so guarded writes run one at a time, and counts the
+// remaining administrators after the change, before committing.
+func withAdminGuard(ctx context.Context, dbConn *sql.DB, queries *db.Queries, guard *AdminGuard, change func(q *db.Queries) error) error {
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := queries.WithTx(tx)
+	if guard != nil {
+		if err := qtx.LockRoleByName(ctx, models.RoleAdmin); err != nil {
+			return err
+		}
+	}
+	if err := change(qtx); err != nil {
+		return err
+	}
+	if guard != nil {
+		admins, err := qtx.CountActiveUsersWithRoles(ctx, guard.Roles)
+		if err != nil {
+			return err
+		}
+		if admins == 0 {
+			return ErrNoAdminLeft
+		}
+	}
+
+	return tx.Commit()
+}
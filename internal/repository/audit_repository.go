@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/db"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// auditRekeyBatchSize is how many records Rekey reads at once
+const auditRekeyBatchSize = 500
+
+// SqlcAuditRepository writes the audit trail. chainKey keys each record's
+// hash and is held outside the database.
+type SqlcAuditRepository struct {
+	db       *sql.DB
+	queries  *db.Queries
+	chainKey []byte
+}
+
+func NewSqlcAuditRepository(dbConn *sql.DB, chainKey []byte) *SqlcAuditRepository {
+	return &SqlcAuditRepository{
+		db:       dbConn,
+		queries:  db.New(dbConn),
+		chainKey: chainKey,
+	}
+}
+
+// Create appends the event to the hash chain. Appends are serialised with an
+// advisory lock so every record links to the one inserted just before it.
+func (r *SqlcAuditRepository) Create(event *models.AuditEvent) error {
+	ctx := context.Background()
+
+	if event.Source == "" {
+		event.Source = models.AuditSourceAccount
+	}
+	event.CreatedAt = event.CreatedAt.UTC().Truncate(time.Microsecond)
+
+	metadata := []byte("{}")
+	if len(event.Metadata) > 0 {
+		var err error
+		if metadata, err = json.Marshal(event.Metadata); err != nil {
+			return err
+		}
+		// Hash the metadata as it will read back from the database
+		event.Metadata = nil
+		if err := json.Unmarshal(metadata, &event.Metadata); err != nil {
+			return err
+		}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+	if err := qtx.LockAuditChain(ctx); err != nil {
+		return err
+	}
+
+	// A legacy unchained tail starts a new chain
+	prev, err := qtx.GetLastAuditHash(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	event.PrevHash = prev.String
+	if err := event.CommitPII(); err != nil {
+		return err
+	}
+	if event.Hash, err = event.ComputeHash(r.chainKey); err != nil {
+		return err
+	}
+
+	event.ID, err = qtx.CreateAuditEvent(ctx, db.CreateAuditEventParams{
+		UserID:        uuid.NullUUID{UUID: event.UserID, Valid: event.UserID != uuid.Nil},
+		Source:        event.Source,
+		EventType:     event.EventType,
+		IpAddress:     sql.NullString{String: event.IPAddress, Valid: event.IPAddress != ""},
+		UserAgent:     sql.NullString{String: event.UserAgent, Valid: event.UserAgent != ""},
+		CorrelationID: sql.NullString{String: event.CorrelationID, Valid: event.CorrelationID != ""},
+		Metadata:      metadata,
+		CreatedAt:     event.CreatedAt,
+		PrevHash:      sql.NullString{String: event.PrevHash, Valid: event.PrevHash != ""},
+		Hash:          sql.NullString{String: event.Hash, Valid: true},
+		PiiNonce:      sql.NullString{String: event.PIINonce, Valid: true},
+		PiiHash:       sql.NullString{String: event.PIIHash, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListByUser returns one page of the user's events, newest first
+func (r *SqlcAuditRepository) ListByUser(userID uuid.UUID, limit, offset int) ([]models.AuditEvent, error) {
+	ctx := context.Background()
+
+	rows, err := r.queries.ListAuditEventsByUser(ctx, db.ListAuditEventsByUserParams{
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toAuditEventModels(rows)
+}
+
+func (r *SqlcAuditRepository) CountByUser(userID uuid.UUID) (int64, error) {
+	ctx := context.Background()
+	return r.queries.CountAuditEventsByUser(ctx, uuid.NullUUID{UUID: userID, Valid: true})
+}
+
+// ListAfter returns up to limit events with an ID above afterID, in chain order
+func (r *SqlcAuditRepository) ListAfter(afterID int64, limit int) ([]models.AuditEvent, error) {
+	ctx := context.Background()
+
+	rows, err := r.queries.ListAuditEventsAfter(ctx, db.ListAuditEventsAfterParams{
+		ID:    afterID,
+		Limit: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toAuditEventModels(rows)
+}
+
+// ListInRange returns up to limit events created in [from, to) with an ID
+// above afterID, in chain order
+func (r *SqlcAuditRepository) ListInRange(from, to time.Time, afterID int64, limit int) ([]models.AuditEvent, error) {
+	ctx := context.Background()
+
+	rows, err := r.queries.ListAuditEventsInRange(ctx, db.ListAuditEventsInRangeParams{
+		CreatedAt:   from,
+		CreatedAt_2: to,
+		ID:          afterID,
+		Limit:       int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toAuditEventModels(rows)
+}
+
+// Rekey re-hashes every chained record under the repository's key, relinking
+// each to its predecessor's new hash, and returns how many it rewrote.
+// Redacted records from before PII commitments keep their hash, which their
+// scrubbed contents can no longer reproduce.
+func (r *SqlcAuditRepository) Rekey() (int64, error) {
+	ctx := context.Background()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+	if err := qtx.LockAuditChain(ctx); err != nil {
+		return 0, err
+	}
+
+	var afterID, rekeyed int64
+	prevHash := ""
+	for {
+		rows, err := qtx.ListAuditEventsAfter(ctx, db.ListAuditEventsAfterParams{
+			ID:    afterID,
+			Limit: auditRekeyBatchSize,
+		})
+		if err != nil {
+			return 0, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		events, err := toAuditEventModels(rows)
+		if err != nil {
+			return 0, err
+		}
+
+		for i := range events {
+			event := &events[i]
+			afterID = event.ID
+			if event.Hash == "" {
+				continue
+			}
+
+			event.PrevHash = prevHash
+			if event.RedactedAt == nil || event.PIIHash != "" {
+				if event.Hash, err = event.ComputeHash(r.chainKey); err != nil {
+					return 0, err
+				}
+			}
+			if err := qtx.UpdateAuditEventHash(ctx, db.UpdateAuditEventHashParams{
+				ID:       event.ID,
+				PrevHash: sql.NullString{String: event.PrevHash, Valid: event.PrevHash != ""},
+				Hash:     sql.NullString{String: event.Hash, Valid: true},
+			}); err != nil {
+				return 0, err
+			}
+			prevHash = event.Hash
+			rekeyed++
+		}
+	}
+
+	return rekeyed, tx.Commit()
+}
+
+func toAuditEventModels(rows []db.AuditEvent) ([]models.AuditEvent, error) {
+	events := make([]models.AuditEvent, 0, len(rows))
+	for _, row := range rows {
+		event, err := toAuditEventModel(row)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+	return events, nil
+}
+
+func toAuditEventModel(row db.AuditEvent) (*models.AuditEvent, error) {
+	event := &models.AuditEvent{
+		ID:            row.ID,
+		UserID:        row.UserID.UUID,
+		Source:        row.Source,
+		EventType:     row.EventType,
+		IPAddress:     row.IpAddress.String,
+		UserAgent:     row.UserAgent.String,
+		CorrelationID: row.CorrelationID.String,
+		CreatedAt:     row.CreatedAt,
+		PrevHash:      row.PrevHash.String,
+		Hash:          row.Hash.String,
+		PIINonce:      row.PiiNonce.String,
+		PIIHash:       row.PiiHash.String,
+		RedactedAt:    nullTimePtr(row.RedactedAt),
+	}
+	if len(row.Metadata) > 0 {
+		if err := json.Unmarshal(row.Metadata, &event.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/db"
+	"github.com/google/uuid"
+)
+
+type SqlcBackupCodeRepository struct {
+	db      *sql.DB
+	queries *db.Queries
+}
+
+func NewSqlcBackupCodeRepository(dbConn *sql.DB) *SqlcBackupCodeRepository {
+	return &SqlcBackupCodeRepository{
+		db:      dbConn,
+		queries: db.New(dbConn),
+	}
+}
+
+// ReplaceForUser atomically swaps the user's code set for a new batch of hashes
+func (r *SqlcBackupCodeRepository) ReplaceForUser(userID uuid.UUID, codeHashes []string) error {
+	ctx := context.Background()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+	if err := qtx.DeleteBackupCodesByUser(ctx, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if err := qtx.CreateBackupCode(ctx, db.CreateBackupCodeParams{
+			UserID:   userID,
+			CodeHash: hash,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Consume marks a code as used. It reports false if the code is unknown or already spent.
+func (r *SqlcBackupCodeRepository) Consume(userID uuid.UUID, codeHash string) (bool, error) {
+	ctx := context.Background()
+	rows, err := r.queries.UseBackupCode(ctx, db.UseBackupCodeParams{
+		UserID:   userID,
+		CodeHash: codeHash,
+		UsedAt:   sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *SqlcBackupCodeRepository) CountUnused(userID uuid.UUID) (int, error) {
+	ctx := context.Background()
+	count, err := r.queries.CountUnusedBackupCodes(ctx, userID)
+	return int(count), err
+}
+
+func (r *SqlcBackupCodeRepository) DeleteForUser(userID uuid.UUID) error {
+	ctx := context.Background()
+	return r.queries.DeleteBackupCodesByUser(ctx, userID)
+}
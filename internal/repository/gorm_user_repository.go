@@ -72,25 +72,6 @@ func (r *GormUserRepository) Update(user *models.User) error {
 	return result.Error
 }
 
-func (r *GormUserRepository) VerifyEmail(token string) error {
-	result := r.db.Model(&models.GormUser{}).
-		Where("email_verify_token = ?", token).
-		Updates(map[string]interface{}{
-			"email_verified":      true,
-			"email_verify_token": nil,
-		})
-	
-	if result.Error != nil {
-		return result.Error
-	}
-	
-	if result.RowsAffected == 0 {
-		return errors.New("invalid verification token")
-	}
-	
-	return nil
-}
-
 func (r *GormUserRepository) IncrementFailedLoginAttempts(email string) error {
 	result := r.db.Model(&models.GormUser{}).
 		Where("email = ?", email).
@@ -120,22 +101,6 @@ func (r *GormUserRepository) ResetFailedAttempts(email string) error {
 	return result.Error
 }
 
-func (r *GormUserRepository) GetByPasswordResetToken(token string) (*models.User, error) {
-	var gormUser models.GormUser
-	
-	result := r.db.Where("password_reset_token = ? AND password_reset_expiry > ?", 
-		token, time.Now()).First(&gormUser)
-	
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, nil
-		}
-		return nil, result.Error
-	}
-	
-	return gormUser.ToUser(), nil
-}
-
 func (r *GormUserRepository) ResetFailedLoginAttempts(email string) error {
 	result := r.db.Model(&models.GormUser{}).
 		Where("email = ?", email).
@@ -1,17 +1,171 @@
 package repository
 
 import (
+	"database/sql"
+	"time"
+
 	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
 )
 
 // UserRepositoryInterface defines the contract for user repository
 type UserRepositoryInterface interface {
-	Create(user *models.User) error
+	// Create, Update and MarkEmailVerified apply any token writes and
+	// record any outbox messages in the same transaction. A token to
+	// consume that is no longer live fails the write with ErrTokenSpent.
+	Create(user *models.User, tokens *TokenChange, outbox ...models.OutboxMessage) error
 	GetByEmail(email string) (*models.User, error)
 	GetByID(id string) (*models.User, error)
-	Update(user *models.User) error
-	VerifyEmail(token string) error
-	IncrementFailedLoginAttempts(email string) error
+	Update(user *models.User, tokens *TokenChange, outbox ...models.OutboxMessage) error
+	// IncrementFailedLoginAttempts returns the count after this failure and
+	// the stored lock expiry; the failure that locks the account sets it
+	// lockout from now
+	IncrementFailedLoginAttempts(email string, lockout time.Duration) (int, sql.NullTime, error)
 	ResetFailedLoginAttempts(email string) error
-	GetByPasswordResetToken(token string) (*models.User, error)
-}
\ No newline at end of file
+	SetTOTPSecret(userID uuid.UUID, secret string) error
+	EnableTOTP(userID uuid.UUID) error
+	DisableTOTP(userID uuid.UUID) error
+	Search(filter *models.UserFilter) ([]models.AdminUserView, error)
+	Count(filter *models.UserFilter) (int64, error)
+	Unlock(userID uuid.UUID) error
+	MarkEmailVerified(userID uuid.UUID, tokens *TokenChange, outbox ...models.OutboxMessage) error
+	// ChangeEmail applies any token writes in the same transaction and
+	// reports false, writing nothing, when the address belongs to another user
+	ChangeEmail(userID uuid.UUID, email string, tokens *TokenChange) (bool, error)
+	// SetDisabled and Delete fail with ErrNoAdminLeft when a non-nil guard
+	// finds no administrator left afterwards
+	SetDisabled(userID uuid.UUID, disabled bool, guard *AdminGuard) error
+	Delete(userID uuid.UUID, guard *AdminGuard) error
+	// SoftDelete marks the account for deletion and records any outbox
+	// messages in the same transaction, failing like Delete under a non-nil
+	// guard; Restore cancels it
+	SoftDelete(userID uuid.UUID, guard *AdminGuard, outbox ...models.OutboxMessage) error
+	Restore(userID uuid.UUID) error
+	ListDueForPurge(deletedBefore time.Time, limit int) ([]uuid.UUID, error)
+	// Purge permanently deletes an account marked for deletion at or before
+	// deletedBefore, reporting false if it was restored in the meantime
+	Purge(userID uuid.UUID, deletedBefore time.Time) (bool, error)
+}
+
+// TokenVersionRepositoryInterface tracks the version stamped into each
+// user's access tokens; bumping it rejects every token issued before
+type TokenVersionRepositoryInterface interface {
+	// GetTokenVersion returns sql.ErrNoRows for a deleted account, so its
+	// tokens stop working even before they are revoked
+	GetTokenVersion(userID uuid.UUID) (int, error)
+	BumpTokenVersion(userID uuid.UUID) (int, error)
+}
+
+// OneTimeTokenRepositoryInterface stores the digests of single-use emailed tokens
+type OneTimeTokenRepositoryInterface interface {
+	// Issue replaces the user's outstanding tokens for the purpose and
+	// records any outbox messages in the same transaction
+	Issue(token *models.OneTimeToken, outbox ...models.OutboxMessage) error
+	// Find returns a live token without using it up, or sql.ErrNoRows
+	Find(purpose, tokenHash string) (*models.OneTimeToken, error)
+	// Revoke uses up the user's outstanding tokens for the purpose
+	Revoke(userID uuid.UUID, purpose string) error
+}
+
+// BackupCodeRepositoryInterface stores hashed 2FA recovery codes
+type BackupCodeRepositoryInterface interface {
+	ReplaceForUser(userID uuid.UUID, codeHashes []string) error
+	Consume(userID uuid.UUID, codeHash string) (bool, error)
+	CountUnused(userID uuid.UUID) (int, error)
+	DeleteForUser(userID uuid.UUID) error
+}
+
+// SessionRepositoryInterface persists per-device login sessions
+type SessionRepositoryInterface interface {
+	Create(session *models.Session) error
+	GetByID(id uuid.UUID) (*models.Session, error)
+	ListActiveByUser(userID uuid.UUID) ([]models.Session, error)
+	// ListByUser returns all the user's sessions, ended ones included
+	ListByUser(userID uuid.UUID) ([]models.Session, error)
+	// HasDevice reports whether the user has ever had a session with the
+	// device ID
+	HasDevice(userID uuid.UUID, deviceID string) (bool, error)
+	Touch(id uuid.UUID, lastSeen, expiresAt time.Time) error
+	Deactivate(id uuid.UUID) error
+}
+
+// OAuthClientRepositoryInterface persists applications registered with the OIDC provider
+type OAuthClientRepositoryInterface interface {
+	Create(client *models.OAuthClient) error
+	GetByClientID(clientID string) (*models.OAuthClient, error)
+}
+
+// RoleRepositoryInterface manages roles, their permissions and user assignments
+type RoleRepositoryInterface interface {
+	GetByID(id uuid.UUID) (*models.Role, error)
+	GetByName(name string) (*models.Role, error)
+	List() ([]models.Role, error)
+	Create(role *models.Role) error
+	Update(role *models.Role, guard *AdminGuard) error
+	// Delete bumps the token versions of users holding any of holders and
+	// removes the role in one transaction, failing with ErrNoAdminLeft when
+	// a non-nil guard finds no administrator left afterwards
+	Delete(id uuid.UUID, holders []uuid.UUID, guard *AdminGuard) error
+	CountActiveUsersWithRoles(roleIDs []uuid.UUID) (int64, error)
+	// BumpHolderTokenVersions bumps the token version of every user holding
+	// any of the roles
+	BumpHolderTokenVersions(roleIDs []uuid.UUID) error
+	ListPermissions(roleID uuid.UUID) ([]models.Permission, error)
+	ListAllPermissions() ([]models.Permission, error)
+	GetPermissionByName(name string) (*models.Permission, error)
+	CreatePermission(permission *models.Permission) error
+	AddPermission(roleID, permissionID uuid.UUID) error
+	RemovePermission(roleID, permissionID uuid.UUID) error
+	ListUserRoles(userID uuid.UUID) ([]models.Role, error)
+	AddUserRole(userID, roleID uuid.UUID) error
+	// RemoveUserRole and SetUserRoles fail with ErrNoAdminLeft when a
+	// non-nil guard finds no administrator left afterwards
+	RemoveUserRole(userID, roleID uuid.UUID, guard *AdminGuard) error
+	SetUserRoles(userID uuid.UUID, roleIDs []uuid.UUID, guard *AdminGuard) error
+}
+
+// AuditRepositoryInterface stores the append-only audit trail
+type AuditRepositoryInterface interface {
+	Create(event *models.AuditEvent) error
+	ListByUser(userID uuid.UUID, limit, offset int) ([]models.AuditEvent, error)
+	CountByUser(userID uuid.UUID) (int64, error)
+	ListAfter(afterID int64, limit int) ([]models.AuditEvent, error)
+	ListInRange(from, to time.Time, afterID int64, limit int) ([]models.AuditEvent, error)
+	// Rekey re-hashes every chained record under the repository's key
+	Rekey() (int64, error)
+}
+
+// WebhookRepositoryInterface stores webhook subscriptions and the durable
+// delivery queue
+type WebhookRepositoryInterface interface {
+	CreateSubscription(subscription *models.WebhookSubscription) error
+	GetSubscription(id uuid.UUID) (*models.WebhookSubscription, error)
+	ListSubscriptions() ([]models.WebhookSubscription, error)
+	UpdateSubscription(subscription *models.WebhookSubscription) error
+	DeleteSubscription(id uuid.UUID) error
+	// Enqueue adds a delivery for every active subscription to eventType
+	Enqueue(eventID uuid.UUID, eventType string, payload []byte) (int64, error)
+	// ClaimDue leases up to limit due deliveries until leaseUntil so
+	// concurrent workers skip them
+	ClaimDue(leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+	MarkDelivered(id uuid.UUID, statusCode int) error
+	MarkFailed(id uuid.UUID, status string, nextAttemptAt time.Time, statusCode int, lastError string) error
+	GetDelivery(id uuid.UUID) (*models.WebhookDelivery, error)
+	ListDeliveries(status string, subscriptionID uuid.UUID, limit, offset int) ([]models.WebhookDelivery, error)
+	CountDeliveries(status string, subscriptionID uuid.UUID) (int64, error)
+	// Replay and ReplayDead requeue dead deliveries with a fresh retry budget
+	Replay(id uuid.UUID) (bool, error)
+	ReplayDead(subscriptionID uuid.UUID) (int64, error)
+}
+
+// OutboxRepositoryInterface hands queued side effects to the outbox worker.
+// Messages are written by the repositories whose changes cause them.
+type OutboxRepositoryInterface interface {
+	// ClaimDue leases up to limit due messages until leaseUntil so
+	// concurrent workers skip them
+	ClaimDue(leaseUntil time.Time, limit int) ([]models.OutboxMessage, error)
+	// Enqueue records messages that don't accompany a repository change
+	Enqueue(messages ...models.OutboxMessage) error
+	MarkSent(id uuid.UUID) error
+	MarkFailed(id uuid.UUID, status string, nextAttemptAt time.Time, lastError string) error
+}
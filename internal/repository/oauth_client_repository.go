@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Flack74/go-auth-system/internal/db"
+	"github.com/Flack74/go-auth-system/internal/models"
+)
+
+type SqlcOAuthClientRepository struct {
+	queries *db.Queries
+}
+
+func NewSqlcOAuthClientRepository(dbConn *sql.DB) *SqlcOAuthClientRepository {
+	return &SqlcOAuthClientRepository{
+		queries: db.New(dbConn),
+	}
+}
+
+func (r *SqlcOAuthClientRepository) Create(client *models.OAuthClient) error {
+	ctx := context.Background()
+
+	dbClient, err := r.queries.CreateOAuthClient(ctx, db.CreateOAuthClientParams{
+		ClientID:         client.ClientID,
+		ClientSecretHash: sql.NullString{String: client.ClientSecretHash, Valid: client.ClientSecretHash != ""},
+		Name:             client.Name,
+		RedirectUris:     client.RedirectURIs,
+		Scopes:           client.Scopes,
+		GrantTypes:       client.GrantTypes,
+	})
+	if err != nil {
+		return err
+	}
+
+	*client = *toOAuthClientModel(dbClient)
+	return nil
+}
+
+func (r *SqlcOAuthClientRepository) GetByClientID(clientID string) (*models.OAuthClient, error) {
+	ctx := context.Background()
+
+	dbClient, err := r.queries.GetOAuthClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toOAuthClientModel(dbClient), nil
+}
+
+// toOAuthClientModel maps a sqlc row onto the domain model
+func toOAuthClientModel(dbClient db.OauthClient) *models.OAuthClient {
+	return &models.OAuthClient{
+		ID:               dbClient.ID,
+		ClientID:         dbClient.ClientID,
+		ClientSecretHash: dbClient.ClientSecretHash.String,
+		Name:             dbClient.Name,
+		RedirectURIs:     dbClient.RedirectUris,
+		Scopes:           dbClient.Scopes,
+		GrantTypes:       dbClient.GrantTypes,
+		IsActive:         dbClient.IsActive,
+		CreatedAt:        dbClient.CreatedAt.Time,
+	}
+}
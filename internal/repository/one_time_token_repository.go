@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/Flack74/go-auth-system/internal/db"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+)
+
+var ErrTokenSpent = errors.New("one-time token already used or expired")
+
+// TokenChange makes one-time token writes part of a user write, so that a
+// token is only spent if the change it authorises commits, and only issued
+// if the change it follows up does. Consume must still be live, or the write
+// fails with ErrTokenSpent; Revoke uses up the user's outstanding tokens for
+// each purpose; Issue then replaces those for its own purpose.
+type TokenChange struct {
+	Consume *models.OneTimeToken
+	Revoke  []string
+	Issue   *models.OneTimeToken
+}
+
+// withTokens runs change, the token writes and the outbox messages in one
+// transaction. Without token writes it is withOutbox.
+func withTokens(ctx context.Context, dbConn *sql.DB, queries *db.Queries, userID uuid.UUID, tokens *TokenChange, outbox []models.OutboxMessage, change func(q *db.Queries) error) error {
+	if tokens == nil {
+		return withOutbox(ctx, dbConn, queries, outbox, change)
+	}
+
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := queries.WithTx(tx)
+	if tokens.Consume != nil {
+		if tokens.Consume.UserID != userID {
+			return ErrTokenSpent
+		}
+		rows, err := qtx.UseOneTimeToken(ctx, tokens.Consume.ID)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrTokenSpent
+		}
+	}
+	if err := change(qtx); err != nil {
+		return err
+	}
+	for _, purpose := range tokens.Revoke {
+		if err := qtx.RevokeOneTimeTokens(ctx, db.RevokeOneTimeTokensParams{
+			UserID:  userID,
+			Purpose: purpose,
+		}); err != nil {
+			return err
+		}
+	}
+	if tokens.Issue != nil {
+		if err := issueOneTimeToken(ctx, qtx, tokens.Issue); err != nil {
+			return err
+		}
+	}
+	if err := enqueueOutbox(ctx, qtx, outbox); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// issueOneTimeToken stores token in place of the user's outstanding tokens
+// for the same purpose
+func issueOneTimeToken(ctx context.Context, q *db.Queries, token *models.OneTimeToken) error {
+	data, err := json.Marshal(token.Data)
+	if err != nil {
+		return err
+	}
+	if token.Data == nil {
+		data = []byte("{}")
+	}
+
+	if err := q.RevokeOneTimeTokens(ctx, db.RevokeOneTimeTokensParams{
+		UserID:  token.UserID,
+		Purpose: token.Purpose,
+	}); err != nil {
+		return err
+	}
+	return q.CreateOneTimeToken(ctx, db.CreateOneTimeTokenParams{
+		UserID:    token.UserID,
+		Purpose:   token.Purpose,
+		TokenHash: token.TokenHash,
+		Data:      data,
+		ExpiresAt: token.ExpiresAt,
+	})
+}
+
+type SqlcOneTimeTokenRepository struct {
+	db      *sql.DB
+	queries *db.Queries
+}
+
+func NewSqlcOneTimeTokenRepository(dbConn *sql.DB) *SqlcOneTimeTokenRepository {
+	return &SqlcOneTimeTokenRepository{
+		db:      dbConn,
+		queries: db.New(dbConn),
+	}
+}
+
+// Issue stores token in place of the user's outstanding tokens for the same
+// purpose, together with any outbox messages in the same transaction
+func (r *SqlcOneTimeTokenRepository) Issue(token *models.OneTimeToken, outbox ...models.OutboxMessage) error {
+	ctx := context.Background()
+	return withOutbox(ctx, r.db, r.queries, outbox, func(q *db.Queries) error {
+		return issueOneTimeToken(ctx, q, token)
+	})
+}
+
+func (r *SqlcOneTimeTokenRepository) Revoke(userID uuid.UUID, purpose string) error {
+	ctx := context.Background()
+	return r.queries.RevokeOneTimeTokens(ctx, db.RevokeOneTimeTokensParams{
+		UserID:  userID,
+		Purpose: purpose,
+	})
+}
+
+// Find returns the unused, unexpired token with the digest without using it
+// up; a write carrying it in a TokenChange does that
+func (r *SqlcOneTimeTokenRepository) Find(purpose, tokenHash string) (*models.OneTimeToken, error) {
+	ctx := context.Background()
+
+	row, err := r.queries.GetLiveOneTimeToken(ctx, db.GetLiveOneTimeTokenParams{
+		TokenHash: tokenHash,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toOneTimeTokenModel(row)
+}
+
+func toOneTimeTokenModel(row db.OneTimeToken) (*models.OneTimeToken, error) {
+	token := &models.OneTimeToken{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		Purpose:   row.Purpose,
+		TokenHash: row.TokenHash,
+		ExpiresAt: row.ExpiresAt,
+		UsedAt:    nullTimePtr(row.UsedAt),
+		CreatedAt: row.CreatedAt,
+	}
+	if err := json.Unmarshal(row.Data, &token.Data); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
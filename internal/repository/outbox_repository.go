@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/db"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+)
+
+type SqlcOutboxRepository struct {
+	queries *db.Queries
+}
+
+func NewSqlcOutboxRepository(dbConn *sql.DB) *SqlcOutboxRepository {
+	return &SqlcOutboxRepository{
+		queries: db.New(dbConn),
+	}
+}
+
+// withOutbox runs change and records the outbox messages in one
+// transaction, so a side effect is queued exactly when the change commits
+func withOutbox(ctx context.Context, dbConn *sql.DB, queries *db.Queries, outbox []models.OutboxMessage, change func(q *db.Queries) error) error {
+	if len(outbox) == 0 {
+		return change(queries)
+	}
+
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := queries.WithTx(tx)
+	if err := change(qtx); err != nil {
+		return err
+	}
+	if err := enqueueOutbox(ctx, qtx, outbox); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func enqueueOutbox(ctx context.Context, q *db.Queries, outbox []models.OutboxMessage) error {
+	for _, message := range outbox {
+		if err := q.CreateOutboxMessage(ctx, db.CreateOutboxMessageParams{
+			Kind:    message.Kind,
+			Payload: message.Payload,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SqlcOutboxRepository) Enqueue(messages ...models.OutboxMessage) error {
+	ctx := context.Background()
+
+	for _, message := range messages {
+		if err := r.queries.CreateOutboxMessage(ctx, db.CreateOutboxMessageParams{
+			Kind:    message.Kind,
+			Payload: message.Payload,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SqlcOutboxRepository) ClaimDue(leaseUntil time.Time, limit int) ([]models.OutboxMessage, error) {
+	ctx := context.Background()
+
+	rows, err := r.queries.ClaimOutboxMessages(ctx, db.ClaimOutboxMessagesParams{
+		LeaseUntil: leaseUntil,
+		BatchSize:  int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]models.OutboxMessage, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, models.OutboxMessage{
+			ID:            row.ID,
+			Kind:          row.Kind,
+			Payload:       row.Payload,
+			Status:        row.Status,
+			Attempts:      int(row.Attempts),
+			NextAttemptAt: row.NextAttemptAt,
+			LastError:     row.LastError.String,
+			CreatedAt:     row.CreatedAt,
+		})
+	}
+	return messages, nil
+}
+
+func (r *SqlcOutboxRepository) MarkSent(id uuid.UUID) error {
+	ctx := context.Background()
+	return r.queries.MarkOutboxMessageSent(ctx, id)
+}
+
+// MarkFailed schedules a retry, or gives up on a dead message and drops any
+// token from its payload; sent messages keep no payload at all
+func (r *SqlcOutboxRepository) MarkFailed(id uuid.UUID, status string, nextAttemptAt time.Time, lastError string) error {
+	ctx := context.Background()
+	return r.queries.MarkOutboxMessageFailed(ctx, db.MarkOutboxMessageFailedParams{
+		ID:            id,
+		Status:        status,
+		NextAttemptAt: nextAttemptAt,
+		LastError:     sql.NullString{String: lastError, Valid: lastError != ""},
+	})
+}
@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Flack74/go-auth-system/internal/db"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+)
+
+type SqlcRoleRepository struct {
+	db      *sql.DB
+	queries *db.Queries
+}
+
+func NewSqlcRoleRepository(dbConn *sql.DB) *SqlcRoleRepository {
+	return &SqlcRoleRepository{
+		db:      dbConn,
+		queries: db.New(dbConn),
+	}
+}
+
+func (r *SqlcRoleRepository) GetByID(id uuid.UUID) (*models.Role, error) {
+	ctx := context.Background()
+
+	dbRole, err := r.queries.GetRoleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toRoleModel(dbRole), nil
+}
+
+func (r *SqlcRoleRepository) GetByName(name string) (*models.Role, error) {
+	ctx := context.Background()
+
+	dbRole, err := r.queries.GetRoleByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return toRoleModel(dbRole), nil
+}
+
+func (r *SqlcRoleRepository) List() ([]models.Role, error) {
+	ctx := context.Background()
+
+	dbRoles, err := r.queries.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]models.Role, 0, len(dbRoles))
+	for _, dbRole := range dbRoles {
+		roles = append(roles, *toRoleModel(dbRole))
+	}
+	return roles, nil
+}
+
+func (r *SqlcRoleRepository) Create(role *models.Role) error {
+	ctx := context.Background()
+
+	dbRole, err := r.queries.CreateRole(ctx, db.CreateRoleParams{
+		Name:         role.Name,
+		Description:  sql.NullString{String: role.Description, Valid: role.Description != ""},
+		InheritsFrom: role.InheritsFrom,
+	})
+	if err != nil {
+		return err
+	}
+
+	*role = *toRoleModel(dbRole)
+	return nil
+}
+
+func (r *SqlcRoleRepository) Update(role *models.Role, guard *AdminGuard) error {
+	ctx := context.Background()
+	return withAdminGuard(ctx, r.db, r.queries, guard, func(q *db.Queries) error {
+		dbRole, err := q.UpdateRole(ctx, db.UpdateRoleParams{
+			ID:           role.ID,
+			Name:         role.Name,
+			Description:  sql.NullString{String: role.Description, Valid: role.Description != ""},
+			InheritsFrom: role.InheritsFrom,
+		})
+		if err != nil {
+			return err
+		}
+
+		*role = *toRoleModel(dbRole)
+		return nil
+	})
+}
+
+// Delete removes a role. Holders lose it and roles inheriting from it stop
+// inheriting anything. The token versions of users holding any of holders
+// are bumped in the same transaction, before their assignments go.
+func (r *SqlcRoleRepository) Delete(id uuid.UUID, holders []uuid.UUID, guard *AdminGuard) error {
+	ctx := context.Background()
+	return withAdminGuard(ctx, r.db, r.queries, guard, func(q *db.Queries) error {
+		if len(holders) > 0 {
+			if err := q.BumpRoleHolderTokenVersions(ctx, holders); err != nil {
+				return err
+			}
+		}
+		return q.DeleteRole(ctx, id)
+	})
+}
+
+// CountActiveUsersWithRoles counts enabled users holding any of the roles,
+// leaving out accounts pending deletion
+func (r *SqlcRoleRepository) CountActiveUsersWithRoles(roleIDs []uuid.UUID) (int64, error) {
+	ctx := context.Background()
+	return r.queries.CountActiveUsersWithRoles(ctx, roleIDs)
+}
+
+// BumpHolderTokenVersions rejects the outstanding access tokens of every
+// user holding any of the roles
+func (r *SqlcRoleRepository) BumpHolderTokenVersions(roleIDs []uuid.UUID) error {
+	ctx := context.Background()
+	return r.queries.BumpRoleHolderTokenVersions(ctx, roleIDs)
+}
+
+func (r *SqlcRoleRepository) ListAllPermissions() ([]models.Permission, error) {
+	ctx := context.Background()
+
+	dbPermissions, err := r.queries.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]models.Permission, 0, len(dbPermissions))
+	for _, dbPermission := range dbPermissions {
+		permissions = append(permissions, *toPermissionModel(dbPermission))
+	}
+	return permissions, nil
+}
+
+func (r *SqlcRoleRepository) GetPermissionByName(name string) (*models.Permission, error) {
+	ctx := context.Background()
+
+	dbPermission, err := r.queries.GetPermissionByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return toPermissionModel(dbPermission), nil
+}
+
+func (r *SqlcRoleRepository) CreatePermission(permission *models.Permission) error {
+	ctx := context.Background()
+
+	dbPermission, err := r.queries.CreatePermission(ctx, db.CreatePermissionParams{
+		Name:        permission.Name,
+		Resource:    permission.Resource,
+		Action:      permission.Action,
+		Description: sql.NullString{String: permission.Description, Valid: permission.Description != ""},
+	})
+	if err != nil {
+		return err
+	}
+
+	*permission = *toPermissionModel(dbPermission)
+	return nil
+}
+
+func (r *SqlcRoleRepository) AddPermission(roleID, permissionID uuid.UUID) error {
+	ctx := context.Background()
+	return r.queries.AddRolePermission(ctx, db.AddRolePermissionParams{
+		RoleID:       roleID,
+		PermissionID: permissionID,
+	})
+}
+
+func (r *SqlcRoleRepository) RemovePermission(roleID, permissionID uuid.UUID) error {
+	ctx := context.Background()
+	return r.queries.RemoveRolePermission(ctx, db.RemoveRolePermissionParams{
+		RoleID:       roleID,
+		PermissionID: permissionID,
+	})
+}
+
+func (r *SqlcRoleRepository) ListPermissions(roleID uuid.UUID) ([]models.Permission, error) {
+	ctx := context.Background()
+
+	dbPermissions, err := r.queries.ListPermissionsByRole(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]models.Permission, 0, len(dbPermissions))
+	for _, dbPermission := range dbPermissions {
+		permissions = append(permissions, *toPermissionModel(dbPermission))
+	}
+	return permissions, nil
+}
+
+// ListUserRoles returns the roles assigned to the user, without inherited
+// ones. It returns sql.ErrNoRows if the user does not exist.
+func (r *SqlcRoleRepository) ListUserRoles(userID uuid.UUID) ([]models.Role, error) {
+	ctx := context.Background()
+
+	exists, err := r.queries.UserExists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	dbRoles, err := r.queries.ListUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]models.Role, 0, len(dbRoles))
+	for _, dbRole := range dbRoles {
+		roles = append(roles, *toRoleModel(dbRole))
+	}
+	return roles, nil
+}
+
+func (r *SqlcRoleRepository) AddUserRole(userID, roleID uuid.UUID) error {
+	ctx := context.Background()
+	return r.queries.AddUserRole(ctx, db.AddUserRoleParams{
+		UserID: userID,
+		RoleID: roleID,
+	})
+}
+
+func (r *SqlcRoleRepository) RemoveUserRole(userID, roleID uuid.UUID, guard *AdminGuard) error {
+	ctx := context.Background()
+	return withAdminGuard(ctx, r.db, r.queries, guard, func(q *db.Queries) error {
+		return q.RemoveUserRole(ctx, db.RemoveUserRoleParams{
+			UserID: userID,
+			RoleID: roleID,
+		})
+	})
+}
+
+// SetUserRoles replaces every role the user holds in one transaction
+func (r *SqlcRoleRepository) SetUserRoles(userID uuid.UUID, roleIDs []uuid.UUID, guard *AdminGuard) error {
+	ctx := context.Background()
+	return withAdminGuard(ctx, r.db, r.queries, guard, func(q *db.Queries) error {
+		if err := q.ClearUserRoles(ctx, userID); err != nil {
+			return err
+		}
+		for _, roleID := range roleIDs {
+			if err := q.AddUserRole(ctx, db.AddUserRoleParams{UserID: userID, RoleID: roleID}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func toRoleModel(dbRole db.Role) *models.Role {
+	return &models.Role{
+		ID:           dbRole.ID,
+		Name:         dbRole.Name,
+		Description:  dbRole.Description.String,
+		InheritsFrom: dbRole.InheritsFrom,
+		CreatedAt:    dbRole.CreatedAt.Time,
+		UpdatedAt:    dbRole.UpdatedAt.Time,
+	}
+}
+
+func toPermissionModel(dbPermission db.Permission) *models.Permission {
+	return &models.Permission{
+		ID:          dbPermission.ID,
+		Name:        dbPermission.Name,
+		Resource:    dbPermission.Resource,
+		Action:      dbPermission.Action,
+		Description: dbPermission.Description.String,
+		CreatedAt:   dbPermission.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/db"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+)
+
+type SqlcSessionRepository struct {
+	queries *db.Queries
+}
+
+func NewSqlcSessionRepository(dbConn *sql.DB) *SqlcSessionRepository {
+	return &SqlcSessionRepository{
+		queries: db.New(dbConn),
+	}
+}
+
+func (r *SqlcSessionRepository) Create(session *models.Session) error {
+	ctx := context.Background()
+
+	dbSession, err := r.queries.CreateSession(ctx, db.CreateSessionParams{
+		ID:        session.ID,
+		UserID:    session.UserID,
+		DeviceID:  session.DeviceID,
+		UserAgent: sql.NullString{String: session.UserAgent, Valid: session.UserAgent != ""},
+		IpAddress: sql.NullString{String: session.IPAddress, Valid: session.IPAddress != ""},
+		TokenHash: sql.NullString{String: session.TokenHash, Valid: session.TokenHash != ""},
+		LastSeen:  sql.NullTime{Time: session.LastSeen, Valid: true},
+		CreatedAt: sql.NullTime{Time: session.CreatedAt, Valid: true},
+		ExpiresAt: session.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	*session = *toSessionModel(dbSession)
+	return nil
+}
+
+func (r *SqlcSessionRepository) GetByID(id uuid.UUID) (*models.Session, error) {
+	ctx := context.Background()
+
+	dbSession, err := r.queries.GetSessionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toSessionModel(dbSession), nil
+}
+
+func (r *SqlcSessionRepository) ListActiveByUser(userID uuid.UUID) ([]models.Session, error) {
+	ctx := context.Background()
+
+	dbSessions, err := r.queries.ListActiveSessionsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.Session, 0, len(dbSessions))
+	for _, dbSession := range dbSessions {
+		sessions = append(sessions, *toSessionModel(dbSession))
+	}
+	return sessions, nil
+}
+
+func (r *SqlcSessionRepository) ListByUser(userID uuid.UUID) ([]models.Session, error) {
+	ctx := context.Background()
+
+	dbSessions, err := r.queries.ListSessionsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.Session, 0, len(dbSessions))
+	for _, dbSession := range dbSessions {
+		sessions = append(sessions, *toSessionModel(dbSession))
+	}
+	return sessions, nil
+}
+
+func (r *SqlcSessionRepository) HasDevice(userID uuid.UUID, deviceID string) (bool, error) {
+	ctx := context.Background()
+	return r.queries.HasSessionForDevice(ctx, db.HasSessionForDeviceParams{
+		UserID:   userID,
+		DeviceID: deviceID,
+	})
+}
+
+func (r *SqlcSessionRepository) Touch(id uuid.UUID, lastSeen, expiresAt time.Time) error {
+	ctx := context.Background()
+	return r.queries.TouchSession(ctx, db.TouchSessionParams{
+		ID:        id,
+		LastSeen:  sql.NullTime{Time: lastSeen, Valid: true},
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (r *SqlcSessionRepository) Deactivate(id uuid.UUID) error {
+	ctx := context.Background()
+	return r.queries.DeactivateSession(ctx, id)
+}
+
+// toSessionModel maps a sqlc row onto the domain model
+func toSessionModel(dbSession db.Session) *models.Session {
+	return &models.Session{
+		ID:        dbSession.ID,
+		UserID:    dbSession.UserID,
+		DeviceID:  dbSession.DeviceID,
+		UserAgent: dbSession.UserAgent.String,
+		IPAddress: dbSession.IpAddress.String,
+		Location:  dbSession.Location.String,
+		IsActive:  dbSession.IsActive.Bool,
+		LastSeen:  dbSession.LastSeen.Time,
+		CreatedAt: dbSession.CreatedAt.Time,
+		ExpiresAt: dbSession.ExpiresAt,
+		TokenHash: dbSession.TokenHash.String,
+	}
+}
@@ -3,36 +3,44 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/Flack74/go-auth-system/internal/db"
 	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/utils"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type SqlcUserRepository struct {
+	db      *sql.DB
 	queries *db.Queries
 }
 
 func NewSqlcUserRepository(dbConn *sql.DB) *SqlcUserRepository {
 	return &SqlcUserRepository{
+		db:      dbConn,
 		queries: db.New(dbConn),
 	}
 }
 
-func (r *SqlcUserRepository) Create(user *models.User) error {
+// Create inserts the user, together with any token writes and outbox
+// messages in the same transaction
+func (r *SqlcUserRepository) Create(user *models.User, tokens *TokenChange, outbox ...models.OutboxMessage) error {
 	ctx := context.Background()
-	
-	_, err := r.queries.CreateUser(ctx, db.CreateUserParams{
-		ID:               user.ID,
-		Email:            user.Email,
-		Password:         user.Password,
-		EmailVerifyToken: user.EmailVerifyToken,
-		CreatedAt:        sql.NullTime{Time: user.CreatedAt, Valid: true},
-		UpdatedAt:        sql.NullTime{Time: user.UpdatedAt, Valid: true},
+
+	return withTokens(ctx, r.db, r.queries, user.ID, tokens, outbox, func(q *db.Queries) error {
+		_, err := q.CreateUser(ctx, db.CreateUserParams{
+			ID:        user.ID,
+			Email:     user.Email,
+			Password:  user.Password,
+			CreatedAt: sql.NullTime{Time: user.CreatedAt, Valid: true},
+			UpdatedAt: sql.NullTime{Time: user.UpdatedAt, Valid: true},
+		})
+		return err
 	})
-	
-	return err
 }
 
 func (r *SqlcUserRepository) GetByEmail(email string) (*models.User, error) {
@@ -43,19 +51,7 @@ func (r *SqlcUserRepository) GetByEmail(email string) (*models.User, error) {
 		return nil, err
 	}
 	
-	return &models.User{
-		ID:                  dbUser.ID,
-		Email:               dbUser.Email,
-		Password:            dbUser.Password,
-		EmailVerified:       dbUser.EmailVerified.Bool,
-		EmailVerifyToken:    dbUser.EmailVerifyToken,
-		PasswordResetToken:  dbUser.PasswordResetToken,
-		PasswordResetExpiry: dbUser.PasswordResetExpiry,
-		FailedLoginAttempts: int(dbUser.FailedLoginAttempts.Int32),
-		LockedUntil:         dbUser.LockedUntil,
-		CreatedAt:           dbUser.CreatedAt.Time,
-		UpdatedAt:           dbUser.UpdatedAt.Time,
-	}, nil
+	return toUserModel(dbUser), nil
 }
 
 func (r *SqlcUserRepository) GetByID(id string) (*models.User, error) {
@@ -71,53 +67,38 @@ func (r *SqlcUserRepository) GetByID(id string) (*models.User, error) {
 		return nil, err
 	}
 	
-	return &models.User{
-		ID:                  dbUser.ID,
-		Email:               dbUser.Email,
-		Password:            dbUser.Password,
-		EmailVerified:       dbUser.EmailVerified.Bool,
-		EmailVerifyToken:    dbUser.EmailVerifyToken,
-		PasswordResetToken:  dbUser.PasswordResetToken,
-		PasswordResetExpiry: dbUser.PasswordResetExpiry,
-		FailedLoginAttempts: int(dbUser.FailedLoginAttempts.Int32),
-		LockedUntil:         dbUser.LockedUntil,
-		CreatedAt:           dbUser.CreatedAt.Time,
-		UpdatedAt:           dbUser.UpdatedAt.Time,
-	}, nil
+	return toUserModel(dbUser), nil
 }
 
-func (r *SqlcUserRepository) Update(user *models.User) error {
+// Update saves the user, together with any token writes and outbox messages
+// in the same transaction
+func (r *SqlcUserRepository) Update(user *models.User, tokens *TokenChange, outbox ...models.OutboxMessage) error {
 	ctx := context.Background()
-	
-	return r.queries.UpdateUser(ctx, db.UpdateUserParams{
-		ID:                  user.ID,
-		Email:               user.Email,
-		Password:            user.Password,
-		EmailVerified:       sql.NullBool{Bool: user.EmailVerified, Valid: true},
-		EmailVerifyToken:    user.EmailVerifyToken,
-		PasswordResetToken:  user.PasswordResetToken,
-		PasswordResetExpiry: user.PasswordResetExpiry,
-		FailedLoginAttempts: sql.NullInt32{Int32: int32(user.FailedLoginAttempts), Valid: true},
-		LockedUntil:         user.LockedUntil,
-		UpdatedAt:           sql.NullTime{Time: time.Now(), Valid: true},
-	})
-}
 
-func (r *SqlcUserRepository) VerifyEmail(token string) error {
-	ctx := context.Background()
-	return r.queries.VerifyEmail(ctx, db.VerifyEmailParams{
-		EmailVerifyToken: sql.NullString{String: token, Valid: true},
-		UpdatedAt:        sql.NullTime{Time: time.Now(), Valid: true},
+	return withTokens(ctx, r.db, r.queries, user.ID, tokens, outbox, func(q *db.Queries) error {
+		return q.UpdateUser(ctx, db.UpdateUserParams{
+			ID:                  user.ID,
+			Email:               user.Email,
+			Password:            user.Password,
+			EmailVerified:       sql.NullBool{Bool: user.EmailVerified, Valid: true},
+			FailedLoginAttempts: sql.NullInt32{Int32: int32(user.FailedLoginAttempts), Valid: true},
+			LockedUntil:         user.LockedUntil,
+			UpdatedAt:           sql.NullTime{Time: time.Now(), Valid: true},
+		})
 	})
 }
 
-func (r *SqlcUserRepository) IncrementFailedLoginAttempts(email string) error {
+func (r *SqlcUserRepository) IncrementFailedLoginAttempts(email string, lockout time.Duration) (int, sql.NullTime, error) {
 	ctx := context.Background()
-	return r.queries.IncrementFailedLoginAttempts(ctx, db.IncrementFailedLoginAttemptsParams{
+	row, err := r.queries.IncrementFailedLoginAttempts(ctx, db.IncrementFailedLoginAttemptsParams{
 		Email:       email,
-		LockedUntil: sql.NullTime{Time: time.Now().Add(30 * time.Minute), Valid: true},
+		LockedUntil: sql.NullTime{Time: time.Now().Add(lockout), Valid: true},
 		UpdatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
 	})
+	if err != nil {
+		return 0, sql.NullTime{}, err
+	}
+	return int(row.FailedLoginAttempts.Int32), row.LockedUntil, nil
 }
 
 func (r *SqlcUserRepository) ResetFailedLoginAttempts(email string) error {
@@ -128,25 +109,295 @@ func (r *SqlcUserRepository) ResetFailedLoginAttempts(email string) error {
 	})
 }
 
-func (r *SqlcUserRepository) GetByPasswordResetToken(token string) (*models.User, error) {
+func (r *SqlcUserRepository) SetTOTPSecret(userID uuid.UUID, secret string) error {
 	ctx := context.Background()
-	
-	dbUser, err := r.queries.GetUserByPasswordResetToken(ctx, sql.NullString{String: token, Valid: true})
+	return r.queries.SetTOTPSecret(ctx, db.SetTOTPSecretParams{
+		ID:         userID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+		UpdatedAt:  sql.NullTime{Time: time.Now(), Valid: true},
+	})
+}
+
+func (r *SqlcUserRepository) EnableTOTP(userID uuid.UUID) error {
+	ctx := context.Background()
+	now := time.Now()
+	return r.queries.EnableTOTP(ctx, db.EnableTOTPParams{
+		ID:             userID,
+		TotpVerifiedAt: sql.NullTime{Time: now, Valid: true},
+		UpdatedAt:      sql.NullTime{Time: now, Valid: true},
+	})
+}
+
+func (r *SqlcUserRepository) DisableTOTP(userID uuid.UUID) error {
+	ctx := context.Background()
+	return r.queries.DisableTOTP(ctx, db.DisableTOTPParams{
+		ID:        userID,
+		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+}
+
+// Search pages through users matching filter, newest first. Page and
+// PerPage must already be normalised by the caller.
+func (r *SqlcUserRepository) Search(filter *models.UserFilter) ([]models.AdminUserView, error) {
+	ctx := context.Background()
+
+	rows, err := r.queries.SearchUsers(ctx, db.SearchUsersParams{
+		Email:    nullEmailPattern(filter.Email),
+		Role:     sql.NullString{String: filter.Role, Valid: filter.Role != ""},
+		Verified: nullBool(filter.Verified),
+		Locked:   nullBool(filter.Locked),
+		Disabled: nullBool(filter.Disabled),
+		Limit:    int32(filter.PerPage),
+		Offset:   int32((filter.Page - 1) * filter.PerPage),
+	})
 	if err != nil {
 		return nil, err
 	}
-	
+
+	users := make([]models.AdminUserView, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, models.AdminUserView{
+			ID:                  row.ID,
+			Email:               row.Email,
+			Roles:               rolesOrDefault(row.RoleNames),
+			EmailVerified:       row.EmailVerified.Bool,
+			TOTPEnabled:         row.TotpEnabled.Bool,
+			FailedLoginAttempts: int(row.FailedLoginAttempts.Int32),
+			LockedUntil:         nullTimePtr(row.LockedUntil),
+			DisabledAt:          nullTimePtr(row.DisabledAt),
+			DeletedAt:           nullTimePtr(row.DeletedAt),
+			CreatedAt:           row.CreatedAt.Time,
+			UpdatedAt:           row.UpdatedAt.Time,
+		})
+	}
+	return users, nil
+}
+
+func (r *SqlcUserRepository) Count(filter *models.UserFilter) (int64, error) {
+	ctx := context.Background()
+	return r.queries.CountUsers(ctx, db.CountUsersParams{
+		Email:    nullEmailPattern(filter.Email),
+		Role:     sql.NullString{String: filter.Role, Valid: filter.Role != ""},
+		Verified: nullBool(filter.Verified),
+		Locked:   nullBool(filter.Locked),
+		Disabled: nullBool(filter.Disabled),
+	})
+}
+
+// Unlock clears the failed login counter and any lockout
+func (r *SqlcUserRepository) Unlock(userID uuid.UUID) error {
+	ctx := context.Background()
+	return r.queries.UnlockUser(ctx, db.UnlockUserParams{
+		ID:        userID,
+		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+}
+
+func (r *SqlcUserRepository) MarkEmailVerified(userID uuid.UUID, tokens *TokenChange, outbox ...models.OutboxMessage) error {
+	ctx := context.Background()
+	return withTokens(ctx, r.db, r.queries, userID, tokens, outbox, func(q *db.Queries) error {
+		return q.MarkEmailVerified(ctx, db.MarkEmailVerifiedParams{
+			ID:        userID,
+			UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		})
+	})
+}
+
+// errEmailTaken rolls back an email change to an address another account holds
+var errEmailTaken = errors.New("email address taken")
+
+// ChangeEmail switches the user to a new, already confirmed address,
+// together with any token writes in the same transaction. It reports false,
+// and makes none of the writes, when another account holds the address,
+// including one that took it concurrently.
+func (r *SqlcUserRepository) ChangeEmail(userID uuid.UUID, email string, tokens *TokenChange) (bool, error) {
+	ctx := context.Background()
+	err := withTokens(ctx, r.db, r.queries, userID, tokens, nil, func(q *db.Queries) error {
+		rows, err := q.ChangeUserEmail(ctx, db.ChangeUserEmailParams{
+			ID:        userID,
+			Email:     email,
+			UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		})
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errEmailTaken
+		}
+		if err != nil {
+			return err
+		}
+		if rows != 1 {
+			return errEmailTaken
+		}
+		return nil
+	})
+	if err == errEmailTaken {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *SqlcUserRepository) SetDisabled(userID uuid.UUID, disabled bool, guard *AdminGuard) error {
+	ctx := context.Background()
+	now := time.Now()
+	return withAdminGuard(ctx, r.db, r.queries, guard, func(q *db.Queries) error {
+		return q.SetUserDisabled(ctx, db.SetUserDisabledParams{
+			ID:         userID,
+			DisabledAt: sql.NullTime{Time: now, Valid: disabled},
+			UpdatedAt:  sql.NullTime{Time: now, Valid: true},
+		})
+	})
+}
+
+func (r *SqlcUserRepository) Delete(userID uuid.UUID, guard *AdminGuard) error {
+	ctx := context.Background()
+	return withAdminGuard(ctx, r.db, r.queries, guard, func(q *db.Queries) error {
+		return q.DeleteUser(ctx, userID)
+	})
+}
+
+// SoftDelete marks the account for deletion, together with any outbox
+// messages in the same transaction. An account already marked keeps its
+// original deletion time.
+func (r *SqlcUserRepository) SoftDelete(userID uuid.UUID, guard *AdminGuard, outbox ...models.OutboxMessage) error {
+	ctx := context.Background()
+	return withAdminGuard(ctx, r.db, r.queries, guard, func(q *db.Queries) error {
+		if err := q.SoftDeleteUser(ctx, db.SoftDeleteUserParams{
+			ID:        userID,
+			DeletedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		}); err != nil {
+			return err
+		}
+		return enqueueOutbox(ctx, q, outbox)
+	})
+}
+
+// Restore cancels a pending deletion
+func (r *SqlcUserRepository) Restore(userID uuid.UUID) error {
+	ctx := context.Background()
+	return r.queries.RestoreUser(ctx, db.RestoreUserParams{
+		ID:        userID,
+		UpdatedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+}
+
+// ListDueForPurge returns accounts marked for deletion at or before deletedBefore
+func (r *SqlcUserRepository) ListDueForPurge(deletedBefore time.Time, limit int) ([]uuid.UUID, error) {
+	ctx := context.Background()
+	return r.queries.ListUsersDueForPurge(ctx, db.ListUsersDueForPurgeParams{
+		DeletedAt: sql.NullTime{Time: deletedBefore, Valid: true},
+		Limit:     int32(limit),
+	})
+}
+
+// Purge permanently deletes an account marked for deletion at or before
+// deletedBefore, in one transaction with scrubbing its personal data: the
+// audit records are redacted, including failed logins recorded against its
+// address before the account was known, and undelivered outbox messages
+// for it are dropped. Sessions, backup codes, role assignments and tokens go
+// with the user row. It reports false when the account was restored or is
+// already gone.
+func (r *SqlcUserRepository) Purge(userID uuid.UUID, deletedBefore time.Time) (bool, error) {
+	ctx := context.Background()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	qtx := r.queries.WithTx(tx)
+	email, err := qtx.PurgeUser(ctx, db.PurgeUserParams{
+		ID:        userID,
+		DeletedAt: sql.NullTime{Time: deletedBefore, Valid: true},
+	})
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := qtx.RedactAuditEventsByUser(ctx, db.RedactAuditEventsByUserParams{
+		RedactedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		UserID:     uuid.NullUUID{UUID: userID, Valid: true},
+		EmailHash:  utils.HashEmail(email),
+	}); err != nil {
+		return false, err
+	}
+	if err := qtx.DeleteOutboxMessagesForUser(ctx, db.DeleteOutboxMessagesForUserParams{
+		Email:  email,
+		UserID: userID.String(),
+	}); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *SqlcUserRepository) GetTokenVersion(userID uuid.UUID) (int, error) {
+	ctx := context.Background()
+	version, err := r.queries.GetUserTokenVersion(ctx, userID)
+	return int(version), err
+}
+
+// BumpTokenVersion increments the user's access token version and returns the new one
+func (r *SqlcUserRepository) BumpTokenVersion(userID uuid.UUID) (int, error) {
+	ctx := context.Background()
+	version, err := r.queries.BumpUserTokenVersion(ctx, userID)
+	return int(version), err
+}
+
+// nullEmailPattern escapes LIKE wildcards so the search is a plain substring match
+func nullEmailPattern(email string) sql.NullString {
+	if email == "" {
+		return sql.NullString{}
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(email)
+	return sql.NullString{String: escaped, Valid: true}
+}
+
+func nullBool(b *bool) sql.NullBool {
+	if b == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *b, Valid: true}
+}
+
+// rolesOrDefault reports users without an assigned role as holding the
+// default user role, which is how they are authorised
+func rolesOrDefault(roles []string) []string {
+	if len(roles) == 0 {
+		return []string{models.RoleUser}
+	}
+	return roles
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// toUserModel maps a sqlc row onto the domain model
+func toUserModel(dbUser db.User) *models.User {
 	return &models.User{
 		ID:                  dbUser.ID,
 		Email:               dbUser.Email,
 		Password:            dbUser.Password,
 		EmailVerified:       dbUser.EmailVerified.Bool,
-		EmailVerifyToken:    dbUser.EmailVerifyToken,
-		PasswordResetToken:  dbUser.PasswordResetToken,
-		PasswordResetExpiry: dbUser.PasswordResetExpiry,
 		FailedLoginAttempts: int(dbUser.FailedLoginAttempts.Int32),
 		LockedUntil:         dbUser.LockedUntil,
+		TOTPSecret:          dbUser.TotpSecret,
+		TOTPEnabled:         dbUser.TotpEnabled.Bool,
+		TOTPVerifiedAt:      dbUser.TotpVerifiedAt,
+		DisabledAt:          dbUser.DisabledAt,
+		DeletedAt:           dbUser.DeletedAt,
 		CreatedAt:           dbUser.CreatedAt.Time,
 		UpdatedAt:           dbUser.UpdatedAt.Time,
-	}, nil
-}
\ No newline at end of file
+	}
+}
@@ -18,14 +18,13 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 
 func (r *UserRepository) Create(user *models.User) error {
 	query := `
-        INSERT INTO users (id, email, password, email_verify_token, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        INSERT INTO users (id, email, password, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5)
     `
 	_, err := r.db.Exec(query,
 		user.ID,
 		user.Email,
 		user.Password,
-		user.EmailVerifyToken,
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
@@ -35,8 +34,7 @@ func (r *UserRepository) Create(user *models.User) error {
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	user := &models.User{}
 	query := `
-        SELECT id, email, password, email_verified, email_verify_token,
-               password_reset_token, password_reset_expiry, failed_login_attempts,
+        SELECT id, email, password, email_verified, failed_login_attempts,
                locked_until, created_at, updated_at
         FROM users
         WHERE email = $1
@@ -46,9 +44,6 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 		&user.Email,
 		&user.Password,
 		&user.EmailVerified,
-		&user.EmailVerifyToken,
-		&user.PasswordResetToken,
-		&user.PasswordResetExpiry,
 		&user.FailedLoginAttempts,
 		&user.LockedUntil,
 		&user.CreatedAt,
@@ -84,9 +79,8 @@ func (r *UserRepository) GetByID(id uuid.UUID) (*models.User, error) {
 func (r *UserRepository) Update(user *models.User) error {
 	query := `
         UPDATE users
-        SET email = $2, password = $3, email_verified = $4, email_verify_token = $5,
-            password_reset_token = $6, password_reset_expiry = $7,
-            failed_login_attempts = $8, locked_until = $9, updated_at = $10
+        SET email = $2, password = $3, email_verified = $4,
+            failed_login_attempts = $5, locked_until = $6, updated_at = $7
         WHERE id = $1
     `
 	_, err := r.db.Exec(query,
@@ -94,9 +88,6 @@ func (r *UserRepository) Update(user *models.User) error {
 		user.Email,
 		user.Password,
 		user.EmailVerified,
-		user.EmailVerifyToken,
-		user.PasswordResetToken,
-		user.PasswordResetExpiry,
 		user.FailedLoginAttempts,
 		user.LockedUntil,
 		time.Now(),
@@ -104,29 +95,6 @@ func (r *UserRepository) Update(user *models.User) error {
 	return err
 }
 
-func (r *UserRepository) VerifyEmail(token string) error {
-	query := `
-        UPDATE users
-        SET email_verified = true, email_verify_token = NULL, updated_at = $2
-        WHERE email_verify_token = $1 AND email_verified = false
-    `
-	result, err := r.db.Exec(query, token, time.Now())
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
-	}
-
-	return nil
-}
-
 func (r *UserRepository) IncrementFailedLoginAttempts(email string) error {
 	query := `
         UPDATE users
@@ -151,31 +119,3 @@ func (r *UserRepository) ResetFailedLoginAttempts(email string) error {
 	_, err := r.db.Exec(query, email, time.Now())
 	return err
 }
-
-func (r *UserRepository) GetByPasswordResetToken(token string) (*models.User, error) {
-	user := &models.User{}
-	query := `
-        SELECT id, email, password, email_verified, email_verify_token,
-               password_reset_token, password_reset_expiry, failed_login_attempts,
-               locked_until, created_at, updated_at
-        FROM users
-        WHERE password_reset_token = $1
-    `
-	err := r.db.QueryRow(query, token).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Password,
-		&user.EmailVerified,
-		&user.EmailVerifyToken,
-		&user.PasswordResetToken,
-		&user.PasswordResetExpiry,
-		&user.FailedLoginAttempts,
-		&user.LockedUntil,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return user, nil
-}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/db"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+)
+
+type SqlcWebhookRepository struct {
+	queries *db.Queries
+}
+
+func NewSqlcWebhookRepository(dbConn *sql.DB) *SqlcWebhookRepository {
+	return &SqlcWebhookRepository{
+		queries: db.New(dbConn),
+	}
+}
+
+func (r *SqlcWebhookRepository) CreateSubscription(subscription *models.WebhookSubscription) error {
+	ctx := context.Background()
+
+	row, err := r.queries.CreateWebhookSubscription(ctx, db.CreateWebhookSubscriptionParams{
+		Url:      subscription.URL,
+		Secret:   subscription.Secret,
+		Events:   subscription.Events,
+		IsActive: subscription.IsActive,
+	})
+	if err != nil {
+		return err
+	}
+
+	*subscription = *toWebhookSubscriptionModel(row)
+	return nil
+}
+
+func (r *SqlcWebhookRepository) GetSubscription(id uuid.UUID) (*models.WebhookSubscription, error) {
+	ctx := context.Background()
+
+	row, err := r.queries.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhookSubscriptionModel(row), nil
+}
+
+func (r *SqlcWebhookRepository) ListSubscriptions() ([]models.WebhookSubscription, error) {
+	ctx := context.Background()
+
+	rows, err := r.queries.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]models.WebhookSubscription, 0, len(rows))
+	for _, row := range rows {
+		subscriptions = append(subscriptions, *toWebhookSubscriptionModel(row))
+	}
+	return subscriptions, nil
+}
+
+func (r *SqlcWebhookRepository) UpdateSubscription(subscription *models.WebhookSubscription) error {
+	ctx := context.Background()
+
+	row, err := r.queries.UpdateWebhookSubscription(ctx, db.UpdateWebhookSubscriptionParams{
+		ID:       subscription.ID,
+		Url:      subscription.URL,
+		Events:   subscription.Events,
+		IsActive: subscription.IsActive,
+	})
+	if err != nil {
+		return err
+	}
+
+	*subscription = *toWebhookSubscriptionModel(row)
+	return nil
+}
+
+// DeleteSubscription removes the subscription and its deliveries; it
+// returns sql.ErrNoRows when there is no such subscription
+func (r *SqlcWebhookRepository) DeleteSubscription(id uuid.UUID) error {
+	ctx := context.Background()
+
+	deleted, err := r.queries.DeleteWebhookSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SqlcWebhookRepository) Enqueue(eventID uuid.UUID, eventType string, payload []byte) (int64, error) {
+	ctx := context.Background()
+	return r.queries.EnqueueWebhookDeliveries(ctx, db.EnqueueWebhookDeliveriesParams{
+		EventID:   eventID,
+		EventType: eventType,
+		Payload:   payload,
+	})
+}
+
+func (r *SqlcWebhookRepository) ClaimDue(leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	ctx := context.Background()
+
+	rows, err := r.queries.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+		LeaseUntil: leaseUntil,
+		BatchSize:  int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toWebhookDeliveryModels(rows), nil
+}
+
+func (r *SqlcWebhookRepository) MarkDelivered(id uuid.UUID, statusCode int) error {
+	ctx := context.Background()
+	return r.queries.MarkWebhookDelivered(ctx, db.MarkWebhookDeliveredParams{
+		ID:             id,
+		LastStatusCode: sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0},
+	})
+}
+
+func (r *SqlcWebhookRepository) MarkFailed(id uuid.UUID, status string, nextAttemptAt time.Time, statusCode int, lastError string) error {
+	ctx := context.Background()
+	return r.queries.MarkWebhookFailed(ctx, db.MarkWebhookFailedParams{
+		ID:             id,
+		Status:         status,
+		NextAttemptAt:  nextAttemptAt,
+		LastStatusCode: sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0},
+		LastError:      sql.NullString{String: lastError, Valid: lastError != ""},
+	})
+}
+
+func (r *SqlcWebhookRepository) GetDelivery(id uuid.UUID) (*models.WebhookDelivery, error) {
+	ctx := context.Background()
+
+	row, err := r.queries.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhookDeliveryModel(row), nil
+}
+
+// ListDeliveries returns one page of deliveries, newest first. An empty
+// status or nil subscriptionID matches every delivery.
+func (r *SqlcWebhookRepository) ListDeliveries(status string, subscriptionID uuid.UUID, limit, offset int) ([]models.WebhookDelivery, error) {
+	ctx := context.Background()
+
+	rows, err := r.queries.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		Status:         sql.NullString{String: status, Valid: status != ""},
+		SubscriptionID: uuid.NullUUID{UUID: subscriptionID, Valid: subscriptionID != uuid.Nil},
+		Limit:          int32(limit),
+		Offset:         int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toWebhookDeliveryModels(rows), nil
+}
+
+func (r *SqlcWebhookRepository) CountDeliveries(status string, subscriptionID uuid.UUID) (int64, error) {
+	ctx := context.Background()
+	return r.queries.CountWebhookDeliveries(ctx, db.CountWebhookDeliveriesParams{
+		Status:         sql.NullString{String: status, Valid: status != ""},
+		SubscriptionID: uuid.NullUUID{UUID: subscriptionID, Valid: subscriptionID != uuid.Nil},
+	})
+}
+
+// Replay requeues a dead delivery and reports whether there was one
+func (r *SqlcWebhookRepository) Replay(id uuid.UUID) (bool, error) {
+	ctx := context.Background()
+	replayed, err := r.queries.ReplayWebhookDelivery(ctx, id)
+	return replayed > 0, err
+}
+
+func (r *SqlcWebhookRepository) ReplayDead(subscriptionID uuid.UUID) (int64, error) {
+	ctx := context.Background()
+	return r.queries.ReplayDeadWebhookDeliveries(ctx, subscriptionID)
+}
+
+func toWebhookSubscriptionModel(row db.WebhookSubscription) *models.WebhookSubscription {
+	return &models.WebhookSubscription{
+		ID:        row.ID,
+		URL:       row.Url,
+		Secret:    row.Secret,
+		Events:    row.Events,
+		IsActive:  row.IsActive,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
+
+func toWebhookDeliveryModel(row db.WebhookDelivery) *models.WebhookDelivery {
+	delivery := &models.WebhookDelivery{
+		ID:             row.ID,
+		SubscriptionID: row.SubscriptionID,
+		EventID:        row.EventID,
+		EventType:      row.EventType,
+		Payload:        row.Payload,
+		Status:         row.Status,
+		Attempts:       int(row.Attempts),
+		NextAttemptAt:  row.NextAttemptAt,
+		LastStatusCode: int(row.LastStatusCode.Int32),
+		LastError:      row.LastError.String,
+		CreatedAt:      row.CreatedAt,
+	}
+	if row.DeliveredAt.Valid {
+		delivery.DeliveredAt = &row.DeliveredAt.Time
+	}
+	return delivery
+}
+
+func toWebhookDeliveryModels(rows []db.WebhookDelivery) []models.WebhookDelivery {
+	deliveries := make([]models.WebhookDelivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, *toWebhookDeliveryModel(row))
+	}
+	return deliveries
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	accountPurgeInterval  = time.Hour
+	accountPurgeBatchSize = 50
+	accountExportPageSize = 200
+)
+
+// AccountService hands users the data held about them and, in the
+// background, purges accounts whose deletion grace period has ended
+type AccountService struct {
+	userRepo    repository.UserRepositoryInterface
+	sessionRepo repository.SessionRepositoryInterface
+	auditRepo   repository.AuditRepositoryInterface
+	logger      *utils.Logger
+	config      *config.Config
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+func NewAccountService(userRepo repository.UserRepositoryInterface, sessionRepo repository.SessionRepositoryInterface, auditRepo repository.AuditRepositoryInterface, config *config.Config) *AccountService {
+	return &AccountService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		auditRepo:   auditRepo,
+		logger:      utils.NewLogger(),
+		config:      config,
+		stop:        make(chan struct{}),
+	}
+}
+
+// ExportAccount gathers the user's profile, sessions and full activity log
+func (s *AccountService) ExportAccount(userID uuid.UUID) (*models.AccountExport, error) {
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.sessionRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.AccountExport{
+		ExportedAt: time.Now().UTC(),
+		Profile:    user,
+		Sessions:   sessions,
+		Activity:   []models.ActivityEntry{},
+	}
+	for offset := 0; ; offset += accountExportPageSize {
+		events, err := s.auditRepo.ListByUser(userID, accountExportPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for i := range events {
+			export.Activity = append(export.Activity, events[i].Activity())
+		}
+		if len(events) < accountExportPageSize {
+			break
+		}
+	}
+	return export, nil
+}
+
+// Start begins purging accounts whose grace period has ended
+func (s *AccountService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.PurgeDue()
+		ticker := time.NewTicker(accountPurgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.PurgeDue()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits for the current batch to finish and stops purging
+func (s *AccountService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// PurgeDue permanently deletes every account whose grace period has ended,
+// one batch at a time, and returns how many were purged
+func (s *AccountService) PurgeDue() int {
+	cutoff := time.Now().Add(-s.config.AccountDeletionGrace)
+	purged := 0
+
+	for {
+		userIDs, err := s.userRepo.ListDueForPurge(cutoff, accountPurgeBatchSize)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to list accounts due for purge")
+			return purged
+		}
+
+		for _, userID := range userIDs {
+			// False when the user restored the account since the batch was listed
+			ok, err := s.userRepo.Purge(userID, cutoff)
+			if err != nil {
+				s.logger.WithField("user_id", userID).WithError(err).Error("Failed to purge account")
+				return purged
+			}
+			if !ok {
+				continue
+			}
+
+			purged++
+			s.logger.WithField("user_id", userID).Info("Purged deleted account")
+			if err := s.auditRepo.Create(&models.AuditEvent{
+				UserID:    userID,
+				Source:    models.AuditSourceAccount,
+				EventType: models.AuditAccountPurged,
+				CreatedAt: time.Now(),
+			}); err != nil {
+				s.logger.WithField("user_id", userID).WithError(err).Error("Failed to record account purge")
+			}
+		}
+
+		if len(userIDs) < accountPurgeBatchSize {
+			return purged
+		}
+		select {
+		case <-s.stop:
+			return purged
+		default:
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubSessionRepo holds sessions in memory
+type stubSessionRepo struct {
+	sessions []models.Session
+}
+
+func (r *stubSessionRepo) Create(session *models.Session) error {
+	r.sessions = append(r.sessions, *session)
+	return nil
+}
+
+func (r *stubSessionRepo) GetByID(id uuid.UUID) (*models.Session, error) {
+	for i := range r.sessions {
+		if r.sessions[i].ID == id {
+			return &r.sessions[i], nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *stubSessionRepo) ListActiveByUser(userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.IsActive {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *stubSessionRepo) ListByUser(userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *stubSessionRepo) HasDevice(userID uuid.UUID, deviceID string) (bool, error) {
+	return false, nil
+}
+
+func (r *stubSessionRepo) Touch(id uuid.UUID, lastSeen, expiresAt time.Time) error {
+	return nil
+}
+
+func (r *stubSessionRepo) Deactivate(id uuid.UUID) error {
+	return nil
+}
+
+func TestAccountService_ExportAccount(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	sessions := &stubSessionRepo{}
+	audit := &stubAuditRepo{}
+	service := NewAccountService(mockRepo, sessions, audit, &config.Config{})
+
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	sessions.Create(&models.Session{ID: uuid.New(), UserID: user.ID, IsActive: true, TokenHash: "secret"})
+	sessions.Create(&models.Session{ID: uuid.New(), UserID: user.ID})
+	sessions.Create(&models.Session{ID: uuid.New(), UserID: uuid.New()})
+	audit.Create(&models.AuditEvent{UserID: user.ID, EventType: models.AuditRegister, CreatedAt: time.Now()})
+	audit.Create(&models.AuditEvent{UserID: uuid.New(), EventType: models.AuditRegister, CreatedAt: time.Now()})
+	audit.Create(&models.AuditEvent{UserID: user.ID, EventType: models.AuditLoginSucceeded, IPAddress: "203.0.113.7", CreatedAt: time.Now()})
+
+	export, err := service.ExportAccount(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, user, export.Profile)
+	assert.Len(t, export.Sessions, 2)
+	if assert.Len(t, export.Activity, 2) {
+		assert.Equal(t, "Account created", export.Activity[0].Description)
+		assert.Equal(t, "203.0.113.7", export.Activity[1].IP)
+	}
+}
+
+func TestAccountService_PurgeDue(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	audit := &stubAuditRepo{}
+	service := NewAccountService(mockRepo, &stubSessionRepo{}, audit, &config.Config{AccountDeletionGrace: 720 * time.Hour})
+
+	purged, restored := uuid.New(), uuid.New()
+	cutoff := mock.MatchedBy(func(at time.Time) bool {
+		return at.Sub(time.Now().Add(-720*time.Hour)).Abs() < time.Minute
+	})
+	mockRepo.On("ListDueForPurge", cutoff, accountPurgeBatchSize).Return([]uuid.UUID{purged, restored}, nil).Once()
+	mockRepo.On("Purge", purged, cutoff).Return(true, nil).Once()
+	// Signed in after the batch was listed
+	mockRepo.On("Purge", restored, cutoff).Return(false, nil).Once()
+
+	assert.Equal(t, 1, service.PurgeDue())
+	if assert.Len(t, audit.events, 1) {
+		assert.Equal(t, purged, audit.events[0].UserID)
+		assert.Equal(t, models.AuditAccountPurged, audit.events[0].EventType)
+	}
+	mockRepo.AssertExpectations(t)
+}
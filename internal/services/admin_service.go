@@ -0,0 +1,265 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Page size bounds for the admin user search
+const (
+	defaultUsersPerPage = 20
+	maxUsersPerPage     = 100
+)
+
+var ErrCannotModifySelf = errors.New("administrators cannot perform this action on their own account")
+
+// AdminService backs the /api/admin user management endpoints
+type AdminService struct {
+	userRepo    repository.UserRepositoryInterface
+	authService *AuthService
+	rbacService *RBACService
+}
+
+func NewAdminService(userRepo repository.UserRepositoryInterface, authService *AuthService, rbacService *RBACService) *AdminService {
+	return &AdminService{
+		userRepo:    userRepo,
+		authService: authService,
+		rbacService: rbacService,
+	}
+}
+
+// SearchUsers returns one page of users matching the filter
+func (s *AdminService) SearchUsers(filter *models.UserFilter) (*models.UserListResponse, error) {
+	filter.Email = strings.ToLower(strings.TrimSpace(filter.Email))
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultUsersPerPage
+	}
+	if filter.PerPage > maxUsersPerPage {
+		filter.PerPage = maxUsersPerPage
+	}
+
+	users, err := s.userRepo.Search(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.userRepo.Count(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.UserListResponse{
+		Users:   users,
+		Total:   total,
+		Page:    filter.Page,
+		PerPage: filter.PerPage,
+	}, nil
+}
+
+// GetUser returns a single user with their account state and role
+func (s *AdminService) GetUser(userID uuid.UUID) (*models.AdminUserView, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.rbacService.UserRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &models.AdminUserView{
+		ID:                  user.ID,
+		Email:               user.Email,
+		Roles:               roles,
+		EmailVerified:       user.EmailVerified,
+		TOTPEnabled:         user.TOTPEnabled,
+		FailedLoginAttempts: user.FailedLoginAttempts,
+		CreatedAt:           user.CreatedAt,
+		UpdatedAt:           user.UpdatedAt,
+	}
+	if user.LockedUntil.Valid {
+		view.LockedUntil = &user.LockedUntil.Time
+	}
+	if user.DisabledAt.Valid {
+		view.DisabledAt = &user.DisabledAt.Time
+	}
+	if user.DeletedAt.Valid {
+		view.DeletedAt = &user.DeletedAt.Time
+	}
+	return view, nil
+}
+
+// UnlockUser clears failed login attempts and any active lockout
+func (s *AdminService) UnlockUser(userID uuid.UUID) error {
+	if _, err := s.getUser(userID); err != nil {
+		return err
+	}
+	return s.userRepo.Unlock(userID)
+}
+
+// VerifyUserEmail marks the user's email as verified without the emailed link
+func (s *AdminService) VerifyUserEmail(userID uuid.UUID) error {
+	if _, err := s.getUser(userID); err != nil {
+		return err
+	}
+	return s.userRepo.MarkEmailVerified(userID, nil)
+}
+
+// ForcePasswordReset invalidates the current password and emails a reset link
+func (s *AdminService) ForcePasswordReset(userID uuid.UUID) error {
+	if _, err := s.getUser(userID); err != nil {
+		return err
+	}
+	return s.authService.ForcePasswordReset(userID)
+}
+
+// SetUserDisabled disables or re-enables an account. Disabling signs the
+// user out of every device first, so a failed sign-out leaves the account
+// as it was and the request can simply be retried.
+func (s *AdminService) SetUserDisabled(adminID, userID uuid.UUID, disabled bool) error {
+	if adminID == userID {
+		return ErrCannotModifySelf
+	}
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	var guard *repository.AdminGuard
+	if disabled {
+		if guard, err = s.lastAdminGuard(user, nil); err != nil {
+			return err
+		}
+	}
+
+	if disabled {
+		if err := s.authService.SignOutEverywhere(userID); err != nil {
+			return err
+		}
+	}
+	if err := s.userRepo.SetDisabled(userID, disabled, guard); err != nil {
+		return lastAdminError(err)
+	}
+	if disabled {
+		s.authService.signOutAfterChange(userID)
+	}
+	return nil
+}
+
+// DeleteUser signs the user out everywhere and removes the account
+func (s *AdminService) DeleteUser(adminID, userID uuid.UUID) error {
+	if adminID == userID {
+		return ErrCannotModifySelf
+	}
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	guard, err := s.lastAdminGuard(user, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authService.SignOutEverywhere(userID); err != nil {
+		return err
+	}
+	if err := s.userRepo.Delete(userID, guard); err != nil {
+		return lastAdminError(err)
+	}
+	return s.rbacService.InvalidateUser(userID)
+}
+
+// GrantRole gives the user another role. Role changes first reject the
+// user's outstanding access tokens so they can't outlive the old grant; a
+// change that then fails only costs the user a token refresh.
+func (s *AdminService) GrantRole(adminID, userID uuid.UUID, role string) error {
+	if adminID == userID {
+		return ErrCannotModifySelf
+	}
+	if _, err := s.getUser(userID); err != nil {
+		return err
+	}
+	if err := s.authService.RevokeAccessTokens(userID); err != nil {
+		return err
+	}
+	return s.rbacService.GrantRole(userID, role)
+}
+
+// RevokeRole takes one role away from the user
+func (s *AdminService) RevokeRole(adminID, userID uuid.UUID, role string) error {
+	if adminID == userID {
+		return ErrCannotModifySelf
+	}
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.rbacService.UserRoles(userID)
+	if err != nil {
+		return err
+	}
+	remaining := make([]string, 0, len(current))
+	for _, r := range current {
+		if r != role {
+			remaining = append(remaining, r)
+		}
+	}
+	guard, err := s.lastAdminGuard(user, remaining)
+	if err != nil {
+		return err
+	}
+	if err := s.authService.RevokeAccessTokens(userID); err != nil {
+		return err
+	}
+	return s.rbacService.RevokeRole(userID, role, guard)
+}
+
+// SetRoles replaces every role the user holds
+func (s *AdminService) SetRoles(adminID, userID uuid.UUID, roles []string) error {
+	if adminID == userID {
+		return ErrCannotModifySelf
+	}
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+	guard, err := s.lastAdminGuard(user, roles)
+	if err != nil {
+		return err
+	}
+	if err := s.authService.RevokeAccessTokens(userID); err != nil {
+		return err
+	}
+	return s.rbacService.SetRoles(userID, roles, guard)
+}
+
+// lastAdminGuard returns the guard for leaving an enabled user holding only
+// the remaining roles; nil means they are losing their account. Disabled
+// users and those pending deletion aren't counted as administrators, so
+// need none.
+func (s *AdminService) lastAdminGuard(user *models.User, remaining []string) (*repository.AdminGuard, error) {
+	if user.IsDisabled() || user.IsDeleted() {
+		return nil, nil
+	}
+	return s.rbacService.LastAdminGuard(user.ID, remaining)
+}
+
+func (s *AdminService) getUser(userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAdminService_RoleChangesRevokeAccessTokensFirst(t *testing.T) {
+	roles := newStubRoleRepo()
+	roles.addRole(models.RoleUser)
+	roles.addRole(models.RoleModerator)
+
+	// Nothing listens here: invalidating the cache fails after the change
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer redisClient.Close()
+
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	admin := NewAdminService(mockRepo, &AuthService{tokenService: mockToken}, NewRBACService(roles, redisClient))
+
+	adminID := uuid.New()
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	roles.userRoles[user.ID] = nil
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+
+	// Each bump must find the roles as they were before the change
+	var seen [][]uuid.UUID
+	mockToken.On("RevokeUserAccessTokens", user.ID).Run(func(mock.Arguments) {
+		seen = append(seen, append([]uuid.UUID{}, roles.userRoles[user.ID]...))
+	}).Return(nil)
+
+	moderator, _ := roles.GetByName(models.RoleModerator)
+	member, _ := roles.GetByName(models.RoleUser)
+
+	// The cache can't be reached once the roles have changed
+	assert.Error(t, admin.GrantRole(adminID, user.ID, models.RoleModerator))
+	assert.Equal(t, []uuid.UUID{moderator.ID}, roles.userRoles[user.ID])
+	assert.Error(t, admin.SetRoles(adminID, user.ID, []string{models.RoleModerator, models.RoleUser}))
+	assert.Equal(t, []uuid.UUID{moderator.ID, member.ID}, roles.userRoles[user.ID])
+	assert.Error(t, admin.RevokeRole(adminID, user.ID, models.RoleModerator))
+	assert.Equal(t, []uuid.UUID{member.ID}, roles.userRoles[user.ID])
+
+	assert.Equal(t, [][]uuid.UUID{{}, {moderator.ID}, {moderator.ID, member.ID}}, seen)
+	mockToken.AssertNumberOfCalls(t, "RevokeUserAccessTokens", 3)
+
+	// A failed bump leaves the roles alone
+	failing := new(MockTokenSvc)
+	failing.On("RevokeUserAccessTokens", user.ID).Return(assert.AnError)
+	admin.authService.tokenService = failing
+	assert.Equal(t, assert.AnError, admin.GrantRole(adminID, user.ID, models.RoleModerator))
+	assert.Equal(t, []uuid.UUID{member.ID}, roles.userRoles[user.ID])
+}
+
+func TestAdminService_SetUserDisabledSignsOutFirst(t *testing.T) {
+	roles := newStubRoleRepo()
+	roles.addRole(models.RoleUser)
+
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+	auth := &AuthService{tokenService: mockToken, sessionService: mockSession, logger: utils.NewLogger()}
+	admin := NewAdminService(mockRepo, auth, NewRBACService(roles, nil))
+
+	adminID := uuid.New()
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	roles.userRoles[user.ID] = nil
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+
+	// A failed sign-out leaves the account enabled, so the request can be retried
+	mockSession.On("RevokeAllUserSessions", user.ID).Return(assert.AnError).Once()
+	assert.Equal(t, assert.AnError, admin.SetUserDisabled(adminID, user.ID, true))
+	mockRepo.AssertNotCalled(t, "SetDisabled", mock.Anything, mock.Anything, mock.Anything)
+
+	// Otherwise the user is signed out before the account is disabled, and
+	// again after it, for devices that signed in meanwhile
+	var disabledAfter int
+	mockSession.On("RevokeAllUserSessions", user.ID).Return(nil).Twice()
+	mockToken.On("RevokeAllUserTokens", user.ID).Return(nil).Twice()
+	mockToken.On("RevokeUserAccessTokens", user.ID).Return(nil).Twice()
+	mockRepo.On("SetDisabled", user.ID, true, (*repository.AdminGuard)(nil)).Run(func(mock.Arguments) {
+		disabledAfter = len(mockSession.Calls)
+	}).Return(nil).Once()
+	assert.NoError(t, admin.SetUserDisabled(adminID, user.ID, true))
+	assert.Equal(t, 2, disabledAfter, "expected the sign-out to come first")
+	mockRepo.AssertExpectations(t)
+	mockSession.AssertExpectations(t)
+	mockToken.AssertExpectations(t)
+}
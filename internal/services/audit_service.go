@@ -0,0 +1,359 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	// auditBatchSize is how many records verification and export read at once
+	auditBatchSize = 500
+	// auditQueueSize bounds the log events waiting to be written
+	auditQueueSize = 1024
+)
+
+var (
+	ErrInvalidExportFormat = errors.New("export format must be jsonl or csv")
+	ErrInvalidExportRange  = errors.New("export range must end after it starts")
+	ErrAuditChainBroken    = errors.New("audit chain does not verify under the old key")
+)
+
+var auditCSVHeader = []string{
+	"id", "created_at", "user_id", "source", "event_type", "ip_address",
+	"user_agent", "correlation_id", "metadata", "prev_hash", "hash",
+	"pii_nonce", "pii_hash",
+}
+
+// AuditService verifies and exports the audit trail. It is also the
+// utils.EventSink that copies logged auth and security events into it.
+type AuditService struct {
+	auditRepo repository.AuditRepositoryInterface
+	chainKey  []byte
+	logger    *utils.Logger
+	queue     chan *models.AuditEvent
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewAuditService verifies the chain with chainKey, the key the repository
+// hashes records with
+func NewAuditService(auditRepo repository.AuditRepositoryInterface, chainKey []byte) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+		chainKey:  chainKey,
+		logger:    utils.NewLogger(),
+		queue:     make(chan *models.AuditEvent, auditQueueSize),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start begins writing queued log events to the audit trail
+func (s *AuditService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			select {
+			case event := <-s.queue:
+				s.write(event)
+			case <-s.stop:
+				s.drain()
+				return
+			}
+		}
+	}()
+}
+
+// Stop writes any events still queued and stops the writer
+func (s *AuditService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *AuditService) drain() {
+	for {
+		select {
+		case event := <-s.queue:
+			s.write(event)
+		default:
+			return
+		}
+	}
+}
+
+func (s *AuditService) write(event *models.AuditEvent) {
+	if err := s.auditRepo.Create(event); err != nil {
+		s.logger.WithField("correlation_id", event.CorrelationID).
+			WithField("event_type", event.EventType).
+			WithError(err).
+			Error("Failed to record audit event")
+	}
+}
+
+// RecordLogEvent queues a logged event for the audit trail. Writes happen in
+// the background so logging never waits on the database; when the queue is
+// full the event is dropped and only the log line remains. An email address
+// is kept only as its digest, which purging the account redacts.
+func (s *AuditService) RecordLogEvent(source, event, correlationID string, data map[string]interface{}) {
+	auditEvent := &models.AuditEvent{
+		Source:        source,
+		EventType:     event,
+		CorrelationID: correlationID,
+		Metadata:      map[string]interface{}{},
+		CreatedAt:     time.Now(),
+	}
+	for k, v := range data {
+		switch k {
+		case "user_id":
+			if id, ok := toUUID(v); ok {
+				auditEvent.UserID = id
+				continue
+			}
+		case "ip":
+			if ip, ok := v.(string); ok {
+				auditEvent.IPAddress = ip
+				continue
+			}
+		case "user_agent":
+			if ua, ok := v.(string); ok {
+				auditEvent.UserAgent = ua
+				continue
+			}
+		case "email":
+			if email, ok := v.(string); ok {
+				auditEvent.Metadata["email_hash"] = utils.HashEmail(email)
+			}
+			continue
+		}
+		auditEvent.Metadata[k] = v
+	}
+
+	select {
+	case s.queue <- auditEvent:
+	default:
+		s.logger.WithField("correlation_id", correlationID).
+			WithField("event_type", event).
+			Error("Audit queue full, dropping event")
+	}
+}
+
+func toUUID(v interface{}) (uuid.UUID, bool) {
+	switch id := v.(type) {
+	case uuid.UUID:
+		return id, true
+	case string:
+		parsed, err := uuid.Parse(id)
+		return parsed, err == nil
+	}
+	return uuid.Nil, false
+}
+
+// VerifyChain walks the audit trail in insertion order and reports the first
+// record whose hash or link to its predecessor does not match. Removing the
+// newest records cannot be detected from the chain alone; compare HeadHash
+// with a previously saved value for that.
+func (s *AuditService) VerifyChain() (*models.AuditChainReport, error) {
+	return s.verifyChain(s.chainKey)
+}
+
+// RekeyChain re-hashes the audit trail under the service's key, after
+// checking it verifies under oldKey: empty for records written before a key
+// was set, or the key being rotated out. Records written while it runs would
+// be hashed under neither, so the API must be stopped first.
+func (s *AuditService) RekeyChain(oldKey []byte) (int64, error) {
+	report, err := s.verifyChain(oldKey)
+	if err != nil {
+		return 0, err
+	}
+	if !report.Valid {
+		return 0, fmt.Errorf("%w: record %d: %s", ErrAuditChainBroken, report.BrokenAt, report.Reason)
+	}
+	return s.auditRepo.Rekey()
+}
+
+func (s *AuditService) verifyChain(key []byte) (*models.AuditChainReport, error) {
+	report := &models.AuditChainReport{}
+	var afterID int64
+	chained := false
+
+	for {
+		events, err := s.auditRepo.ListAfter(afterID, auditBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		for i := range events {
+			event := &events[i]
+			afterID = event.ID
+
+			// Records from before chaining was introduced
+			if event.Hash == "" && !chained {
+				report.Unchained++
+				continue
+			}
+			chained = true
+
+			if event.Hash == "" {
+				return broken(report, event.ID, "record has no hash"), nil
+			}
+			if event.PrevHash != report.HeadHash {
+				return broken(report, event.ID, "previous hash does not match the preceding record"), nil
+			}
+
+			// A redacted record's commitment can't be checked, so it must
+			// hold nothing it could have been checked against
+			if event.RedactedAt != nil && !event.Scrubbed() {
+				return broken(report, event.ID, "redacted record still holds personal data"), nil
+			}
+			// Scrubbed records from before PII commitments still link the
+			// chain, but their contents no longer hash to the stored value
+			if event.PIIHash == "" && event.RedactedAt != nil {
+				report.HeadHash = event.Hash
+				report.Redacted++
+				continue
+			}
+			// Otherwise the hash covers the commitment, which the personal
+			// data must match until it is scrubbed
+			if event.PIIHash != "" && event.RedactedAt == nil {
+				matches, err := event.PIIMatches()
+				if err != nil {
+					return nil, err
+				}
+				if !matches {
+					return broken(report, event.ID, "personal data does not match its commitment"), nil
+				}
+			}
+			hash, err := event.ComputeHash(key)
+			if err != nil {
+				return nil, err
+			}
+			if hash != event.Hash {
+				return broken(report, event.ID, "record contents do not match its hash"), nil
+			}
+
+			report.HeadHash = event.Hash
+			report.Checked++
+		}
+	}
+
+	report.Valid = true
+	return report, nil
+}
+
+func broken(report *models.AuditChainReport, id int64, reason string) *models.AuditChainReport {
+	report.BrokenAt = id
+	report.Reason = reason
+	return report
+}
+
+// ValidateExport checks the export query and fills in the default format
+func (s *AuditService) ValidateExport(query *models.AuditExportQuery) error {
+	query.Format = strings.ToLower(strings.TrimSpace(query.Format))
+	if query.Format == "" {
+		query.Format = models.AuditExportJSONL
+	}
+	if query.Format != models.AuditExportJSONL && query.Format != models.AuditExportCSV {
+		return ErrInvalidExportFormat
+	}
+	if !query.To.After(query.From) {
+		return ErrInvalidExportRange
+	}
+	return nil
+}
+
+// Export writes every event created in the query's range to w, in chain
+// order, as JSON Lines or CSV. The query must have passed ValidateExport.
+func (s *AuditService) Export(w io.Writer, query *models.AuditExportQuery) error {
+	var csvWriter *csv.Writer
+	encoder := json.NewEncoder(w)
+	if query.Format == models.AuditExportCSV {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(auditCSVHeader); err != nil {
+			return err
+		}
+	}
+
+	var afterID int64
+	for {
+		events, err := s.auditRepo.ListInRange(query.From, query.To, afterID, auditBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		for i := range events {
+			event := &events[i]
+			afterID = event.ID
+
+			if csvWriter == nil {
+				if err := encoder.Encode(event); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := csvWriter.Write(auditCSVRecord(event)); err != nil {
+				return err
+			}
+		}
+
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	return nil
+}
+
+func auditCSVRecord(event *models.AuditEvent) []string {
+	metadata := []byte("{}")
+	if len(event.Metadata) > 0 {
+		if encoded, err := json.Marshal(event.Metadata); err == nil {
+			metadata = encoded
+		}
+	}
+
+	userID := ""
+	if event.UserID != uuid.Nil {
+		userID = event.UserID.String()
+	}
+
+	return []string{
+		strconv.FormatInt(event.ID, 10),
+		event.CreatedAt.UTC().Format(time.RFC3339Nano),
+		userID,
+		event.Source,
+		event.EventType,
+		event.IPAddress,
+		event.UserAgent,
+		event.CorrelationID,
+		string(metadata),
+		event.PrevHash,
+		event.Hash,
+		event.PIINonce,
+		event.PIIHash,
+	}
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"github.com/google/uuid"
+)
+
+func seedAuditTrail(t *testing.T, repo *stubAuditRepo, start time.Time, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		err := repo.Create(&models.AuditEvent{
+			UserID:    uuid.New(),
+			Source:    models.AuditSourceAccount,
+			EventType: models.AuditLoginSucceeded,
+			IPAddress: "203.0.113.7",
+			Metadata:  map[string]interface{}{"attempt": float64(i)},
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+}
+
+func TestAuditService_VerifyChain(t *testing.T) {
+	repo := &stubAuditRepo{}
+	// Rows written before chaining carry no hash
+	repo.events = append(repo.events, models.AuditEvent{ID: 1, EventType: models.AuditRegister})
+	seedAuditTrail(t, repo, time.Now(), 5)
+	service := NewAuditService(repo, nil)
+
+	report, err := service.VerifyChain()
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !report.Valid || report.Checked != 5 || report.Unchained != 1 || report.HeadHash != repo.events[5].Hash {
+		t.Fatalf("expected an intact chain, got %+v", report)
+	}
+
+	// Editing a record invalidates its own hash
+	repo.events[3].Metadata["attempt"] = float64(99)
+	report, _ = service.VerifyChain()
+	if report.Valid || report.BrokenAt != repo.events[3].ID {
+		t.Fatalf("expected a break at %d, got %+v", repo.events[3].ID, report)
+	}
+	repo.events[3].Metadata["attempt"] = float64(2)
+
+	// Deleting a record breaks the link from the next one
+	next := repo.events[4].ID
+	repo.events = append(repo.events[:3], repo.events[4:]...)
+	report, _ = service.VerifyChain()
+	if report.Valid || report.BrokenAt != next {
+		t.Fatalf("expected a break at %d, got %+v", next, report)
+	}
+}
+
+func TestAuditService_VerifyChain_Keyed(t *testing.T) {
+	repo := &stubAuditRepo{}
+	seedAuditTrail(t, repo, time.Now(), 3)
+	repo.key = []byte(strings.Repeat("k", 32))
+	service := NewAuditService(repo, repo.key)
+
+	// Records hashed before the key was set don't verify under it
+	report, err := service.VerifyChain()
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if report.Valid || report.BrokenAt != repo.events[0].ID {
+		t.Fatalf("expected unkeyed records to fail, got %+v", report)
+	}
+
+	rekeyed, err := service.RekeyChain(nil)
+	if err != nil || rekeyed != 3 {
+		t.Fatalf("expected 3 records rekeyed, got %d, %v", rekeyed, err)
+	}
+	seedAuditTrail(t, repo, time.Now(), 2)
+	report, _ = service.VerifyChain()
+	if !report.Valid || report.Checked != 5 {
+		t.Fatalf("expected an intact chain, got %+v", report)
+	}
+
+	// Without the key, an edited record can't be given a matching hash
+	repo.events[1].Metadata["attempt"] = float64(99)
+	for i := 1; i < len(repo.events); i++ {
+		repo.events[i].PrevHash = repo.events[i-1].Hash
+		if repo.events[i].Hash, err = repo.events[i].ComputeHash(nil); err != nil {
+			t.Fatalf("hash: %v", err)
+		}
+	}
+	report, _ = service.VerifyChain()
+	if report.Valid || report.BrokenAt != repo.events[1].ID {
+		t.Fatalf("expected a break at %d, got %+v", repo.events[1].ID, report)
+	}
+
+	// A chain that doesn't verify under the old key is left alone
+	hash := repo.events[1].Hash
+	if _, err := service.RekeyChain(repo.key); !errors.Is(err, ErrAuditChainBroken) {
+		t.Fatalf("expected ErrAuditChainBroken, got %v", err)
+	}
+	if repo.events[1].Hash != hash {
+		t.Fatalf("a refused rekey must not rewrite hashes")
+	}
+}
+
+func TestAuditService_VerifyChain_Redacted(t *testing.T) {
+	repo := &stubAuditRepo{}
+	seedAuditTrail(t, repo, time.Now(), 4)
+	service := NewAuditService(repo, nil)
+
+	// A purged account's records lose their personal data and its salt, but
+	// the commitment to it keeps them verifiable
+	redactedAt := time.Now()
+	repo.events[1].IPAddress = ""
+	repo.events[1].Metadata = nil
+	repo.events[1].PIINonce = ""
+	repo.events[1].RedactedAt = &redactedAt
+
+	report, err := service.VerifyChain()
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !report.Valid || report.Checked != 4 || report.Redacted != 0 || report.HeadHash != repo.events[3].Hash {
+		t.Fatalf("expected an intact chain, got %+v", report)
+	}
+
+	// So editing what a redacted record still holds is detected
+	repo.events[1].EventType = models.AuditLoginFailed
+	report, _ = service.VerifyChain()
+	if report.Valid || report.BrokenAt != repo.events[1].ID {
+		t.Fatalf("expected a break at %d, got %+v", repo.events[1].ID, report)
+	}
+	repo.events[1].EventType = models.AuditLoginSucceeded
+
+	// As is personal data that no longer matches its commitment, even with
+	// the hash recomputed to cover it
+	repo.events[2].IPAddress = "198.51.100.1"
+	report, _ = service.VerifyChain()
+	if report.Valid || report.BrokenAt != repo.events[2].ID || report.Reason != "personal data does not match its commitment" {
+		t.Fatalf("expected a commitment break at %d, got %+v", repo.events[2].ID, report)
+	}
+	repo.events[2].IPAddress = "203.0.113.7"
+
+	// Records redacted before commitments can only be checked for their link
+	legacy := &repo.events[3]
+	legacy.PIINonce, legacy.PIIHash = "", ""
+	if legacy.Hash, err = legacy.ComputeHash(nil); err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	legacy.IPAddress = ""
+	legacy.Metadata = nil
+	legacy.RedactedAt = &redactedAt
+	report, _ = service.VerifyChain()
+	if !report.Valid || report.Checked != 3 || report.Redacted != 1 {
+		t.Fatalf("expected one unverifiable redacted record, got %+v", report)
+	}
+
+	// A redacted record must not keep, or regain, personal data its
+	// commitment can no longer vouch for
+	tampered := []func(*models.AuditEvent){
+		func(e *models.AuditEvent) { e.IPAddress = "198.51.100.1" },
+		func(e *models.AuditEvent) { e.UserAgent = "curl/8.0" },
+		func(e *models.AuditEvent) { e.PIINonce = "00" },
+		func(e *models.AuditEvent) { e.Metadata = map[string]interface{}{"email_hash": "forged"} },
+	}
+	for _, tamper := range tampered {
+		for _, i := range []int{1, 3} {
+			event := repo.events[i]
+			tamper(&repo.events[i])
+			report, _ = service.VerifyChain()
+			if report.Valid || report.BrokenAt != event.ID || report.Reason != "redacted record still holds personal data" {
+				t.Fatalf("expected a redaction break at %d, got %+v", event.ID, report)
+			}
+			repo.events[i] = event
+		}
+	}
+
+	// Redaction doesn't hide a removed record
+	next := repo.events[2].ID
+	repo.events = append(repo.events[:1], repo.events[2:]...)
+	report, _ = service.VerifyChain()
+	if report.Valid || report.BrokenAt != next {
+		t.Fatalf("expected a break at %d, got %+v", next, report)
+	}
+}
+
+func TestAuditService_Export(t *testing.T) {
+	repo := &stubAuditRepo{}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	seedAuditTrail(t, repo, start, 4)
+	service := NewAuditService(repo, nil)
+
+	query := &models.AuditExportQuery{From: start.Add(time.Minute), To: start.Add(3 * time.Minute), Format: "CSV"}
+	if err := service.ValidateExport(query); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	var out bytes.Buffer
+	if err := service.Export(&out, query); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if len(records) != 3 || records[1][0] != "2" || records[2][0] != "3" {
+		t.Fatalf("expected the header and events 2 and 3, got %v", records)
+	}
+	if records[2][9] != repo.events[1].Hash || records[2][10] != repo.events[2].Hash {
+		t.Fatalf("expected the chain hashes in the export, got %v", records[2])
+	}
+
+	query = &models.AuditExportQuery{From: start, To: start.Add(time.Hour)}
+	if err := service.ValidateExport(query); err != nil || query.Format != models.AuditExportJSONL {
+		t.Fatalf("expected jsonl by default, got %q (%v)", query.Format, err)
+	}
+	out.Reset()
+	if err := service.Export(&out, query); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(lines))
+	}
+	var event models.AuditEvent
+	if err := json.Unmarshal([]byte(lines[3]), &event); err != nil || event.Hash != repo.events[3].Hash {
+		t.Fatalf("unexpected line %s (%v)", lines[3], err)
+	}
+
+	if err := service.ValidateExport(&models.AuditExportQuery{From: start, To: start.Add(time.Hour), Format: "xml"}); err != ErrInvalidExportFormat {
+		t.Fatalf("expected ErrInvalidExportFormat, got %v", err)
+	}
+	if err := service.ValidateExport(&models.AuditExportQuery{From: start, To: start}); err != ErrInvalidExportRange {
+		t.Fatalf("expected ErrInvalidExportRange, got %v", err)
+	}
+}
+
+func TestAuditService_RecordLogEvent(t *testing.T) {
+	repo := &stubAuditRepo{}
+	service := NewAuditService(repo, nil)
+	userID := uuid.New()
+
+	service.Start()
+	service.RecordLogEvent(models.AuditSourceSecurity, "session_revoked", "corr-1", map[string]interface{}{
+		"user_id":    userID,
+		"ip":         "198.51.100.4",
+		"session_id": "abc",
+	})
+	service.Stop()
+
+	if len(repo.events) != 1 {
+		t.Fatalf("expected one recorded event, got %d", len(repo.events))
+	}
+	event := repo.events[0]
+	if event.UserID != userID || event.IPAddress != "198.51.100.4" || event.Source != models.AuditSourceSecurity || event.CorrelationID != "corr-1" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if len(event.Metadata) != 1 || event.Metadata["session_id"] != "abc" || event.Hash == "" {
+		t.Fatalf("unexpected metadata or hash %+v", event)
+	}
+}
+
+func TestAuditService_RecordLogEvent_HashesEmail(t *testing.T) {
+	repo := &stubAuditRepo{}
+	service := NewAuditService(repo, nil)
+
+	// As the auth handlers log failed logins, before any account is known
+	utils.SetEventSink(service)
+	defer utils.SetEventSink(nil)
+	logger := utils.NewLogger()
+	logger.SetOutput(io.Discard)
+
+	service.Start()
+	logger.LogAuthEvent(context.Background(), "login", "User@Example.com", false)
+	logger.LogSecurityEvent(context.Background(), "account_locked_login_attempt", map[string]interface{}{
+		"email": "user@example.com",
+		"ip":    "198.51.100.4",
+	})
+	service.Stop()
+
+	if len(repo.events) != 2 {
+		t.Fatalf("expected two recorded events, got %d", len(repo.events))
+	}
+	for _, event := range repo.events {
+		metadata, err := json.Marshal(event.Metadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(strings.ToLower(string(metadata)), "user@example.com") {
+			t.Fatalf("expected no address in the audit trail, got %s", metadata)
+		}
+		if event.Metadata["email_hash"] != utils.HashEmail("user@example.com") {
+			t.Fatalf("expected the address digest, got %+v", event.Metadata)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"strings"
@@ -14,29 +15,76 @@ import (
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrAccountLocked      = errors.New("account locked due to too many failed attempts")
-	ErrEmailNotVerified   = errors.New("email not verified")
-	ErrUserAlreadyExists  = errors.New("user already exists")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrAccountLocked       = errors.New("account locked due to too many failed attempts")
+	ErrAccountDisabled     = errors.New("account disabled")
+	ErrAccountDeleted      = errors.New("account scheduled for deletion")
+	ErrEmailNotVerified    = errors.New("email not verified")
+	ErrUserAlreadyExists   = errors.New("user already exists")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired MFA challenge")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrRefreshTokenRotated = errors.New("refresh token already rotated")
+	ErrRefreshTokenClient  = errors.New("refresh token was issued to a different client")
+	ErrTooManyRequests     = errors.New("too many requests, try again later")
+	ErrPasswordUnchanged   = errors.New("new password must differ from the current one")
+	ErrEmailUnchanged      = errors.New("new email is the current one")
+	ErrEmailInUse          = errors.New("email already in use")
+)
+
+// maxFailedLoginAttempts matches IncrementFailedLoginAttempts, which locks
+// the account on this many consecutive failures
+const maxFailedLoginAttempts = 5
+
+// Verification resend throttles, per client IP and per email address
+const (
+	verifyResendWindow   = time.Hour
+	verifyResendPerIP    = 10
+	verifyResendPerEmail = 3
+)
+
+// Password re-confirmation throttle for sensitive account changes, per user
+const (
+	reauthWindow  = 15 * time.Minute
+	reauthPerUser = 5
+)
+
+// Page size bounds for the activity log
+const (
+	defaultActivityPerPage = 20
+	maxActivityPerPage     = 100
 )
 
 type AuthService struct {
-	userRepo     repository.UserRepositoryInterface
-	tokenService TokenServiceInterface
-	emailService EmailServiceInterface
-	config       *config.Config
+	userRepo       repository.UserRepositoryInterface
+	tokenRepo      repository.OneTimeTokenRepositoryInterface
+	tokenService   TokenServiceInterface
+	totpService    TOTPServiceInterface
+	sessionService SessionServiceInterface
+	rbacService    *RBACService
+	auditRepo      repository.AuditRepositoryInterface
+	outboxRepo     repository.OutboxRepositoryInterface
+	throttle       ThrottleInterface
+	logger         *utils.Logger
+	config         *config.Config
 }
 
-func NewAuthService(userRepo repository.UserRepositoryInterface, tokenService TokenServiceInterface, emailService EmailServiceInterface, config *config.Config) *AuthService {
+func NewAuthService(userRepo repository.UserRepositoryInterface, tokenRepo repository.OneTimeTokenRepositoryInterface, tokenService TokenServiceInterface, totpService TOTPServiceInterface, sessionService SessionServiceInterface, rbacService *RBACService, auditRepo repository.AuditRepositoryInterface, outboxRepo repository.OutboxRepositoryInterface, throttle ThrottleInterface, config *config.Config) *AuthService {
 	return &AuthService{
-		userRepo:     userRepo,
-		tokenService: tokenService,
-		emailService: emailService,
-		config:       config,
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		tokenService:   tokenService,
+		totpService:    totpService,
+		sessionService: sessionService,
+		rbacService:    rbacService,
+		auditRepo:      auditRepo,
+		outboxRepo:     outboxRepo,
+		throttle:       throttle,
+		logger:         utils.NewLogger(),
+		config:         config,
 	}
 }
 
-func (s *AuthService) Register(req *models.CreateUserRequest) (*models.AuthResponse, error) {
+func (s *AuthService) Register(req *models.CreateUserRequest, client *models.ClientInfo) (*models.AuthResponse, error) {
 	// Normalize email
 	email := strings.ToLower(strings.TrimSpace(req.Email))
 
@@ -52,35 +100,32 @@ func (s *AuthService) Register(req *models.CreateUserRequest) (*models.AuthRespo
 		return nil, err
 	}
 
-	// Generate email verification token
-	verifyToken := uuid.New().String()
-
 	// Create user
 	user := &models.User{
-		ID:               uuid.New(),
-		Email:            email,
-		Password:         hashedPassword,
-		EmailVerifyToken: sql.NullString{String: verifyToken, Valid: true},
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		ID:        uuid.New(),
+		Email:     email,
+		Password:  hashedPassword,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	// The account, its verification token and the email carrying it are
+	// written together, so no account is left without a link
+	verification, message, err := s.newEmailVerification(user, client.Locale)
+	if err != nil {
 		return nil, err
 	}
-
-	// Send verification email
-	if err := s.emailService.SendVerificationEmail(email, verifyToken); err != nil {
-		// Log error but don't fail registration
-	}
-
-	// Generate tokens
-	accessToken, err := s.tokenService.GenerateAccessToken(user.ID)
+	webhook, err := webhookMessages(models.AuditRegister, user.ID)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.userRepo.Create(user, &repository.TokenChange{Issue: verification}, append(webhook, message)...); err != nil {
+		return nil, err
+	}
+	s.RecordEvent(user.ID, models.AuditRegister, client, nil)
 
-	refreshToken, err := s.tokenService.GenerateRefreshToken(user.ID)
+	// Generate tokens
+	accessToken, refreshToken, err := s.startTokenSession(user.ID, OAuthGrant{}, client)
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +137,7 @@ func (s *AuthService) Register(req *models.CreateUserRequest) (*models.AuthRespo
 	}, nil
 }
 
-func (s *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, error) {
+func (s *AuthService) Login(req *models.LoginRequest, client *models.ClientInfo) (*models.AuthResponse, error) {
 	// Normalize email
 	email := strings.ToLower(strings.TrimSpace(req.Email))
 
@@ -100,6 +145,10 @@ func (s *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, err
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
 		if err == sql.ErrNoRows {
+			s.RecordEvent(uuid.Nil, models.AuditLoginFailed, client, map[string]interface{}{
+				"email_hash": utils.HashEmail(email),
+				"reason":     "unknown_email",
+			})
 			return nil, ErrInvalidCredentials
 		}
 		return nil, err
@@ -107,31 +156,135 @@ func (s *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, err
 
 	// Check if account is locked
 	if user.LockedUntil.Valid && user.LockedUntil.Time.After(time.Now()) {
+		s.loginFailed(user, client, "account_locked")
 		return nil, ErrAccountLocked
 	}
 
 	// Verify password
 	if err := utils.CheckPassword(req.Password, user.Password); err != nil {
-		// Increment failed login attempts
-		s.userRepo.IncrementFailedLoginAttempts(email)
+		s.countFailedLogin(user, client, "invalid_password")
 		return nil, ErrInvalidCredentials
 	}
 
+	// Only reveal the account is disabled or deleted to someone who knows
+	// the password
+	if user.IsDisabled() {
+		s.loginFailed(user, client, "account_disabled")
+		return nil, ErrAccountDisabled
+	}
+	if user.IsDeleted() {
+		s.loginFailed(user, client, "account_deleted")
+		return nil, ErrAccountDeleted
+	}
+
 	// Check if email is verified
 	if !user.EmailVerified && s.config.Env == "production" {
+		s.loginFailed(user, client, "email_not_verified")
 		return nil, ErrEmailNotVerified
 	}
 
+	// Second factor required: hand out a challenge instead of tokens
+	if user.TOTPEnabled {
+		challenge, err := s.tokenService.GenerateMFAChallenge(user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.AuthResponse{
+			MFARequired: true,
+			MFAToken:    challenge,
+		}, nil
+	}
+
+	return s.completeLogin(user, client)
+}
+
+// LoginMFA exchanges an MFA challenge plus a valid second factor for tokens
+func (s *AuthService) LoginMFA(req *models.MFALoginRequest, client *models.ClientInfo) (*models.AuthResponse, error) {
+	// Only a missing challenge is the caller's fault; a store outage is ours
+	userID, err := s.tokenService.ValidateMFAChallenge(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID.String())
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Lockout may have kicked in since the password step
+	if user.LockedUntil.Valid && user.LockedUntil.Time.After(time.Now()) {
+		s.loginFailed(user, client, "account_locked")
+		return nil, ErrAccountLocked
+	}
+	if user.IsDisabled() {
+		s.loginFailed(user, client, "account_disabled")
+		return nil, ErrAccountDisabled
+	}
+	if user.IsDeleted() {
+		s.loginFailed(user, client, "account_deleted")
+		return nil, ErrAccountDeleted
+	}
+
+	if err := s.totpService.VerifyLoginCode(user, req.Code); err != nil {
+		if err != ErrInvalidTOTPCode && err != ErrTOTPNotSetup {
+			return nil, err
+		}
+		s.tokenService.FailMFAChallenge(req.MFAToken)
+		s.countFailedLogin(user, client, "invalid_2fa_code")
+		return nil, ErrInvalidTOTPCode
+	}
+
+	// Single use: a concurrent request with the same challenge loses here
+	if err := s.tokenService.ConsumeMFAChallenge(req.MFAToken); err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(user, client)
+}
+
+// completeLogin resets lockout counters and issues either a session or the token pair
+func (s *AuthService) completeLogin(user *models.User, client *models.ClientInfo) (*models.AuthResponse, error) {
 	// Reset failed login attempts
-	s.userRepo.ResetFailedLoginAttempts(email)
+	s.userRepo.ResetFailedLoginAttempts(user.Email)
+	s.RecordEvent(user.ID, models.AuditLoginSucceeded, client, map[string]interface{}{
+		"mfa": user.TOTPEnabled,
+	})
+	s.notifyNewDevice(user, client)
+
+	if client.UseSession {
+		_, sessionToken, err := s.sessionService.CreateSession(user.ID, client)
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.AuthResponse{
+			User:         user,
+			SessionToken: sessionToken,
+		}, nil
+	}
 
 	// Generate tokens
-	accessToken, err := s.tokenService.GenerateAccessToken(user.ID)
+	accessToken, refreshToken, err := s.startTokenSession(user.ID, OAuthGrant{}, client)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := s.tokenService.GenerateRefreshToken(user.ID)
+	return &models.AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		User:         user,
+	}, nil
+}
+
+// IssueClientTokens starts a token session for a user who authorised an
+// OAuth client. The tokens are only usable by that client, within the scope
+// the user consented to.
+func (s *AuthService) IssueClientTokens(user *models.User, grant OAuthGrant, client *models.ClientInfo) (*models.AuthResponse, error) {
+	accessToken, refreshToken, err := s.startTokenSession(user.ID, grant, client)
 	if err != nil {
 		return nil, err
 	}
@@ -140,99 +293,782 @@ func (s *AuthService) Login(req *models.LoginRequest) (*models.AuthResponse, err
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		User:         user,
+		Scope:        grant.Scope,
 	}, nil
 }
 
-func (s *AuthService) RefreshToken(refreshToken string) (*models.AuthResponse, error) {
-	// Validate refresh token
-	userID, err := s.tokenService.ValidateRefreshToken(refreshToken)
+// startTokenSession records a device session and issues a JWT pair bound to
+// it, and to grant's client and scope when it has one
+func (s *AuthService) startTokenSession(userID uuid.UUID, grant OAuthGrant, client *models.ClientInfo) (string, string, error) {
+	tokenClient := *client
+	tokenClient.UseSession = false
+
+	session, _, err := s.sessionService.CreateSession(userID, &tokenClient)
 	if err != nil {
-		return nil, err
+		return "", "", err
 	}
 
-	// Revoke old refresh token
-	if err := s.tokenService.RevokeRefreshToken(refreshToken); err != nil {
-		return nil, err
+	accessToken, err := s.generateAccessToken(userID, session.ID, grant)
+	if err != nil {
+		return "", "", err
 	}
 
-	// Generate new tokens
-	accessToken, err := s.tokenService.GenerateAccessToken(userID)
+	var refreshToken string
+	if grant.ClientID == "" {
+		refreshToken, err = s.tokenService.GenerateRefreshToken(userID, session.ID)
+	} else {
+		refreshToken, err = s.tokenService.GenerateDelegatedRefreshToken(userID, session.ID, grant)
+	}
 	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// generateAccessToken issues a first-party access token, or a delegated one
+// when grant names an OAuth client
+func (s *AuthService) generateAccessToken(userID, sessionID uuid.UUID, grant OAuthGrant) (string, error) {
+	if grant.ClientID == "" {
+		return s.tokenService.GenerateAccessToken(userID, sessionID)
+	}
+	return s.tokenService.GenerateDelegatedAccessToken(userID, sessionID, grant)
+}
+
+// RefreshToken rotates a first-party refresh token. Tokens issued to OAuth
+// clients are rejected here; they go through RefreshClientToken.
+func (s *AuthService) RefreshToken(refreshToken string, client *models.ClientInfo) (*models.AuthResponse, error) {
+	return s.refreshToken(refreshToken, "", client)
+}
+
+// RefreshClientToken rotates a refresh token on behalf of the OAuth client
+// it was issued to, keeping its scope
+func (s *AuthService) RefreshClientToken(refreshToken, clientID string, client *models.ClientInfo) (*models.AuthResponse, error) {
+	return s.refreshToken(refreshToken, clientID, client)
+}
+
+func (s *AuthService) refreshToken(refreshToken, clientID string, client *models.ClientInfo) (*models.AuthResponse, error) {
+	// Rotate: the presented token is retired and its successor joins the same family
+	claims, newRefreshToken, err := s.tokenService.RotateRefreshToken(refreshToken, clientID)
+	if err == ErrRefreshTokenReused {
+		// A rotated token came back: assume it was stolen and kill the whole family
+		s.logger.LogSecurityEvent(context.Background(), "refresh_token_reuse", map[string]interface{}{
+			"user_id":    claims.UserID,
+			"session_id": claims.SessionID,
+			"token_id":   claims.ID,
+		})
+		s.RecordEvent(claims.UserID, models.AuditRefreshTokenReused, client, map[string]interface{}{
+			"session_id": claims.SessionID,
+		})
+		if revokeErr := s.revokeTokenFamily(claims); revokeErr != nil {
+			return nil, revokeErr
+		}
 		return nil, err
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The account may have been deleted or disabled since the token was issued
+	user, err := s.userRepo.GetByID(claims.UserID.String())
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if user == nil || user.IsDeleted() || user.IsDisabled() {
+		if revokeErr := s.revokeTokenFamily(claims); revokeErr != nil {
+			return nil, revokeErr
+		}
+		return nil, ErrInvalidToken
+	}
 
-	newRefreshToken, err := s.tokenService.GenerateRefreshToken(userID)
+	// Generate new tokens for the same session and grant
+	grant := OAuthGrant{ClientID: claims.ClientID, Scope: claims.Scope}
+	accessToken, err := s.generateAccessToken(claims.UserID, claims.SessionID, grant)
 	if err != nil {
 		return nil, err
 	}
 
+	if claims.SessionID != uuid.Nil {
+		s.sessionService.TouchSession(claims.SessionID)
+	}
+	s.RecordEvent(claims.UserID, models.AuditTokenRefreshed, client, map[string]interface{}{
+		"session_id": claims.SessionID,
+	})
+
 	return &models.AuthResponse{
 		AccessToken:  accessToken,
 		RefreshToken: newRefreshToken,
+		Scope:        claims.Scope,
 	}, nil
 }
 
-func (s *AuthService) Logout(userID uuid.UUID, accessToken string) error {
+// revokeTokenFamily revokes every token descended from the same login. The
+// family is the session; tokens issued before sessions existed fall back to
+// revoking all of the user's refresh tokens.
+func (s *AuthService) revokeTokenFamily(claims *TokenClaims) error {
+	if claims.SessionID == uuid.Nil {
+		return s.tokenService.RevokeAllUserTokens(claims.UserID)
+	}
+	return s.sessionService.RevokeSession(claims.SessionID)
+}
+
+// Logout signs the user out of every device: all sessions, all refresh
+// tokens and the access token presented with this request
+func (s *AuthService) Logout(userID uuid.UUID, accessToken string, sessionID uuid.UUID, client *models.ClientInfo) error {
+	// Revoke the session this request authenticated with, including its refresh tokens
+	if sessionID != uuid.Nil {
+		if err := s.sessionService.RevokeSession(sessionID); err != nil {
+			return err
+		}
+	}
+
 	// Revoke access token
-	if err := s.tokenService.RevokeAccessToken(accessToken); err != nil {
+	if accessToken != "" {
+		if err := s.tokenService.RevokeAccessToken(accessToken); err != nil {
+			return err
+		}
+	}
+
+	// Revoke every other device through the per-user indexes
+	if err := s.SignOutEverywhere(userID); err != nil {
+		return err
+	}
+
+	s.RecordEvent(userID, models.AuditLogout, client, nil)
+	return nil
+}
+
+// SignOutEverywhere revokes all of the user's sessions and refresh tokens.
+// Access tokens issued before now stop validating immediately.
+func (s *AuthService) SignOutEverywhere(userID uuid.UUID) error {
+	if err := s.sessionService.RevokeAllUserSessions(userID); err != nil {
+		return err
+	}
+	if err := s.tokenService.RevokeAllUserTokens(userID); err != nil {
 		return err
 	}
-	
-	// Revoke all refresh tokens for this user (more secure)
-	// This ensures complete logout across all devices
-	return nil // TokenService handles refresh token cleanup
+	return s.tokenService.RevokeUserAccessTokens(userID)
 }
 
-func (s *AuthService) VerifyEmail(token string) error {
-	return s.userRepo.VerifyEmail(token)
+// RevokeAccessTokens rejects the user's outstanding access tokens but keeps
+// their sessions, so clients pick up the change on their next refresh
+func (s *AuthService) RevokeAccessTokens(userID uuid.UUID) error {
+	return s.tokenService.RevokeUserAccessTokens(userID)
 }
 
-func (s *AuthService) ForgotPassword(email string) error {
-	user, err := s.userRepo.GetByEmail(strings.ToLower(strings.TrimSpace(email)))
+// VerifyEmail uses up the token and marks the address verified in one write
+func (s *AuthService) VerifyEmail(token string, client *models.ClientInfo) error {
+	found, err := s.findToken(models.TokenPurposeVerifyEmail, token)
 	if err != nil {
+		return err
+	}
+
+	webhook, err := webhookMessages(models.AuditEmailVerified, found.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.MarkEmailVerified(found.UserID, &repository.TokenChange{Consume: found}, webhook...); err != nil {
+		return tokenError(err)
+	}
+
+	s.RecordEvent(found.UserID, models.AuditEmailVerified, client, nil)
+	return nil
+}
+
+// ResendVerification issues a fresh verification link, replacing the old
+// one. It answers the same way whether or not the address belongs to an
+// unverified account, so only the throttles can turn a request away.
+func (s *AuthService) ResendVerification(email string, client *models.ClientInfo) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	limits := []struct {
+		key   string
+		limit int
+	}{
+		{"verify_resend:ip:" + client.IPAddress, verifyResendPerIP},
+		{"verify_resend:email:" + email, verifyResendPerEmail},
+	}
+	for _, l := range limits {
+		allowed, err := s.throttle.Allow(l.key, l.limit, verifyResendWindow)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return ErrTooManyRequests
+		}
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerified || user.IsDisabled() || user.IsDeleted() {
+		return nil
+	}
+
+	if err := s.sendEmailVerification(user, client.Locale); err != nil {
+		return err
+	}
+
+	s.RecordEvent(user.ID, models.AuditVerificationResent, client, nil)
+	return nil
+}
+
+// sendEmailVerification issues a verification token, replacing any earlier
+// one, and queues the email carrying it
+func (s *AuthService) sendEmailVerification(user *models.User, locale string) error {
+	return s.issueToken(user.ID, models.TokenPurposeVerifyEmail, s.config.EmailVerifyExpiry, nil,
+		models.OutboxVerificationEmail, models.OutboxEmail{To: user.Email, Locale: locale})
+}
+
+func (s *AuthService) newEmailVerification(user *models.User, locale string) (*models.OneTimeToken, models.OutboxMessage, error) {
+	return newTokenEmail(user.ID, models.TokenPurposeVerifyEmail, s.config.EmailVerifyExpiry, nil,
+		models.OutboxVerificationEmail, models.OutboxEmail{To: user.Email, Locale: locale})
+}
+
+func (s *AuthService) ForgotPassword(email string, client *models.ClientInfo) error {
+	user, err := s.userRepo.GetByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil || user.IsDeleted() {
 		// Don't reveal if user exists
 		return nil
 	}
 
-	// Generate reset token
-	resetToken := uuid.New().String()
-	user.PasswordResetToken = sql.NullString{String: resetToken, Valid: true}
-	user.PasswordResetExpiry = sql.NullTime{Time: time.Now().Add(1 * time.Hour), Valid: true}
+	if err := s.sendPasswordReset(user, client.Locale); err != nil {
+		return err
+	}
+
+	s.RecordEvent(user.ID, models.AuditPasswordResetRequested, client, nil)
+	return nil
+}
 
-	if err := s.userRepo.Update(user); err != nil {
+// ForcePasswordReset is the administrator's version of ForgotPassword: the
+// current password stops working, every device is signed out and the user is
+// emailed a reset link
+func (s *AuthService) ForcePasswordReset(userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
 		return err
 	}
 
-	// Send reset email
-	return s.emailService.SendPasswordResetEmail(user.Email, resetToken)
+	// The password is cleared only together with queueing the link that
+	// sets a new one. No bcrypt hash is empty, so the old password can
+	// never match again.
+	reset, message, err := s.newPasswordReset(user, "")
+	if err != nil {
+		return err
+	}
+	if err := s.SignOutEverywhere(userID); err != nil {
+		return err
+	}
+	user.Password = ""
+	if err := s.userRepo.Update(user, &repository.TokenChange{Issue: reset}, message); err != nil {
+		return err
+	}
+	s.signOutAfterChange(userID)
+
+	s.RecordEvent(user.ID, models.AuditPasswordResetRequested, nil, map[string]interface{}{
+		"forced": true,
+	})
+	return nil
 }
 
-func (s *AuthService) ResetPassword(token, newPassword string) error {
-	// Find user by reset token
-	user, err := s.userRepo.GetByPasswordResetToken(token)
+// sendPasswordReset issues a reset token, replacing any earlier one, and
+// queues the email in locale, or the default locale when empty
+func (s *AuthService) sendPasswordReset(user *models.User, locale string) error {
+	return s.issueToken(user.ID, models.TokenPurposePasswordReset, passwordResetExpiry, nil,
+		models.OutboxPasswordResetEmail, models.OutboxEmail{To: user.Email, Locale: locale})
+}
+
+func (s *AuthService) newPasswordReset(user *models.User, locale string) (*models.OneTimeToken, models.OutboxMessage, error) {
+	return newTokenEmail(user.ID, models.TokenPurposePasswordReset, passwordResetExpiry, nil,
+		models.OutboxPasswordResetEmail, models.OutboxEmail{To: user.Email, Locale: locale})
+}
+
+// ResetPassword uses up the token and sets the new password in one write,
+// so a failed or rejected reset leaves the link working
+func (s *AuthService) ResetPassword(token, newPassword string, client *models.ClientInfo) error {
+	if err := utils.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+	hashedPassword, err := utils.HashPassword(newPassword, s.config.BcryptCost)
 	if err != nil {
-		return errors.New("invalid token")
+		return err
 	}
 
-	// Validate token expiry
-	if !user.PasswordResetExpiry.Valid || user.PasswordResetExpiry.Time.Before(time.Now()) {
-		return errors.New("token expired")
+	found, err := s.findToken(models.TokenPurposePasswordReset, token)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(found.UserID.String())
+	if err != nil {
+		return err
+	}
+	// A link sent before the deletion must not reopen the account
+	if user.IsDeleted() {
+		return ErrInvalidToken
+	}
+
+	if err := s.setPassword(user, hashedPassword, client, &repository.TokenChange{Consume: found}); err != nil {
+		return tokenError(err)
+	}
+
+	s.RecordEvent(user.ID, models.AuditPasswordResetCompleted, client, nil)
+	return nil
+}
+
+// ChangePassword replaces the password of a signed-in user who knows the
+// current one. Like a reset, it signs out every device, this one included.
+func (s *AuthService) ChangePassword(userID uuid.UUID, currentPassword, newPassword string, client *models.ClientInfo) error {
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
+		return err
+	}
+
+	if err := s.reauthenticate(user, currentPassword); err != nil {
+		return err
+	}
+	if err := utils.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+	if utils.CheckPassword(newPassword, user.Password) == nil {
+		return ErrPasswordUnchanged
 	}
 
-	// Hash new password
 	hashedPassword, err := utils.HashPassword(newPassword, s.config.BcryptCost)
 	if err != nil {
 		return err
 	}
 
-	// Update password and clear reset token
+	if err := s.setPassword(user, hashedPassword, client, nil); err != nil {
+		return err
+	}
+
+	s.RecordEvent(user.ID, models.AuditPasswordChanged, client, nil)
+	return nil
+}
+
+// RequestEmailChange emails a confirmation link to newEmail and a notice to
+// the current address. The account keeps its address until the link is used.
+func (s *AuthService) RequestEmailChange(userID uuid.UUID, newEmail, password string, client *models.ClientInfo) error {
+	newEmail = utils.NormalizeEmail(newEmail)
+
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
+		return err
+	}
+
+	if user.IsDeleted() {
+		return ErrAccountDeleted
+	}
+	if err := s.reauthenticate(user, password); err != nil {
+		return err
+	}
+	if newEmail == user.Email {
+		return ErrEmailUnchanged
+	}
+
+	// Checked again when the change is confirmed
+	existing, err := s.userRepo.GetByEmail(newEmail)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if existing != nil {
+		return ErrEmailInUse
+	}
+
+	notice, err := models.NewOutboxMessage(models.OutboxEmailChangeNoticeEmail, models.OutboxEmail{
+		To:       user.Email,
+		Locale:   client.Locale,
+		NewEmail: newEmail,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.issueToken(user.ID, models.TokenPurposeEmailChange, emailChangeExpiry, map[string]string{"email": newEmail},
+		models.OutboxEmailChangeEmail, models.OutboxEmail{To: newEmail, Locale: client.Locale}, notice); err != nil {
+		return err
+	}
+
+	// The audit trail is kept after the account is purged, so it never
+	// holds an address
+	s.RecordEvent(user.ID, models.AuditEmailChangeRequested, client, nil)
+	return nil
+}
+
+// ConfirmEmailChange moves the account to the address the token was sent
+// to, which is verified by the user opening the link. The token is used up,
+// and reset links already sent to the old address are revoked, in the same
+// write, so none of it happens unless the address changes.
+func (s *AuthService) ConfirmEmailChange(token string, client *models.ClientInfo) error {
+	found, err := s.findToken(models.TokenPurposeEmailChange, token)
+	if err != nil {
+		return err
+	}
+
+	newEmail := found.Data["email"]
+	if newEmail == "" {
+		return ErrInvalidToken
+	}
+
+	user, err := s.userRepo.GetByID(found.UserID.String())
+	if err != nil {
+		return err
+	}
+	if user.IsDeleted() {
+		return ErrInvalidToken
+	}
+
+	// Another account may have taken the address since the link was sent
+	changed, err := s.userRepo.ChangeEmail(found.UserID, newEmail, &repository.TokenChange{
+		Consume: found,
+		Revoke:  []string{models.TokenPurposePasswordReset},
+	})
+	if err != nil {
+		return tokenError(err)
+	}
+	if !changed {
+		return ErrEmailInUse
+	}
+
+	s.RecordEvent(found.UserID, models.AuditEmailChanged, client, nil)
+	return nil
+}
+
+// DeleteAccount schedules the account for deletion once the grace period
+// ends and signs out every device. Until then the account can't be used, but
+// RestoreAccount brings it back. The password, and the second factor when
+// enabled, must be confirmed first, and the last administrator can't leave.
+func (s *AuthService) DeleteAccount(userID uuid.UUID, password, code string, client *models.ClientInfo) (time.Time, error) {
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.reauthenticate(user, password); err != nil {
+		return time.Time{}, err
+	}
+	if user.TOTPEnabled {
+		if err := s.totpService.VerifyLoginCode(user, code); err != nil {
+			return time.Time{}, ErrInvalidTOTPCode
+		}
+	}
+
+	guard, err := s.rbacService.LastAdminGuard(user.ID, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	purgeAt := time.Now().Add(s.config.AccountDeletionGrace)
+	notice, err := models.NewOutboxMessage(models.OutboxAccountDeletionEmail, models.OutboxEmail{
+		To:     user.Email,
+		Locale: client.Locale,
+		Until:  purgeAt,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.SignOutEverywhere(user.ID); err != nil {
+		return time.Time{}, err
+	}
+	if err := s.userRepo.SoftDelete(user.ID, guard, notice); err != nil {
+		return time.Time{}, lastAdminError(err)
+	}
+	s.signOutAfterChange(user.ID)
+
+	s.RecordEvent(user.ID, models.AuditAccountDeletionRequested, client, map[string]interface{}{
+		"purge_after": purgeAt.UTC().Format(time.RFC3339),
+	})
+	return purgeAt, nil
+}
+
+// RestoreAccount cancels a pending deletion for someone who can sign in to
+// the account: the password, and the second factor when enabled. Failures
+// count towards the lockout like a sign-in. It signs nobody in; the user
+// logs in afterwards as usual.
+func (s *AuthService) RestoreAccount(req *models.RestoreAccountRequest, client *models.ClientInfo) error {
+	user, err := s.userRepo.GetByEmail(utils.NormalizeEmail(req.Email))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+
+	if user.LockedUntil.Valid && user.LockedUntil.Time.After(time.Now()) {
+		return ErrAccountLocked
+	}
+	if err := utils.CheckPassword(req.Password, user.Password); err != nil {
+		s.countFailedLogin(user, client, "invalid_password")
+		return ErrInvalidCredentials
+	}
+	if user.IsDisabled() {
+		return ErrAccountDisabled
+	}
+	if user.TOTPEnabled {
+		if err := s.totpService.VerifyLoginCode(user, req.Code); err != nil {
+			if err != ErrInvalidTOTPCode && err != ErrTOTPNotSetup {
+				return err
+			}
+			s.countFailedLogin(user, client, "invalid_2fa_code")
+			return ErrInvalidTOTPCode
+		}
+	}
+	if !user.IsDeleted() {
+		return nil
+	}
+
+	if err := s.userRepo.Restore(user.ID); err != nil {
+		return err
+	}
+	s.RecordEvent(user.ID, models.AuditAccountRestored, client, nil)
+	return nil
+}
+
+// reauthenticate confirms the password of a signed-in user before a
+// sensitive change. Attempts are throttled so a stolen session can't be used
+// to guess it.
+func (s *AuthService) reauthenticate(user *models.User, password string) error {
+	allowed, err := s.throttle.Allow("reauth:user:"+user.ID.String(), reauthPerUser, reauthWindow)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrTooManyRequests
+	}
+
+	if err := utils.CheckPassword(password, user.Password); err != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// setPassword signs out every device, so whoever held the old password loses
+// access, then stores the new hash, together with any token writes, and
+// emails the user about the change. Signing out first means an error always
+// leaves the old password in place.
+func (s *AuthService) setPassword(user *models.User, hashedPassword string, client *models.ClientInfo, tokens *repository.TokenChange) error {
+	notice, err := models.NewOutboxMessage(models.OutboxPasswordChangedEmail, models.OutboxEmail{
+		To:        user.Email,
+		Locale:    client.Locale,
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+		At:        time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	outbox, err := webhookMessages(models.AuditPasswordChanged, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.SignOutEverywhere(user.ID); err != nil {
+		return err
+	}
 	user.Password = hashedPassword
-	user.PasswordResetToken = sql.NullString{}
-	user.PasswordResetExpiry = sql.NullTime{}
+	if err := s.userRepo.Update(user, tokens, append(outbox, notice)...); err != nil {
+		return err
+	}
+	s.signOutAfterChange(user.ID)
+	return nil
+}
 
-	return s.userRepo.Update(user)
+// signOutAfterChange signs out, once more, devices that signed in while a
+// change was being written, e.g. with the old password while it was being
+// replaced. The change has been made by then, so a failure is logged rather
+// than returned.
+func (s *AuthService) signOutAfterChange(userID uuid.UUID) {
+	if err := s.SignOutEverywhere(userID); err != nil {
+		s.logger.WithField("user_id", userID).
+			WithError(err).
+			Error("Failed to sign out devices after an account change")
+	}
 }
 
 func (s *AuthService) GetUserByID(userID uuid.UUID) (*models.User, error) {
 	return s.userRepo.GetByID(userID.String())
 }
+
+// ActivityLog returns one page of the user's audit trail, newest first
+func (s *AuthService) ActivityLog(userID uuid.UUID, query *models.ActivityLogQuery) (*models.ActivityLogResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PerPage < 1 {
+		query.PerPage = defaultActivityPerPage
+	}
+	if query.PerPage > maxActivityPerPage {
+		query.PerPage = maxActivityPerPage
+	}
+
+	events, err := s.auditRepo.ListByUser(userID, query.PerPage, (query.Page-1)*query.PerPage)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.auditRepo.CountByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.ActivityLogResponse{
+		Activities: make([]models.ActivityEntry, 0, len(events)),
+		Total:      total,
+		Page:       query.Page,
+		PerPage:    query.PerPage,
+	}
+	for _, event := range events {
+		response.Activities = append(response.Activities, event.Activity())
+	}
+	return response, nil
+}
+
+// RecordEvent appends an event to the audit trail. client may be nil for
+// actions with no originating request. A failed write is logged rather than
+// returned so auditing never blocks the action itself.
+func (s *AuthService) RecordEvent(userID uuid.UUID, eventType string, client *models.ClientInfo, metadata map[string]interface{}) {
+	event := &models.AuditEvent{
+		UserID:    userID,
+		Source:    models.AuditSourceAccount,
+		EventType: eventType,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	}
+	if client != nil {
+		event.IPAddress = client.IPAddress
+		event.UserAgent = client.UserAgent
+		event.CorrelationID = client.CorrelationID
+	}
+
+	if err := s.auditRepo.Create(event); err != nil {
+		s.logger.WithField("correlation_id", event.CorrelationID).
+			WithField("event_type", eventType).
+			WithError(err).
+			Error("Failed to record audit event")
+	}
+}
+
+// webhookMessages returns the outbox message for the webhook an audit event
+// triggers, if any, to be written in the transaction making the change
+func webhookMessages(eventType string, userID uuid.UUID) ([]models.OutboxMessage, error) {
+	webhookEvent, ok := auditWebhookEvents[eventType]
+	if !ok {
+		return nil, nil
+	}
+
+	message, err := models.NewOutboxMessage(models.OutboxWebhookEvent, models.OutboxWebhook{
+		Event: webhookEvent,
+		Data: map[string]interface{}{
+			"user_id":     userID,
+			"occurred_at": time.Now().UTC(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []models.OutboxMessage{message}, nil
+}
+
+// loginFailed records a rejected login for an existing account
+func (s *AuthService) loginFailed(user *models.User, client *models.ClientInfo, reason string) {
+	s.RecordEvent(user.ID, models.AuditLoginFailed, client, map[string]interface{}{
+		"reason": reason,
+	})
+}
+
+// countFailedLogin records a failed credential check against the lockout
+// counter, noting when this failure locks the account. The decision uses
+// the count the database returns, so concurrent failures are all counted.
+func (s *AuthService) countFailedLogin(user *models.User, client *models.ClientInfo, reason string) {
+	attempts, lockedUntil, err := s.userRepo.IncrementFailedLoginAttempts(user.Email, s.config.AccountLockoutDuration)
+	s.loginFailed(user, client, reason)
+	if err != nil {
+		s.logger.WithField("correlation_id", client.CorrelationID).
+			WithError(err).
+			Error("Failed to count a failed login")
+		return
+	}
+
+	if attempts >= maxFailedLoginAttempts {
+		s.RecordEvent(user.ID, models.AuditAccountLocked, client, map[string]interface{}{
+			"failed_attempts": attempts,
+		})
+
+		s.queueEmail(models.OutboxLockoutEmail, models.OutboxEmail{
+			To:     user.Email,
+			Locale: client.Locale,
+			At:     time.Now(),
+			Until:  lockedUntil.Time,
+		})
+		s.queueWebhook(models.AuditAccountLocked, user.ID)
+	}
+}
+
+// notifyNewDevice emails the user when they sign in from a device none of
+// their sessions has used. It runs before the new session is recorded.
+func (s *AuthService) notifyNewDevice(user *models.User, client *models.ClientInfo) {
+	if s.outboxRepo == nil {
+		return
+	}
+
+	known, err := s.sessionService.IsKnownDevice(user.ID, client)
+	if err != nil {
+		s.logger.WithField("correlation_id", client.CorrelationID).
+			WithError(err).
+			Error("Failed to check for a new device")
+		return
+	}
+	if known {
+		return
+	}
+
+	s.queueEmail(models.OutboxNewDeviceEmail, models.OutboxEmail{
+		To:        user.Email,
+		Locale:    client.Locale,
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+		At:        time.Now(),
+	})
+}
+
+// queueWebhook hands the webhook for an audit event that doesn't accompany
+// a user change to the outbox. A failure is logged, as for queueEmail.
+func (s *AuthService) queueWebhook(eventType string, userID uuid.UUID) {
+	if s.outboxRepo == nil {
+		return
+	}
+
+	messages, err := webhookMessages(eventType, userID)
+	if err == nil && len(messages) > 0 {
+		err = s.outboxRepo.Enqueue(messages...)
+	}
+	if err != nil {
+		s.logger.WithField("event_type", eventType).
+			WithError(err).
+			Error("Failed to queue webhook")
+	}
+}
+
+// queueEmail hands a notification that doesn't accompany a user change to
+// the outbox. Like auditing, a failure is logged rather than returned.
+func (s *AuthService) queueEmail(kind string, email models.OutboxEmail) {
+	if s.outboxRepo == nil {
+		return
+	}
+
+	message, err := models.NewOutboxMessage(kind, email)
+	if err == nil {
+		err = s.outboxRepo.Enqueue(message)
+	}
+	if err != nil {
+		s.logger.WithField("kind", kind).
+			WithError(err).
+			Error("Failed to queue email")
+	}
+}
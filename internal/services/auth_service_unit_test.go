@@ -1,26 +1,39 @@
 package services
 
 import (
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/Flack74/go-auth-system/internal/config"
 	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
 	"github.com/Flack74/go-auth-system/internal/utils"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-// Mock interfaces
+// Mock interfaces. With tokens set, a successful write applies its token
+// writes and outbox messages there, as the transaction would.
 type MockUserRepo struct {
 	mock.Mock
+	tokens *stubOneTimeTokenRepo
 }
 
-func (m *MockUserRepo) Create(user *models.User) error {
-	args := m.Called(user)
-	return args.Error(0)
+func (m *MockUserRepo) Create(user *models.User, tokens *repository.TokenChange, outbox ...models.OutboxMessage) error {
+	args := m.Called(user, tokens, outbox)
+	return m.apply(args.Error(0), user.ID, tokens, outbox)
+}
+
+func (m *MockUserRepo) apply(err error, userID uuid.UUID, tokens *repository.TokenChange, outbox []models.OutboxMessage) error {
+	if err != nil || m.tokens == nil {
+		return err
+	}
+	return m.tokens.apply(userID, tokens, outbox)
 }
 
 func (m *MockUserRepo) GetByEmail(email string) (*models.User, error) {
@@ -44,56 +57,146 @@ func (m *MockUserRepo) LockAccount(email string, lockDuration time.Duration) err
 	return args.Error(0)
 }
 
-func (m *MockUserRepo) Update(user *models.User) error {
-	args := m.Called(user)
+func (m *MockUserRepo) Update(user *models.User, tokens *repository.TokenChange, outbox ...models.OutboxMessage) error {
+	args := m.Called(user, tokens, outbox)
+	return m.apply(args.Error(0), user.ID, tokens, outbox)
+}
+
+func (m *MockUserRepo) IncrementFailedLoginAttempts(email string, lockout time.Duration) (int, sql.NullTime, error) {
+	args := m.Called(email, lockout)
+	return args.Int(0), args.Get(1).(sql.NullTime), args.Error(2)
+}
+
+func (m *MockUserRepo) ResetFailedLoginAttempts(email string) error {
+	args := m.Called(email)
 	return args.Error(0)
 }
 
-func (m *MockUserRepo) VerifyEmail(token string) error {
-	args := m.Called(token)
+func (m *MockUserRepo) SetTOTPSecret(userID uuid.UUID, secret string) error {
+	args := m.Called(userID, secret)
 	return args.Error(0)
 }
 
-func (m *MockUserRepo) IncrementFailedLoginAttempts(email string) error {
-	args := m.Called(email)
+func (m *MockUserRepo) EnableTOTP(userID uuid.UUID) error {
+	args := m.Called(userID)
 	return args.Error(0)
 }
 
-func (m *MockUserRepo) ResetFailedLoginAttempts(email string) error {
-	args := m.Called(email)
+func (m *MockUserRepo) DisableTOTP(userID uuid.UUID) error {
+	args := m.Called(userID)
 	return args.Error(0)
 }
 
-func (m *MockUserRepo) GetByPasswordResetToken(token string) (*models.User, error) {
-	args := m.Called(token)
+func (m *MockUserRepo) Search(filter *models.UserFilter) ([]models.AdminUserView, error) {
+	args := m.Called(filter)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*models.User), args.Error(1)
+	return args.Get(0).([]models.AdminUserView), args.Error(1)
+}
+
+func (m *MockUserRepo) Count(filter *models.UserFilter) (int64, error) {
+	args := m.Called(filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRepo) Unlock(userID uuid.UUID) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) MarkEmailVerified(userID uuid.UUID, tokens *repository.TokenChange, outbox ...models.OutboxMessage) error {
+	args := m.Called(userID, tokens, outbox)
+	return m.apply(args.Error(0), userID, tokens, outbox)
+}
+
+func (m *MockUserRepo) ChangeEmail(userID uuid.UUID, email string, tokens *repository.TokenChange) (bool, error) {
+	args := m.Called(userID, email, tokens)
+	if !args.Bool(0) {
+		return false, args.Error(1)
+	}
+	return true, m.apply(args.Error(1), userID, tokens, nil)
+}
+
+func (m *MockUserRepo) SetDisabled(userID uuid.UUID, disabled bool, guard *repository.AdminGuard) error {
+	args := m.Called(userID, disabled, guard)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) Delete(userID uuid.UUID, guard *repository.AdminGuard) error {
+	args := m.Called(userID, guard)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) SoftDelete(userID uuid.UUID, guard *repository.AdminGuard, outbox ...models.OutboxMessage) error {
+	args := m.Called(userID, guard, outbox)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) Restore(userID uuid.UUID) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) ListDueForPurge(deletedBefore time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(deletedBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockUserRepo) Purge(userID uuid.UUID, deletedBefore time.Time) (bool, error) {
+	args := m.Called(userID, deletedBefore)
+	return args.Bool(0), args.Error(1)
 }
 
 type MockTokenSvc struct {
 	mock.Mock
 }
 
-func (m *MockTokenSvc) GenerateAccessToken(userID uuid.UUID) (string, error) {
-	args := m.Called(userID)
+func (m *MockTokenSvc) GenerateAccessToken(userID, sessionID uuid.UUID) (string, error) {
+	args := m.Called(userID, sessionID)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockTokenSvc) GenerateRefreshToken(userID uuid.UUID) (string, error) {
-	args := m.Called(userID)
+func (m *MockTokenSvc) GenerateRefreshToken(userID, sessionID uuid.UUID) (string, error) {
+	args := m.Called(userID, sessionID)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockTokenSvc) ValidateAccessToken(token string) (uuid.UUID, error) {
+func (m *MockTokenSvc) ValidateAccessToken(token string) (*TokenClaims, error) {
 	args := m.Called(token)
-	return args.Get(0).(uuid.UUID), args.Error(1)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TokenClaims), args.Error(1)
 }
 
-func (m *MockTokenSvc) ValidateRefreshToken(token string) (uuid.UUID, error) {
+func (m *MockTokenSvc) ValidateRefreshToken(token string) (*TokenClaims, error) {
 	args := m.Called(token)
-	return args.Get(0).(uuid.UUID), args.Error(1)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TokenClaims), args.Error(1)
+}
+
+func (m *MockTokenSvc) GenerateDelegatedAccessToken(userID, sessionID uuid.UUID, grant OAuthGrant) (string, error) {
+	args := m.Called(userID, sessionID, grant)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTokenSvc) GenerateDelegatedRefreshToken(userID, sessionID uuid.UUID, grant OAuthGrant) (string, error) {
+	args := m.Called(userID, sessionID, grant)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTokenSvc) RotateRefreshToken(token, clientID string) (*TokenClaims, string, error) {
+	args := m.Called(token, clientID)
+	if args.Get(0) == nil {
+		return nil, args.String(1), args.Error(2)
+	}
+	return args.Get(0).(*TokenClaims), args.String(1), args.Error(2)
 }
 
 func (m *MockTokenSvc) RevokeToken(token string) error {
@@ -111,48 +214,274 @@ func (m *MockTokenSvc) RevokeAccessToken(token string) error {
 	return args.Error(0)
 }
 
+func (m *MockTokenSvc) RevokeSessionTokens(sessionID uuid.UUID) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+func (m *MockTokenSvc) RevokeAllUserTokens(userID uuid.UUID) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockTokenSvc) RevokeUserAccessTokens(userID uuid.UUID) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockTokenSvc) CurrentTokenVersion(userID uuid.UUID) (int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTokenSvc) TokenVersion(userID uuid.UUID) (int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTokenSvc) GenerateMFAChallenge(userID uuid.UUID) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTokenSvc) ValidateMFAChallenge(challenge string) (uuid.UUID, error) {
+	args := m.Called(challenge)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockTokenSvc) FailMFAChallenge(challenge string) error {
+	args := m.Called(challenge)
+	return args.Error(0)
+}
+
+func (m *MockTokenSvc) ConsumeMFAChallenge(challenge string) error {
+	args := m.Called(challenge)
+	return args.Error(0)
+}
+
+type MockTOTPSvc struct {
+	mock.Mock
+}
+
+func (m *MockTOTPSvc) VerifyLoginCode(user *models.User, code string) error {
+	args := m.Called(user, code)
+	return args.Error(0)
+}
+
+type MockSessionSvc struct {
+	mock.Mock
+}
+
+func (m *MockSessionSvc) CreateSession(userID uuid.UUID, client *models.ClientInfo) (*models.Session, string, error) {
+	args := m.Called(userID, client)
+	if args.Get(0) == nil {
+		return nil, args.String(1), args.Error(2)
+	}
+	return args.Get(0).(*models.Session), args.String(1), args.Error(2)
+}
+
+func (m *MockSessionSvc) IsKnownDevice(userID uuid.UUID, client *models.ClientInfo) (bool, error) {
+	args := m.Called(userID, client)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionSvc) TouchSession(sessionID uuid.UUID) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+func (m *MockSessionSvc) RevokeSession(sessionID uuid.UUID) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+func (m *MockSessionSvc) RevokeAllUserSessions(userID uuid.UUID) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func outboxKinds(outbox []models.OutboxMessage) []string {
+	kinds := make([]string, 0, len(outbox))
+	for _, message := range outbox {
+		kinds = append(kinds, message.Kind)
+	}
+	return kinds
+}
+
+// stubAuditRepo collects recorded events for inspection, hashing them
+// under key when it is set
+type stubAuditRepo struct {
+	events []models.AuditEvent
+	key    []byte
+}
+
+// Create chains events the way the database repository does
+func (r *stubAuditRepo) Create(event *models.AuditEvent) error {
+	event.ID = int64(len(r.events) + 1)
+	event.CreatedAt = event.CreatedAt.UTC().Truncate(time.Microsecond)
+	event.PrevHash = ""
+	if len(r.events) > 0 {
+		event.PrevHash = r.events[len(r.events)-1].Hash
+	}
+	if err := event.CommitPII(); err != nil {
+		return err
+	}
+	hash, err := event.ComputeHash(r.key)
+	if err != nil {
+		return err
+	}
+	event.Hash = hash
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *stubAuditRepo) Rekey() (int64, error) {
+	var rekeyed int64
+	prevHash := ""
+	for i := range r.events {
+		event := &r.events[i]
+		if event.Hash == "" {
+			continue
+		}
+		event.PrevHash = prevHash
+		if event.RedactedAt == nil || event.PIIHash != "" {
+			hash, err := event.ComputeHash(r.key)
+			if err != nil {
+				return rekeyed, err
+			}
+			event.Hash = hash
+		}
+		prevHash = event.Hash
+		rekeyed++
+	}
+	return rekeyed, nil
+}
+
+func (r *stubAuditRepo) ListByUser(userID uuid.UUID, limit, offset int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	for _, event := range r.events {
+		if event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *stubAuditRepo) CountByUser(userID uuid.UUID) (int64, error) {
+	events, _ := r.ListByUser(userID, 0, 0)
+	return int64(len(events)), nil
+}
+
+func (r *stubAuditRepo) ListAfter(afterID int64, limit int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	for _, event := range r.events {
+		if event.ID > afterID && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *stubAuditRepo) ListInRange(from, to time.Time, afterID int64, limit int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	for _, event := range r.events {
+		if event.ID > afterID && !event.CreatedAt.Before(from) && event.CreatedAt.Before(to) && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *stubAuditRepo) types() []string {
+	var types []string
+	for _, event := range r.events {
+		types = append(types, event.EventType)
+	}
+	return types
+}
+
 type MockEmailSvc struct {
 	mock.Mock
 }
 
-func (m *MockEmailSvc) SendVerificationEmail(email, token string) error {
-	args := m.Called(email, token)
+func (m *MockEmailSvc) SendVerificationEmail(email, token, locale string) error {
+	args := m.Called(email, token, locale)
+	return args.Error(0)
+}
+
+func (m *MockEmailSvc) SendPasswordResetEmail(email, token, locale string) error {
+	args := m.Called(email, token, locale)
+	return args.Error(0)
+}
+
+func (m *MockEmailSvc) SendEmailChangeEmail(email, token, locale string) error {
+	args := m.Called(email, token, locale)
+	return args.Error(0)
+}
+
+func (m *MockEmailSvc) SendEmailChangeNoticeEmail(email, newEmail, locale string) error {
+	args := m.Called(email, newEmail, locale)
 	return args.Error(0)
 }
 
-func (m *MockEmailSvc) SendPasswordResetEmail(email, token string) error {
-	args := m.Called(email, token)
+func (m *MockEmailSvc) SendPasswordChangedEmail(email, locale string, client *models.ClientInfo, at time.Time) error {
+	args := m.Called(email, locale, client, at)
+	return args.Error(0)
+}
+
+func (m *MockEmailSvc) SendNewDeviceEmail(email, locale string, client *models.ClientInfo, at time.Time) error {
+	args := m.Called(email, locale, client, at)
+	return args.Error(0)
+}
+
+func (m *MockEmailSvc) SendLockoutEmail(email, locale string, until time.Time) error {
+	args := m.Called(email, locale, until)
+	return args.Error(0)
+}
+
+func (m *MockEmailSvc) SendAccountDeletionEmail(email, locale string, purgeAt time.Time) error {
+	args := m.Called(email, locale, purgeAt)
 	return args.Error(0)
 }
 
 // Unit Tests
 func TestAuthService_Register_Success(t *testing.T) {
-	mockRepo := new(MockUserRepo)
+	tokens := &stubOneTimeTokenRepo{}
+	mockRepo := &MockUserRepo{tokens: tokens}
 	mockToken := new(MockTokenSvc)
-	mockEmail := new(MockEmailSvc)
+	mockSession := new(MockSessionSvc)
 	
-	cfg := &config.Config{BcryptCost: 4}
+	cfg := &config.Config{BcryptCost: 4, EmailVerifyExpiry: 24 * time.Hour}
 	service := &AuthService{
-		userRepo:     mockRepo,
-		tokenService: mockToken,
-		emailService: mockEmail,
-		config:       cfg,
+		userRepo:       mockRepo,
+		tokenRepo:      tokens,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         cfg,
+		auditRepo:      &stubAuditRepo{},
 	}
 
 	req := &models.CreateUserRequest{
 		Email:    "test@example.com",
 		Password: "TestPass123!",
 	}
+	session := &models.Session{ID: uuid.New()}
 
 	// Mock expectations
 	mockRepo.On("GetByEmail", "test@example.com").Return(nil, errors.New("not found"))
-	mockRepo.On("Create", mock.AnythingOfType("*models.User")).Return(nil)
-	mockToken.On("GenerateAccessToken", mock.AnythingOfType("uuid.UUID")).Return("access_token", nil)
-	mockToken.On("GenerateRefreshToken", mock.AnythingOfType("uuid.UUID")).Return("refresh_token", nil)
-	mockEmail.On("SendVerificationEmail", "test@example.com", mock.AnythingOfType("string")).Return(nil)
+	// The verification token, its email and the user.registered webhook
+	// commit with the account
+	mockRepo.On("Create", mock.AnythingOfType("*models.User"), mock.MatchedBy(func(change *repository.TokenChange) bool {
+		return change != nil && change.Issue != nil && change.Consume == nil
+	}), mock.MatchedBy(func(outbox []models.OutboxMessage) bool {
+		return assert.ObjectsAreEqual([]string{models.OutboxWebhookEvent, models.OutboxVerificationEmail}, outboxKinds(outbox))
+	})).Return(nil)
+	mockSession.On("CreateSession", mock.AnythingOfType("uuid.UUID"), mock.AnythingOfType("*models.ClientInfo")).Return(session, "", nil)
+	mockToken.On("GenerateAccessToken", mock.AnythingOfType("uuid.UUID"), session.ID).Return("access_token", nil)
+	mockToken.On("GenerateRefreshToken", mock.AnythingOfType("uuid.UUID"), session.ID).Return("refresh_token", nil)
 
 	// Execute
-	response, err := service.Register(req)
+	response, err := service.Register(req, &models.ClientInfo{})
 
 	// Assert
 	assert.NoError(t, err)
@@ -161,20 +490,25 @@ func TestAuthService_Register_Success(t *testing.T) {
 	assert.Equal(t, "refresh_token", response.RefreshToken)
 	mockRepo.AssertExpectations(t)
 	mockToken.AssertExpectations(t)
-	mockEmail.AssertExpectations(t)
+
+	// Only the digest of the emailed token is stored
+	issued, email := tokens.last(t, models.OutboxVerificationEmail)
+	assert.Equal(t, models.TokenPurposeVerifyEmail, issued.Purpose)
+	assert.Equal(t, hashOneTimeToken(email.Token), issued.TokenHash)
+	assert.NotEqual(t, email.Token, issued.TokenHash)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), issued.ExpiresAt, time.Minute)
 }
 
 func TestAuthService_Register_UserExists(t *testing.T) {
 	mockRepo := new(MockUserRepo)
 	mockToken := new(MockTokenSvc)
-	mockEmail := new(MockEmailSvc)
 	
 	cfg := &config.Config{BcryptCost: 4}
 	service := &AuthService{
 		userRepo:     mockRepo,
 		tokenService: mockToken,
-		emailService: mockEmail,
 		config:       cfg,
+		auditRepo:    &stubAuditRepo{},
 	}
 
 	req := &models.CreateUserRequest{
@@ -191,7 +525,7 @@ func TestAuthService_Register_UserExists(t *testing.T) {
 	mockRepo.On("GetByEmail", "existing@example.com").Return(existingUser, nil)
 
 	// Execute
-	response, err := service.Register(req)
+	response, err := service.Register(req, &models.ClientInfo{})
 
 	// Assert
 	assert.Error(t, err)
@@ -203,14 +537,15 @@ func TestAuthService_Register_UserExists(t *testing.T) {
 func TestAuthService_Login_Success(t *testing.T) {
 	mockRepo := new(MockUserRepo)
 	mockToken := new(MockTokenSvc)
-	mockEmail := new(MockEmailSvc)
+	mockSession := new(MockSessionSvc)
 	
 	cfg := &config.Config{}
 	service := &AuthService{
-		userRepo:     mockRepo,
-		tokenService: mockToken,
-		emailService: mockEmail,
-		config:       cfg,
+		userRepo:       mockRepo,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         cfg,
+		auditRepo:      &stubAuditRepo{},
 	}
 
 	req := &models.LoginRequest{
@@ -225,15 +560,17 @@ func TestAuthService_Login_Success(t *testing.T) {
 		Password:      hashedPassword,
 		EmailVerified: true,
 	}
+	session := &models.Session{ID: uuid.New(), UserID: user.ID}
 
 	// Mock expectations
 	mockRepo.On("GetByEmail", "test@example.com").Return(user, nil)
 	mockRepo.On("ResetFailedLoginAttempts", "test@example.com").Return(nil)
-	mockToken.On("GenerateAccessToken", user.ID).Return("access_token", nil)
-	mockToken.On("GenerateRefreshToken", user.ID).Return("refresh_token", nil)
+	mockSession.On("CreateSession", user.ID, mock.AnythingOfType("*models.ClientInfo")).Return(session, "", nil)
+	mockToken.On("GenerateAccessToken", user.ID, session.ID).Return("access_token", nil)
+	mockToken.On("GenerateRefreshToken", user.ID, session.ID).Return("refresh_token", nil)
 
 	// Execute
-	response, err := service.Login(req)
+	response, err := service.Login(req, &models.ClientInfo{})
 
 	// Assert
 	assert.NoError(t, err)
@@ -246,14 +583,13 @@ func TestAuthService_Login_Success(t *testing.T) {
 func TestAuthService_Login_WrongPassword(t *testing.T) {
 	mockRepo := new(MockUserRepo)
 	mockToken := new(MockTokenSvc)
-	mockEmail := new(MockEmailSvc)
 	
 	cfg := &config.Config{}
 	service := &AuthService{
 		userRepo:     mockRepo,
 		tokenService: mockToken,
-		emailService: mockEmail,
 		config:       cfg,
+		auditRepo:    &stubAuditRepo{},
 	}
 
 	req := &models.LoginRequest{
@@ -271,10 +607,10 @@ func TestAuthService_Login_WrongPassword(t *testing.T) {
 
 	// Mock expectations
 	mockRepo.On("GetByEmail", "test@example.com").Return(user, nil)
-	mockRepo.On("IncrementFailedLoginAttempts", "test@example.com").Return(nil)
+	mockRepo.On("IncrementFailedLoginAttempts", "test@example.com", mock.Anything).Return(1, sql.NullTime{}, nil)
 
 	// Execute
-	response, err := service.Login(req)
+	response, err := service.Login(req, &models.ClientInfo{})
 
 	// Assert
 	assert.Error(t, err)
@@ -286,14 +622,13 @@ func TestAuthService_Login_WrongPassword(t *testing.T) {
 func TestAuthService_Login_AccountLocked(t *testing.T) {
 	mockRepo := new(MockUserRepo)
 	mockToken := new(MockTokenSvc)
-	mockEmail := new(MockEmailSvc)
 	
 	cfg := &config.Config{}
 	service := &AuthService{
 		userRepo:     mockRepo,
 		tokenService: mockToken,
-		emailService: mockEmail,
 		config:       cfg,
+		auditRepo:    &stubAuditRepo{},
 	}
 
 	req := &models.LoginRequest{
@@ -313,11 +648,1177 @@ func TestAuthService_Login_AccountLocked(t *testing.T) {
 	mockRepo.On("GetByEmail", "locked@example.com").Return(user, nil)
 
 	// Execute
-	response, err := service.Login(req)
+	response, err := service.Login(req, &models.ClientInfo{})
 
 	// Assert
 	assert.Error(t, err)
 	assert.Equal(t, ErrAccountLocked, err)
 	assert.Nil(t, response)
 	mockRepo.AssertExpectations(t)
-}
\ No newline at end of file
+}
+
+func TestAuthService_Login_MFARequired(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+
+	service := &AuthService{
+		userRepo:     mockRepo,
+		tokenService: mockToken,
+		config:       &config.Config{},
+		auditRepo:    &stubAuditRepo{},
+	}
+
+	hashedPassword, _ := utils.HashPassword("TestPass123!", 4)
+	user := &models.User{
+		ID:            uuid.New(),
+		Email:         "mfa@example.com",
+		Password:      hashedPassword,
+		EmailVerified: true,
+		TOTPEnabled:   true,
+	}
+
+	// Mock expectations: no tokens are issued before the second factor
+	mockRepo.On("GetByEmail", "mfa@example.com").Return(user, nil)
+	mockToken.On("GenerateMFAChallenge", user.ID).Return("challenge", nil)
+
+	// Execute
+	response, err := service.Login(&models.LoginRequest{Email: "mfa@example.com", Password: "TestPass123!"}, &models.ClientInfo{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, response.MFARequired)
+	assert.Equal(t, "challenge", response.MFAToken)
+	assert.Empty(t, response.AccessToken)
+	mockRepo.AssertExpectations(t)
+	mockToken.AssertExpectations(t)
+	mockToken.AssertNotCalled(t, "GenerateAccessToken", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_LoginMFA_Success(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockTOTP := new(MockTOTPSvc)
+	mockSession := new(MockSessionSvc)
+
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenService:   mockToken,
+		totpService:    mockTOTP,
+		sessionService: mockSession,
+		config:         &config.Config{},
+		auditRepo:      &stubAuditRepo{},
+	}
+
+	user := &models.User{
+		ID:          uuid.New(),
+		Email:       "mfa@example.com",
+		TOTPEnabled: true,
+	}
+	session := &models.Session{ID: uuid.New(), UserID: user.ID}
+
+	// Mock expectations
+	mockToken.On("ValidateMFAChallenge", "challenge").Return(user.ID, nil)
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	mockTOTP.On("VerifyLoginCode", user, "123456").Return(nil)
+	mockToken.On("ConsumeMFAChallenge", "challenge").Return(nil)
+	mockRepo.On("ResetFailedLoginAttempts", "mfa@example.com").Return(nil)
+	mockSession.On("CreateSession", user.ID, mock.AnythingOfType("*models.ClientInfo")).Return(session, "", nil)
+	mockToken.On("GenerateAccessToken", user.ID, session.ID).Return("access_token", nil)
+	mockToken.On("GenerateRefreshToken", user.ID, session.ID).Return("refresh_token", nil)
+
+	// Execute
+	response, err := service.LoginMFA(&models.MFALoginRequest{MFAToken: "challenge", Code: "123456"}, &models.ClientInfo{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "access_token", response.AccessToken)
+	assert.Equal(t, "refresh_token", response.RefreshToken)
+	mockRepo.AssertExpectations(t)
+	mockToken.AssertExpectations(t)
+	mockTOTP.AssertExpectations(t)
+}
+
+func TestAuthService_LoginMFA_InvalidCode(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockTOTP := new(MockTOTPSvc)
+
+	service := &AuthService{
+		userRepo:     mockRepo,
+		tokenService: mockToken,
+		totpService:  mockTOTP,
+		config:       &config.Config{},
+		auditRepo:    &stubAuditRepo{},
+	}
+
+	user := &models.User{
+		ID:          uuid.New(),
+		Email:       "mfa@example.com",
+		TOTPEnabled: true,
+	}
+
+	// Mock expectations: the challenge is charged, not consumed
+	mockToken.On("ValidateMFAChallenge", "challenge").Return(user.ID, nil)
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	mockTOTP.On("VerifyLoginCode", user, "000000").Return(ErrInvalidTOTPCode)
+	mockToken.On("FailMFAChallenge", "challenge").Return(nil)
+	mockRepo.On("IncrementFailedLoginAttempts", "mfa@example.com", mock.Anything).Return(1, sql.NullTime{}, nil)
+
+	// Execute
+	response, err := service.LoginMFA(&models.MFALoginRequest{MFAToken: "challenge", Code: "000000"}, &models.ClientInfo{})
+
+	// Assert
+	assert.Equal(t, ErrInvalidTOTPCode, err)
+	assert.Nil(t, response)
+	mockToken.AssertNotCalled(t, "ConsumeMFAChallenge", "challenge")
+	mockRepo.AssertExpectations(t)
+	mockToken.AssertExpectations(t)
+}
+
+func TestAuthService_LoginMFA_StoreFailure(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockTOTP := new(MockTOTPSvc)
+
+	service := &AuthService{
+		userRepo:     mockRepo,
+		tokenService: mockToken,
+		totpService:  mockTOTP,
+		config:       &config.Config{},
+		auditRepo:    &stubAuditRepo{},
+	}
+
+	outage := errors.New("redis: connection refused")
+	user := &models.User{ID: uuid.New(), Email: "mfa@example.com", TOTPEnabled: true}
+
+	// An outage reading the challenge is not reported as a bad challenge
+	mockToken.On("ValidateMFAChallenge", "down").Return(uuid.Nil, outage).Once()
+	_, err := service.LoginMFA(&models.MFALoginRequest{MFAToken: "down", Code: "123456"}, &models.ClientInfo{})
+	assert.Equal(t, outage, err)
+
+	// Nor as a wrong code, and the attempt isn't counted against the user
+	mockToken.On("ValidateMFAChallenge", "challenge").Return(user.ID, nil)
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	mockTOTP.On("VerifyLoginCode", user, "123456").Return(outage).Once()
+	_, err = service.LoginMFA(&models.MFALoginRequest{MFAToken: "challenge", Code: "123456"}, &models.ClientInfo{})
+	assert.Equal(t, outage, err)
+	mockToken.AssertNotCalled(t, "FailMFAChallenge", mock.Anything)
+	mockRepo.AssertNotCalled(t, "IncrementFailedLoginAttempts", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_SessionMode(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         &config.Config{},
+		auditRepo:      &stubAuditRepo{},
+	}
+
+	hashedPassword, _ := utils.HashPassword("TestPass123!", 4)
+	user := &models.User{
+		ID:            uuid.New(),
+		Email:         "session@example.com",
+		Password:      hashedPassword,
+		EmailVerified: true,
+	}
+	client := &models.ClientInfo{DeviceID: "laptop", UseSession: true}
+
+	// Mock expectations
+	mockRepo.On("GetByEmail", "session@example.com").Return(user, nil)
+	mockRepo.On("ResetFailedLoginAttempts", "session@example.com").Return(nil)
+	mockSession.On("CreateSession", user.ID, client).Return(&models.Session{ID: uuid.New(), UserID: user.ID}, "session_token", nil)
+
+	// Execute
+	response, err := service.Login(&models.LoginRequest{Email: "session@example.com", Password: "TestPass123!"}, client)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "session_token", response.SessionToken)
+	assert.Empty(t, response.AccessToken)
+	mockSession.AssertExpectations(t)
+	mockToken.AssertNotCalled(t, "GenerateAccessToken", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_RefreshToken_KeepsSession(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         &config.Config{},
+		auditRepo:      &stubAuditRepo{},
+	}
+
+	claims := &TokenClaims{UserID: uuid.New(), SessionID: uuid.New()}
+
+	// Mock expectations: the new pair stays bound to the same session
+	mockToken.On("RotateRefreshToken", "old_refresh", "").Return(claims, "refresh_token", nil)
+	mockRepo.On("GetByID", claims.UserID.String()).Return(&models.User{ID: claims.UserID}, nil)
+	mockToken.On("GenerateAccessToken", claims.UserID, claims.SessionID).Return("access_token", nil)
+	mockSession.On("TouchSession", claims.SessionID).Return(nil)
+
+	// Execute
+	response, err := service.RefreshToken("old_refresh", &models.ClientInfo{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh_token", response.RefreshToken)
+	mockToken.AssertExpectations(t)
+	mockSession.AssertExpectations(t)
+}
+
+func TestAuthService_RefreshClientToken_KeepsGrant(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         &config.Config{},
+		auditRepo:      &stubAuditRepo{},
+	}
+
+	claims := &TokenClaims{UserID: uuid.New(), SessionID: uuid.New(), ClientID: "billing", Scope: "openid"}
+	grant := OAuthGrant{ClientID: "billing", Scope: "openid"}
+
+	// Mock expectations: the client's new access token keeps its client and scope
+	mockToken.On("RotateRefreshToken", "client_refresh", "billing").Return(claims, "refresh_token", nil)
+	mockRepo.On("GetByID", claims.UserID.String()).Return(&models.User{ID: claims.UserID}, nil)
+	mockToken.On("GenerateDelegatedAccessToken", claims.UserID, claims.SessionID, grant).Return("access_token", nil)
+	mockSession.On("TouchSession", claims.SessionID).Return(nil)
+
+	// Execute
+	response, err := service.RefreshClientToken("client_refresh", "billing", &models.ClientInfo{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "openid", response.Scope)
+	mockToken.AssertExpectations(t)
+	mockToken.AssertNotCalled(t, "GenerateAccessToken", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_RefreshToken_DeletedAccount(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         &config.Config{},
+		auditRepo:      &stubAuditRepo{},
+	}
+
+	claims := &TokenClaims{UserID: uuid.New(), SessionID: uuid.New()}
+	user := &models.User{ID: claims.UserID, DeletedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+
+	// Mock expectations: the family is revoked rather than refreshed
+	mockToken.On("RotateRefreshToken", "old_refresh", "").Return(claims, "refresh_token", nil)
+	mockRepo.On("GetByID", claims.UserID.String()).Return(user, nil)
+	mockSession.On("RevokeSession", claims.SessionID).Return(nil)
+
+	// Execute
+	response, err := service.RefreshToken("old_refresh", &models.ClientInfo{})
+
+	// Assert
+	assert.Equal(t, ErrInvalidToken, err)
+	assert.Nil(t, response)
+	mockSession.AssertExpectations(t)
+	mockToken.AssertNotCalled(t, "GenerateAccessToken", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_RefreshToken_ReuseRevokesFamily(t *testing.T) {
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+
+	service := &AuthService{
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		logger:         utils.NewLogger(),
+		config:         &config.Config{},
+		auditRepo:      &stubAuditRepo{},
+	}
+
+	claims := &TokenClaims{UserID: uuid.New(), SessionID: uuid.New()}
+
+	// Mock expectations: the session holding the family is revoked
+	mockToken.On("RotateRefreshToken", "stolen_refresh", "").Return(claims, "", ErrRefreshTokenReused)
+	mockSession.On("RevokeSession", claims.SessionID).Return(nil)
+
+	// Execute
+	response, err := service.RefreshToken("stolen_refresh", &models.ClientInfo{})
+
+	// Assert
+	assert.Equal(t, ErrRefreshTokenReused, err)
+	assert.Nil(t, response)
+	mockSession.AssertExpectations(t)
+	mockToken.AssertNotCalled(t, "GenerateAccessToken", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_RefreshToken_GraceWindow(t *testing.T) {
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+
+	service := &AuthService{
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         &config.Config{},
+		auditRepo:      &stubAuditRepo{},
+	}
+
+	claims := &TokenClaims{UserID: uuid.New(), SessionID: uuid.New()}
+
+	// Mock expectations: a concurrent refresh whose successor has itself been
+	// rotated is rejected without revoking anything
+	mockToken.On("RotateRefreshToken", "old_refresh", "").Return(claims, "", ErrRefreshTokenRotated)
+
+	// Execute
+	response, err := service.RefreshToken("old_refresh", &models.ClientInfo{})
+
+	// Assert
+	assert.Equal(t, ErrRefreshTokenRotated, err)
+	assert.Nil(t, response)
+	mockSession.AssertNotCalled(t, "RevokeSession", mock.Anything)
+}
+
+func TestAuthService_Logout_RevokesAllDevices(t *testing.T) {
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+
+	service := &AuthService{
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         &config.Config{},
+		auditRepo:      &stubAuditRepo{},
+	}
+
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	// Mock expectations
+	mockSession.On("RevokeSession", sessionID).Return(nil)
+	mockToken.On("RevokeAccessToken", "access_token").Return(nil)
+	mockSession.On("RevokeAllUserSessions", userID).Return(nil)
+	mockToken.On("RevokeAllUserTokens", userID).Return(nil)
+	mockToken.On("RevokeUserAccessTokens", userID).Return(nil)
+
+	// Execute
+	err := service.Logout(userID, "access_token", sessionID, &models.ClientInfo{})
+
+	// Assert
+	assert.NoError(t, err)
+	mockToken.AssertExpectations(t)
+	mockSession.AssertExpectations(t)
+}
+
+func TestAuthService_Login_AccountDisabled(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+
+	service := &AuthService{
+		userRepo:     mockRepo,
+		tokenService: mockToken,
+		config:       &config.Config{},
+		auditRepo:    &stubAuditRepo{},
+	}
+
+	hashedPassword, _ := utils.HashPassword("TestPass123!", 4)
+	user := &models.User{
+		ID:            uuid.New(),
+		Email:         "disabled@example.com",
+		Password:      hashedPassword,
+		EmailVerified: true,
+	}
+	user.DisabledAt.Time = time.Now()
+	user.DisabledAt.Valid = true
+
+	// Mock expectations
+	mockRepo.On("GetByEmail", "disabled@example.com").Return(user, nil)
+
+	// Execute
+	response, err := service.Login(&models.LoginRequest{
+		Email:    "disabled@example.com",
+		Password: "TestPass123!",
+	}, &models.ClientInfo{})
+
+	// Assert
+	assert.Equal(t, ErrAccountDisabled, err)
+	assert.Nil(t, response)
+	mockToken.AssertNotCalled(t, "GenerateAccessToken", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_ForcePasswordReset(t *testing.T) {
+	tokens := &stubOneTimeTokenRepo{}
+	mockRepo := &MockUserRepo{tokens: tokens}
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenRepo:      tokens,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         &config.Config{},
+		auditRepo:      &stubAuditRepo{},
+	}
+
+	user := &models.User{
+		ID:       uuid.New(),
+		Email:    "reset@example.com",
+		Password: "$2a$10$existinghash",
+	}
+
+	// Mock expectations
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	// The password is cleared in the same write that issues the reset link
+	mockRepo.On("Update", mock.MatchedBy(func(u *models.User) bool {
+		return u.Password == ""
+	}), mock.MatchedBy(func(change *repository.TokenChange) bool {
+		return change != nil && change.Issue != nil
+	}), mock.MatchedBy(func(outbox []models.OutboxMessage) bool {
+		return assert.ObjectsAreEqual([]string{models.OutboxPasswordResetEmail}, outboxKinds(outbox))
+	})).Return(nil)
+	mockSession.On("RevokeAllUserSessions", user.ID).Return(nil)
+	mockToken.On("RevokeAllUserTokens", user.ID).Return(nil)
+	mockToken.On("RevokeUserAccessTokens", user.ID).Return(nil)
+
+	// Execute
+	err := service.ForcePasswordReset(user.ID)
+
+	// Assert
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockSession.AssertExpectations(t)
+	mockToken.AssertExpectations(t)
+
+	issued, email := tokens.last(t, models.OutboxPasswordResetEmail)
+	assert.Equal(t, models.TokenPurposePasswordReset, issued.Purpose)
+	assert.Equal(t, user.ID, issued.UserID)
+	assert.Equal(t, "reset@example.com", email.To)
+}
+
+func TestAuthService_Login_AuditsFailureAndLockout(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	audit := &stubAuditRepo{}
+	outbox := &stubOutboxRepo{}
+
+	service := &AuthService{
+		userRepo:   mockRepo,
+		config:     &config.Config{AccountLockoutDuration: 30 * time.Minute},
+		auditRepo:  audit,
+		outboxRepo: outbox,
+	}
+
+	hashedPassword, _ := utils.HashPassword("CorrectPassword", 4)
+	user := &models.User{
+		ID:                  uuid.New(),
+		Email:               "test@example.com",
+		Password:            hashedPassword,
+		EmailVerified:       true,
+		FailedLoginAttempts: 0,
+	}
+
+	// Concurrent failures since the user was read: the returned count decides
+	mockRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+	lockedUntil := time.Now().Add(29 * time.Minute).UTC()
+	mockRepo.On("IncrementFailedLoginAttempts", "test@example.com", 30*time.Minute).
+		Return(maxFailedLoginAttempts, sql.NullTime{Time: lockedUntil, Valid: true}, nil)
+
+	client := &models.ClientInfo{IPAddress: "203.0.113.7", UserAgent: "test-agent", CorrelationID: "corr-1", Locale: "es"}
+	_, err := service.Login(&models.LoginRequest{Email: "test@example.com", Password: "WrongPassword"}, client)
+
+	assert.Equal(t, ErrInvalidCredentials, err)
+	assert.Equal(t, []string{models.AuditLoginFailed, models.AuditAccountLocked}, audit.types())
+	event := audit.events[0]
+	assert.Equal(t, user.ID, event.UserID)
+	assert.Equal(t, "203.0.113.7", event.IPAddress)
+	assert.Equal(t, "test-agent", event.UserAgent)
+	assert.Equal(t, "corr-1", event.CorrelationID)
+	assert.Equal(t, "invalid_password", event.Metadata["reason"])
+
+	// The lockout is also emailed, in the client's language, and published
+	if assert.Len(t, outbox.messages, 2) {
+		assert.Equal(t, models.OutboxLockoutEmail, outbox.messages[0].Kind)
+		assert.Equal(t, models.OutboxWebhookEvent, outbox.messages[1].Kind)
+		var email models.OutboxEmail
+		assert.NoError(t, json.Unmarshal(outbox.messages[0].Payload, &email))
+		assert.Equal(t, "test@example.com", email.To)
+		assert.Equal(t, "es", email.Locale)
+		// The email gives the lock expiry as stored
+		assert.True(t, lockedUntil.Equal(email.Until), "expected %v, got %v", lockedUntil, email.Until)
+	}
+
+	// Failures for unknown addresses keep only a digest of what was typed
+	mockRepo.On("GetByEmail", "nobody@example.com").Return(nil, sql.ErrNoRows)
+	_, err = service.Login(&models.LoginRequest{Email: " Nobody@Example.com", Password: "WrongPassword"}, client)
+	assert.Equal(t, ErrInvalidCredentials, err)
+	unknown := audit.events[len(audit.events)-1]
+	assert.Equal(t, uuid.Nil, unknown.UserID)
+	assert.Equal(t, utils.HashEmail("nobody@example.com"), unknown.Metadata["email_hash"])
+	assert.NotContains(t, unknown.Metadata, "email")
+}
+
+func TestAuthService_Login_NotifiesNewDevice(t *testing.T) {
+	hashedPassword, _ := utils.HashPassword("TestPass123!", 4)
+	user := &models.User{
+		ID:            uuid.New(),
+		Email:         "test@example.com",
+		Password:      hashedPassword,
+		EmailVerified: true,
+	}
+	session := &models.Session{ID: uuid.New(), UserID: user.ID}
+
+	for _, known := range []bool{false, true} {
+		mockRepo := new(MockUserRepo)
+		mockToken := new(MockTokenSvc)
+		mockSession := new(MockSessionSvc)
+		outbox := &stubOutboxRepo{}
+		service := &AuthService{
+			userRepo:       mockRepo,
+			tokenService:   mockToken,
+			sessionService: mockSession,
+			config:         &config.Config{},
+			auditRepo:      &stubAuditRepo{},
+			outboxRepo:     outbox,
+		}
+
+		client := &models.ClientInfo{DeviceID: "laptop", UserAgent: "test-agent", IPAddress: "203.0.113.7"}
+		mockRepo.On("GetByEmail", "test@example.com").Return(user, nil)
+		mockRepo.On("ResetFailedLoginAttempts", "test@example.com").Return(nil)
+		mockSession.On("IsKnownDevice", user.ID, client).Return(known, nil)
+		mockSession.On("CreateSession", user.ID, mock.AnythingOfType("*models.ClientInfo")).Return(session, "", nil)
+		mockToken.On("GenerateAccessToken", user.ID, session.ID).Return("access_token", nil)
+		mockToken.On("GenerateRefreshToken", user.ID, session.ID).Return("refresh_token", nil)
+
+		_, err := service.Login(&models.LoginRequest{Email: "test@example.com", Password: "TestPass123!"}, client)
+		assert.NoError(t, err)
+		mockSession.AssertExpectations(t)
+
+		if known {
+			assert.Empty(t, outbox.messages, "known devices are not announced")
+			continue
+		}
+		if assert.Len(t, outbox.messages, 1) {
+			assert.Equal(t, models.OutboxNewDeviceEmail, outbox.messages[0].Kind)
+			var email models.OutboxEmail
+			assert.NoError(t, json.Unmarshal(outbox.messages[0].Payload, &email))
+			assert.Equal(t, "test-agent", email.UserAgent)
+			assert.Equal(t, "203.0.113.7", email.IPAddress)
+		}
+	}
+}
+
+func TestAuthService_ActivityLog(t *testing.T) {
+	audit := &stubAuditRepo{}
+	service := &AuthService{auditRepo: audit}
+
+	userID := uuid.New()
+	service.RecordEvent(userID, models.AuditLoginSucceeded, &models.ClientInfo{IPAddress: "203.0.113.7"}, nil)
+	service.RecordEvent(uuid.New(), models.AuditLogout, nil, nil)
+
+	response, err := service.ActivityLog(userID, &models.ActivityLogQuery{PerPage: 1000})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), response.Total)
+	assert.Equal(t, 1, response.Page)
+	assert.Equal(t, maxActivityPerPage, response.PerPage)
+	assert.Len(t, response.Activities, 1)
+	assert.Equal(t, "login", response.Activities[0].Type)
+	assert.Equal(t, models.AuditLoginSucceeded, response.Activities[0].Event)
+	assert.Equal(t, "203.0.113.7", response.Activities[0].IP)
+}
+
+// stubThrottle counts attempts per key in memory
+type stubThrottle struct {
+	counts map[string]int
+}
+
+func (t *stubThrottle) Allow(key string, limit int, window time.Duration) (bool, error) {
+	if t.counts == nil {
+		t.counts = map[string]int{}
+	}
+	t.counts[key]++
+	return t.counts[key] <= limit, nil
+}
+
+func TestAuthService_ResendVerification(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	audit := &stubAuditRepo{}
+	tokens := &stubOneTimeTokenRepo{}
+	service := &AuthService{
+		userRepo:  mockRepo,
+		tokenRepo: tokens,
+		config:    &config.Config{EmailVerifyExpiry: 24 * time.Hour},
+		auditRepo: audit,
+		throttle:  &stubThrottle{},
+	}
+	client := &models.ClientInfo{IPAddress: "203.0.113.7", Locale: "es"}
+
+	user := &models.User{ID: uuid.New(), Email: "pending@example.com"}
+	mockRepo.On("GetByEmail", "pending@example.com").Return(user, nil)
+	mockRepo.On("GetByEmail", "verified@example.com").Return(&models.User{ID: uuid.New(), EmailVerified: true}, nil)
+	mockRepo.On("GetByEmail", "nobody@example.com").Return(nil, sql.ErrNoRows)
+
+	// Unknown and already verified addresses get the same answer as a real resend
+	assert.NoError(t, service.ResendVerification("nobody@example.com", client))
+	assert.NoError(t, service.ResendVerification("verified@example.com", client))
+	assert.Empty(t, audit.events)
+
+	assert.NoError(t, service.ResendVerification(" Pending@Example.com ", client))
+	assert.Equal(t, []string{models.AuditVerificationResent}, audit.types())
+	assert.Len(t, tokens.issued, 1)
+
+	issued, email := tokens.last(t, models.OutboxVerificationEmail)
+	assert.Equal(t, user.ID, issued.UserID)
+	assert.Equal(t, "es", email.Locale)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), issued.ExpiresAt, time.Minute)
+}
+
+func TestAuthService_ResendVerification_Throttled(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	service := &AuthService{
+		userRepo:  mockRepo,
+		config:    &config.Config{},
+		auditRepo: &stubAuditRepo{},
+		throttle:  &stubThrottle{},
+	}
+	mockRepo.On("GetByEmail", mock.Anything).Return(nil, sql.ErrNoRows)
+
+	// Per address, whether or not it exists
+	client := &models.ClientInfo{IPAddress: "203.0.113.7"}
+	for i := 0; i < verifyResendPerEmail; i++ {
+		assert.NoError(t, service.ResendVerification("nobody@example.com", client))
+	}
+	assert.Equal(t, ErrTooManyRequests, service.ResendVerification("nobody@example.com", client))
+
+	// Per client IP, across addresses
+	other := &models.ClientInfo{IPAddress: "198.51.100.1"}
+	for i := 0; i < verifyResendPerIP; i++ {
+		assert.NoError(t, service.ResendVerification(fmt.Sprintf("user%d@example.com", i), other))
+	}
+	assert.Equal(t, ErrTooManyRequests, service.ResendVerification("another@example.com", other))
+}
+
+// stubOneTimeTokenRepo keeps issued tokens in memory, keyed by digest
+type stubOneTimeTokenRepo struct {
+	issued []*models.OneTimeToken
+	outbox []models.OutboxMessage
+}
+
+func (r *stubOneTimeTokenRepo) Issue(token *models.OneTimeToken, outbox ...models.OutboxMessage) error {
+	now := time.Now()
+	for _, existing := range r.issued {
+		if existing.UserID == token.UserID && existing.Purpose == token.Purpose && existing.UsedAt == nil {
+			existing.UsedAt = &now
+		}
+	}
+	if token.ID == uuid.Nil {
+		token.ID = uuid.New()
+	}
+	r.issued = append(r.issued, token)
+	r.outbox = append(r.outbox, outbox...)
+	return nil
+}
+
+func (r *stubOneTimeTokenRepo) Find(purpose, tokenHash string) (*models.OneTimeToken, error) {
+	for _, token := range r.issued {
+		if token.TokenHash == tokenHash && token.Purpose == purpose && token.UsedAt == nil && token.ExpiresAt.After(time.Now()) {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// apply makes the token writes and records the outbox messages of a user
+// write, or none of them when the token to consume is spent
+func (r *stubOneTimeTokenRepo) apply(userID uuid.UUID, tokens *repository.TokenChange, outbox []models.OutboxMessage) error {
+	if tokens != nil && tokens.Consume != nil {
+		var live *models.OneTimeToken
+		for _, token := range r.issued {
+			if token.ID == tokens.Consume.ID && token.UserID == userID && token.UsedAt == nil && token.ExpiresAt.After(time.Now()) {
+				live = token
+			}
+		}
+		if live == nil {
+			return repository.ErrTokenSpent
+		}
+		now := time.Now()
+		live.UsedAt = &now
+	}
+	if tokens != nil {
+		for _, purpose := range tokens.Revoke {
+			r.Revoke(userID, purpose)
+		}
+		if tokens.Issue != nil {
+			return r.Issue(tokens.Issue, outbox...)
+		}
+	}
+	r.outbox = append(r.outbox, outbox...)
+	return nil
+}
+
+func (r *stubOneTimeTokenRepo) Revoke(userID uuid.UUID, purpose string) error {
+	now := time.Now()
+	for _, token := range r.issued {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+// last returns the newest token and the email of kind that carried it
+func (r *stubOneTimeTokenRepo) last(t *testing.T, kind string) (*models.OneTimeToken, models.OutboxEmail) {
+	t.Helper()
+	if len(r.issued) == 0 || len(r.outbox) == 0 {
+		t.Fatal("expected a token to be issued")
+	}
+
+	message := r.outbox[len(r.outbox)-1]
+	assert.Equal(t, kind, message.Kind)
+	var email models.OutboxEmail
+	if err := json.Unmarshal(message.Payload, &email); err != nil {
+		t.Fatal(err)
+	}
+	return r.issued[len(r.issued)-1], email
+}
+
+func TestAuthService_VerifyEmail_SingleUse(t *testing.T) {
+	tokens := &stubOneTimeTokenRepo{}
+	mockRepo := &MockUserRepo{tokens: tokens}
+	service := &AuthService{
+		userRepo:  mockRepo,
+		tokenRepo: tokens,
+		config:    &config.Config{EmailVerifyExpiry: time.Hour},
+		auditRepo: &stubAuditRepo{},
+	}
+
+	user := &models.User{ID: uuid.New(), Email: "pending@example.com"}
+	mockRepo.On("MarkEmailVerified", user.ID, mock.Anything, mock.Anything).Return(nil).Once()
+
+	assert.NoError(t, service.sendEmailVerification(user, ""))
+	_, first := tokens.last(t, models.OutboxVerificationEmail)
+	assert.NoError(t, service.sendEmailVerification(user, ""))
+	_, second := tokens.last(t, models.OutboxVerificationEmail)
+
+	// A newer link replaces the older one, and each works only once
+	assert.Equal(t, ErrInvalidToken, service.VerifyEmail(first.Token, &models.ClientInfo{}))
+	assert.NoError(t, service.VerifyEmail(second.Token, &models.ClientInfo{}))
+	assert.Equal(t, ErrInvalidToken, service.VerifyEmail(second.Token, &models.ClientInfo{}))
+	assert.Equal(t, ErrInvalidToken, service.VerifyEmail("", &models.ClientInfo{}))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthService_ResetPassword_RejectsWrongPurposeAndExpired(t *testing.T) {
+	tokens := &stubOneTimeTokenRepo{}
+	mockRepo := &MockUserRepo{tokens: tokens}
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenRepo:      tokens,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         &config.Config{BcryptCost: 4, EmailVerifyExpiry: time.Hour},
+		auditRepo:      &stubAuditRepo{},
+	}
+
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	// The reset link is used up in the same write that sets the password
+	mockRepo.On("Update", user, mock.MatchedBy(func(change *repository.TokenChange) bool {
+		return change != nil && change.Consume != nil && change.Consume.Purpose == models.TokenPurposePasswordReset
+	}), mock.MatchedBy(func(outbox []models.OutboxMessage) bool {
+		return assert.ObjectsAreEqual([]string{models.OutboxWebhookEvent, models.OutboxPasswordChangedEmail}, outboxKinds(outbox))
+	})).Return(nil).Once()
+	// Once before the new password is stored and once after
+	mockSession.On("RevokeAllUserSessions", user.ID).Return(nil).Twice()
+	mockToken.On("RevokeAllUserTokens", user.ID).Return(nil).Twice()
+	mockToken.On("RevokeUserAccessTokens", user.ID).Return(nil).Twice()
+
+	// A verification link cannot reset a password
+	assert.NoError(t, service.sendEmailVerification(user, ""))
+	_, verification := tokens.last(t, models.OutboxVerificationEmail)
+	assert.Equal(t, ErrInvalidToken, service.ResetPassword(verification.Token, "NewPass123!", &models.ClientInfo{}))
+
+	// Nor can an expired reset link
+	assert.NoError(t, service.sendPasswordReset(user, ""))
+	issued, reset := tokens.last(t, models.OutboxPasswordResetEmail)
+	issued.ExpiresAt = time.Now().Add(-time.Minute)
+	assert.Equal(t, ErrInvalidToken, service.ResetPassword(reset.Token, "NewPass123!", &models.ClientInfo{}))
+
+	assert.NoError(t, service.sendPasswordReset(user, ""))
+	_, reset = tokens.last(t, models.OutboxPasswordResetEmail)
+	assert.NoError(t, service.ResetPassword(reset.Token, "NewPass123!", &models.ClientInfo{}))
+	assert.NoError(t, utils.CheckPassword("NewPass123!", user.Password))
+
+	// Only a completed reset signs the user out everywhere
+	mockRepo.AssertExpectations(t)
+	mockSession.AssertExpectations(t)
+	mockToken.AssertExpectations(t)
+}
+
+func TestAuthService_ResetPassword_KeepsLinkUntilApplied(t *testing.T) {
+	tokens := &stubOneTimeTokenRepo{}
+	mockRepo := &MockUserRepo{tokens: tokens}
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenRepo:      tokens,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         &config.Config{BcryptCost: 4},
+		auditRepo:      &stubAuditRepo{},
+		logger:         utils.NewLogger(),
+	}
+
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Password: "$2a$10$existinghash"}
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	mockRepo.On("Update", user, mock.Anything, mock.Anything).Return(errors.New("connection reset")).Once()
+	mockSession.On("RevokeAllUserSessions", user.ID).Return(nil)
+	mockToken.On("RevokeAllUserTokens", user.ID).Return(nil)
+	mockToken.On("RevokeUserAccessTokens", user.ID).Return(nil)
+
+	assert.NoError(t, service.sendPasswordReset(user, ""))
+	issued, reset := tokens.last(t, models.OutboxPasswordResetEmail)
+
+	// Neither a weak password nor a failed write uses up the link
+	assert.Equal(t, utils.ErrPasswordTooShort, service.ResetPassword(reset.Token, "Ab1!", &models.ClientInfo{}))
+	assert.EqualError(t, service.ResetPassword(reset.Token, "NewPass123!", &models.ClientInfo{}), "connection reset")
+	assert.Nil(t, issued.UsedAt)
+
+	// A link spent by a concurrent request between lookup and write is invalid
+	mockRepo.On("Update", user, mock.Anything, mock.Anything).Return(repository.ErrTokenSpent).Once()
+	assert.Equal(t, ErrInvalidToken, service.ResetPassword(reset.Token, "NewPass123!", &models.ClientInfo{}))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+	audit := &stubAuditRepo{}
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         &config.Config{BcryptCost: 4},
+		auditRepo:      audit,
+		throttle:       &stubThrottle{},
+	}
+
+	hash, _ := utils.HashPassword("OldPass123!", 4)
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Password: hash}
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	mockRepo.On("Update", user, (*repository.TokenChange)(nil), mock.MatchedBy(func(outbox []models.OutboxMessage) bool {
+		return assert.ObjectsAreEqual([]string{models.OutboxWebhookEvent, models.OutboxPasswordChangedEmail}, outboxKinds(outbox))
+	})).Return(nil).Once()
+	// Once before the new password is stored and once after
+	mockSession.On("RevokeAllUserSessions", user.ID).Return(nil).Twice()
+	mockToken.On("RevokeAllUserTokens", user.ID).Return(nil).Twice()
+	mockToken.On("RevokeUserAccessTokens", user.ID).Return(nil).Twice()
+
+	client := &models.ClientInfo{}
+	assert.Equal(t, ErrInvalidCredentials, service.ChangePassword(user.ID, "WrongPass123!", "NewPass123!", client))
+	assert.Equal(t, utils.ErrPasswordNoSpecial, service.ChangePassword(user.ID, "OldPass123!", "NewPass1234", client))
+	assert.Equal(t, ErrPasswordUnchanged, service.ChangePassword(user.ID, "OldPass123!", "OldPass123!", client))
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+
+	assert.NoError(t, service.ChangePassword(user.ID, "OldPass123!", "NewPass123!", client))
+	assert.NoError(t, utils.CheckPassword("NewPass123!", user.Password))
+	assert.Equal(t, []string{models.AuditPasswordChanged}, audit.types())
+	mockRepo.AssertExpectations(t)
+	mockSession.AssertExpectations(t)
+	mockToken.AssertExpectations(t)
+
+	// Guessing the current password through a hijacked session is throttled
+	assert.Equal(t, ErrInvalidCredentials, service.ChangePassword(user.ID, "OldPass123!", "OtherPass123!", client))
+	assert.Equal(t, ErrTooManyRequests, service.ChangePassword(user.ID, "NewPass123!", "OtherPass123!", client))
+}
+
+func TestAuthService_ChangePassword_SignOutFailure(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		config:         &config.Config{BcryptCost: 4},
+		auditRepo:      &stubAuditRepo{},
+		throttle:       &stubThrottle{},
+		logger:         utils.NewLogger(),
+	}
+
+	hash, _ := utils.HashPassword("OldPass123!", 4)
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Password: hash}
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	mockToken.On("RevokeAllUserTokens", user.ID).Return(nil)
+	mockToken.On("RevokeUserAccessTokens", user.ID).Return(nil)
+
+	// Failing to sign out reports an error and keeps the old password
+	mockSession.On("RevokeAllUserSessions", user.ID).Return(errors.New("redis down")).Once()
+	assert.EqualError(t, service.ChangePassword(user.ID, "OldPass123!", "NewPass123!", &models.ClientInfo{}), "redis down")
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, hash, user.Password)
+
+	// Once the password is stored, the change is reported as made
+	mockSession.On("RevokeAllUserSessions", user.ID).Return(nil).Once()
+	mockRepo.On("Update", user, mock.Anything, mock.Anything).Return(nil).Once()
+	mockSession.On("RevokeAllUserSessions", user.ID).Return(errors.New("redis down")).Once()
+	assert.NoError(t, service.ChangePassword(user.ID, "OldPass123!", "NewPass123!", &models.ClientInfo{}))
+	assert.NoError(t, utils.CheckPassword("NewPass123!", user.Password))
+	mockRepo.AssertExpectations(t)
+	mockSession.AssertExpectations(t)
+}
+
+func TestAuthService_EmailChange(t *testing.T) {
+	tokens := &stubOneTimeTokenRepo{}
+	mockRepo := &MockUserRepo{tokens: tokens}
+	audit := &stubAuditRepo{}
+	service := &AuthService{
+		userRepo:  mockRepo,
+		tokenRepo: tokens,
+		config:    &config.Config{BcryptCost: 4},
+		auditRepo: audit,
+		throttle:  &stubThrottle{},
+	}
+
+	hash, _ := utils.HashPassword("TestPass123!", 4)
+	user := &models.User{ID: uuid.New(), Email: "old@example.com", Password: hash}
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	mockRepo.On("GetByEmail", "taken@example.com").Return(&models.User{ID: uuid.New()}, nil)
+	mockRepo.On("GetByEmail", "new@example.com").Return(nil, sql.ErrNoRows)
+
+	client := &models.ClientInfo{Locale: "es"}
+	assert.Equal(t, ErrInvalidCredentials, service.RequestEmailChange(user.ID, "new@example.com", "WrongPass123!", client))
+	assert.Equal(t, ErrEmailUnchanged, service.RequestEmailChange(user.ID, "Old@Example.com", "TestPass123!", client))
+	assert.Equal(t, ErrEmailInUse, service.RequestEmailChange(user.ID, "taken@example.com", "TestPass123!", client))
+	assert.Empty(t, tokens.issued)
+
+	// A pending reset link was sent to the old address
+	assert.NoError(t, service.sendPasswordReset(user, ""))
+	reset := tokens.issued[0]
+
+	assert.NoError(t, service.RequestEmailChange(user.ID, " New@Example.com ", "TestPass123!", client))
+	issued := tokens.issued[len(tokens.issued)-1]
+	assert.Equal(t, models.TokenPurposeEmailChange, issued.Purpose)
+	assert.Equal(t, "new@example.com", issued.Data["email"])
+
+	// The link goes to the new address and the notice to the old one, together
+	outbox := tokens.outbox[len(tokens.outbox)-2:]
+	var confirm, notice models.OutboxEmail
+	assert.NoError(t, json.Unmarshal(outbox[0].Payload, &confirm))
+	assert.NoError(t, json.Unmarshal(outbox[1].Payload, &notice))
+	assert.Equal(t, models.OutboxEmailChangeEmail, outbox[0].Kind)
+	assert.Equal(t, "new@example.com", confirm.To)
+	assert.Equal(t, models.OutboxEmailChangeNoticeEmail, outbox[1].Kind)
+	assert.Equal(t, "old@example.com", notice.To)
+	assert.Equal(t, "new@example.com", notice.NewEmail)
+
+	// Uniqueness is checked again when the change is confirmed, and a
+	// refused change uses up nothing
+	mockRepo.On("ChangeEmail", user.ID, "new@example.com", mock.Anything).Return(false, nil).Once()
+	assert.Equal(t, ErrEmailInUse, service.ConfirmEmailChange(confirm.Token, client))
+	assert.Nil(t, reset.UsedAt)
+	assert.Nil(t, issued.UsedAt)
+
+	assert.NoError(t, service.RequestEmailChange(user.ID, "new@example.com", "TestPass123!", client))
+	outbox = tokens.outbox[len(tokens.outbox)-2:]
+	assert.NoError(t, json.Unmarshal(outbox[0].Payload, &confirm))
+	mockRepo.On("ChangeEmail", user.ID, "new@example.com", mock.MatchedBy(func(change *repository.TokenChange) bool {
+		return change != nil && change.Consume != nil &&
+			assert.ObjectsAreEqual([]string{models.TokenPurposePasswordReset}, change.Revoke)
+	})).Return(true, nil).Once()
+	assert.NoError(t, service.ConfirmEmailChange(confirm.Token, client))
+	assert.NotNil(t, reset.UsedAt)
+	assert.Equal(t, ErrInvalidToken, service.ConfirmEmailChange(confirm.Token, client))
+
+	assert.Equal(t, []string{
+		models.AuditEmailChangeRequested,
+		models.AuditEmailChangeRequested,
+		models.AuditEmailChanged,
+	}, audit.types())
+	// The audit trail outlives the account, so it holds no address
+	for _, event := range audit.events {
+		assert.Empty(t, event.Metadata)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthService_DeleteAccount(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+	mockTOTP := new(MockTOTPSvc)
+	audit := &stubAuditRepo{}
+	roles := newStubRoleRepo()
+	roles.addRole(models.RoleUser)
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenService:   mockToken,
+		totpService:    mockTOTP,
+		sessionService: mockSession,
+		rbacService:    NewRBACService(roles, nil),
+		config:         &config.Config{BcryptCost: 4, AccountDeletionGrace: 72 * time.Hour},
+		auditRepo:      audit,
+		throttle:       &stubThrottle{},
+	}
+
+	hash, _ := utils.HashPassword("TestPass123!", 4)
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Password: hash, TOTPEnabled: true}
+	roles.userRoles[user.ID] = nil
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	mockTOTP.On("VerifyLoginCode", user, "000000").Return(ErrInvalidTOTPCode)
+	mockTOTP.On("VerifyLoginCode", user, "123456").Return(nil)
+
+	client := &models.ClientInfo{Locale: "es"}
+	_, err := service.DeleteAccount(user.ID, "WrongPass123!", "123456", client)
+	assert.Equal(t, ErrInvalidCredentials, err)
+	_, err = service.DeleteAccount(user.ID, "TestPass123!", "000000", client)
+	assert.Equal(t, ErrInvalidTOTPCode, err)
+	mockRepo.AssertNotCalled(t, "SoftDelete", mock.Anything, mock.Anything, mock.Anything)
+
+	var notice models.OutboxEmail
+	mockRepo.On("SoftDelete", user.ID, (*repository.AdminGuard)(nil), mock.MatchedBy(func(outbox []models.OutboxMessage) bool {
+		return len(outbox) == 1 && outbox[0].Kind == models.OutboxAccountDeletionEmail &&
+			json.Unmarshal(outbox[0].Payload, &notice) == nil
+	})).Return(nil).Once()
+	mockSession.On("RevokeAllUserSessions", user.ID).Return(nil).Twice()
+	mockToken.On("RevokeAllUserTokens", user.ID).Return(nil).Twice()
+	mockToken.On("RevokeUserAccessTokens", user.ID).Return(nil).Twice()
+
+	purgeAt, err := service.DeleteAccount(user.ID, "TestPass123!", "123456", client)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(72*time.Hour), purgeAt, time.Minute)
+	assert.Equal(t, "user@example.com", notice.To)
+	assert.Equal(t, "es", notice.Locale)
+	assert.True(t, purgeAt.Equal(notice.Until))
+	assert.Equal(t, []string{models.AuditAccountDeletionRequested}, audit.types())
+	mockRepo.AssertExpectations(t)
+	mockSession.AssertExpectations(t)
+	mockToken.AssertExpectations(t)
+}
+
+func TestAuthService_DeleteAccount_KeepsLastAdmin(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+	roles := newStubRoleRepo()
+	admin := roles.addRole(models.RoleAdmin)
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		rbacService:    NewRBACService(roles, nil),
+		config:         &config.Config{BcryptCost: 4, AccountDeletionGrace: 72 * time.Hour},
+		auditRepo:      &stubAuditRepo{},
+		throttle:       &stubThrottle{},
+	}
+
+	hash, _ := utils.HashPassword("TestPass123!", 4)
+	user := &models.User{ID: uuid.New(), Email: "admin@example.com", Password: hash}
+	roles.userRoles[user.ID] = []uuid.UUID{admin.ID}
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+
+	// The only enabled administrator can't schedule their own deletion
+	roles.adminCount = 1
+	_, err := service.DeleteAccount(user.ID, "TestPass123!", "", &models.ClientInfo{})
+	assert.Equal(t, ErrLastAdmin, err)
+	mockRepo.AssertNotCalled(t, "SoftDelete", mock.Anything, mock.Anything, mock.Anything)
+
+	// With another administrator the deletion is written under the guard
+	roles.adminCount = 2
+	mockSession.On("RevokeAllUserSessions", user.ID).Return(nil)
+	mockToken.On("RevokeAllUserTokens", user.ID).Return(nil)
+	mockToken.On("RevokeUserAccessTokens", user.ID).Return(nil)
+	mockRepo.On("SoftDelete", user.ID, &repository.AdminGuard{Roles: []uuid.UUID{admin.ID}}, mock.Anything).
+		Return(repository.ErrNoAdminLeft).Once()
+	_, err = service.DeleteAccount(user.ID, "TestPass123!", "", &models.ClientInfo{})
+	assert.Equal(t, ErrLastAdmin, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Login_RejectsDeletedAccount(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockToken := new(MockTokenSvc)
+	mockSession := new(MockSessionSvc)
+	audit := &stubAuditRepo{}
+	service := &AuthService{
+		userRepo:       mockRepo,
+		tokenService:   mockToken,
+		sessionService: mockSession,
+		logger:         utils.NewLogger(),
+		config:         &config.Config{},
+		auditRepo:      audit,
+	}
+
+	hash, _ := utils.HashPassword("TestPass123!", 4)
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Password: hash, EmailVerified: true}
+	user.DeletedAt = sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+	mockRepo.On("GetByEmail", "user@example.com").Return(user, nil)
+
+	// Signing in no longer cancels the deletion
+	response, err := service.Login(&models.LoginRequest{Email: "user@example.com", Password: "TestPass123!"}, &models.ClientInfo{})
+	assert.Equal(t, ErrAccountDeleted, err)
+	assert.Nil(t, response)
+	assert.Equal(t, []string{models.AuditLoginFailed}, audit.types())
+	mockRepo.AssertNotCalled(t, "Restore", mock.Anything)
+	mockSession.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+
+	// Nor does a reset link sent before it
+	mockRepo.tokens = &stubOneTimeTokenRepo{}
+	service.tokenRepo = mockRepo.tokens
+	assert.NoError(t, service.sendPasswordReset(user, ""))
+	_, reset := mockRepo.tokens.last(t, models.OutboxPasswordResetEmail)
+	mockRepo.On("GetByID", user.ID.String()).Return(user, nil)
+	assert.Equal(t, ErrInvalidToken, service.ResetPassword(reset.Token, "NewPass123!", &models.ClientInfo{}))
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_RestoreAccount(t *testing.T) {
+	mockRepo := new(MockUserRepo)
+	mockTOTP := new(MockTOTPSvc)
+	audit := &stubAuditRepo{}
+	service := &AuthService{
+		userRepo:    mockRepo,
+		totpService: mockTOTP,
+		logger:      utils.NewLogger(),
+		config:      &config.Config{},
+		auditRepo:   audit,
+	}
+
+	hash, _ := utils.HashPassword("TestPass123!", 4)
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Password: hash, TOTPEnabled: true}
+	user.DeletedAt = sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+	mockRepo.On("GetByEmail", "user@example.com").Return(user, nil)
+	mockRepo.On("IncrementFailedLoginAttempts", "user@example.com", mock.Anything).Return(1, sql.NullTime{}, nil)
+	mockTOTP.On("VerifyLoginCode", user, "000000").Return(ErrInvalidTOTPCode)
+	mockTOTP.On("VerifyLoginCode", user, "123456").Return(nil)
+
+	client := &models.ClientInfo{}
+	err := service.RestoreAccount(&models.RestoreAccountRequest{Email: "user@example.com", Password: "WrongPass123!", Code: "123456"}, client)
+	assert.Equal(t, ErrInvalidCredentials, err)
+	err = service.RestoreAccount(&models.RestoreAccountRequest{Email: "user@example.com", Password: "TestPass123!", Code: "000000"}, client)
+	assert.Equal(t, ErrInvalidTOTPCode, err)
+	mockRepo.AssertNotCalled(t, "Restore", mock.Anything)
+
+	mockRepo.On("Restore", user.ID).Return(nil).Once()
+	err = service.RestoreAccount(&models.RestoreAccountRequest{Email: " User@Example.com", Password: "TestPass123!", Code: "123456"}, client)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AuditAccountRestored, audit.types()[len(audit.types())-1])
+	mockRepo.AssertExpectations(t)
+}
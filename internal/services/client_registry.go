@@ -0,0 +1,174 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+)
+
+var ErrInvalidClient = errors.New("unknown client or bad client credentials")
+
+// ClientRegistry manages the applications allowed to obtain tokens from us
+type ClientRegistry struct {
+	clientRepo repository.OAuthClientRepositoryInterface
+}
+
+func NewClientRegistry(clientRepo repository.OAuthClientRepositoryInterface) *ClientRegistry {
+	return &ClientRegistry{
+		clientRepo: clientRepo,
+	}
+}
+
+// defaultGrantTypes are granted to clients registered without explicit grants
+var defaultGrantTypes = []string{"authorization_code", "refresh_token"}
+
+// Register adds an application to the registry. Public clients get no
+// secret; for confidential ones the plaintext secret is returned once.
+// Machine clients use the client_credentials grant and are limited to the
+// scopes they are registered with.
+func (r *ClientRegistry) Register(reg models.OAuthClientRegistration) (*models.OAuthClient, string, error) {
+	grantTypes := reg.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = defaultGrantTypes
+	}
+
+	client := &models.OAuthClient{
+		Name:         reg.Name,
+		RedirectURIs: reg.RedirectURIs,
+		Scopes:       reg.Scopes,
+		GrantTypes:   grantTypes,
+	}
+	if client.RedirectURIs == nil {
+		client.RedirectURIs = []string{}
+	}
+	if client.Scopes == nil {
+		client.Scopes = []string{}
+	}
+
+	for _, grant := range grantTypes {
+		switch grant {
+		case "authorization_code", "refresh_token":
+		case "client_credentials":
+			// A public client cannot prove who it is, so it cannot act as itself
+			if reg.Public {
+				return nil, "", errors.New("public clients cannot use the client_credentials grant")
+			}
+		default:
+			return nil, "", fmt.Errorf("unsupported grant type %q", grant)
+		}
+	}
+
+	if client.AllowsGrant("authorization_code") && len(client.RedirectURIs) == 0 {
+		return nil, "", errors.New("authorization_code clients need at least one redirect URI")
+	}
+	for _, uri := range client.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return nil, "", err
+		}
+	}
+	for _, scope := range client.Scopes {
+		if err := validateScopeToken(scope); err != nil {
+			return nil, "", err
+		}
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, "", err
+	}
+
+	client.ClientID = hex.EncodeToString(idBytes)
+
+	secret := ""
+	if !reg.Public {
+		secretBytes := make([]byte, 32)
+		if _, err := rand.Read(secretBytes); err != nil {
+			return nil, "", err
+		}
+		secret = base64.RawURLEncoding.EncodeToString(secretBytes)
+		client.ClientSecretHash = hashOAuthSecret(secret)
+	}
+
+	if err := r.clientRepo.Create(client); err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+// Get returns an active client by its public ID
+func (r *ClientRegistry) Get(clientID string) (*models.OAuthClient, error) {
+	client, err := r.clientRepo.GetByClientID(clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidClient
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// Authenticate checks the client secret; public clients must not send one
+func (r *ClientRegistry) Authenticate(clientID, secret string) (*models.OAuthClient, error) {
+	client, err := r.Get(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.IsPublic() {
+		if secret != "" {
+			return nil, ErrInvalidClient
+		}
+		return client, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashOAuthSecret(secret)), []byte(client.ClientSecretHash)) != 1 {
+		return nil, ErrInvalidClient
+	}
+	return client, nil
+}
+
+// validateRedirectURI enforces absolute https URIs without fragments;
+// plain http is only accepted for loopback development clients
+func validateRedirectURI(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+		return fmt.Errorf("invalid redirect URI %q", raw)
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if host := u.Hostname(); host == "localhost" || host == "127.0.0.1" || host == "::1" {
+			return nil
+		}
+	}
+	return fmt.Errorf("redirect URI %q must use https", raw)
+}
+
+// validateScopeToken applies the RFC 6749 section 3.3 scope-token grammar
+func validateScopeToken(scope string) error {
+	if scope == "" {
+		return errors.New("empty scope")
+	}
+	for _, r := range scope {
+		if r < 0x21 || r > 0x7e || r == '"' || r == '\\' {
+			return fmt.Errorf("invalid scope %q", scope)
+		}
+	}
+	return nil
+}
+
+func hashOAuthSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,56 +2,131 @@ package services
 
 import (
 	"fmt"
+	"net/url"
+	"time"
 
 	"github.com/Flack74/go-auth-system/internal/config"
-	"gopkg.in/gomail.v2"
+	"github.com/Flack74/go-auth-system/internal/models"
 )
 
+// emailTimeFormat is how times appear in email bodies
+const emailTimeFormat = "2 Jan 2006 15:04 MST"
+
 type EmailService struct {
-	config *config.Config
+	config    *config.Config
+	transport EmailTransport
+	templates *EmailTemplates
+}
+
+// emailData is what the email templates can refer to
+type emailData struct {
+	Email       string
+	NewEmail    string
+	Link        string // the action the email asks for
+	UserAgent   string
+	IPAddress   string
+	Time        string
+	LockedUntil string
+	PurgeAt     string
+}
+
+func NewEmailService(config *config.Config, transport EmailTransport, templates *EmailTemplates) *EmailService {
+	return &EmailService{
+		config:    config,
+		transport: transport,
+		templates: templates,
+	}
+}
+
+func (s *EmailService) SendVerificationEmail(email, token, locale string) error {
+	return s.send(email, EmailTemplateVerification, locale, emailData{
+		Email: email,
+		Link:  fmt.Sprintf("%s/auth/verify?token=%s", s.config.BaseURL, url.QueryEscape(token)),
+	})
 }
 
-func NewEmailService(config *config.Config) *EmailService {
-	return &EmailService{config: config}
+func (s *EmailService) SendPasswordResetEmail(email, token, locale string) error {
+	return s.send(email, EmailTemplatePasswordReset, locale, emailData{
+		Email: email,
+		Link:  fmt.Sprintf("%s/auth/password/reset?token=%s", s.config.BaseURL, url.QueryEscape(token)),
+	})
 }
 
-func (s *EmailService) SendVerificationEmail(email, token string) error {
-	subject := "Verify Your Email Address"
-	verifyURL := fmt.Sprintf("%s/auth/verify?token=%s", s.config.BaseURL, token)
-	
-	body := fmt.Sprintf(`
-		<h2>Email Verification</h2>
-		<p>Please click the link below to verify your email address:</p>
-		<a href="%s">Verify Email</a>
-		<p>If you didn't create an account, please ignore this email.</p>
-	`, verifyURL)
+// SendEmailChangeEmail asks the owner of a new address to confirm it
+func (s *EmailService) SendEmailChangeEmail(email, token, locale string) error {
+	return s.send(email, EmailTemplateEmailChange, locale, emailData{
+		Email: email,
+		Link:  fmt.Sprintf("%s/auth/email/confirm?token=%s", s.config.BaseURL, url.QueryEscape(token)),
+	})
+}
 
-	return s.sendEmail(email, subject, body)
+// SendEmailChangeNoticeEmail warns the current address that the account is
+// moving to newEmail
+func (s *EmailService) SendEmailChangeNoticeEmail(email, newEmail, locale string) error {
+	return s.send(email, EmailTemplateEmailChangeNotice, locale, emailData{
+		Email:    email,
+		NewEmail: newEmail,
+		Link:     s.config.FrontendURL + "/forgot-password",
+	})
 }
 
-func (s *EmailService) SendPasswordResetEmail(email, token string) error {
-	subject := "Password Reset Request"
-	resetURL := fmt.Sprintf("%s/auth/password/reset?token=%s", s.config.BaseURL, token)
-	
-	body := fmt.Sprintf(`
-		<h2>Password Reset</h2>
-		<p>You requested a password reset. Click the link below to reset your password:</p>
-		<a href="%s">Reset Password</a>
-		<p>This link will expire in 1 hour.</p>
-		<p>If you didn't request this, please ignore this email.</p>
-	`, resetURL)
+// SendPasswordChangedEmail tells the user their password was changed and
+// their other devices signed out
+func (s *EmailService) SendPasswordChangedEmail(email, locale string, client *models.ClientInfo, at time.Time) error {
+	return s.send(email, EmailTemplatePasswordChanged, locale, emailData{
+		Email:     email,
+		Link:      s.config.FrontendURL + "/forgot-password",
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+		Time:      at.UTC().Format(emailTimeFormat),
+	})
+}
 
-	return s.sendEmail(email, subject, body)
+// SendNewDeviceEmail tells the user about a sign-in from an unfamiliar device
+func (s *EmailService) SendNewDeviceEmail(email, locale string, client *models.ClientInfo, at time.Time) error {
+	return s.send(email, EmailTemplateNewDevice, locale, emailData{
+		Email:     email,
+		Link:      s.config.FrontendURL + "/forgot-password",
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+		Time:      at.UTC().Format(emailTimeFormat),
+	})
 }
 
-func (s *EmailService) sendEmail(to, subject, body string) error {
-	m := gomail.NewMessage()
-	m.SetHeader("From", s.config.EmailFrom)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/html", body)
+// SendAccountDeletionEmail confirms the account will be deleted at purgeAt
+// unless the user restores it before then
+func (s *EmailService) SendAccountDeletionEmail(email, locale string, purgeAt time.Time) error {
+	return s.send(email, EmailTemplateAccountDeletion, locale, emailData{
+		Email:   email,
+		Link:    s.config.FrontendURL + "/account/restore",
+		PurgeAt: purgeAt.UTC().Format(emailTimeFormat),
+	})
+}
 
-	d := gomail.NewDialer(s.config.SMTPHost, s.config.SMTPPort, s.config.SMTPUser, s.config.SMTPPassword)
+// SendLockoutEmail tells the user their account was locked after failed sign-ins
+func (s *EmailService) SendLockoutEmail(email, locale string, until time.Time) error {
+	return s.send(email, EmailTemplateLockout, locale, emailData{
+		Email:       email,
+		Link:        s.config.FrontendURL + "/forgot-password",
+		LockedUntil: until.UTC().Format(emailTimeFormat),
+	})
+}
 
-	return d.DialAndSend(m)
-}
\ No newline at end of file
+func (s *EmailService) send(to, template, locale string, data emailData) error {
+	if locale == "" {
+		locale = s.config.EmailDefaultLocale
+	}
+
+	rendered, err := s.templates.Render(template, locale, data)
+	if err != nil {
+		return err
+	}
+
+	return s.transport.Send(&EmailMessage{
+		From:    s.config.EmailFrom,
+		To:      to,
+		Subject: rendered.Subject,
+		Text:    rendered.Text,
+		HTML:    rendered.HTML,
+	})
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"mime/quotedprintable"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/models"
+)
+
+func TestEmailTemplates_LocaleFallback(t *testing.T) {
+	templates, err := NewEmailTemplates("", "en")
+	if err != nil {
+		t.Fatalf("load templates: %v", err)
+	}
+
+	cases := map[string]string{
+		"":         "Verify Your Email Address",
+		"en-US":    "Verify Your Email Address",
+		"es":       "Verifica tu dirección de correo",
+		"es-MX":    "Verifica tu dirección de correo",
+		"pt-BR":    "Verify Your Email Address",
+		"../../..": "Verify Your Email Address",
+	}
+	for locale, subject := range cases {
+		rendered, err := templates.Render(EmailTemplateVerification, locale, emailData{Link: "https://example.com/verify"})
+		if err != nil {
+			t.Fatalf("render %q: %v", locale, err)
+		}
+		if rendered.Subject != subject {
+			t.Errorf("locale %q: expected subject %q, got %q", locale, subject, rendered.Subject)
+		}
+		if !strings.Contains(rendered.Text, "https://example.com/verify") || !strings.Contains(rendered.HTML, `href="https://example.com/verify"`) {
+			t.Errorf("locale %q: link missing from %+v", locale, rendered)
+		}
+	}
+}
+
+func TestEmailTemplates_Overrides(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "es"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Only the Spanish HTML body is overridden; everything else is built in
+	override := `<p>{{.UserAgent}}</p>`
+	if err := os.WriteFile(filepath.Join(dir, "es", "new_device.html.tmpl"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := NewEmailTemplates(dir, "en")
+	if err != nil {
+		t.Fatalf("load templates: %v", err)
+	}
+
+	data := emailData{UserAgent: "<script>alert(1)</script>"}
+	rendered, err := templates.Render(EmailTemplateNewDevice, "es", data)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if rendered.HTML != "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>" {
+		t.Errorf("expected the escaped override, got %q", rendered.HTML)
+	}
+	if rendered.Subject != "Nuevo inicio de sesión en tu cuenta" {
+		t.Errorf("expected the built-in Spanish subject, got %q", rendered.Subject)
+	}
+
+	rendered, err = templates.Render(EmailTemplateNewDevice, "en", data)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if strings.HasPrefix(rendered.HTML, "<p>") {
+		t.Errorf("the override should only apply to Spanish, got %q", rendered.HTML)
+	}
+}
+
+func TestEmailTemplates_RequireSubject(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lockout.txt.tmpl"), []byte("Locked until {{.LockedUntil}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := NewEmailTemplates(dir, "en")
+	if err != nil {
+		t.Fatalf("load templates: %v", err)
+	}
+	if _, err := templates.Render(EmailTemplateLockout, "en", emailData{}); err == nil {
+		t.Fatal("expected an error for a template without a subject")
+	}
+}
+
+func TestEmailService_FileTransport(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		EmailTransport:     "file",
+		EmailCaptureDir:    dir,
+		EmailDefaultLocale: "en",
+		EmailFrom:          "noreply@example.com",
+		FrontendURL:        "https://app.example.com",
+	}
+	transport, err := NewEmailTransport(cfg)
+	if err != nil {
+		t.Fatalf("transport: %v", err)
+	}
+	templates, err := NewEmailTemplates("", cfg.EmailDefaultLocale)
+	if err != nil {
+		t.Fatalf("load templates: %v", err)
+	}
+	service := NewEmailService(cfg, transport, templates)
+
+	until := time.Date(2030, 1, 2, 15, 4, 0, 0, time.UTC)
+	if err := service.SendLockoutEmail("user@example.com", "", until); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	files, err := os.ReadDir(filepath.Join(dir, "new"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one captured message, got %v (%v)", files, err)
+	}
+	if leftovers, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(leftovers) != 0 {
+		t.Errorf("expected tmp/ to be empty, got %v", leftovers)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, "new", files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The bodies are quoted-printable, which wraps long lines
+	content, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"To: user@example.com",
+		"Subject: Your Account Has Been Locked",
+		"multipart/alternative",
+		"text/plain",
+		"text/html",
+		"2 Jan 2030 15:04 UTC",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("captured message is missing %q", want)
+		}
+	}
+}
+
+func TestEmailService_NewDeviceEmail(t *testing.T) {
+	captured := &captureTransport{}
+	templates, err := NewEmailTemplates("", "en")
+	if err != nil {
+		t.Fatalf("load templates: %v", err)
+	}
+	service := NewEmailService(&config.Config{FrontendURL: "https://app.example.com"}, captured, templates)
+
+	client := &models.ClientInfo{UserAgent: "Firefox", IPAddress: "203.0.113.7"}
+	if err := service.SendNewDeviceEmail("user@example.com", "es-AR", client, time.Now()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	message := captured.messages[0]
+	if message.Subject != "Nuevo inicio de sesión en tu cuenta" {
+		t.Errorf("expected the Spanish template, got %q", message.Subject)
+	}
+	if !strings.Contains(message.Text, "Firefox") || !strings.Contains(message.Text, "203.0.113.7") {
+		t.Errorf("device details missing from %q", message.Text)
+	}
+	if !strings.Contains(message.HTML, "https://app.example.com/forgot-password") {
+		t.Errorf("reset link missing from %q", message.HTML)
+	}
+}
+
+// captureTransport keeps sent messages in memory
+type captureTransport struct {
+	messages []*EmailMessage
+}
+
+func (t *captureTransport) Send(message *EmailMessage) error {
+	t.messages = append(t.messages, message)
+	return nil
+}
+
+func TestLogTransport_RedactsLinksAndTokens(t *testing.T) {
+	token := "Zq3xV9pL0mKc7wYtR2bN5sHd8fGj4aUe"
+	body := "Verify your email: https://app.example.com/verify?token=" + token + "\n" +
+		"Or enter this token: " + token + "\nThanks, the team"
+
+	redacted := redactEmailBody(body)
+	if strings.Contains(redacted, token) || strings.Contains(redacted, "https://") {
+		t.Fatalf("link or token left in logged body: %q", redacted)
+	}
+	if !strings.Contains(redacted, "Verify your email: [link redacted]") || !strings.Contains(redacted, "Thanks, the team") {
+		t.Fatalf("unexpected redaction: %q", redacted)
+	}
+}
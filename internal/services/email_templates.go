@@ -0,0 +1,203 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+
+	"golang.org/x/text/language"
+)
+
+// Email template names. Each has a <name>.txt.tmpl plain text body, which
+// also defines the "subject" template, and a <name>.html.tmpl HTML body.
+const (
+	EmailTemplateVerification      = "verification"
+	EmailTemplatePasswordReset     = "password_reset"
+	EmailTemplatePasswordChanged   = "password_changed"
+	EmailTemplateEmailChange       = "email_change"
+	EmailTemplateEmailChangeNotice = "email_change_notice"
+	EmailTemplateNewDevice         = "new_device"
+	EmailTemplateLockout           = "lockout"
+	EmailTemplateAccountDeletion   = "account_deletion"
+)
+
+//go:embed templates/email
+var defaultEmailTemplates embed.FS
+
+var errMissingSubject = errors.New(`email template does not define "subject"`)
+
+// EmailTemplates renders the email bodies. Templates in the override
+// directory win over the built-in ones, and a locale subdirectory such as
+// es/ or pt-BR/ wins over the default language. A missing file falls back,
+// in order, to the region's language, the default locale and the unlocalized
+// template.
+type EmailTemplates struct {
+	sources       []fs.FS
+	defaultLocale string
+
+	mu    sync.Mutex
+	cache map[string]*emailTemplate
+}
+
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// RenderedEmail holds the parts of a rendered template
+type RenderedEmail struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// NewEmailTemplates loads overrides from dir, if set, on top of the built-in templates
+func NewEmailTemplates(dir, defaultLocale string) (*EmailTemplates, error) {
+	builtin, err := fs.Sub(defaultEmailTemplates, "templates/email")
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []fs.FS{builtin}
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("email template path %s is not a directory", dir)
+		}
+		sources = []fs.FS{os.DirFS(dir), builtin}
+	}
+
+	return &EmailTemplates{
+		sources:       sources,
+		defaultLocale: canonicalLocale(defaultLocale),
+		cache:         map[string]*emailTemplate{},
+	}, nil
+}
+
+// canonicalLocale returns the BCP 47 form of locale, or "" when it isn't a
+// valid tag. Only canonical tags are used as directory names.
+func canonicalLocale(locale string) string {
+	tag, err := language.Parse(strings.TrimSpace(locale))
+	if err != nil || tag == language.Und {
+		return ""
+	}
+	return tag.String()
+}
+
+// localeChain lists the directories to try for locale, most specific first
+func (t *EmailTemplates) localeChain(locale string) []string {
+	var chain []string
+	add := func(dir string) {
+		for _, seen := range chain {
+			if seen == dir {
+				return
+			}
+		}
+		chain = append(chain, dir)
+	}
+
+	for _, l := range []string{canonicalLocale(locale), t.defaultLocale} {
+		if l == "" {
+			continue
+		}
+		add(l)
+		if base, _ := language.Make(l).Base(); base.String() != l {
+			add(base.String())
+		}
+	}
+	add(".")
+	return chain
+}
+
+// find reads the first file called name along the locale chain
+func (t *EmailTemplates) find(chain []string, name string) (string, string, error) {
+	for _, dir := range chain {
+		path := name
+		if dir != "." {
+			path = dir + "/" + name
+		}
+		for _, source := range t.sources {
+			content, err := fs.ReadFile(source, path)
+			if err == nil {
+				return path, string(content), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", "", err
+			}
+		}
+	}
+	return "", "", fmt.Errorf("email template %s not found", name)
+}
+
+func (t *EmailTemplates) load(name, locale string) (*emailTemplate, error) {
+	key := name + "|" + canonicalLocale(locale)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tmpl, ok := t.cache[key]; ok {
+		return tmpl, nil
+	}
+
+	chain := t.localeChain(locale)
+
+	textPath, textSource, err := t.find(chain, name+".txt.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.New(textPath).Parse(textSource)
+	if err != nil {
+		return nil, err
+	}
+	if text.Lookup("subject") == nil {
+		return nil, fmt.Errorf("%s: %w", textPath, errMissingSubject)
+	}
+
+	htmlPath, htmlSource, err := t.find(chain, name+".html.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.New(htmlPath).Parse(htmlSource)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &emailTemplate{text: text, html: html}
+	t.cache[key] = tmpl
+	return tmpl, nil
+}
+
+// Render executes the named template in the closest available locale
+func (t *EmailTemplates) Render(name, locale string, data interface{}) (*RenderedEmail, error) {
+	tmpl, err := t.load(name, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	return &RenderedEmail{
+		// A subject is a single header line
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"gopkg.in/gomail.v2"
+)
+
+// EmailMessage is a rendered email ready to hand to a transport
+type EmailMessage struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// EmailTransport delivers rendered emails
+type EmailTransport interface {
+	Send(message *EmailMessage) error
+}
+
+// NewEmailTransport returns the transport selected by EMAIL_TRANSPORT
+func NewEmailTransport(cfg *config.Config) (EmailTransport, error) {
+	switch cfg.EmailTransport {
+	case "", "smtp":
+		return NewSMTPTransport(cfg), nil
+	case "file":
+		return NewFileTransport(cfg.EmailCaptureDir)
+	case "log":
+		return NewLogTransport(), nil
+	default:
+		return nil, fmt.Errorf("unknown email transport %q", cfg.EmailTransport)
+	}
+}
+
+// mimeMessage builds a multipart/alternative message with the plain text
+// part first, so clients that understand HTML prefer it
+func mimeMessage(message *EmailMessage) *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetHeader("From", message.From)
+	m.SetHeader("To", message.To)
+	m.SetHeader("Subject", message.Subject)
+	m.SetBody("text/plain", message.Text)
+	if message.HTML != "" {
+		m.AddAlternative("text/html", message.HTML)
+	}
+	return m
+}
+
+// SMTPTransport sends through the configured mail server
+type SMTPTransport struct {
+	dialer *gomail.Dialer
+}
+
+func NewSMTPTransport(cfg *config.Config) *SMTPTransport {
+	return &SMTPTransport{
+		dialer: gomail.NewDialer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword),
+	}
+}
+
+func (t *SMTPTransport) Send(message *EmailMessage) error {
+	return t.dialer.DialAndSend(mimeMessage(message))
+}
+
+// FileTransport captures emails as .eml files in a maildir instead of
+// sending them, for development and tests. Messages are written to tmp/
+// and renamed into new/, so a reader never sees a partial file.
+type FileTransport struct {
+	dir string
+}
+
+func NewFileTransport(dir string) (*FileTransport, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &FileTransport{dir: dir}, nil
+}
+
+func (t *FileTransport) Send(message *EmailMessage) error {
+	unique := make([]byte, 8)
+	if _, err := rand.Read(unique); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d.%s.eml", time.Now().UnixNano(), hex.EncodeToString(unique))
+
+	tmpPath := filepath.Join(t.dir, "tmp", name)
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := mimeMessage(message).WriteTo(file); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(t.dir, "new", name))
+}
+
+// Links and token-like strings in a logged body, which would let anyone who
+// reads the log act on the recipient's behalf
+var (
+	logRedactLink  = regexp.MustCompile(`https?://\S+`)
+	logRedactToken = regexp.MustCompile(`[A-Za-z0-9_\-]{24,}`)
+)
+
+// LogTransport writes emails to the application log, with links and tokens
+// redacted. Nothing is delivered, so it is only meant for local development.
+type LogTransport struct {
+	logger *utils.Logger
+}
+
+func NewLogTransport() *LogTransport {
+	return &LogTransport{logger: utils.NewLogger()}
+}
+
+func (t *LogTransport) Send(message *EmailMessage) error {
+	t.logger.WithField("to", message.To).
+		WithField("subject", message.Subject).
+		WithField("body", redactEmailBody(message.Text)).
+		Info("Email (log transport)")
+	return nil
+}
+
+func redactEmailBody(body string) string {
+	body = logRedactLink.ReplaceAllString(body, "[link redacted]")
+	return logRedactToken.ReplaceAllString(body, "[redacted]")
+}
@@ -1,17 +1,60 @@
 package services
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+)
 
 type TokenServiceInterface interface {
-	GenerateAccessToken(userID uuid.UUID) (string, error)
-	GenerateRefreshToken(userID uuid.UUID) (string, error)
-	ValidateAccessToken(token string) (uuid.UUID, error)
-	ValidateRefreshToken(token string) (uuid.UUID, error)
+	GenerateAccessToken(userID, sessionID uuid.UUID) (string, error)
+	GenerateRefreshToken(userID, sessionID uuid.UUID) (string, error)
+	GenerateDelegatedAccessToken(userID, sessionID uuid.UUID, grant OAuthGrant) (string, error)
+	GenerateDelegatedRefreshToken(userID, sessionID uuid.UUID, grant OAuthGrant) (string, error)
+	ValidateAccessToken(token string) (*TokenClaims, error)
+	ValidateRefreshToken(token string) (*TokenClaims, error)
+	RotateRefreshToken(token, clientID string) (*TokenClaims, string, error)
 	RevokeAccessToken(token string) error
 	RevokeRefreshToken(token string) error
+	RevokeSessionTokens(sessionID uuid.UUID) error
+	RevokeAllUserTokens(userID uuid.UUID) error
+	RevokeUserAccessTokens(userID uuid.UUID) error
+	CurrentTokenVersion(userID uuid.UUID) (int, error)
+	TokenVersion(userID uuid.UUID) (int, error)
+	GenerateMFAChallenge(userID uuid.UUID) (string, error)
+	ValidateMFAChallenge(challenge string) (uuid.UUID, error)
+	FailMFAChallenge(challenge string) error
+	ConsumeMFAChallenge(challenge string) error
 }
 
 type EmailServiceInterface interface {
-	SendVerificationEmail(email, token string) error
-	SendPasswordResetEmail(email, token string) error
-}
\ No newline at end of file
+	SendVerificationEmail(email, token, locale string) error
+	SendPasswordResetEmail(email, token, locale string) error
+	SendEmailChangeEmail(email, token, locale string) error
+	SendEmailChangeNoticeEmail(email, newEmail, locale string) error
+	SendPasswordChangedEmail(email, locale string, client *models.ClientInfo, at time.Time) error
+	SendNewDeviceEmail(email, locale string, client *models.ClientInfo, at time.Time) error
+	SendLockoutEmail(email, locale string, until time.Time) error
+	SendAccountDeletionEmail(email, locale string, purgeAt time.Time) error
+}
+
+type TOTPServiceInterface interface {
+	VerifyLoginCode(user *models.User, code string) error
+}
+
+type SessionServiceInterface interface {
+	CreateSession(userID uuid.UUID, client *models.ClientInfo) (*models.Session, string, error)
+	IsKnownDevice(userID uuid.UUID, client *models.ClientInfo) (bool, error)
+	TouchSession(sessionID uuid.UUID) error
+	RevokeSession(sessionID uuid.UUID) error
+	RevokeAllUserSessions(userID uuid.UUID) error
+}
+
+type ThrottleInterface interface {
+	Allow(key string, limit int, window time.Duration) (bool, error)
+}
+
+type WebhookPublisherInterface interface {
+	Publish(eventType string, data map[string]interface{}) error
+}
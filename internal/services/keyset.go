@@ -0,0 +1,345 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnsupportedSigningAlg = errors.New("unsupported JWT signing algorithm")
+	ErrUnknownSigningKey     = errors.New("unknown signing key")
+	ErrSymmetricKeySet       = errors.New("ID tokens require an asymmetric signing key")
+)
+
+// rsaKeyBits is the modulus size for generated RS256 keys
+const rsaKeyBits = 2048
+
+// SigningKey is one entry of the keyset, identified in tokens by its kid
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+}
+
+// KeySet signs tokens with the active key and verifies them against every
+// key still on disk, so a rotated-out key keeps verifying until it is deleted.
+// In HS256 mode it wraps the shared secret and publishes no keys.
+type KeySet struct {
+	mu     sync.RWMutex
+	dir    string
+	method jwt.SigningMethod
+	secret []byte
+	keys   map[string]*SigningKey
+	active *SigningKey
+}
+
+// JWK is a public key in RFC 7517 form
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the body served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeySet builds the keyset selected by JWT_SIGNING_ALG
+func NewKeySet(cfg *config.Config) (*KeySet, error) {
+	method, err := signingMethod(cfg.JWTSigningAlg)
+	if err != nil {
+		return nil, err
+	}
+	if method == jwt.SigningMethodHS256 {
+		return NewHMACKeySet(cfg.JWTSecret), nil
+	}
+	return LoadKeySet(cfg.JWTKeyDir, cfg.JWTSigningAlg)
+}
+
+// NewHMACKeySet returns a keyset for the legacy shared-secret mode
+func NewHMACKeySet(secret string) *KeySet {
+	return &KeySet{
+		method: jwt.SigningMethodHS256,
+		secret: []byte(secret),
+		keys:   map[string]*SigningKey{},
+	}
+}
+
+// LoadKeySet reads every PKCS#8 PEM key in dir. The newest key (by kid)
+// using alg signs new tokens; one is generated if none exists yet.
+func LoadKeySet(dir, alg string) (*KeySet, error) {
+	method, err := signingMethod(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{dir: dir, method: method, keys: map[string]*SigningKey{}}
+	if err := ks.load(); err != nil {
+		return nil, err
+	}
+
+	if ks.active == nil {
+		if _, err := ks.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) load() error {
+	files, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		key, err := readSigningKey(file)
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", file, err)
+		}
+		ks.keys[key.ID] = key
+
+		// kids sort by creation time, so the last match is the newest
+		if key.Method.Alg() == ks.method.Alg() {
+			ks.active = key
+		}
+	}
+	return nil
+}
+
+// Rotate generates a new key, writes it to the key directory and makes it
+// the signing key. Previously issued tokens stay valid under their old kid.
+func (ks *KeySet) Rotate() (*SigningKey, error) {
+	if ks.secret != nil {
+		return nil, errors.New("HS256 keysets cannot be rotated")
+	}
+
+	private, err := generatePrivateKey(ks.method.Alg())
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	kid := fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405.000000Z"), hex.EncodeToString(suffix))
+
+	block := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(ks.dir, kid+".pem"), block, 0600); err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: kid, Method: ks.method, Private: private}
+
+	ks.mu.Lock()
+	ks.keys[kid] = key
+	ks.active = key
+	ks.mu.Unlock()
+
+	return key, nil
+}
+
+// Sign serialises claims with the active key, stamping its kid in the header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.secret != nil {
+		return jwt.NewWithClaims(ks.method, claims).SignedString(ks.secret)
+	}
+
+	ks.mu.RLock()
+	key := ks.active
+	ks.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc resolves the verification key for a parsed token. The token's alg
+// must match the key it names, which rules out algorithm confusion.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if ks.secret != nil {
+		if token.Method.Alg() != ks.method.Alg() {
+			return nil, ErrUnsupportedSigningAlg
+		}
+		return ks.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	ks.mu.RLock()
+	key, ok := ks.keys[kid]
+	ks.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrUnknownSigningKey
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrUnsupportedSigningAlg
+	}
+	return key.Private.Public(), nil
+}
+
+// Asymmetric reports whether tokens can be verified with published public
+// keys, rather than only with the shared secret
+func (ks *KeySet) Asymmetric() bool {
+	return ks.secret == nil
+}
+
+// Alg is the algorithm new tokens are signed with
+func (ks *KeySet) Alg() string {
+	return ks.method.Alg()
+}
+
+// ValidMethods lists the algorithms this keyset will accept
+func (ks *KeySet) ValidMethods() []string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	seen := map[string]bool{ks.method.Alg(): true}
+	methods := []string{ks.method.Alg()}
+	for _, key := range ks.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWKS returns the public half of every verification key
+func (ks *KeySet) JWKS() JWKSet {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, kid := range kids {
+		key := ks.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+
+		switch pub := key.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	switch strings.ToUpper(alg) {
+	case "HS256":
+		return jwt.SigningMethodHS256, nil
+	case "RS256":
+		return jwt.SigningMethodRS256, nil
+	case "ES256":
+		return jwt.SigningMethodES256, nil
+	case "EDDSA":
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, ErrUnsupportedSigningAlg
+}
+
+func generatePrivateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case "RS256":
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case "ES256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	}
+	return nil, ErrUnsupportedSigningAlg
+}
+
+// readSigningKey parses a PKCS#8 key file; the kid is the file name
+func readSigningKey(file string) (*SigningKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, Private: private}, nil
+	case *ecdsa.PrivateKey:
+		if private.Curve != elliptic.P256() {
+			return nil, ErrUnsupportedSigningAlg
+		}
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodES256, Private: private}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, Private: private}, nil
+	}
+	return nil, ErrUnsupportedSigningAlg
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func testClaims() *TokenClaims {
+	return &TokenClaims{
+		UserID: uuid.New(),
+		Type:   "access",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+func parseWith(ks *KeySet, tokenString string) error {
+	_, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, ks.Keyfunc, jwt.WithValidMethods(ks.ValidMethods()))
+	return err
+}
+
+func TestKeySet_SignAndVerify(t *testing.T) {
+	for _, alg := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			ks, err := LoadKeySet(t.TempDir(), alg)
+			if err != nil {
+				t.Fatalf("LoadKeySet: %v", err)
+			}
+
+			tokenString, err := ks.Sign(testClaims())
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			if err := parseWith(ks, tokenString); err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+
+			jwks := ks.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].Alg != alg {
+				t.Fatalf("unexpected JWKS: %+v", jwks)
+			}
+		})
+	}
+}
+
+func TestKeySet_RotationKeepsOldKeys(t *testing.T) {
+	dir := t.TempDir()
+	ks, err := LoadKeySet(dir, "ES256")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+
+	oldToken, _ := ks.Sign(testClaims())
+	if _, err := ks.Rotate(); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	// A fresh load sees both keys, as a restarted instance would
+	reloaded, err := LoadKeySet(dir, "ES256")
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if err := parseWith(reloaded, oldToken); err != nil {
+		t.Fatalf("token signed before rotation rejected: %v", err)
+	}
+	if len(reloaded.JWKS().Keys) != 2 {
+		t.Fatalf("expected 2 published keys, got %d", len(reloaded.JWKS().Keys))
+	}
+}
+
+func TestKeySet_RejectsForeignTokens(t *testing.T) {
+	ks, err := LoadKeySet(t.TempDir(), "RS256")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+
+	// An HS256 token must never verify against an asymmetric keyset
+	hmacToken, _ := NewHMACKeySet("a-secret-that-is-long-enough-for-hs256").Sign(testClaims())
+	if err := parseWith(ks, hmacToken); err == nil {
+		t.Fatal("expected HS256 token to be rejected")
+	}
+
+	other, _ := LoadKeySet(t.TempDir(), "RS256")
+	foreign, _ := other.Sign(testClaims())
+	if err := parseWith(ks, foreign); err == nil {
+		t.Fatal("expected token from unknown key to be rejected")
+	}
+}
+
+func TestKeySet_HMACPublishesNothing(t *testing.T) {
+	ks := NewHMACKeySet("a-secret-that-is-long-enough-for-hs256")
+
+	tokenString, err := ks.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := parseWith(ks, tokenString); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(ks.JWKS().Keys) != 0 {
+		t.Fatal("HS256 keyset must not publish keys")
+	}
+}
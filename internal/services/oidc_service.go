@@ -0,0 +1,376 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// authorizationCodeTTL bounds how long a code may wait to be exchanged
+const authorizationCodeTTL = time.Minute
+
+var (
+	ErrInvalidRedirectURI      = errors.New("redirect_uri is not registered for this client")
+	ErrInvalidGrant            = errors.New("authorization grant is invalid, expired or already used")
+	ErrUnsupportedGrantType    = errors.New("unsupported grant_type")
+	ErrUnsupportedResponseType = errors.New("only response_type=code is supported")
+	ErrInvalidScope            = errors.New("requested scope is not supported")
+	ErrPKCERequired            = errors.New("PKCE with code_challenge_method=S256 is required")
+	ErrUnauthorizedClient      = errors.New("client is not authorized to use this grant type")
+)
+
+// supportedScopes are the scopes clients may request
+var supportedScopes = map[string]bool{
+	"openid": true,
+	"email":  true,
+}
+
+type OIDCService struct {
+	clients      *ClientRegistry
+	userRepo     repository.UserRepositoryInterface
+	authService  *AuthService
+	tokenService *TokenService
+	redisClient  *redis.Client
+	config       *config.Config
+}
+
+// authorizationCode is the Redis value behind an issued code
+type authorizationCode struct {
+	ClientID      string    `json:"client_id"`
+	RedirectURI   string    `json:"redirect_uri"`
+	UserID        uuid.UUID `json:"user_id"`
+	Scope         string    `json:"scope"`
+	Nonce         string    `json:"nonce,omitempty"`
+	CodeChallenge string    `json:"code_challenge"`
+	AuthTime      time.Time `json:"auth_time"`
+}
+
+func NewOIDCService(clients *ClientRegistry, userRepo repository.UserRepositoryInterface, authService *AuthService, tokenService *TokenService, redisClient *redis.Client, config *config.Config) *OIDCService {
+	return &OIDCService{
+		clients:      clients,
+		userRepo:     userRepo,
+		authService:  authService,
+		tokenService: tokenService,
+		redisClient:  redisClient,
+		config:       config,
+	}
+}
+
+// Discovery builds the /.well-known/openid-configuration document
+func (s *OIDCService) Discovery() models.OpenIDConfiguration {
+	issuer := strings.TrimRight(s.config.OIDCIssuer, "/")
+	return models.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserInfoEndpoint:                  issuer + "/oauth/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{s.tokenService.SigningAlg()},
+		ScopesSupported:                   []string{"openid", "email"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "email", "email_verified"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	}
+}
+
+// ValidateAuthorizeRequest checks the client and redirect URI. Failures here
+// must be shown to the user, never redirected to the unverified URI.
+func (s *OIDCService) ValidateAuthorizeRequest(req *models.AuthorizeRequest) (*models.OAuthClient, error) {
+	client, err := s.clients.Get(req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !redirectURIAllowed(client, req.RedirectURI) {
+		return nil, ErrInvalidRedirectURI
+	}
+	if !client.AllowsGrant("authorization_code") {
+		return nil, ErrUnauthorizedClient
+	}
+
+	return client, nil
+}
+
+// Authorize issues a single-use authorization code for the signed-in user
+func (s *OIDCService) Authorize(client *models.OAuthClient, req *models.AuthorizeRequest, userID uuid.UUID) (string, error) {
+	if req.ResponseType != "code" {
+		return "", ErrUnsupportedResponseType
+	}
+
+	scope, err := normalizeScope(req.Scope)
+	if err != nil {
+		return "", err
+	}
+
+	// Every client, confidential or not, must use PKCE
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return "", ErrPKCERequired
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(bytes)
+
+	entry, err := json.Marshal(authorizationCode{
+		ClientID:      client.ClientID,
+		RedirectURI:   req.RedirectURI,
+		UserID:        userID,
+		Scope:         scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AuthTime:      time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	key := fmt.Sprintf("oauth_code:%s", hashOAuthSecret(code))
+	if err := s.redisClient.Set(ctx, key, entry, authorizationCodeTTL).Err(); err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// Token handles the token endpoint for the supported grants
+func (s *OIDCService) Token(req *models.TokenRequest) (*models.TokenResponse, error) {
+	switch req.GrantType {
+	case "authorization_code", "refresh_token":
+		// User grants belong to the OIDC provider, which may be switched off
+		if !s.config.OIDCEnabled {
+			return nil, ErrUnsupportedGrantType
+		}
+	case "client_credentials":
+	default:
+		return nil, ErrUnsupportedGrantType
+	}
+
+	client, err := s.clients.Authenticate(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrant(req.GrantType) {
+		return nil, ErrUnauthorizedClient
+	}
+
+	switch req.GrantType {
+	case "authorization_code":
+		return s.exchangeCode(client, req)
+	case "refresh_token":
+		return s.refresh(client, req)
+	}
+	return s.clientCredentials(client, req)
+}
+
+// clientCredentials issues a token to the client itself, for service-to-service
+// calls. No refresh token is returned (RFC 6749 section 4.4.3).
+func (s *OIDCService) clientCredentials(client *models.OAuthClient, req *models.TokenRequest) (*models.TokenResponse, error) {
+	// Registration forbids it, but never let a client without a secret act as itself
+	if client.IsPublic() {
+		return nil, ErrUnauthorizedClient
+	}
+
+	scopes, err := grantedClientScopes(client, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.tokenService.GenerateClientToken(client.ClientID, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.config.JWTAccessExpiry.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+func (s *OIDCService) exchangeCode(client *models.OAuthClient, req *models.TokenRequest) (*models.TokenResponse, error) {
+	ctx := context.Background()
+
+	// GETDEL makes the code single use even under concurrent exchanges
+	key := fmt.Sprintf("oauth_code:%s", hashOAuthSecret(req.Code))
+	data, err := s.redisClient.GetDel(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrInvalidGrant
+		}
+		return nil, err
+	}
+
+	var code authorizationCode
+	if err := json.Unmarshal([]byte(data), &code); err != nil {
+		return nil, err
+	}
+
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, ErrInvalidGrant
+	}
+	if !verifyPKCE(req.CodeVerifier, code.CodeChallenge) {
+		return nil, ErrInvalidGrant
+	}
+
+	user, err := s.userRepo.GetByID(code.UserID.String())
+	if err != nil || user.IsDeleted() {
+		return nil, ErrInvalidGrant
+	}
+
+	// Each authorised client gets its own session, listed and revocable like
+	// any device. Its tokens carry the client and consented scope.
+	grant := OAuthGrant{ClientID: client.ClientID, Scope: code.Scope}
+	auth, err := s.authService.IssueClientTokens(user, grant, &models.ClientInfo{
+		DeviceID:  "oauth:" + client.ClientID,
+		UserAgent: client.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.TokenResponse{
+		AccessToken:  auth.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.config.JWTAccessExpiry.Seconds()),
+		RefreshToken: auth.RefreshToken,
+		Scope:        auth.Scope,
+	}
+
+	if hasScope(code.Scope, "openid") {
+		idToken, err := s.tokenService.GenerateIDToken(user, client.ClientID, code.Nonce, code.AuthTime, hasScope(code.Scope, "email"))
+		if err != nil {
+			return nil, err
+		}
+		response.IDToken = idToken
+	}
+
+	return response, nil
+}
+
+// refresh rotates a refresh token issued to this client. Tokens issued to
+// another client, or first-party ones, are refused.
+func (s *OIDCService) refresh(client *models.OAuthClient, req *models.TokenRequest) (*models.TokenResponse, error) {
+	auth, err := s.authService.RefreshClientToken(req.RefreshToken, client.ClientID, &models.ClientInfo{
+		DeviceID:  "oauth:" + client.ClientID,
+		UserAgent: client.Name,
+	})
+	if err != nil {
+		return nil, ErrInvalidGrant
+	}
+
+	return &models.TokenResponse{
+		AccessToken:  auth.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.config.JWTAccessExpiry.Seconds()),
+		RefreshToken: auth.RefreshToken,
+		Scope:        auth.Scope,
+	}, nil
+}
+
+// UserInfo returns the claims the userinfo endpoint exposes
+func (s *OIDCService) UserInfo(userID uuid.UUID) (*models.UserInfo, error) {
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	verified := user.EmailVerified
+	return &models.UserInfo{
+		Subject:       user.ID.String(),
+		Email:         user.Email,
+		EmailVerified: &verified,
+	}, nil
+}
+
+// redirectURIAllowed requires an exact match with a registered URI
+func redirectURIAllowed(client *models.OAuthClient, redirectURI string) bool {
+	for _, registered := range client.RedirectURIs {
+		if registered == redirectURI {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeScope rejects unknown scopes and de-duplicates the rest
+func normalizeScope(scope string) (string, error) {
+	seen := map[string]bool{}
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if !supportedScopes[s] {
+			return "", ErrInvalidScope
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	return strings.Join(scopes, " "), nil
+}
+
+// grantedClientScopes narrows a client_credentials request to the client's
+// registered scopes; an empty request is granted all of them
+func grantedClientScopes(client *models.OAuthClient, requested string) ([]string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return client.Scopes, nil
+	}
+
+	allowed := map[string]bool{}
+	for _, s := range client.Scopes {
+		allowed[s] = true
+	}
+
+	seen := map[string]bool{}
+	var scopes []string
+	for _, s := range strings.Fields(requested) {
+		if !allowed[s] {
+			return nil, ErrInvalidScope
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, nil
+}
+
+func hasScope(scope, want string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyPKCE checks an S256 code_verifier against the stored challenge
+func verifyPKCE(verifier, challenge string) bool {
+	// RFC 7636 section 4.1 length bounds
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+)
+
+type stubClientRepo struct {
+	clients map[string]*models.OAuthClient
+}
+
+func (r *stubClientRepo) Create(client *models.OAuthClient) error {
+	r.clients[client.ClientID] = client
+	return nil
+}
+
+func (r *stubClientRepo) GetByClientID(clientID string) (*models.OAuthClient, error) {
+	client, ok := r.clients[clientID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return client, nil
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	verifier := strings.Repeat("a1-_", 11)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	if !verifyPKCE(verifier, challenge) {
+		t.Fatal("expected matching verifier to pass")
+	}
+	if verifyPKCE(verifier+"x", challenge) {
+		t.Fatal("expected different verifier to fail")
+	}
+	if verifyPKCE("short", challenge) {
+		t.Fatal("expected verifier below 43 chars to fail")
+	}
+}
+
+func TestValidateRedirectURI(t *testing.T) {
+	valid := []string{
+		"https://app.example.com/callback",
+		"http://localhost:5173/callback",
+		"http://127.0.0.1/cb",
+	}
+	for _, uri := range valid {
+		if err := validateRedirectURI(uri); err != nil {
+			t.Fatalf("%s: unexpected error %v", uri, err)
+		}
+	}
+
+	invalid := []string{
+		"http://app.example.com/callback",
+		"https://app.example.com/callback#frag",
+		"/relative/callback",
+		"javascript:alert(1)",
+	}
+	for _, uri := range invalid {
+		if err := validateRedirectURI(uri); err == nil {
+			t.Fatalf("%s: expected rejection", uri)
+		}
+	}
+}
+
+func TestNormalizeScope(t *testing.T) {
+	scope, err := normalizeScope("openid email openid")
+	if err != nil || scope != "openid email" {
+		t.Fatalf("got %q, %v", scope, err)
+	}
+	if _, err := normalizeScope("openid admin"); err != ErrInvalidScope {
+		t.Fatalf("expected ErrInvalidScope, got %v", err)
+	}
+}
+
+func TestClientRegistry_Authenticate(t *testing.T) {
+	registry := NewClientRegistry(&stubClientRepo{clients: map[string]*models.OAuthClient{}})
+
+	confidential, secret, err := registry.Register(models.OAuthClientRegistration{
+		Name:         "Billing",
+		RedirectURIs: []string{"https://billing.example.com/cb"},
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if secret == "" || confidential.ClientSecretHash == secret {
+		t.Fatal("expected a secret stored only as a hash")
+	}
+	if _, err := registry.Authenticate(confidential.ClientID, secret); err != nil {
+		t.Fatalf("valid secret rejected: %v", err)
+	}
+	if _, err := registry.Authenticate(confidential.ClientID, "wrong"); err != ErrInvalidClient {
+		t.Fatalf("expected ErrInvalidClient, got %v", err)
+	}
+
+	public, _, err := registry.Register(models.OAuthClientRegistration{
+		Name:         "SPA",
+		RedirectURIs: []string{"https://spa.example.com/cb"},
+		Public:       true,
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := registry.Authenticate(public.ClientID, ""); err != nil {
+		t.Fatalf("public client rejected: %v", err)
+	}
+	if _, err := registry.Authenticate("missing", ""); err != ErrInvalidClient {
+		t.Fatalf("expected ErrInvalidClient, got %v", err)
+	}
+
+	if !redirectURIAllowed(public, "https://spa.example.com/cb") || redirectURIAllowed(public, "https://spa.example.com/cb/") {
+		t.Fatal("redirect URIs must match exactly")
+	}
+}
+
+func TestClientRegistry_RegisterMachineClient(t *testing.T) {
+	registry := NewClientRegistry(&stubClientRepo{clients: map[string]*models.OAuthClient{}})
+
+	client, secret, err := registry.Register(models.OAuthClientRegistration{
+		Name:       "Report job",
+		GrantTypes: []string{"client_credentials"},
+		Scopes:     []string{"reports:read"},
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if secret == "" || !client.AllowsGrant("client_credentials") || client.AllowsGrant("authorization_code") {
+		t.Fatalf("unexpected machine client %+v", client)
+	}
+
+	rejected := []models.OAuthClientRegistration{
+		{Name: "Public job", GrantTypes: []string{"client_credentials"}, Public: true},
+		{Name: "Bad scope", GrantTypes: []string{"client_credentials"}, Scopes: []string{`a"b`}},
+		{Name: "No redirect"},
+		{Name: "Implicit", GrantTypes: []string{"implicit"}},
+	}
+	for _, reg := range rejected {
+		if _, _, err := registry.Register(reg); err == nil {
+			t.Fatalf("%s: expected rejection", reg.Name)
+		}
+	}
+}
+
+func TestOIDCService_ClientCredentials(t *testing.T) {
+	cfg := &config.Config{JWTAccessExpiry: time.Minute}
+	tokenService := NewTokenService(cfg, NewHMACKeySet(strings.Repeat("k", 32)), nil, nil)
+	registry := NewClientRegistry(&stubClientRepo{clients: map[string]*models.OAuthClient{}})
+	oidc := NewOIDCService(registry, nil, nil, tokenService, nil, cfg)
+
+	machine, secret, err := registry.Register(models.OAuthClientRegistration{
+		Name:       "Report job",
+		GrantTypes: []string{"client_credentials"},
+		Scopes:     []string{"reports:read", "users:read"},
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	response, err := oidc.Token(&models.TokenRequest{
+		GrantType:    "client_credentials",
+		ClientID:     machine.ClientID,
+		ClientSecret: secret,
+		Scope:        "reports:read",
+	})
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if response.RefreshToken != "" || response.Scope != "reports:read" {
+		t.Fatalf("unexpected response %+v", response)
+	}
+
+	token, err := tokenService.parseClaims(response.AccessToken)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	principal := token.Claims.(*TokenClaims).Principal()
+	if !principal.IsClient() || principal.ClientID != machine.ClientID || !principal.HasScope("reports:read") || principal.HasScope("users:read") {
+		t.Fatalf("unexpected principal %+v", principal)
+	}
+
+	if _, err := oidc.Token(&models.TokenRequest{
+		GrantType:    "client_credentials",
+		ClientID:     machine.ClientID,
+		ClientSecret: secret,
+		Scope:        "admin",
+	}); err != ErrInvalidScope {
+		t.Fatalf("expected ErrInvalidScope, got %v", err)
+	}
+
+	browser, browserSecret, err := registry.Register(models.OAuthClientRegistration{
+		Name:         "Billing",
+		RedirectURIs: []string{"https://billing.example.com/cb"},
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := oidc.Token(&models.TokenRequest{
+		GrantType:    "client_credentials",
+		ClientID:     browser.ClientID,
+		ClientSecret: browserSecret,
+	}); err != ErrUnauthorizedClient {
+		t.Fatalf("expected ErrUnauthorizedClient, got %v", err)
+	}
+
+	// User grants are refused while the OIDC provider is switched off
+	if _, err := oidc.Token(&models.TokenRequest{
+		GrantType:    "authorization_code",
+		ClientID:     browser.ClientID,
+		ClientSecret: browserSecret,
+	}); err != ErrUnsupportedGrantType {
+		t.Fatalf("expected ErrUnsupportedGrantType, got %v", err)
+	}
+}
+
+func TestTokenService_IDTokenNeedsAsymmetricKey(t *testing.T) {
+	cfg := &config.Config{JWTAccessExpiry: time.Minute, OIDCIssuer: "https://auth.example.com"}
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+
+	hmac := NewTokenService(cfg, NewHMACKeySet(strings.Repeat("k", 32)), nil, nil)
+	if _, err := hmac.GenerateIDToken(user, "billing", "", time.Now(), true); err != ErrSymmetricKeySet {
+		t.Fatalf("expected ErrSymmetricKeySet, got %v", err)
+	}
+
+	keys, err := LoadKeySet(t.TempDir(), "ES256")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	if _, err := NewTokenService(cfg, keys, nil, nil).GenerateIDToken(user, "billing", "", time.Now(), true); err != nil {
+		t.Fatalf("GenerateIDToken: %v", err)
+	}
+}
+
+func TestTokenService_DelegatedTokens(t *testing.T) {
+	userID := uuid.New()
+	versions := &stubTokenVersionRepo{versions: map[uuid.UUID]int{userID: 0}}
+	cfg := &config.Config{JWTAccessExpiry: time.Minute, JWTRefreshExpiry: time.Hour}
+	tokenService := NewTokenService(cfg, NewHMACKeySet(strings.Repeat("k", 32)), nil, versions)
+	grant := OAuthGrant{ClientID: "billing", Scope: "openid"}
+
+	accessToken, err := tokenService.GenerateDelegatedAccessToken(userID, uuid.New(), grant)
+	if err != nil {
+		t.Fatalf("GenerateDelegatedAccessToken: %v", err)
+	}
+	token, err := tokenService.parseClaims(accessToken)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	principal := token.Claims.(*TokenClaims).Principal()
+	if principal.IsClient() || !principal.IsScoped() || principal.UserID != userID || !principal.HasScope("openid") || principal.HasScope("email") {
+		t.Fatalf("unexpected principal %+v", principal)
+	}
+
+	// First-party routes refuse a client's token outright
+	if _, err := tokenService.ValidateAccessToken(accessToken); err == nil {
+		t.Fatal("expected a delegated token to be refused as a first-party access token")
+	}
+
+	// Refresh tokens only rotate for the client they were issued to
+	refreshToken, err := tokenService.signRefreshToken(uuid.New().String(), refreshTokenRecord{UserID: userID, ClientID: grant.ClientID, Scope: grant.Scope})
+	if err != nil {
+		t.Fatalf("signRefreshToken: %v", err)
+	}
+	for _, clientID := range []string{"other", ""} {
+		if _, _, err := tokenService.RotateRefreshToken(refreshToken, clientID); err != ErrRefreshTokenClient {
+			t.Fatalf("client %q: expected ErrRefreshTokenClient, got %v", clientID, err)
+		}
+	}
+
+	firstParty, err := tokenService.signRefreshToken(uuid.New().String(), refreshTokenRecord{UserID: userID})
+	if err != nil {
+		t.Fatalf("signRefreshToken: %v", err)
+	}
+	if _, _, err := tokenService.RotateRefreshToken(firstParty, grant.ClientID); err != ErrRefreshTokenClient {
+		t.Fatalf("expected ErrRefreshTokenClient for a first-party token, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/google/uuid"
+)
+
+// oneTimeTokenBytes is the entropy of an emailed token
+const oneTimeTokenBytes = 32
+
+// How long emailed links work
+const (
+	passwordResetExpiry = time.Hour
+	emailChangeExpiry   = 24 * time.Hour
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// newOneTimeToken returns a random URL-safe token and its digest
+func newOneTimeToken() (string, string, error) {
+	raw := make([]byte, oneTimeTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashOneTimeToken(token), nil
+}
+
+// hashOneTimeToken is the SHA-256 digest stored in place of a token
+func hashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueToken replaces the user's outstanding token for purpose with a new
+// one and queues email, of the given outbox kind, carrying it, along with
+// any notices. The token is never stored without its email or the other way
+// round.
+func (s *AuthService) issueToken(userID uuid.UUID, purpose string, ttl time.Duration, data map[string]string, kind string, email models.OutboxEmail, notices ...models.OutboxMessage) error {
+	token, message, err := newTokenEmail(userID, purpose, ttl, data, kind, email)
+	if err != nil {
+		return err
+	}
+	return s.tokenRepo.Issue(token, append([]models.OutboxMessage{message}, notices...)...)
+}
+
+// newTokenEmail creates a token for purpose and the outbox message, of the
+// given kind, that emails it. Both go in the same write, as with issueToken.
+func newTokenEmail(userID uuid.UUID, purpose string, ttl time.Duration, data map[string]string, kind string, email models.OutboxEmail) (*models.OneTimeToken, models.OutboxMessage, error) {
+	token, digest, err := newOneTimeToken()
+	if err != nil {
+		return nil, models.OutboxMessage{}, err
+	}
+
+	email.Token = token
+	message, err := models.NewOutboxMessage(kind, email)
+	if err != nil {
+		return nil, models.OutboxMessage{}, err
+	}
+
+	return &models.OneTimeToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: digest,
+		Data:      data,
+		ExpiresAt: time.Now().Add(ttl),
+	}, message, nil
+}
+
+// findToken looks up a live token issued for purpose without using it up;
+// the write it authorises consumes it. Unknown, expired, spent and
+// wrong-purpose tokens all give ErrInvalidToken.
+func (s *AuthService) findToken(purpose, token string) (*models.OneTimeToken, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	found, err := s.tokenRepo.Find(purpose, hashOneTimeToken(token))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	return found, err
+}
+
+// tokenError maps a token spent by a concurrent request to ErrInvalidToken
+func tokenError(err error) error {
+	if err == repository.ErrTokenSpent {
+		return ErrInvalidToken
+	}
+	return err
+}
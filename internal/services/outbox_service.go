@@ -0,0 +1,189 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/Flack74/go-auth-system/internal/utils"
+)
+
+const (
+	outboxPollInterval = 2 * time.Second
+	outboxBatchSize    = 20
+	// outboxLease keeps a claimed message from other workers; only a
+	// crashed worker's claims run out
+	outboxLease       = 2 * time.Minute
+	outboxMaxAttempts = 10
+	outboxRetryBase   = 15 * time.Second
+	outboxRetryMax    = time.Hour
+)
+
+// OutboxHandler carries out one kind of outbox message
+type OutboxHandler func(payload json.RawMessage) error
+
+// OutboxService is the background worker for the transactional outbox. It
+// delivers messages queued by the repositories, retrying failures with
+// exponential backoff.
+type OutboxService struct {
+	outboxRepo repository.OutboxRepositoryInterface
+	handlers   map[string]OutboxHandler
+	logger     *utils.Logger
+	stop       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewOutboxService creates the worker with handlers for the email kinds and
+// for webhook events, which it hands to webhooks to deliver
+func NewOutboxService(outboxRepo repository.OutboxRepositoryInterface, emailService EmailServiceInterface, webhooks WebhookPublisherInterface) *OutboxService {
+	s := &OutboxService{
+		outboxRepo: outboxRepo,
+		handlers:   map[string]OutboxHandler{},
+		logger:     utils.NewLogger(),
+		stop:       make(chan struct{}),
+	}
+	s.Handle(models.OutboxVerificationEmail, emailHandler(func(email *models.OutboxEmail) error {
+		return emailService.SendVerificationEmail(email.To, email.Token, email.Locale)
+	}))
+	s.Handle(models.OutboxPasswordResetEmail, emailHandler(func(email *models.OutboxEmail) error {
+		return emailService.SendPasswordResetEmail(email.To, email.Token, email.Locale)
+	}))
+	s.Handle(models.OutboxPasswordChangedEmail, emailHandler(func(email *models.OutboxEmail) error {
+		client := &models.ClientInfo{UserAgent: email.UserAgent, IPAddress: email.IPAddress}
+		return emailService.SendPasswordChangedEmail(email.To, email.Locale, client, email.At)
+	}))
+	s.Handle(models.OutboxEmailChangeEmail, emailHandler(func(email *models.OutboxEmail) error {
+		return emailService.SendEmailChangeEmail(email.To, email.Token, email.Locale)
+	}))
+	s.Handle(models.OutboxEmailChangeNoticeEmail, emailHandler(func(email *models.OutboxEmail) error {
+		return emailService.SendEmailChangeNoticeEmail(email.To, email.NewEmail, email.Locale)
+	}))
+	s.Handle(models.OutboxNewDeviceEmail, emailHandler(func(email *models.OutboxEmail) error {
+		client := &models.ClientInfo{UserAgent: email.UserAgent, IPAddress: email.IPAddress}
+		return emailService.SendNewDeviceEmail(email.To, email.Locale, client, email.At)
+	}))
+	s.Handle(models.OutboxLockoutEmail, emailHandler(func(email *models.OutboxEmail) error {
+		return emailService.SendLockoutEmail(email.To, email.Locale, email.Until)
+	}))
+	s.Handle(models.OutboxAccountDeletionEmail, emailHandler(func(email *models.OutboxEmail) error {
+		return emailService.SendAccountDeletionEmail(email.To, email.Locale, email.Until)
+	}))
+	s.Handle(models.OutboxWebhookEvent, func(payload json.RawMessage) error {
+		var event models.OutboxWebhook
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return err
+		}
+		return webhooks.Publish(event.Event, event.Data)
+	})
+	return s
+}
+
+// Handle registers the handler for a message kind
+func (s *OutboxService) Handle(kind string, handler OutboxHandler) {
+	s.handlers[kind] = handler
+}
+
+// emailHandler decodes the OutboxEmail payload for send
+func emailHandler(send func(email *models.OutboxEmail) error) OutboxHandler {
+	return func(payload json.RawMessage) error {
+		var email models.OutboxEmail
+		if err := json.Unmarshal(payload, &email); err != nil {
+			return err
+		}
+		return send(&email)
+	}
+}
+
+// backoff doubles base for every failure after the first, up to max
+func backoff(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	return delay
+}
+
+// Start begins polling for due messages
+func (s *OutboxService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(outboxPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.ProcessDue()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits for the current batch to finish and stops polling
+func (s *OutboxService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// ProcessDue carries out every message that is due, one batch at a time
+func (s *OutboxService) ProcessDue() {
+	for {
+		messages, err := s.outboxRepo.ClaimDue(time.Now().Add(outboxLease), outboxBatchSize)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to claim outbox messages")
+			return
+		}
+
+		for i := range messages {
+			s.process(&messages[i])
+		}
+
+		if len(messages) < outboxBatchSize {
+			return
+		}
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+	}
+}
+
+func (s *OutboxService) process(message *models.OutboxMessage) {
+	err := fmt.Errorf("no handler for outbox message kind %q", message.Kind)
+	if handler, ok := s.handlers[message.Kind]; ok {
+		err = handler(message.Payload)
+	}
+
+	if err == nil {
+		if err := s.outboxRepo.MarkSent(message.ID); err != nil {
+			s.logger.WithField("message_id", message.ID).WithError(err).Error("Failed to mark outbox message sent")
+		}
+		return
+	}
+
+	attempts := message.Attempts + 1
+	status := models.OutboxPending
+	if attempts >= outboxMaxAttempts {
+		status = models.OutboxDead
+	}
+	s.logger.WithField("message_id", message.ID).
+		WithField("kind", message.Kind).
+		WithField("attempts", attempts).
+		WithField("status", status).
+		WithError(err).
+		Warn("Outbox message failed")
+
+	next := time.Now().Add(backoff(attempts, outboxRetryBase, outboxRetryMax))
+	if err := s.outboxRepo.MarkFailed(message.ID, status, next, err.Error()); err != nil {
+		s.logger.WithField("message_id", message.ID).WithError(err).Error("Failed to record outbox failure")
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// stubOutboxRepo is an in-memory outbox
+type stubOutboxRepo struct {
+	messages []*models.OutboxMessage
+}
+
+func (r *stubOutboxRepo) add(t *testing.T, kind string, payload interface{}) *models.OutboxMessage {
+	t.Helper()
+	message, err := models.NewOutboxMessage(kind, payload)
+	if err != nil {
+		t.Fatalf("new message: %v", err)
+	}
+	message.ID = uuid.New()
+	message.Status = models.OutboxPending
+	message.NextAttemptAt = time.Now()
+	r.messages = append(r.messages, &message)
+	return &message
+}
+
+func (r *stubOutboxRepo) Enqueue(messages ...models.OutboxMessage) error {
+	for i := range messages {
+		message := messages[i]
+		message.ID = uuid.New()
+		message.Status = models.OutboxPending
+		message.NextAttemptAt = time.Now()
+		r.messages = append(r.messages, &message)
+	}
+	return nil
+}
+
+func (r *stubOutboxRepo) ClaimDue(leaseUntil time.Time, limit int) ([]models.OutboxMessage, error) {
+	var due []models.OutboxMessage
+	for _, m := range r.messages {
+		if m.Status == models.OutboxPending && !m.NextAttemptAt.After(time.Now()) && len(due) < limit {
+			m.NextAttemptAt = leaseUntil
+			due = append(due, *m)
+		}
+	}
+	return due, nil
+}
+
+func (r *stubOutboxRepo) find(id uuid.UUID) *models.OutboxMessage {
+	for _, m := range r.messages {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+func (r *stubOutboxRepo) MarkSent(id uuid.UUID) error {
+	m := r.find(id)
+	m.Status = models.OutboxSent
+	m.Attempts++
+	return nil
+}
+
+func (r *stubOutboxRepo) MarkFailed(id uuid.UUID, status string, nextAttemptAt time.Time, lastError string) error {
+	m := r.find(id)
+	m.Status = status
+	m.Attempts++
+	m.NextAttemptAt = nextAttemptAt
+	m.LastError = lastError
+	return nil
+}
+
+func TestOutboxService_SendsQueuedEmails(t *testing.T) {
+	repo := &stubOutboxRepo{}
+	email := new(MockEmailSvc)
+	service := NewOutboxService(repo, email, &stubPublisher{})
+
+	verification := repo.add(t, models.OutboxVerificationEmail, models.OutboxEmail{To: "new@example.com", Token: "verify-token", Locale: "es"})
+	reset := repo.add(t, models.OutboxPasswordResetEmail, models.OutboxEmail{To: "reset@example.com", Token: "reset-token"})
+	email.On("SendVerificationEmail", "new@example.com", "verify-token", "es").Return(nil)
+	email.On("SendPasswordResetEmail", "reset@example.com", "reset-token", "").Return(errors.New("smtp unavailable")).Once()
+
+	service.ProcessDue()
+
+	email.AssertExpectations(t)
+	if verification.Status != models.OutboxSent {
+		t.Fatalf("expected the verification email to be sent, got %+v", verification)
+	}
+	if reset.Status != models.OutboxPending || reset.Attempts != 1 || reset.LastError != "smtp unavailable" {
+		t.Fatalf("expected the reset email to be retried, got %+v", reset)
+	}
+	if wait := time.Until(reset.NextAttemptAt); wait < outboxRetryBase-time.Second {
+		t.Fatalf("expected the retry to back off, next attempt in %v", wait)
+	}
+
+	// Once the mail server is back the retry goes through
+	email.On("SendPasswordResetEmail", "reset@example.com", "reset-token", "").Return(nil)
+	reset.NextAttemptAt = time.Now()
+	service.ProcessDue()
+	if reset.Status != models.OutboxSent || reset.Attempts != 2 {
+		t.Fatalf("expected the retry to succeed, got %+v", reset)
+	}
+}
+
+func TestOutboxService_DeadLettersUnknownKinds(t *testing.T) {
+	repo := &stubOutboxRepo{}
+	service := NewOutboxService(repo, new(MockEmailSvc), &stubPublisher{})
+	message := repo.add(t, "sms.unknown", map[string]string{})
+
+	for message.Status == models.OutboxPending {
+		message.NextAttemptAt = time.Now()
+		service.ProcessDue()
+	}
+	if message.Status != models.OutboxDead || message.Attempts != outboxMaxAttempts {
+		t.Fatalf("expected a dead message after %d attempts, got %+v", outboxMaxAttempts, message)
+	}
+}
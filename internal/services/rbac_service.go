@@ -0,0 +1,685 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// permissionCacheTTL bounds how stale a cached permission set can get if an
+// invalidation is ever missed
+const permissionCacheTTL = 10 * time.Minute
+
+// rbacGenerationKey is bumped to invalidate every cached permission set at
+// once, e.g. when a role's permissions change
+const rbacGenerationKey = "rbac:generation"
+
+var (
+	ErrRoleNotFound          = errors.New("role not found")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrRoleExists            = errors.New("role already exists")
+	ErrBuiltinRole           = errors.New("built-in roles cannot be renamed or deleted")
+	ErrInvalidRoleName       = errors.New("role names must be 2-50 lowercase letters, digits, '-' or '_'")
+	ErrPermissionNotFound    = errors.New("permission not found")
+	ErrPermissionExists      = errors.New("permission already exists")
+	ErrInvalidPermissionName = errors.New("resource and action must be lowercase letters, digits, '-' or '_'")
+	ErrLastAdmin             = errors.New("cannot remove the last active administrator")
+	ErrInvalidInheritance    = errors.New("inherited role does not exist or would create a cycle")
+)
+
+var (
+	roleNamePattern       = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+	permissionPartPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,48}$`)
+)
+
+// RBACService resolves a user's roles and effective permissions
+type RBACService struct {
+	roleRepo    repository.RoleRepositoryInterface
+	redisClient *redis.Client
+}
+
+func NewRBACService(roleRepo repository.RoleRepositoryInterface, redisClient *redis.Client) *RBACService {
+	return &RBACService{
+		roleRepo:    roleRepo,
+		redisClient: redisClient,
+	}
+}
+
+// UserPermissions returns the user's expanded roles and permissions, from the cache
+// when possible
+func (s *RBACService) UserPermissions(userID uuid.UUID) (*models.EffectivePermissions, error) {
+	ctx := context.Background()
+	key := s.cacheKey(ctx, userID)
+
+	if data, err := s.redisClient.Get(ctx, key).Result(); err == nil {
+		var cached models.EffectivePermissions
+		if json.Unmarshal([]byte(data), &cached) == nil {
+			return &cached, nil
+		}
+	}
+
+	effective, err := s.resolve(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A cache write failure only costs a database round trip next time
+	if data, err := json.Marshal(effective); err == nil {
+		s.redisClient.Set(ctx, key, data, permissionCacheTTL)
+	}
+
+	return effective, nil
+}
+
+// resolve reads the user's roles from the database and expands them through
+// inheritance. Users without an explicit role get the default user role.
+func (s *RBACService) resolve(userID uuid.UUID) (*models.EffectivePermissions, error) {
+	held, err := s.heldRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := s.roleRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	effective := &models.EffectivePermissions{
+		Roles:       []string{},
+		Permissions: []string{},
+	}
+	seen := map[string]bool{}
+	for _, role := range expandRoles(held, all) {
+		effective.Roles = append(effective.Roles, role.Name)
+
+		permissions, err := s.roleRepo.ListPermissions(role.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range permissions {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				effective.Permissions = append(effective.Permissions, p.Name)
+			}
+		}
+	}
+	sort.Strings(effective.Roles)
+	sort.Strings(effective.Permissions)
+	return effective, nil
+}
+
+// heldRoles returns the roles assigned to the user, or the default user role
+// if they have none
+func (s *RBACService) heldRoles(userID uuid.UUID) ([]models.Role, error) {
+	held, err := s.roleRepo.ListUserRoles(userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if len(held) > 0 {
+		return held, nil
+	}
+
+	role, err := s.roleRepo.GetByName(models.RoleUser)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return []models.Role{*role}, nil
+}
+
+// expandRoles returns held plus every role they inherit from, each once.
+// Inheritance cycles are cut wherever they are first revisited.
+func expandRoles(held, all []models.Role) []models.Role {
+	byID := make(map[uuid.UUID]models.Role, len(all))
+	for _, role := range all {
+		byID[role.ID] = role
+	}
+
+	var expanded []models.Role
+	visited := map[uuid.UUID]bool{}
+	for _, role := range held {
+		for !visited[role.ID] {
+			visited[role.ID] = true
+			expanded = append(expanded, role)
+
+			if !role.InheritsFrom.Valid {
+				break
+			}
+			parent, ok := byID[role.InheritsFrom.UUID]
+			if !ok {
+				break
+			}
+			role = parent
+		}
+	}
+	return expanded
+}
+
+// UserRoles returns the names of the roles assigned to the user, without
+// inherited ones
+func (s *RBACService) UserRoles(userID uuid.UUID) ([]string, error) {
+	held, err := s.heldRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(held))
+	for _, role := range held {
+		names = append(names, role.Name)
+	}
+	return names, nil
+}
+
+// ListRoles returns every role with its permissions
+func (s *RBACService) ListRoles() ([]models.Role, error) {
+	roles, err := s.roleRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range roles {
+		permissions, err := s.roleRepo.ListPermissions(roles[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		roles[i].Permissions = permissions
+	}
+	return roles, nil
+}
+
+// GetRole returns a role with its permissions
+func (s *RBACService) GetRole(roleID uuid.UUID) (*models.Role, error) {
+	role, err := s.getRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Permissions, err = s.roleRepo.ListPermissions(roleID)
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// CreateRole adds a custom role with no permissions of its own
+func (s *RBACService) CreateRole(req *models.CreateRoleRequest) (*models.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, ErrInvalidRoleName
+	}
+	if _, err := s.roleRepo.GetByName(req.Name); err == nil {
+		return nil, ErrRoleExists
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	inheritsFrom, err := s.inheritance(uuid.Nil, req.Inherits)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{Name: req.Name, Description: req.Description, InheritsFrom: inheritsFrom}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+
+	role.Permissions = []models.Permission{}
+	return role, s.InvalidateAll()
+}
+
+// UpdateRole renames a custom role, changes what it inherits from, or
+// changes any role's description. A rename or new parent first rejects the
+// access tokens of everyone holding the role, directly or by inheritance, as
+// a change to their own roles would.
+func (s *RBACService) UpdateRole(roleID uuid.UUID, req *models.UpdateRoleRequest) (*models.Role, error) {
+	role, err := s.getRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != role.Name {
+		if models.IsBuiltinRole(role.Name) {
+			return nil, ErrBuiltinRole
+		}
+		if !roleNamePattern.MatchString(req.Name) {
+			return nil, ErrInvalidRoleName
+		}
+		if _, err := s.roleRepo.GetByName(req.Name); err == nil {
+			return nil, ErrRoleExists
+		} else if err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
+	inheritsFrom := role.InheritsFrom
+	if req.Inherits != nil {
+		inheritsFrom, err = s.inheritance(roleID, *req.Inherits)
+		if err != nil {
+			return nil, err
+		}
+		if inheritsFrom != role.InheritsFrom && models.IsBuiltinRole(role.Name) {
+			return nil, ErrBuiltinRole
+		}
+	}
+
+	guard, err := s.inheritanceGuard(role, inheritsFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != role.Name || inheritsFrom != role.InheritsFrom {
+		all, err := s.roleRepo.List()
+		if err != nil {
+			return nil, err
+		}
+		if err := s.roleRepo.BumpHolderTokenVersions(inheritingRoles(role.ID, all)); err != nil {
+			return nil, err
+		}
+	}
+
+	role.Name = req.Name
+	role.Description = req.Description
+	role.InheritsFrom = inheritsFrom
+	if err := s.roleRepo.Update(role, guard); err != nil {
+		return nil, lastAdminError(err)
+	}
+
+	// Cached entries carry role names and inherited permissions
+	return role, s.InvalidateAll()
+}
+
+// DeleteRole removes a custom role. Holders left with no role fall back to
+// the user role. Everyone holding it, directly or by inheritance, loses their
+// access tokens, and it is refused if it would leave no administrator.
+func (s *RBACService) DeleteRole(roleID uuid.UUID) error {
+	role, err := s.getRole(roleID)
+	if err != nil {
+		return err
+	}
+	if models.IsBuiltinRole(role.Name) {
+		return ErrBuiltinRole
+	}
+
+	all, err := s.roleRepo.List()
+	if err != nil {
+		return err
+	}
+	guard, err := s.deletionGuard(role, all)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.Delete(roleID, inheritingRoles(roleID, all), guard); err != nil {
+		return lastAdminError(err)
+	}
+	return s.InvalidateAll()
+}
+
+// deletionGuard returns ErrLastAdmin if deleting role would leave no enabled
+// administrator, and a guard if it takes admin away from some users. Roles
+// inheriting from it stop inheriting anything.
+func (s *RBACService) deletionGuard(role *models.Role, all []models.Role) (*repository.AdminGuard, error) {
+	before := adminRoles(all)
+
+	remaining := make([]models.Role, 0, len(all))
+	for _, r := range all {
+		if r.ID == role.ID {
+			continue
+		}
+		if r.InheritsFrom.Valid && r.InheritsFrom.UUID == role.ID {
+			r.InheritsFrom = uuid.NullUUID{}
+		}
+		remaining = append(remaining, r)
+	}
+	after := adminRoles(remaining)
+	if len(after) == len(before) {
+		// The role granted nobody admin
+		return nil, nil
+	}
+
+	guard := &repository.AdminGuard{Roles: after}
+	admins, err := s.roleRepo.CountActiveUsersWithRoles(guard.Roles)
+	if err != nil {
+		return nil, err
+	}
+	if admins == 0 {
+		return nil, ErrLastAdmin
+	}
+	return guard, nil
+}
+
+// ListPermissions returns every permission that can be attached to a role
+func (s *RBACService) ListPermissions() ([]models.Permission, error) {
+	return s.roleRepo.ListAllPermissions()
+}
+
+// CreatePermission defines a new resource.action permission
+func (s *RBACService) CreatePermission(req *models.CreatePermissionRequest) (*models.Permission, error) {
+	if !permissionPartPattern.MatchString(req.Resource) || !permissionPartPattern.MatchString(req.Action) {
+		return nil, ErrInvalidPermissionName
+	}
+
+	name := req.Resource + "." + req.Action
+	if _, err := s.roleRepo.GetPermissionByName(name); err == nil {
+		return nil, ErrPermissionExists
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	permission := &models.Permission{
+		Name:        name,
+		Resource:    req.Resource,
+		Action:      req.Action,
+		Description: req.Description,
+	}
+	if err := s.roleRepo.CreatePermission(permission); err != nil {
+		return nil, err
+	}
+	return permission, nil
+}
+
+// AttachPermission grants a permission to everyone holding the role
+func (s *RBACService) AttachPermission(roleID uuid.UUID, permissionName string) error {
+	permission, err := s.rolePermission(roleID, permissionName)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.AddPermission(roleID, permission.ID); err != nil {
+		return err
+	}
+	return s.InvalidateAll()
+}
+
+// DetachPermission revokes a permission from everyone holding the role
+func (s *RBACService) DetachPermission(roleID uuid.UUID, permissionName string) error {
+	permission, err := s.rolePermission(roleID, permissionName)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.RemovePermission(roleID, permission.ID); err != nil {
+		return err
+	}
+	return s.InvalidateAll()
+}
+
+// LastAdminGuard returns the guard a change must be written under when
+// userID is currently an administrator and would not be one holding only the
+// remaining roles; pass nil when the user is losing their account. It is nil
+// when the change cannot remove an administrator, and ErrLastAdmin when no
+// other enabled administrator exists now. The guarded write checks again, so
+// concurrent demotions can't both pass.
+func (s *RBACService) LastAdminGuard(userID uuid.UUID, remaining []string) (*repository.AdminGuard, error) {
+	effective, err := s.resolve(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !effective.HasRole(models.RoleAdmin) {
+		return nil, nil
+	}
+
+	all, err := s.roleRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	kept, err := rolesByName(all, remaining)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range expandRoles(kept, all) {
+		if role.Name == models.RoleAdmin {
+			return nil, nil
+		}
+	}
+
+	guard := &repository.AdminGuard{Roles: adminRoles(all)}
+	admins, err := s.roleRepo.CountActiveUsersWithRoles(guard.Roles)
+	if err != nil {
+		return nil, err
+	}
+	if admins <= 1 {
+		return nil, ErrLastAdmin
+	}
+	return guard, nil
+}
+
+// inheritingRoles returns roleID and every role inheriting from it, directly
+// or through others
+func inheritingRoles(roleID uuid.UUID, all []models.Role) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, role := range all {
+		for _, r := range expandRoles([]models.Role{role}, all) {
+			if r.ID == roleID {
+				ids = append(ids, role.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// inheritanceGuard returns ErrLastAdmin if re-parenting role would leave no
+// enabled administrator, and a guard if it takes admin away from some users
+func (s *RBACService) inheritanceGuard(role *models.Role, inheritsFrom uuid.NullUUID) (*repository.AdminGuard, error) {
+	if inheritsFrom == role.InheritsFrom {
+		return nil, nil
+	}
+
+	all, err := s.roleRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	before := adminRoles(all)
+	for i := range all {
+		if all[i].ID == role.ID {
+			all[i].InheritsFrom = inheritsFrom
+		}
+	}
+	after := adminRoles(all)
+	if len(after) >= len(before) {
+		// The role and everything inheriting it gain or lose admin together
+		return nil, nil
+	}
+
+	guard := &repository.AdminGuard{Roles: after}
+	admins, err := s.roleRepo.CountActiveUsersWithRoles(guard.Roles)
+	if err != nil {
+		return nil, err
+	}
+	if admins == 0 {
+		return nil, ErrLastAdmin
+	}
+	return guard, nil
+}
+
+// adminRoles returns every role that is admin or inherits it: anyone holding
+// one is an administrator
+func adminRoles(all []models.Role) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, role := range all {
+		for _, r := range expandRoles([]models.Role{role}, all) {
+			if r.Name == models.RoleAdmin {
+				ids = append(ids, role.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// lastAdminError reports a write refused by its AdminGuard as ErrLastAdmin
+func lastAdminError(err error) error {
+	if err == repository.ErrNoAdminLeft {
+		return ErrLastAdmin
+	}
+	return err
+}
+
+// GrantRole gives the user the named role alongside those they hold
+func (s *RBACService) GrantRole(userID uuid.UUID, roleName string) error {
+	role, err := s.roleByName(roleName)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.AddUserRole(userID, role.ID); err != nil {
+		return err
+	}
+	return s.InvalidateUser(userID)
+}
+
+// RevokeRole takes the named role away from the user, under guard if the
+// user may be the last administrator
+func (s *RBACService) RevokeRole(userID uuid.UUID, roleName string, guard *repository.AdminGuard) error {
+	role, err := s.roleByName(roleName)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.RemoveUserRole(userID, role.ID, guard); err != nil {
+		return lastAdminError(err)
+	}
+	return s.InvalidateUser(userID)
+}
+
+// SetRoles replaces every role the user holds with the named ones, under
+// guard if the user may be the last administrator
+func (s *RBACService) SetRoles(userID uuid.UUID, roleNames []string, guard *repository.AdminGuard) error {
+	all, err := s.roleRepo.List()
+	if err != nil {
+		return err
+	}
+
+	roles, err := rolesByName(all, roleNames)
+	if err != nil {
+		return err
+	}
+
+	roleIDs := make([]uuid.UUID, 0, len(roles))
+	for _, role := range roles {
+		roleIDs = append(roleIDs, role.ID)
+	}
+	if err := s.roleRepo.SetUserRoles(userID, roleIDs, guard); err != nil {
+		return lastAdminError(err)
+	}
+	return s.InvalidateUser(userID)
+}
+
+// InvalidateUser drops the cached permissions for one user
+func (s *RBACService) InvalidateUser(userID uuid.UUID) error {
+	ctx := context.Background()
+	return s.redisClient.Del(ctx, s.cacheKey(ctx, userID)).Err()
+}
+
+// InvalidateAll drops every cached permission set by moving to a new
+// generation; stale entries simply expire
+func (s *RBACService) InvalidateAll() error {
+	ctx := context.Background()
+	return s.redisClient.Incr(ctx, rbacGenerationKey).Err()
+}
+
+// inheritance resolves the role named by parentName as the parent of roleID,
+// which is uuid.Nil for a role that does not exist yet
+func (s *RBACService) inheritance(roleID uuid.UUID, parentName string) (uuid.NullUUID, error) {
+	if parentName == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	all, err := s.roleRepo.List()
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	parents, err := rolesByName(all, []string{parentName})
+	if err != nil {
+		return uuid.NullUUID{}, ErrInvalidInheritance
+	}
+	for _, role := range expandRoles(parents, all) {
+		if role.ID == roleID {
+			return uuid.NullUUID{}, ErrInvalidInheritance
+		}
+	}
+	return uuid.NullUUID{UUID: parents[0].ID, Valid: true}, nil
+}
+
+// rolesByName looks up each named role in all, failing on the first unknown
+// name
+func rolesByName(all []models.Role, names []string) ([]models.Role, error) {
+	roles := make([]models.Role, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, role := range all {
+			if role.Name == name {
+				roles = append(roles, role)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrRoleNotFound
+		}
+	}
+	return roles, nil
+}
+
+func (s *RBACService) roleByName(name string) (*models.Role, error) {
+	role, err := s.roleRepo.GetByName(name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *RBACService) getRole(roleID uuid.UUID) (*models.Role, error) {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// rolePermission checks both ends of a role/permission link exist
+func (s *RBACService) rolePermission(roleID uuid.UUID, permissionName string) (*models.Permission, error) {
+	if _, err := s.getRole(roleID); err != nil {
+		return nil, err
+	}
+
+	permission, err := s.roleRepo.GetPermissionByName(permissionName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPermissionNotFound
+		}
+		return nil, err
+	}
+	return permission, nil
+}
+
+func (s *RBACService) cacheKey(ctx context.Context, userID uuid.UUID) string {
+	generation, err := s.redisClient.Get(ctx, rbacGenerationKey).Result()
+	if err != nil {
+		generation = "0"
+	}
+	return fmt.Sprintf("rbac:effective:%s:%s", generation, userID)
+}
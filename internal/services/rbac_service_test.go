@@ -0,0 +1,461 @@
+package services
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+type stubRoleRepo struct {
+	roles       map[uuid.UUID]*models.Role
+	permissions map[uuid.UUID][]models.Permission
+	userRoles   map[uuid.UUID][]uuid.UUID
+	adminCount  int64
+	// bumped records the roles passed to each BumpHolderTokenVersions call
+	bumped [][]uuid.UUID
+}
+
+func newStubRoleRepo() *stubRoleRepo {
+	return &stubRoleRepo{
+		roles:       map[uuid.UUID]*models.Role{},
+		permissions: map[uuid.UUID][]models.Permission{},
+		userRoles:   map[uuid.UUID][]uuid.UUID{},
+	}
+}
+
+func (r *stubRoleRepo) addRole(name string, permissions ...string) *models.Role {
+	return r.addChildRole(name, nil, permissions...)
+}
+
+func (r *stubRoleRepo) addChildRole(name string, parent *models.Role, permissions ...string) *models.Role {
+	role := &models.Role{ID: uuid.New(), Name: name}
+	if parent != nil {
+		role.InheritsFrom = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+	r.roles[role.ID] = role
+	for _, p := range permissions {
+		r.permissions[role.ID] = append(r.permissions[role.ID], models.Permission{ID: uuid.New(), Name: p})
+	}
+	return role
+}
+
+func (r *stubRoleRepo) GetByID(id uuid.UUID) (*models.Role, error) {
+	role, ok := r.roles[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	found := *role
+	return &found, nil
+}
+
+func (r *stubRoleRepo) GetByName(name string) (*models.Role, error) {
+	for _, role := range r.roles {
+		if role.Name == name {
+			return role, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *stubRoleRepo) List() ([]models.Role, error) {
+	var roles []models.Role
+	for _, role := range r.roles {
+		roles = append(roles, *role)
+	}
+	return roles, nil
+}
+
+func (r *stubRoleRepo) Create(role *models.Role) error {
+	role.ID = uuid.New()
+	r.roles[role.ID] = role
+	return nil
+}
+
+// Update checks a guard against the assignments it holds, as the database does
+func (r *stubRoleRepo) Update(role *models.Role, guard *repository.AdminGuard) error {
+	saved := *role
+	previous := r.roles[role.ID]
+	r.roles[role.ID] = &saved
+	if guard != nil && r.countHolders(guard.Roles) == 0 {
+		r.roles[role.ID] = previous
+		return repository.ErrNoAdminLeft
+	}
+	return nil
+}
+
+func (r *stubRoleRepo) countHolders(roleIDs []uuid.UUID) int64 {
+	var holders int64
+	for _, held := range r.userRoles {
+	users:
+		for _, id := range held {
+			for _, roleID := range roleIDs {
+				if id == roleID {
+					holders++
+					break users
+				}
+			}
+		}
+	}
+	return holders
+}
+
+// Delete checks a guard against the assignments left, as the database does
+func (r *stubRoleRepo) Delete(id uuid.UUID, holders []uuid.UUID, guard *repository.AdminGuard) error {
+	if guard != nil && r.countHolders(guard.Roles) == 0 {
+		return repository.ErrNoAdminLeft
+	}
+	r.bumped = append(r.bumped, holders)
+	delete(r.roles, id)
+	return nil
+}
+
+func (r *stubRoleRepo) CountActiveUsersWithRoles(roleIDs []uuid.UUID) (int64, error) {
+	return r.adminCount, nil
+}
+
+func (r *stubRoleRepo) BumpHolderTokenVersions(roleIDs []uuid.UUID) error {
+	r.bumped = append(r.bumped, roleIDs)
+	return nil
+}
+
+func (r *stubRoleRepo) ListPermissions(roleID uuid.UUID) ([]models.Permission, error) {
+	return r.permissions[roleID], nil
+}
+
+func (r *stubRoleRepo) ListAllPermissions() ([]models.Permission, error) {
+	var all []models.Permission
+	for _, permissions := range r.permissions {
+		all = append(all, permissions...)
+	}
+	return all, nil
+}
+
+func (r *stubRoleRepo) GetPermissionByName(name string) (*models.Permission, error) {
+	for _, permissions := range r.permissions {
+		for _, p := range permissions {
+			if p.Name == name {
+				return &p, nil
+			}
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *stubRoleRepo) CreatePermission(permission *models.Permission) error {
+	permission.ID = uuid.New()
+	return nil
+}
+
+func (r *stubRoleRepo) AddPermission(roleID, permissionID uuid.UUID) error {
+	return nil
+}
+
+func (r *stubRoleRepo) RemovePermission(roleID, permissionID uuid.UUID) error {
+	return nil
+}
+
+func (r *stubRoleRepo) ListUserRoles(userID uuid.UUID) ([]models.Role, error) {
+	roleIDs, ok := r.userRoles[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	var roles []models.Role
+	for _, id := range roleIDs {
+		roles = append(roles, *r.roles[id])
+	}
+	return roles, nil
+}
+
+func (r *stubRoleRepo) AddUserRole(userID, roleID uuid.UUID) error {
+	r.userRoles[userID] = append(r.userRoles[userID], roleID)
+	return nil
+}
+
+// guarded stands in for the database's post-write check: with a guard, the
+// write fails unless another administrator is counted
+func (r *stubRoleRepo) guarded(guard *repository.AdminGuard) error {
+	if guard != nil && r.adminCount <= 1 {
+		return repository.ErrNoAdminLeft
+	}
+	return nil
+}
+
+func (r *stubRoleRepo) RemoveUserRole(userID, roleID uuid.UUID, guard *repository.AdminGuard) error {
+	if err := r.guarded(guard); err != nil {
+		return err
+	}
+	var kept []uuid.UUID
+	for _, id := range r.userRoles[userID] {
+		if id != roleID {
+			kept = append(kept, id)
+		}
+	}
+	r.userRoles[userID] = kept
+	return nil
+}
+
+func (r *stubRoleRepo) SetUserRoles(userID uuid.UUID, roleIDs []uuid.UUID, guard *repository.AdminGuard) error {
+	if err := r.guarded(guard); err != nil {
+		return err
+	}
+	r.userRoles[userID] = roleIDs
+	return nil
+}
+
+func TestRBACService_Resolve(t *testing.T) {
+	repo := newStubRoleRepo()
+	user := repo.addRole(models.RoleUser, models.PermissionUsersRead)
+	moderator := repo.addChildRole(models.RoleModerator, user, models.PermissionContentModerate)
+	repo.addChildRole(models.RoleAdmin, moderator, models.PermissionAdminAccess)
+	billing := repo.addRole("billing", "billing.read")
+	rbac := NewRBACService(repo, nil)
+
+	defaulted := uuid.New()
+	repo.userRoles[defaulted] = nil
+	effective, err := rbac.resolve(defaulted)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if !reflect.DeepEqual(effective.Roles, []string{models.RoleUser}) || !reflect.DeepEqual(effective.Permissions, []string{models.PermissionUsersRead}) {
+		t.Fatalf("users without a role should get the user role, got %+v", effective)
+	}
+
+	assigned := uuid.New()
+	repo.userRoles[assigned] = []uuid.UUID{moderator.ID, billing.ID}
+	effective, err = rbac.resolve(assigned)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if !reflect.DeepEqual(effective.Roles, []string{"billing", models.RoleModerator, models.RoleUser}) {
+		t.Fatalf("moderator should inherit user alongside billing, got %v", effective.Roles)
+	}
+	if !reflect.DeepEqual(effective.Permissions, []string{"billing.read", models.PermissionContentModerate, models.PermissionUsersRead}) {
+		t.Fatalf("unexpected permissions %v", effective.Permissions)
+	}
+
+	if _, err := rbac.resolve(uuid.New()); err != ErrUserNotFound {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestRBACService_InheritanceCycle(t *testing.T) {
+	repo := newStubRoleRepo()
+	parent := repo.addRole("support")
+	child := repo.addChildRole("support-lead", parent)
+	rbac := NewRBACService(repo, nil)
+
+	_, err := rbac.UpdateRole(parent.ID, &models.UpdateRoleRequest{Name: "support", Inherits: &child.Name})
+	if err != ErrInvalidInheritance {
+		t.Fatalf("expected ErrInvalidInheritance, got %v", err)
+	}
+	if _, err := rbac.CreateRole(&models.CreateRoleRequest{Name: "auditor", Inherits: "missing"}); err != ErrInvalidInheritance {
+		t.Fatalf("expected ErrInvalidInheritance, got %v", err)
+	}
+
+	// A cycle already in the database must not hang resolution
+	parent.InheritsFrom = uuid.NullUUID{UUID: child.ID, Valid: true}
+	userID := uuid.New()
+	repo.userRoles[userID] = []uuid.UUID{child.ID}
+	effective, err := rbac.resolve(userID)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if !reflect.DeepEqual(effective.Roles, []string{"support", "support-lead"}) {
+		t.Fatalf("unexpected roles %v", effective.Roles)
+	}
+}
+
+func TestRBACService_GuardLastAdmin(t *testing.T) {
+	repo := newStubRoleRepo()
+	user := repo.addRole(models.RoleUser, models.PermissionUsersRead)
+	moderator := repo.addChildRole(models.RoleModerator, user)
+	admin := repo.addChildRole(models.RoleAdmin, moderator, models.PermissionAdminAccess)
+	rbac := NewRBACService(repo, nil)
+
+	adminID := uuid.New()
+	repo.userRoles[adminID] = []uuid.UUID{admin.ID}
+	regularID := uuid.New()
+	repo.userRoles[regularID] = nil
+
+	repo.adminCount = 1
+	if _, err := rbac.LastAdminGuard(adminID, nil); err != ErrLastAdmin {
+		t.Fatalf("expected ErrLastAdmin, got %v", err)
+	}
+	if _, err := rbac.LastAdminGuard(adminID, []string{models.RoleModerator}); err != ErrLastAdmin {
+		t.Fatalf("moderator does not include admin, expected ErrLastAdmin, got %v", err)
+	}
+	if guard, err := rbac.LastAdminGuard(adminID, []string{models.RoleAdmin}); err != nil || guard != nil {
+		t.Fatalf("keeping the admin role needs no guard, got %v, %v", guard, err)
+	}
+	if guard, err := rbac.LastAdminGuard(regularID, nil); err != nil || guard != nil {
+		t.Fatalf("non-admins are never the last admin, got %v, %v", guard, err)
+	}
+
+	repo.adminCount = 2
+	guard, err := rbac.LastAdminGuard(adminID, nil)
+	if err != nil || guard == nil || len(guard.Roles) != 1 || guard.Roles[0] != admin.ID {
+		t.Fatalf("expected a guard over the admin role, got %+v, %v", guard, err)
+	}
+
+	// Another admin was demoted concurrently: the guarded write refuses
+	repo.adminCount = 1
+	if err := rbac.SetRoles(adminID, []string{models.RoleUser}, guard); err != ErrLastAdmin {
+		t.Fatalf("expected the guarded write to fail with ErrLastAdmin, got %v", err)
+	}
+}
+
+func TestRBACService_UpdateRoleInheritance(t *testing.T) {
+	repo := newStubRoleRepo()
+	user := repo.addRole(models.RoleUser, models.PermissionUsersRead)
+	moderator := repo.addChildRole(models.RoleModerator, user)
+	admin := repo.addChildRole(models.RoleAdmin, moderator, models.PermissionAdminAccess)
+	owner := repo.addChildRole("owner", admin)
+
+	// Nothing listens here: invalidating the cache fails after the role is saved
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer redisClient.Close()
+	rbac := NewRBACService(repo, redisClient)
+
+	// Omitting inherits keeps it, so built-in roles can be described
+	_, err := rbac.UpdateRole(admin.ID, &models.UpdateRoleRequest{Name: models.RoleAdmin, Description: "Full access"})
+	if err == ErrBuiltinRole || repo.roles[admin.ID].Description != "Full access" || repo.roles[admin.ID].InheritsFrom.UUID != moderator.ID {
+		t.Fatalf("expected a description-only update, got %+v, %v", repo.roles[admin.ID], err)
+	}
+	rbac.UpdateRole(owner.ID, &models.UpdateRoleRequest{Name: "owner"})
+	if repo.roles[owner.ID].InheritsFrom.UUID != admin.ID {
+		t.Fatalf("expected inherits to be kept, got %+v", repo.roles[owner.ID])
+	}
+
+	// The only administrator holds admin through owner
+	repo.userRoles[uuid.New()] = []uuid.UUID{owner.ID}
+	repo.adminCount = 1
+	cleared := ""
+	if _, err := rbac.UpdateRole(owner.ID, &models.UpdateRoleRequest{Name: "owner", Inherits: &cleared}); err != ErrLastAdmin {
+		t.Fatalf("expected ErrLastAdmin, got %v", err)
+	}
+
+	// Another admin was counted but is gone by the time of the write
+	repo.adminCount = 2
+	if _, err := rbac.UpdateRole(owner.ID, &models.UpdateRoleRequest{Name: "owner", Inherits: &cleared}); err != ErrLastAdmin {
+		t.Fatalf("expected the guarded write to fail with ErrLastAdmin, got %v", err)
+	}
+
+	repo.userRoles[uuid.New()] = []uuid.UUID{admin.ID}
+	rbac.UpdateRole(owner.ID, &models.UpdateRoleRequest{Name: "owner", Inherits: &cleared})
+	if repo.roles[owner.ID].InheritsFrom.Valid {
+		t.Fatalf("expected inherits to be cleared, got %+v", repo.roles[owner.ID])
+	}
+}
+
+func TestRBACService_UpdateRoleRevokesHolderTokens(t *testing.T) {
+	repo := newStubRoleRepo()
+	user := repo.addRole(models.RoleUser)
+	moderator := repo.addChildRole(models.RoleModerator, user)
+	editor := repo.addChildRole("editor", user)
+	senior := repo.addChildRole("senior-editor", editor)
+
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer redisClient.Close()
+	rbac := NewRBACService(repo, redisClient)
+
+	// A description changes nobody's access
+	rbac.UpdateRole(editor.ID, &models.UpdateRoleRequest{Name: "editor", Description: "Edits content"})
+	if len(repo.bumped) != 0 {
+		t.Fatalf("expected no tokens revoked, got %v", repo.bumped)
+	}
+
+	// Renaming reaches the roles inheriting it too, but not its parent
+	rbac.UpdateRole(editor.ID, &models.UpdateRoleRequest{Name: "writer"})
+	if len(repo.bumped) != 1 || !sameRoles(repo.bumped[0], editor.ID, senior.ID) {
+		t.Fatalf("expected editor and senior-editor holders revoked, got %v", repo.bumped)
+	}
+
+	parent := models.RoleModerator
+	rbac.UpdateRole(senior.ID, &models.UpdateRoleRequest{Name: "senior-editor", Inherits: &parent})
+	if len(repo.bumped) != 2 || !sameRoles(repo.bumped[1], senior.ID) {
+		t.Fatalf("expected senior-editor holders revoked, got %v", repo.bumped)
+	}
+	if repo.roles[senior.ID].InheritsFrom.UUID != moderator.ID {
+		t.Fatalf("expected senior-editor to inherit moderator, got %+v", repo.roles[senior.ID])
+	}
+}
+
+func sameRoles(got []uuid.UUID, want ...uuid.UUID) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, id := range got {
+		seen[id] = true
+	}
+	for _, id := range want {
+		if !seen[id] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRBACService_DeleteRoleGuardsLastAdmin(t *testing.T) {
+	repo := newStubRoleRepo()
+	user := repo.addRole(models.RoleUser)
+	admin := repo.addChildRole(models.RoleAdmin, user)
+	owner := repo.addChildRole("owner", admin)
+	root := repo.addChildRole("root", owner)
+	editor := repo.addChildRole("editor", user)
+
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer redisClient.Close()
+	rbac := NewRBACService(repo, redisClient)
+
+	// The only administrator holds admin through root, which reaches it via owner
+	repo.userRoles[uuid.New()] = []uuid.UUID{root.ID}
+	if err := rbac.DeleteRole(owner.ID); err != ErrLastAdmin {
+		t.Fatalf("expected ErrLastAdmin, got %v", err)
+	}
+
+	// Another admin was counted but is gone by the time of the write
+	repo.adminCount = 2
+	if err := rbac.DeleteRole(owner.ID); err != ErrLastAdmin {
+		t.Fatalf("expected the guarded write to fail with ErrLastAdmin, got %v", err)
+	}
+	if _, ok := repo.roles[owner.ID]; !ok || len(repo.bumped) != 0 {
+		t.Fatalf("expected nothing to change, got %v", repo.bumped)
+	}
+
+	// A role granting nobody admin needs no guard; its holders are revoked
+	rbac.DeleteRole(editor.ID)
+	if _, ok := repo.roles[editor.ID]; ok || len(repo.bumped) != 1 || !sameRoles(repo.bumped[0], editor.ID) {
+		t.Fatalf("expected editor deleted and its holders revoked, got %v", repo.bumped)
+	}
+
+	repo.userRoles[uuid.New()] = []uuid.UUID{admin.ID}
+	rbac.DeleteRole(owner.ID)
+	if _, ok := repo.roles[owner.ID]; ok || len(repo.bumped) != 2 || !sameRoles(repo.bumped[1], owner.ID, root.ID) {
+		t.Fatalf("expected owner deleted and owner and root holders revoked, got %v", repo.bumped)
+	}
+}
+
+func TestRBACService_BuiltinRolesAreProtected(t *testing.T) {
+	repo := newStubRoleRepo()
+	admin := repo.addRole(models.RoleAdmin)
+	rbac := NewRBACService(repo, nil)
+
+	if err := rbac.DeleteRole(admin.ID); err != ErrBuiltinRole {
+		t.Fatalf("expected ErrBuiltinRole, got %v", err)
+	}
+	if _, err := rbac.UpdateRole(admin.ID, &models.UpdateRoleRequest{Name: "superuser"}); err != ErrBuiltinRole {
+		t.Fatalf("expected ErrBuiltinRole, got %v", err)
+	}
+	if _, err := rbac.CreateRole(&models.CreateRoleRequest{Name: "Bad Name"}); err != ErrInvalidRoleName {
+		t.Fatalf("expected ErrInvalidRoleName, got %v", err)
+	}
+	if _, err := rbac.CreateRole(&models.CreateRoleRequest{Name: models.RoleAdmin}); err != ErrRoleExists {
+		t.Fatalf("expected ErrRoleExists, got %v", err)
+	}
+}
@@ -3,28 +3,67 @@ package services
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
+// SessionCookieName is the cookie carrying the opaque session token
+const SessionCookieName = "session_id"
+
+// sessionTouchInterval throttles last_seen writes to the sessions table
+const sessionTouchInterval = time.Minute
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// sessionStore is the part of Redis that sessions and their index live in
+type sessionStore interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd
+	Del(ctx context.Context, keys ...string) *redis.IntCmd
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+}
+
 type SessionService struct {
-	redisClient *redis.Client
-	config      *config.Config
+	sessionRepo  repository.SessionRepositoryInterface
+	tokenService TokenServiceInterface
+	redisClient  sessionStore
+	config       *config.Config
+}
+
+// sessionEntry is the Redis value for an active session token. Version is
+// the user's token version when the session was created.
+type sessionEntry struct {
+	SessionID uuid.UUID `json:"session_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Version   int       `json:"ver,omitempty"`
 }
 
-func NewSessionService(redisClient *redis.Client, config *config.Config) *SessionService {
+func NewSessionService(sessionRepo repository.SessionRepositoryInterface, tokenService TokenServiceInterface, redisClient *redis.Client, config *config.Config) *SessionService {
 	return &SessionService{
-		redisClient: redisClient,
-		config:      config,
+		sessionRepo:  sessionRepo,
+		tokenService: tokenService,
+		redisClient:  redisClient,
+		config:       config,
 	}
 }
 
-// GenerateSessionID creates a secure random session ID
-func (s *SessionService) GenerateSessionID() (string, error) {
+// GenerateSessionToken creates a secure random session token
+func (s *SessionService) GenerateSessionToken() (string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", err
@@ -32,75 +71,256 @@ func (s *SessionService) GenerateSessionID() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// CreateSession stores user session in Redis
-func (s *SessionService) CreateSession(userID uuid.UUID) (string, error) {
+// CreateSession records a device session. Cookie sessions also get an opaque
+// token, of which only the digest is persisted; JWT sessions are tracked
+// through the sid claim and live as long as their refresh tokens.
+func (s *SessionService) CreateSession(userID uuid.UUID, client *models.ClientInfo) (*models.Session, string, error) {
 	ctx := context.Background()
-	
-	sessionID, err := s.GenerateSessionID()
+
+	deviceID := client.DeviceID
+	if deviceID == "" {
+		deviceID = uuid.New().String()
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:        uuid.New(),
+		UserID:    userID,
+		DeviceID:  deviceID,
+		UserAgent: client.UserAgent,
+		IPAddress: client.IPAddress,
+		IsActive:  true,
+		LastSeen:  now,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.config.JWTRefreshExpiry),
+	}
+
+	if !client.UseSession {
+		if err := s.sessionRepo.Create(session); err != nil {
+			return nil, "", err
+		}
+		if err := s.indexSession(ctx, session); err != nil {
+			return nil, "", err
+		}
+		return session, "", nil
+	}
+
+	token, err := s.GenerateSessionToken()
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
-	
-	key := fmt.Sprintf("session:%s", sessionID)
-	err = s.redisClient.Set(ctx, key, userID.String(), s.config.SessionTimeout).Err()
+	session.TokenHash = hashSessionToken(token)
+	session.ExpiresAt = now.Add(s.config.SessionTimeout)
+
+	version, err := s.tokenService.CurrentTokenVersion(userID)
 	if err != nil {
-		return "", err
+		return nil, "", err
+	}
+
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, "", err
+	}
+
+	entry, err := json.Marshal(sessionEntry{SessionID: session.ID, UserID: userID, Version: version})
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := fmt.Sprintf("session:%s", session.TokenHash)
+	if err := s.redisClient.Set(ctx, key, entry, s.config.SessionTimeout).Err(); err != nil {
+		return nil, "", err
+	}
+
+	if err := s.indexSession(ctx, session); err != nil {
+		return nil, "", err
 	}
-	
-	return sessionID, nil
+
+	return session, token, nil
 }
 
-// ValidateSession checks if session exists and returns user ID
-func (s *SessionService) ValidateSession(sessionID string) (uuid.UUID, error) {
+// indexSession adds the session to its user's set so revoking every session
+// never has to scan the keyspace
+func (s *SessionService) indexSession(ctx context.Context, session *models.Session) error {
+	key := fmt.Sprintf("user_sessions:%s", session.UserID)
+	if err := s.redisClient.SAdd(ctx, key, session.ID.String()).Err(); err != nil {
+		return err
+	}
+	return s.redisClient.Expire(ctx, key, s.indexTTL()).Err()
+}
+
+// indexTTL outlasts the full lifetime of any session in the index, so
+// pushing the index expiry to it keeps every session it holds covered
+func (s *SessionService) indexTTL() time.Duration {
+	ttl := s.config.JWTRefreshExpiry
+	if s.config.SessionTimeout > ttl {
+		ttl = s.config.SessionTimeout
+	}
+	return ttl
+}
+
+// ValidateSession resolves a session token to its user and session IDs,
+// sliding the expiry forward on every successful check. Like access tokens,
+// sessions created before the user's token version was bumped are rejected.
+func (s *SessionService) ValidateSession(token string) (uuid.UUID, uuid.UUID, error) {
 	ctx := context.Background()
-	
-	key := fmt.Sprintf("session:%s", sessionID)
-	userIDStr, err := s.redisClient.Get(ctx, key).Result()
+
+	key := fmt.Sprintf("session:%s", hashSessionToken(token))
+	data, err := s.redisClient.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return uuid.Nil, fmt.Errorf("session not found")
+			return uuid.Nil, uuid.Nil, fmt.Errorf("session not found")
 		}
-		return uuid.Nil, err
+		return uuid.Nil, uuid.Nil, err
 	}
-	
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		return uuid.Nil, err
+
+	var entry sessionEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	// A version that can't be read, for a deleted user or during a database
+	// outage, fails closed
+	version, err := s.tokenService.TokenVersion(entry.UserID)
+	if err != nil || entry.Version < version {
+		return uuid.Nil, uuid.Nil, errors.New("session revoked")
 	}
-	
-	// Extend session expiry on access
+
+	// Extend session expiry on access, and the index's with it so revoking
+	// every session still finds this one
 	s.redisClient.Expire(ctx, key, s.config.SessionTimeout)
-	
-	return userID, nil
+	s.redisClient.Expire(ctx, fmt.Sprintf("user_sessions:%s", entry.UserID), s.indexTTL())
+
+	// Persist last_seen at most once per interval
+	touchKey := fmt.Sprintf("session_touch:%s", entry.SessionID)
+	if fresh, err := s.redisClient.SetNX(ctx, touchKey, "1", sessionTouchInterval).Result(); err == nil && fresh {
+		now := time.Now()
+		s.sessionRepo.Touch(entry.SessionID, now, now.Add(s.config.SessionTimeout))
+	}
+
+	return entry.UserID, entry.SessionID, nil
+}
+
+// IsKnownDevice reports whether the user has signed in from this device
+// before. Only the device ID counts: a user agent is shared by every
+// install of the same browser and is trivially copied.
+func (s *SessionService) IsKnownDevice(userID uuid.UUID, client *models.ClientInfo) (bool, error) {
+	if client.DeviceID == "" {
+		return false, nil
+	}
+	return s.sessionRepo.HasDevice(userID, client.DeviceID)
+}
+
+// TouchSession records activity on a JWT session after a token refresh
+func (s *SessionService) TouchSession(sessionID uuid.UUID) error {
+	now := time.Now()
+	return s.sessionRepo.Touch(sessionID, now, now.Add(s.config.JWTRefreshExpiry))
 }
 
-// RevokeSession deletes session from Redis
-func (s *SessionService) RevokeSession(sessionID string) error {
+// ListSessions returns the user's active sessions, flagging the current one
+func (s *SessionService) ListSessions(userID, currentSessionID uuid.UUID) ([]models.Session, error) {
+	sessions, err := s.sessionRepo.ListActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession deactivates the session row and kills every credential
+// issued to it: the cookie token, refresh tokens and live access tokens
+func (s *SessionService) RevokeSession(sessionID uuid.UUID) error {
 	ctx := context.Background()
-	key := fmt.Sprintf("session:%s", sessionID)
-	return s.redisClient.Del(ctx, key).Err()
+
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session.TokenHash != "" {
+		if err := s.redisClient.Del(ctx, fmt.Sprintf("session:%s", session.TokenHash)).Err(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.tokenService.RevokeSessionTokens(sessionID); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.Deactivate(sessionID); err != nil {
+		return err
+	}
+
+	s.redisClient.SRem(ctx, fmt.Sprintf("user_sessions:%s", session.UserID), sessionID.String())
+	return nil
 }
 
-// RevokeAllUserSessions revokes all sessions for a user
+// RevokeUserSession revokes a session on behalf of its owner
+func (s *SessionService) RevokeUserSession(userID, sessionID uuid.UUID) error {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	// Don't reveal other users' sessions
+	if session.UserID != userID || !session.IsActive {
+		return ErrSessionNotFound
+	}
+
+	return s.RevokeSession(sessionID)
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current session
+func (s *SessionService) RevokeOtherSessions(userID, currentSessionID uuid.UUID) (int, error) {
+	sessions, err := s.sessionRepo.ListActiveByUser(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == currentSessionID {
+			continue
+		}
+		if err := s.RevokeSession(session.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// RevokeAllUserSessions revokes every session in the user's index
 func (s *SessionService) RevokeAllUserSessions(userID uuid.UUID) error {
 	ctx := context.Background()
-	
-	pattern := "session:*"
-	keys, err := s.redisClient.Keys(ctx, pattern).Result()
+	key := fmt.Sprintf("user_sessions:%s", userID)
+
+	members, err := s.redisClient.SMembers(ctx, key).Result()
 	if err != nil {
 		return err
 	}
-	
-	for _, key := range keys {
-		storedUserID, err := s.redisClient.Get(ctx, key).Result()
+
+	for _, member := range members {
+		sessionID, err := uuid.Parse(member)
 		if err != nil {
 			continue
 		}
-		
-		if storedUserID == userID.String() {
-			s.redisClient.Del(ctx, key)
+
+		// Rows may already be gone, e.g. after account deletion
+		if err := s.RevokeSession(sessionID); err != nil && err != sql.ErrNoRows {
+			return err
 		}
 	}
-	
-	return nil
-}
\ No newline at end of file
+
+	return s.redisClient.Del(ctx, key).Err()
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
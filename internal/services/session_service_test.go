@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubSessionStore keeps keys in memory and expires them against a clock
+// the test moves forward
+type stubSessionStore struct {
+	now     time.Time
+	values  map[string]string
+	sets    map[string]map[string]bool
+	expires map[string]time.Time
+}
+
+func newStubSessionStore() *stubSessionStore {
+	return &stubSessionStore{
+		now:     time.Now(),
+		values:  map[string]string{},
+		sets:    map[string]map[string]bool{},
+		expires: map[string]time.Time{},
+	}
+}
+
+// advance moves the clock and drops the keys that expired meanwhile
+func (s *stubSessionStore) advance(d time.Duration) {
+	s.now = s.now.Add(d)
+	for key, at := range s.expires {
+		if !s.now.Before(at) {
+			s.del(key)
+		}
+	}
+}
+
+func (s *stubSessionStore) exists(key string) bool {
+	_, value := s.values[key]
+	_, set := s.sets[key]
+	return value || set
+}
+
+func (s *stubSessionStore) del(key string) bool {
+	existed := s.exists(key)
+	delete(s.values, key)
+	delete(s.sets, key)
+	delete(s.expires, key)
+	return existed
+}
+
+func (s *stubSessionStore) Get(ctx context.Context, key string) *redis.StringCmd {
+	value, ok := s.values[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+	return redis.NewStringResult(value, nil)
+}
+
+func (s *stubSessionStore) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	switch v := value.(type) {
+	case []byte:
+		s.values[key] = string(v)
+	case string:
+		s.values[key] = v
+	}
+	delete(s.expires, key)
+	if expiration > 0 {
+		s.expires[key] = s.now.Add(expiration)
+	}
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (s *stubSessionStore) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	if s.exists(key) {
+		return redis.NewBoolResult(false, nil)
+	}
+	s.Set(ctx, key, value, expiration)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (s *stubSessionStore) Expire(ctx context.Context, key string, expiration time.Duration) *redis.BoolCmd {
+	if !s.exists(key) {
+		return redis.NewBoolResult(false, nil)
+	}
+	s.expires[key] = s.now.Add(expiration)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (s *stubSessionStore) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	var deleted int64
+	for _, key := range keys {
+		if s.del(key) {
+			deleted++
+		}
+	}
+	return redis.NewIntResult(deleted, nil)
+}
+
+func (s *stubSessionStore) SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	if s.sets[key] == nil {
+		s.sets[key] = map[string]bool{}
+	}
+	for _, member := range members {
+		s.sets[key][member.(string)] = true
+	}
+	return redis.NewIntResult(int64(len(members)), nil)
+}
+
+func (s *stubSessionStore) SMembers(ctx context.Context, key string) *redis.StringSliceCmd {
+	members := []string{}
+	for member := range s.sets[key] {
+		members = append(members, member)
+	}
+	return redis.NewStringSliceResult(members, nil)
+}
+
+func (s *stubSessionStore) SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd {
+	for _, member := range members {
+		delete(s.sets[key], member.(string))
+	}
+	return redis.NewIntResult(int64(len(members)), nil)
+}
+
+func TestSessionService_RevokeAllFindsSlidSessions(t *testing.T) {
+	store := newStubSessionStore()
+	mockToken := new(MockTokenSvc)
+	mockToken.On("RevokeSessionTokens", mock.Anything).Return(nil)
+	mockToken.On("CurrentTokenVersion", mock.Anything).Return(0, nil)
+	mockToken.On("TokenVersion", mock.Anything).Return(0, nil)
+	service := &SessionService{
+		sessionRepo:  &stubSessionRepo{},
+		tokenService: mockToken,
+		redisClient:  store,
+		config:       &config.Config{SessionTimeout: time.Hour, JWTRefreshExpiry: 30 * time.Minute},
+	}
+
+	userID := uuid.New()
+	_, token, err := service.CreateSession(userID, &models.ClientInfo{UseSession: true})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	// Each check slides the session past the index's original expiry
+	for i := 0; i < 3; i++ {
+		store.advance(45 * time.Minute)
+		_, _, err := service.ValidateSession(token)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, service.RevokeAllUserSessions(userID))
+	_, _, err = service.ValidateSession(token)
+	assert.Error(t, err, "a slid session must still be revoked")
+	mockToken.AssertNumberOfCalls(t, "RevokeSessionTokens", 1)
+}
+
+func TestSessionService_ValidateSessionChecksTokenVersion(t *testing.T) {
+	mockToken := new(MockTokenSvc)
+	service := &SessionService{
+		sessionRepo:  &stubSessionRepo{},
+		tokenService: mockToken,
+		redisClient:  newStubSessionStore(),
+		config:       &config.Config{SessionTimeout: time.Hour, JWTRefreshExpiry: time.Hour},
+	}
+
+	userID := uuid.New()
+	mockToken.On("CurrentTokenVersion", userID).Return(2, nil).Once()
+	_, token, err := service.CreateSession(userID, &models.ClientInfo{UseSession: true})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	mockToken.On("TokenVersion", userID).Return(2, nil).Once()
+	gotUser, _, err := service.ValidateSession(token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, gotUser)
+
+	// Bumping the version, e.g. on a password change, ends the session
+	mockToken.On("TokenVersion", userID).Return(3, nil).Once()
+	_, _, err = service.ValidateSession(token)
+	assert.Error(t, err)
+
+	// As does a version that can't be read
+	mockToken.On("TokenVersion", userID).Return(0, assert.AnError).Once()
+	_, _, err = service.ValidateSession(token)
+	assert.Error(t, err)
+	mockToken.AssertExpectations(t)
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisThrottle counts attempts per key in fixed windows. The window starts
+// with the first attempt, so retrying while throttled does not extend it.
+type RedisThrottle struct {
+	redisClient *redis.Client
+}
+
+func NewRedisThrottle(redisClient *redis.Client) *RedisThrottle {
+	return &RedisThrottle{redisClient: redisClient}
+}
+
+// Allow counts an attempt against key and reports whether it is within limit
+func (t *RedisThrottle) Allow(key string, limit int, window time.Duration) (bool, error) {
+	ctx := context.Background()
+
+	pipe := t.redisClient.TxPipeline()
+	pipe.SetNX(ctx, key, 0, window)
+	count := pipe.Incr(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+
+	return count.Val() <= int64(limit), nil
+}
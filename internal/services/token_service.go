@@ -2,118 +2,335 @@ package services
 
 import (
     "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
     "errors"
     "fmt"
+    "strings"
     "time"
 
     "github.com/golang-jwt/jwt/v5"
     "github.com/google/uuid"
     "github.com/redis/go-redis/v9"
     "github.com/Flack74/go-auth-system/internal/config"
+    "github.com/Flack74/go-auth-system/internal/models"
+    "github.com/Flack74/go-auth-system/internal/repository"
 )
 
 type TokenService struct {
-    config      *config.Config
-    redisClient *redis.Client
+    config       *config.Config
+    keys         *KeySet
+    redisClient  *redis.Client
+    blacklist    revocationList
+    versions     repository.TokenVersionRepositoryInterface
+    versionCache *tokenVersionCache
+}
+
+// revocationList is where revoked tokens and sessions are blacklisted,
+// which validation checks before anything else
+type revocationList interface {
+    Exists(ctx context.Context, keys ...string) *redis.IntCmd
+}
+
+// Token types carried in the "type" claim
+const (
+    tokenTypeAccess    = "access"
+    tokenTypeRefresh   = "refresh"
+    tokenTypeClient    = "client"
+    // tokenTypeDelegated is a user's access token held by an OAuth client
+    tokenTypeDelegated = "delegated"
+)
+
+// OAuthGrant is the client a delegated token was issued to and the scope the
+// user consented to. The zero value is a first-party grant.
+type OAuthGrant struct {
+    ClientID string
+    Scope    string
 }
 
 type TokenClaims struct {
-    UserID uuid.UUID `json:"user_id"`
-    Type   string    `json:"type"`
+    UserID    uuid.UUID `json:"user_id"`
+    SessionID uuid.UUID `json:"sid"`
+    Type      string    `json:"type"`
+    ClientID  string    `json:"client_id,omitempty"`
+    Scope     string    `json:"scope,omitempty"`
+    // Version is the user's token version when an access token was issued
+    Version   int       `json:"ver,omitempty"`
     jwt.RegisteredClaims
 }
 
-func NewTokenService(config *config.Config, redisClient *redis.Client) *TokenService {
+// Principal describes who the token was issued to
+func (c *TokenClaims) Principal() *models.Principal {
+    switch c.Type {
+    case tokenTypeClient:
+        return &models.Principal{ClientID: c.ClientID, Scopes: strings.Fields(c.Scope)}
+    case tokenTypeDelegated:
+        return &models.Principal{UserID: c.UserID, ClientID: c.ClientID, Scopes: strings.Fields(c.Scope)}
+    }
+    return &models.Principal{UserID: c.UserID}
+}
+
+func NewTokenService(config *config.Config, keys *KeySet, redisClient *redis.Client, versions repository.TokenVersionRepositoryInterface) *TokenService {
     return &TokenService{
-        config:      config,
-        redisClient: redisClient,
+        config:       config,
+        keys:         keys,
+        redisClient:  redisClient,
+        blacklist:    redisClient,
+        versions:     versions,
+        versionCache: newTokenVersionCache(tokenVersionTTL),
     }
 }
 
-func (s *TokenService) GenerateAccessToken(userID uuid.UUID) (string, error) {
+// JWKS returns the public keys that verify tokens issued by this service
+func (s *TokenService) JWKS() JWKSet {
+    return s.keys.JWKS()
+}
+
+// SigningAlg is the algorithm advertised for ID tokens
+func (s *TokenService) SigningAlg() string {
+    return s.keys.Alg()
+}
+
+func (s *TokenService) parseClaims(tokenString string) (*jwt.Token, error) {
+    return jwt.ParseWithClaims(tokenString, &TokenClaims{}, s.keys.Keyfunc, jwt.WithValidMethods(s.keys.ValidMethods()))
+}
+
+func (s *TokenService) GenerateAccessToken(userID, sessionID uuid.UUID) (string, error) {
+    return s.generateUserToken(userID, sessionID, OAuthGrant{})
+}
+
+// GenerateDelegatedAccessToken issues an access token for a user that only
+// the OAuth client in grant may use, limited to the consented scope
+func (s *TokenService) GenerateDelegatedAccessToken(userID, sessionID uuid.UUID, grant OAuthGrant) (string, error) {
+    return s.generateUserToken(userID, sessionID, grant)
+}
+
+func (s *TokenService) generateUserToken(userID, sessionID uuid.UUID, grant OAuthGrant) (string, error) {
+    version, err := s.CurrentTokenVersion(userID)
+    if err != nil {
+        return "", err
+    }
+
     claims := TokenClaims{
-        UserID: userID,
-        Type:   "access",
+        UserID:    userID,
+        SessionID: sessionID,
+        Type:      tokenTypeAccess,
+        Version:   version,
         RegisteredClaims: jwt.RegisteredClaims{
             ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.config.JWTAccessExpiry)),
             IssuedAt:  jwt.NewNumericDate(time.Now()),
             ID:        uuid.New().String(),
         },
     }
+    if grant.ClientID != "" {
+        claims.Type = tokenTypeDelegated
+        claims.ClientID = grant.ClientID
+        claims.Scope = grant.Scope
+    }
+
+    return s.keys.Sign(claims)
+}
+
+func (s *TokenService) GenerateRefreshToken(userID, sessionID uuid.UUID) (string, error) {
+    return s.issueRefreshToken(uuid.New().String(), refreshTokenRecord{UserID: userID, SessionID: sessionID})
+}
 
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    return token.SignedString([]byte(s.config.JWTSecret))
+// GenerateDelegatedRefreshToken issues a refresh token that only the OAuth
+// client in grant may redeem. Its successors keep the same client and scope.
+func (s *TokenService) GenerateDelegatedRefreshToken(userID, sessionID uuid.UUID, grant OAuthGrant) (string, error) {
+    return s.issueRefreshToken(uuid.New().String(), refreshTokenRecord{
+        UserID:    userID,
+        SessionID: sessionID,
+        ClientID:  grant.ClientID,
+        Scope:     grant.Scope,
+    })
 }
 
-func (s *TokenService) GenerateRefreshToken(userID uuid.UUID) (string, error) {
-    tokenID := uuid.New().String()
+// refreshTokenRecord is the Redis value for a live refresh token. ParentID
+// links a rotated token to the one it replaced; ClientID and Scope are set
+// for tokens issued to an OAuth client.
+type refreshTokenRecord struct {
+    UserID    uuid.UUID `json:"user_id"`
+    SessionID uuid.UUID `json:"session_id"`
+    ParentID  string    `json:"parent_id,omitempty"`
+    ClientID  string    `json:"client_id,omitempty"`
+    Scope     string    `json:"scope,omitempty"`
+}
+
+// rotationRecord is left behind when a refresh token is rotated so a later
+// replay of it can be told apart from an unknown token
+type rotationRecord struct {
+    ChildID   string    `json:"child_id"`
+    RotatedAt time.Time `json:"rotated_at"`
+}
+
+// signRefreshToken signs a refresh token with the given ID. Whether it is
+// live is decided by its Redis record, not the signature.
+func (s *TokenService) signRefreshToken(tokenID string, record refreshTokenRecord) (string, error) {
     claims := TokenClaims{
-        UserID: userID,
-        Type:   "refresh",
+        UserID:    record.UserID,
+        SessionID: record.SessionID,
+        Type:      tokenTypeRefresh,
+        ClientID:  record.ClientID,
+        Scope:     record.Scope,
         RegisteredClaims: jwt.RegisteredClaims{
             ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.config.JWTRefreshExpiry)),
             IssuedAt:  jwt.NewNumericDate(time.Now()),
             ID:        tokenID,
         },
     }
+    return s.keys.Sign(claims)
+}
+
+func (s *TokenService) issueRefreshToken(tokenID string, record refreshTokenRecord) (string, error) {
+    tokenString, err := s.signRefreshToken(tokenID, record)
+    if err != nil {
+        return "", err
+    }
 
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    tokenString, err := token.SignedString([]byte(s.config.JWTSecret))
+    data, err := json.Marshal(record)
     if err != nil {
         return "", err
     }
 
+    userID, sessionID := record.UserID, record.SessionID
+
     // Store in Redis for revocation checking
     ctx := context.Background()
     key := fmt.Sprintf("refresh_token:%s", tokenID)
-    err = s.redisClient.Set(ctx, key, userID.String(), s.config.JWTRefreshExpiry).Err()
+    err = s.redisClient.Set(ctx, key, data, s.config.JWTRefreshExpiry).Err()
     if err != nil {
         return "", err
     }
 
+    // Index the token under its user so every device can be signed out at once
+    userKey := fmt.Sprintf("user_refresh_tokens:%s", userID)
+    if err := s.redisClient.SAdd(ctx, userKey, tokenID).Err(); err != nil {
+        return "", err
+    }
+    s.redisClient.Expire(ctx, userKey, s.config.JWTRefreshExpiry)
+
+    // Track the token under its session so revoking the session can find it
+    if sessionID != uuid.Nil {
+        sessionKey := fmt.Sprintf("session_refresh_tokens:%s", sessionID)
+        if err := s.redisClient.SAdd(ctx, sessionKey, tokenID).Err(); err != nil {
+            return "", err
+        }
+        s.redisClient.Expire(ctx, sessionKey, s.config.JWTRefreshExpiry)
+    }
+
     return tokenString, nil
 }
 
-func (s *TokenService) ValidateAccessToken(tokenString string) (uuid.UUID, error) {
-    token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-        return []byte(s.config.JWTSecret), nil
-    })
+// GenerateClientToken issues an access token whose subject is a machine
+// client rather than a user. It carries no session and cannot be refreshed.
+func (s *TokenService) GenerateClientToken(clientID string, scopes []string) (string, error) {
+    now := time.Now()
+    claims := TokenClaims{
+        Type:     tokenTypeClient,
+        ClientID: clientID,
+        Scope:    strings.Join(scopes, " "),
+        RegisteredClaims: jwt.RegisteredClaims{
+            Subject:   clientID,
+            ExpiresAt: jwt.NewNumericDate(now.Add(s.config.JWTAccessExpiry)),
+            IssuedAt:  jwt.NewNumericDate(now),
+            ID:        uuid.New().String(),
+        },
+    }
+
+    return s.keys.Sign(claims)
+}
+
+// ValidateAccessToken accepts only first-party user access tokens. Tokens
+// held by OAuth clients are rejected: they are limited to a scope.
+func (s *TokenService) ValidateAccessToken(tokenString string) (*TokenClaims, error) {
+    return s.validateBearer(tokenString, tokenTypeAccess)
+}
+
+// ValidatePrincipalToken accepts user access tokens as well as client and
+// delegated tokens, whose scope the caller must check
+func (s *TokenService) ValidatePrincipalToken(tokenString string) (*TokenClaims, error) {
+    return s.validateBearer(tokenString, tokenTypeAccess, tokenTypeClient, tokenTypeDelegated)
+}
+
+func (s *TokenService) validateBearer(tokenString string, types ...string) (*TokenClaims, error) {
+    token, err := s.parseClaims(tokenString)
 
     if err != nil {
-        return uuid.Nil, err
+        return nil, err
     }
 
     claims, ok := token.Claims.(*TokenClaims)
-    if !ok || !token.Valid || claims.Type != "access" {
-        return uuid.Nil, errors.New("invalid token")
+    if !ok || !token.Valid || !containsString(types, claims.Type) {
+        return nil, errors.New("invalid token")
     }
 
-    // Check if token is blacklisted
+    // Check if the token or the session it belongs to is blacklisted
     ctx := context.Background()
-    key := fmt.Sprintf("blacklist:access:%s", claims.ID)
-    exists, err := s.redisClient.Exists(ctx, key).Result()
+    keys := []string{fmt.Sprintf("blacklist:access:%s", claims.ID)}
+    if claims.SessionID != uuid.Nil {
+        keys = append(keys, fmt.Sprintf("revoked_session:%s", claims.SessionID))
+    }
+    exists, err := s.blacklist.Exists(ctx, keys...).Result()
     if err != nil {
-        return uuid.Nil, err
+        return nil, err
     }
     if exists > 0 {
-        return uuid.Nil, errors.New("token revoked")
+        return nil, errors.New("token revoked")
     }
 
-    return claims.UserID, nil
+    // Reject user tokens issued before the user's version was bumped. A
+    // version that can't be read, for a deleted user or during a database
+    // outage, fails authentication rather than the request.
+    if claims.Type == tokenTypeAccess || claims.Type == tokenTypeDelegated {
+        version, err := s.TokenVersion(claims.UserID)
+        if err != nil || claims.Version < version {
+            return nil, errors.New("token revoked")
+        }
+    }
+
+    return claims, nil
 }
 
-func (s *TokenService) ValidateRefreshToken(tokenString string) (uuid.UUID, error) {
-    token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-        return []byte(s.config.JWTSecret), nil
-    })
+// CurrentTokenVersion reads the user's token version for a credential about
+// to be issued. It skips the cache, which may be stale on this instance.
+func (s *TokenService) CurrentTokenVersion(userID uuid.UUID) (int, error) {
+    version, err := s.versions.GetTokenVersion(userID)
+    if err != nil {
+        return 0, err
+    }
+    s.versionCache.set(userID, version)
+    return version, nil
+}
 
+// TokenVersion returns the user's current token version, from the cache
+// when it was read recently
+func (s *TokenService) TokenVersion(userID uuid.UUID) (int, error) {
+    if version, ok := s.versionCache.get(userID); ok {
+        return version, nil
+    }
+    version, err := s.versions.GetTokenVersion(userID)
     if err != nil {
-        return uuid.Nil, err
+        return 0, err
     }
+    s.versionCache.set(userID, version)
+    return version, nil
+}
 
-    claims, ok := token.Claims.(*TokenClaims)
-    if !ok || !token.Valid || claims.Type != "refresh" {
-        return uuid.Nil, errors.New("invalid refresh token")
+func containsString(values []string, want string) bool {
+    for _, v := range values {
+        if v == want {
+            return true
+        }
+    }
+    return false
+}
+
+func (s *TokenService) ValidateRefreshToken(tokenString string) (*TokenClaims, error) {
+    claims, err := s.parseRefreshToken(tokenString)
+    if err != nil {
+        return nil, err
     }
 
     // Check if token exists in Redis
@@ -121,19 +338,126 @@ func (s *TokenService) ValidateRefreshToken(tokenString string) (uuid.UUID, erro
     key := fmt.Sprintf("refresh_token:%s", claims.ID)
     exists, err := s.redisClient.Exists(ctx, key).Result()
     if err != nil {
-        return uuid.Nil, err
+        return nil, err
     }
     if exists == 0 {
-        return uuid.Nil, errors.New("refresh token not found or expired")
+        return nil, errors.New("refresh token not found or expired")
     }
 
-    return claims.UserID, nil
+    return claims, nil
 }
 
-func (s *TokenService) RevokeAccessToken(tokenString string) error {
-    token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-        return []byte(s.config.JWTSecret), nil
+// RotateRefreshToken exchanges a refresh token for its successor in the same
+// family. A token presented again inside the grace window for concurrent
+// refreshes gets the successor it was already rotated to, or
+// ErrRefreshTokenRotated if that has since been rotated too. Later it returns
+// ErrRefreshTokenReused. The claims are returned either way. Only the OAuth
+// client the token was issued to may rotate it; clientID is empty for
+// first-party tokens.
+func (s *TokenService) RotateRefreshToken(tokenString, clientID string) (*TokenClaims, string, error) {
+    claims, err := s.parseRefreshToken(tokenString)
+    if err != nil {
+        return nil, "", err
+    }
+    if claims.ClientID != clientID {
+        return nil, "", ErrRefreshTokenClient
+    }
+
+    ctx := context.Background()
+    key := fmt.Sprintf("refresh_token:%s", claims.ID)
+    rotatedKey := fmt.Sprintf("refresh_rotated:%s", claims.ID)
+
+    // Deleting the live record is the atomic claim on this rotation
+    deleted, err := s.redisClient.Del(ctx, key).Result()
+    if err != nil {
+        return nil, "", err
+    }
+    if deleted == 0 {
+        data, err := s.redisClient.Get(ctx, rotatedKey).Result()
+        if err != nil {
+            if err == redis.Nil {
+                return nil, "", errors.New("refresh token not found or expired")
+            }
+            return nil, "", err
+        }
+
+        var rotation rotationRecord
+        if err := json.Unmarshal([]byte(data), &rotation); err != nil {
+            return nil, "", err
+        }
+        if time.Since(rotation.RotatedAt) > s.config.RefreshReuseGrace {
+            return claims, "", ErrRefreshTokenReused
+        }
+
+        // Hand the concurrent caller the same successor
+        live, err := s.redisClient.Exists(ctx, fmt.Sprintf("refresh_token:%s", rotation.ChildID)).Result()
+        if err != nil {
+            return nil, "", err
+        }
+        if live == 0 {
+            return claims, "", ErrRefreshTokenRotated
+        }
+        childToken, err := s.signRefreshToken(rotation.ChildID, refreshTokenRecord{
+            UserID:    claims.UserID,
+            SessionID: claims.SessionID,
+            ClientID:  claims.ClientID,
+            Scope:     claims.Scope,
+        })
+        if err != nil {
+            return nil, "", err
+        }
+        return claims, childToken, nil
+    }
+
+    s.redisClient.SRem(ctx, fmt.Sprintf("user_refresh_tokens:%s", claims.UserID), claims.ID)
+    if claims.SessionID != uuid.Nil {
+        s.redisClient.SRem(ctx, fmt.Sprintf("session_refresh_tokens:%s", claims.SessionID), claims.ID)
+    }
+
+    // Record the parent -> child link before the child exists, so a replay
+    // racing this rotation is already recognised
+    childID := uuid.New().String()
+    rotation, err := json.Marshal(rotationRecord{ChildID: childID, RotatedAt: time.Now()})
+    if err != nil {
+        return nil, "", err
+    }
+    if ttl := time.Until(claims.ExpiresAt.Time); ttl > 0 {
+        if err := s.redisClient.Set(ctx, rotatedKey, rotation, ttl).Err(); err != nil {
+            return nil, "", err
+        }
+    }
+
+    newToken, err := s.issueRefreshToken(childID, refreshTokenRecord{
+        UserID:    claims.UserID,
+        SessionID: claims.SessionID,
+        ParentID:  claims.ID,
+        ClientID:  claims.ClientID,
+        Scope:     claims.Scope,
     })
+    if err != nil {
+        return nil, "", err
+    }
+
+    return claims, newToken, nil
+}
+
+func (s *TokenService) parseRefreshToken(tokenString string) (*TokenClaims, error) {
+    token, err := s.parseClaims(tokenString)
+
+    if err != nil {
+        return nil, err
+    }
+
+    claims, ok := token.Claims.(*TokenClaims)
+    if !ok || !token.Valid || claims.Type != tokenTypeRefresh {
+        return nil, errors.New("invalid refresh token")
+    }
+
+    return claims, nil
+}
+
+func (s *TokenService) RevokeAccessToken(tokenString string) error {
+    token, err := s.parseClaims(tokenString)
 
     if err != nil {
         return err
@@ -155,9 +479,7 @@ func (s *TokenService) RevokeAccessToken(tokenString string) error {
 }
 
 func (s *TokenService) RevokeRefreshToken(tokenString string) error {
-    token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-        return []byte(s.config.JWTSecret), nil
-    })
+    token, err := s.parseClaims(tokenString)
 
     if err != nil {
         return err
@@ -171,5 +493,178 @@ func (s *TokenService) RevokeRefreshToken(tokenString string) error {
     // Remove from Redis
     ctx := context.Background()
     key := fmt.Sprintf("refresh_token:%s", claims.ID)
-    return s.redisClient.Del(ctx, key).Err()
+    if err := s.redisClient.Del(ctx, key).Err(); err != nil {
+        return err
+    }
+
+    s.redisClient.SRem(ctx, fmt.Sprintf("user_refresh_tokens:%s", claims.UserID), claims.ID)
+    if claims.SessionID != uuid.Nil {
+        s.redisClient.SRem(ctx, fmt.Sprintf("session_refresh_tokens:%s", claims.SessionID), claims.ID)
+    }
+    return nil
+}
+
+// RevokeAllUserTokens deletes every refresh token issued to the user. The
+// per-user index keeps this proportional to the user's own tokens.
+func (s *TokenService) RevokeAllUserTokens(userID uuid.UUID) error {
+    ctx := context.Background()
+    userKey := fmt.Sprintf("user_refresh_tokens:%s", userID)
+
+    tokenIDs, err := s.redisClient.SMembers(ctx, userKey).Result()
+    if err != nil {
+        return err
+    }
+
+    keys := []string{userKey}
+    for _, tokenID := range tokenIDs {
+        keys = append(keys, fmt.Sprintf("refresh_token:%s", tokenID))
+    }
+    return s.redisClient.Del(ctx, keys...).Err()
+}
+
+// RevokeUserAccessTokens bumps the user's token version, rejecting every
+// access token issued to them so far, whichever session it belongs to. Other
+// instances notice once their cached version expires.
+func (s *TokenService) RevokeUserAccessTokens(userID uuid.UUID) error {
+    version, err := s.versions.BumpTokenVersion(userID)
+    if err != nil {
+        return err
+    }
+    s.versionCache.set(userID, version)
+    return nil
+}
+
+// RevokeSessionTokens deletes every refresh token issued to a session and
+// rejects its outstanding access tokens until they would have expired anyway
+func (s *TokenService) RevokeSessionTokens(sessionID uuid.UUID) error {
+    ctx := context.Background()
+    sessionKey := fmt.Sprintf("session_refresh_tokens:%s", sessionID)
+
+    tokenIDs, err := s.redisClient.SMembers(ctx, sessionKey).Result()
+    if err != nil {
+        return err
+    }
+
+    keys := []string{sessionKey}
+    for _, tokenID := range tokenIDs {
+        keys = append(keys, fmt.Sprintf("refresh_token:%s", tokenID))
+    }
+    if err := s.redisClient.Del(ctx, keys...).Err(); err != nil {
+        return err
+    }
+
+    key := fmt.Sprintf("revoked_session:%s", sessionID)
+    return s.redisClient.Set(ctx, key, "revoked", s.config.JWTAccessExpiry).Err()
+}
+
+// maxMFAAttempts bounds how many wrong codes a single challenge accepts
+const maxMFAAttempts = 5
+
+// GenerateMFAChallenge issues a short-lived, single-use token proving the
+// password step succeeded for userID
+func (s *TokenService) GenerateMFAChallenge(userID uuid.UUID) (string, error) {
+    bytes := make([]byte, 32)
+    if _, err := rand.Read(bytes); err != nil {
+        return "", err
+    }
+    challenge := hex.EncodeToString(bytes)
+
+    ctx := context.Background()
+    key := fmt.Sprintf("mfa_challenge:%s", challenge)
+    if err := s.redisClient.Set(ctx, key, userID.String(), s.config.MFAChallengeExpiry).Err(); err != nil {
+        return "", err
+    }
+
+    return challenge, nil
+}
+
+// ValidateMFAChallenge returns the user the challenge was issued to
+func (s *TokenService) ValidateMFAChallenge(challenge string) (uuid.UUID, error) {
+    ctx := context.Background()
+    key := fmt.Sprintf("mfa_challenge:%s", challenge)
+    userIDStr, err := s.redisClient.Get(ctx, key).Result()
+    if err != nil {
+        if err == redis.Nil {
+            return uuid.Nil, ErrInvalidMFAChallenge
+        }
+        return uuid.Nil, err
+    }
+
+    userID, err := uuid.Parse(userIDStr)
+    if err != nil {
+        return uuid.Nil, ErrInvalidMFAChallenge
+    }
+    return userID, nil
+}
+
+// FailMFAChallenge records a wrong code and burns the challenge after maxMFAAttempts
+func (s *TokenService) FailMFAChallenge(challenge string) error {
+    ctx := context.Background()
+    attemptsKey := fmt.Sprintf("mfa_challenge_attempts:%s", challenge)
+
+    attempts, err := s.redisClient.Incr(ctx, attemptsKey).Result()
+    if err != nil {
+        return err
+    }
+    s.redisClient.Expire(ctx, attemptsKey, s.config.MFAChallengeExpiry)
+
+    if attempts >= maxMFAAttempts {
+        return s.redisClient.Del(ctx, fmt.Sprintf("mfa_challenge:%s", challenge), attemptsKey).Err()
+    }
+    return nil
+}
+
+// ConsumeMFAChallenge deletes the challenge; only the first caller succeeds
+func (s *TokenService) ConsumeMFAChallenge(challenge string) error {
+    ctx := context.Background()
+    deleted, err := s.redisClient.Del(ctx, fmt.Sprintf("mfa_challenge:%s", challenge)).Result()
+    if err != nil {
+        return err
+    }
+    if deleted == 0 {
+        return ErrInvalidMFAChallenge
+    }
+
+    s.redisClient.Del(ctx, fmt.Sprintf("mfa_challenge_attempts:%s", challenge))
+    return nil
+}
+
+// IDTokenClaims are the OIDC claims we assert about a user
+type IDTokenClaims struct {
+    Email         string `json:"email,omitempty"`
+    EmailVerified *bool  `json:"email_verified,omitempty"`
+    Nonce         string `json:"nonce,omitempty"`
+    AuthTime      int64  `json:"auth_time,omitempty"`
+    jwt.RegisteredClaims
+}
+
+// GenerateIDToken issues an OIDC ID token for user, audience-restricted to
+// the client. Email claims are only included when the email scope was granted.
+// The keyset must be asymmetric so relying parties never need our secret.
+func (s *TokenService) GenerateIDToken(user *models.User, clientID, nonce string, authTime time.Time, includeEmail bool) (string, error) {
+    if !s.keys.Asymmetric() {
+        return "", ErrSymmetricKeySet
+    }
+
+    now := time.Now()
+    claims := IDTokenClaims{
+        Nonce:    nonce,
+        AuthTime: authTime.Unix(),
+        RegisteredClaims: jwt.RegisteredClaims{
+            Issuer:    s.config.OIDCIssuer,
+            Subject:   user.ID.String(),
+            Audience:  jwt.ClaimStrings{clientID},
+            ExpiresAt: jwt.NewNumericDate(now.Add(s.config.JWTAccessExpiry)),
+            IssuedAt:  jwt.NewNumericDate(now),
+            ID:        uuid.New().String(),
+        },
+    }
+
+    if includeEmail {
+        verified := user.EmailVerified
+        claims.Email = user.Email
+        claims.EmailVerified = &verified
+    }
+
+    return s.keys.Sign(claims)
 }
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// tokenVersionTTL bounds how long another instance keeps accepting tokens
+// after a user's version is bumped; the bumping instance sees it at once
+const (
+	tokenVersionTTL       = 5 * time.Second
+	tokenVersionCacheSize = 10000
+)
+
+type tokenVersionEntry struct {
+	version   int
+	expiresAt time.Time
+}
+
+// tokenVersionCache keeps recently read token versions in process so that
+// validating a token doesn't cost a database round trip
+type tokenVersionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[uuid.UUID]tokenVersionEntry
+}
+
+func newTokenVersionCache(ttl time.Duration) *tokenVersionCache {
+	return &tokenVersionCache{
+		ttl:     ttl,
+		entries: map[uuid.UUID]tokenVersionEntry{},
+	}
+}
+
+func (c *tokenVersionCache) get(userID uuid.UUID) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return 0, false
+	}
+	return entry.version, true
+}
+
+func (c *tokenVersionCache) set(userID uuid.UUID, version int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= tokenVersionCacheSize {
+		for id, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+		// Every entry is live: start over rather than grow without bound
+		if len(c.entries) >= tokenVersionCacheSize {
+			c.entries = map[uuid.UUID]tokenVersionEntry{}
+		}
+	}
+	c.entries[userID] = tokenVersionEntry{version: version, expiresAt: now.Add(c.ttl)}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// stubTokenVersionRepo counts reads so tests can see the cache at work
+type stubTokenVersionRepo struct {
+	versions map[uuid.UUID]int
+	reads    int
+}
+
+func (r *stubTokenVersionRepo) GetTokenVersion(userID uuid.UUID) (int, error) {
+	r.reads++
+	version, ok := r.versions[userID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return version, nil
+}
+
+func (r *stubTokenVersionRepo) BumpTokenVersion(userID uuid.UUID) (int, error) {
+	if _, ok := r.versions[userID]; !ok {
+		return 0, sql.ErrNoRows
+	}
+	r.versions[userID]++
+	return r.versions[userID], nil
+}
+
+// stubBlacklist reports revoked of the keys looked up as blacklisted
+type stubBlacklist struct {
+	revoked int64
+}
+
+func (b stubBlacklist) Exists(ctx context.Context, keys ...string) *redis.IntCmd {
+	return redis.NewIntResult(b.revoked, nil)
+}
+
+// newVersionedTokenService is a token service whose only Redis use,
+// the blacklist check, is answered by blacklist
+func newVersionedTokenService(keys *KeySet, blacklist revocationList, versions repository.TokenVersionRepositoryInterface) *TokenService {
+	tokenService := NewTokenService(&config.Config{JWTAccessExpiry: time.Minute}, keys, nil, versions)
+	tokenService.blacklist = blacklist
+	return tokenService
+}
+
+func TestTokenService_VersionRevokesAccessTokens(t *testing.T) {
+	userID := uuid.New()
+	versions := &stubTokenVersionRepo{versions: map[uuid.UUID]int{userID: 3}}
+	tokenService := newVersionedTokenService(NewHMACKeySet(strings.Repeat("k", 32)), stubBlacklist{}, versions)
+
+	tokenString, err := tokenService.GenerateAccessToken(userID, uuid.New())
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	token, err := tokenService.parseClaims(tokenString)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if claims := token.Claims.(*TokenClaims); claims.Version != 3 {
+		t.Fatalf("expected version 3 in the claims, got %d", claims.Version)
+	}
+
+	// Issuing refreshes the cache, so checking the version needs no read
+	versions.reads = 0
+	if version, err := tokenService.TokenVersion(userID); err != nil || version != 3 || versions.reads != 0 {
+		t.Fatalf("expected cached version 3, got %d (%v) after %d reads", version, err, versions.reads)
+	}
+
+	// Bumping the version rejects the token without a database read
+	if err := tokenService.RevokeUserAccessTokens(userID); err != nil {
+		t.Fatalf("RevokeUserAccessTokens: %v", err)
+	}
+	if _, err := tokenService.ValidateAccessToken(tokenString); err == nil || err.Error() != "token revoked" {
+		t.Fatalf("expected the token to be revoked, got %v", err)
+	}
+	if versions.reads != 0 {
+		t.Fatalf("expected the bumped version to be cached, got %d reads", versions.reads)
+	}
+
+	// Tokens of deleted users are rejected too
+	delete(versions.versions, userID)
+	tokenService.versionCache = newTokenVersionCache(tokenVersionTTL)
+	if _, err := tokenService.ValidateAccessToken(tokenString); err == nil || err.Error() != "token revoked" {
+		t.Fatalf("expected the token to be revoked, got %v", err)
+	}
+}
+
+func TestTokenService_ValidateChecksBlacklistFirst(t *testing.T) {
+	userID := uuid.New()
+	versions := &stubTokenVersionRepo{versions: map[uuid.UUID]int{userID: 1}}
+	keys := NewHMACKeySet(strings.Repeat("k", 32))
+
+	issuer := newVersionedTokenService(keys, stubBlacklist{}, versions)
+	tokenString, err := issuer.GenerateAccessToken(userID, uuid.New())
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	// A blacklisted token is rejected without reading the version
+	revoked := newVersionedTokenService(keys, stubBlacklist{revoked: 1}, versions)
+	versions.reads = 0
+	if _, err := revoked.ValidateAccessToken(tokenString); err == nil || err.Error() != "token revoked" {
+		t.Fatalf("expected the token to be revoked, got %v", err)
+	}
+	if versions.reads != 0 {
+		t.Fatalf("expected no version read, got %d", versions.reads)
+	}
+
+	// A version that can't be read fails authentication, not the request
+	failing := newVersionedTokenService(keys, stubBlacklist{}, failingTokenVersionRepo{})
+	if _, err := failing.ValidateAccessToken(tokenString); err == nil || err.Error() != "token revoked" {
+		t.Fatalf("expected an authentication failure, got %v", err)
+	}
+}
+
+// failingTokenVersionRepo stands in for an unreachable database
+type failingTokenVersionRepo struct{}
+
+func (failingTokenVersionRepo) GetTokenVersion(uuid.UUID) (int, error) {
+	return 0, errors.New("connection refused")
+}
+
+func (failingTokenVersionRepo) BumpTokenVersion(uuid.UUID) (int, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestTokenVersionCache_Expires(t *testing.T) {
+	cache := newTokenVersionCache(time.Millisecond)
+	userID := uuid.New()
+
+	cache.set(userID, 2)
+	if version, ok := cache.get(userID); !ok || version != 2 {
+		t.Fatalf("expected version 2, got %d (%v)", version, ok)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.get(userID); ok {
+		t.Fatal("expected the entry to expire")
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/config"
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrTOTPNotEnabled     = errors.New("two-factor authentication not enabled")
+	ErrTOTPNotSetup       = errors.New("two-factor authentication setup not started")
+	ErrInvalidTOTPCode    = errors.New("invalid two-factor code")
+)
+
+type TOTPService struct {
+	userRepo       repository.UserRepositoryInterface
+	backupCodeRepo repository.BackupCodeRepositoryInterface
+	redisClient    *redis.Client
+	config         *config.Config
+}
+
+func NewTOTPService(userRepo repository.UserRepositoryInterface, backupCodeRepo repository.BackupCodeRepositoryInterface, redisClient *redis.Client, config *config.Config) *TOTPService {
+	return &TOTPService{
+		userRepo:       userRepo,
+		backupCodeRepo: backupCodeRepo,
+		redisClient:    redisClient,
+		config:         config,
+	}
+}
+
+// Setup generates a new pending secret and recovery codes.
+// 2FA stays disabled until Enable confirms a code.
+func (s *TOTPService) Setup(userID uuid.UUID) (*models.TOTPSetupResponse, error) {
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetTOTPSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	backupCodes, err := s.issueBackupCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TOTPSetupResponse{
+		Secret:      secret,
+		QRCode:      utils.TOTPProvisioningURI(s.config.TOTPIssuer, user.Email, secret),
+		BackupCodes: backupCodes,
+	}, nil
+}
+
+// Enable confirms enrollment with a code from the authenticator app
+func (s *TOTPService) Enable(userID uuid.UUID, code string) error {
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
+		return err
+	}
+
+	if user.TOTPEnabled {
+		return ErrTOTPAlreadyEnabled
+	}
+	if !user.TOTPSecret.Valid {
+		return ErrTOTPNotSetup
+	}
+
+	if err := s.VerifyCode(user, code); err != nil {
+		return err
+	}
+
+	return s.userRepo.EnableTOTP(user.ID)
+}
+
+// Disable turns off 2FA after re-checking the account password
+func (s *TOTPService) Disable(userID uuid.UUID, password string) error {
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+
+	if err := utils.CheckPassword(password, user.Password); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	if err := s.userRepo.DisableTOTP(user.ID); err != nil {
+		return err
+	}
+
+	return s.backupCodeRepo.DeleteForUser(user.ID)
+}
+
+// RegenerateBackupCodes invalidates every outstanding recovery code and issues a new set.
+// A current TOTP code is required so a stolen session alone cannot mint codes.
+func (s *TOTPService) RegenerateBackupCodes(userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(userID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.TOTPEnabled {
+		return nil, ErrTOTPNotEnabled
+	}
+
+	if err := s.VerifyCode(user, code); err != nil {
+		return nil, err
+	}
+
+	return s.issueBackupCodes(user.ID)
+}
+
+// BackupCodesRemaining returns how many unused recovery codes the user has left
+func (s *TOTPService) BackupCodesRemaining(userID uuid.UUID) (int, error) {
+	return s.backupCodeRepo.CountUnused(userID)
+}
+
+// VerifyLoginCode accepts either a TOTP code or an unused recovery code
+func (s *TOTPService) VerifyLoginCode(user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == utils.TOTPDigits {
+		return s.VerifyCode(user, code)
+	}
+
+	consumed, err := s.backupCodeRepo.Consume(user.ID, utils.HashBackupCode(code))
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidTOTPCode
+	}
+
+	return nil
+}
+
+// VerifyCode checks a TOTP code against the user's stored secret.
+// Each time step is accepted once per user so an observed code cannot be replayed.
+func (s *TOTPService) VerifyCode(user *models.User, code string) error {
+	if !user.TOTPSecret.Valid {
+		return ErrTOTPNotSetup
+	}
+
+	step, err := utils.ValidateTOTPCode(user.TOTPSecret.String, code, time.Now())
+	if err != nil {
+		return ErrInvalidTOTPCode
+	}
+
+	ctx := context.Background()
+	key := fmt.Sprintf("totp_used:%s:%d", user.ID, step)
+	window := time.Duration(2*utils.TOTPSkew+1) * utils.TOTPPeriod
+	fresh, err := s.redisClient.SetNX(ctx, key, "1", window).Result()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidTOTPCode
+	}
+
+	return nil
+}
+
+// issueBackupCodes stores hashes of a fresh batch and returns the plaintext once
+func (s *TOTPService) issueBackupCodes(userID uuid.UUID) ([]string, error) {
+	codes, err := utils.GenerateBackupCodes(utils.BackupCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashBackupCode(code)
+	}
+
+	if err := s.backupCodeRepo.ReplaceForUser(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
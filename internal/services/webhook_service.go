@@ -0,0 +1,480 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/Flack74/go-auth-system/internal/repository"
+	"github.com/Flack74/go-auth-system/internal/utils"
+	"github.com/google/uuid"
+)
+
+// Headers sent with every webhook delivery. The signature is the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret.
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+const (
+	webhookPollInterval = 5 * time.Second
+	webhookBatchSize    = 20
+	// webhookLease keeps a claimed delivery from other workers; it is
+	// longer than a delivery can take so only a crashed worker's claims expire
+	webhookLease       = 2 * time.Minute
+	webhookTimeout     = 10 * time.Second
+	webhookMaxAttempts = 8
+	webhookRetryBase   = 30 * time.Second
+	webhookRetryMax    = 6 * time.Hour
+	// webhookErrorLimit caps how much of a failed response is kept
+	webhookErrorLimit = 512
+
+	defaultDeliveriesPerPage = 20
+	maxDeliveriesPerPage     = 100
+)
+
+var (
+	ErrWebhookNotFound        = errors.New("webhook subscription not found")
+	ErrWebhookDeliveryMissing = errors.New("webhook delivery not found")
+	ErrWebhookNotReplayable   = errors.New("only dead deliveries can be replayed")
+	ErrInvalidWebhookURL      = errors.New("webhook URL must be an absolute https URL")
+	ErrUnknownWebhookEvent    = errors.New("unknown webhook event")
+	ErrInvalidDeliveryFilter  = errors.New("invalid delivery filter")
+
+	errWebhookAddressRefused = errors.New("webhook endpoint is not a public address")
+	errWebhookRedirect       = errors.New("webhook endpoint redirected")
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, which netip doesn't
+// count as private but cloud providers use for metadata services
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// auditWebhookEvents maps audit events to the webhook events they trigger
+var auditWebhookEvents = map[string]string{
+	models.AuditRegister:               models.WebhookUserRegistered,
+	models.AuditEmailVerified:          models.WebhookUserEmailVerified,
+	models.AuditPasswordResetCompleted: models.WebhookUserPasswordChanged,
+	models.AuditPasswordChanged:        models.WebhookUserPasswordChanged,
+	models.AuditAccountLocked:          models.WebhookUserLocked,
+}
+
+// WebhookService manages webhook subscriptions and delivers queued events
+// to them in the background with exponential backoff
+type WebhookService struct {
+	webhookRepo repository.WebhookRepositoryInterface
+	httpClient  *http.Client
+	logger      *utils.Logger
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepositoryInterface) *WebhookService {
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		httpClient:  newWebhookClient(),
+		logger:      utils.NewLogger(),
+		stop:        make(chan struct{}),
+	}
+}
+
+// newWebhookClient returns the client deliveries are sent with. Endpoints
+// are chosen by admins but resolved at delivery time, so the dialer checks
+// every address actually connected to, and redirects are not followed.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: refuseInternalAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the connection on our behalf, past the dialer
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return errWebhookRedirect
+		},
+	}
+}
+
+// refuseInternalAddress is a net.Dialer Control function that refuses
+// loopback, private, link-local (which includes 169.254.169.254, the cloud
+// metadata service), shared, multicast and unspecified addresses
+func refuseInternalAddress(network, address string, c syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errWebhookAddressRefused, address)
+	}
+	addr := addrPort.Addr().Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || sharedAddressSpace.Contains(addr) {
+		return fmt.Errorf("%w: %s", errWebhookAddressRefused, addr)
+	}
+	return nil
+}
+
+// Publish queues eventType for every subscription that wants it. The queue
+// lives in the database, so events survive restarts until delivered.
+func (s *WebhookService) Publish(eventType string, data map[string]interface{}) error {
+	payload := models.WebhookPayload{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.webhookRepo.Enqueue(payload.ID, eventType, body)
+	return err
+}
+
+// SignWebhookPayload returns the signature sent in WebhookSignatureHeader
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff is the wait before the next try after attempts failures
+func webhookBackoff(attempts int) time.Duration {
+	return backoff(attempts, webhookRetryBase, webhookRetryMax)
+}
+
+// Start begins polling for due deliveries
+func (s *WebhookService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(webhookPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.ProcessDue()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits for the current batch to finish and stops polling
+func (s *WebhookService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// ProcessDue delivers every delivery that is due, one batch at a time
+func (s *WebhookService) ProcessDue() {
+	subscriptions := map[uuid.UUID]*models.WebhookSubscription{}
+	for {
+		deliveries, err := s.webhookRepo.ClaimDue(time.Now().Add(webhookLease), webhookBatchSize)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to claim webhook deliveries")
+			return
+		}
+
+		for i := range deliveries {
+			s.attempt(&deliveries[i], subscriptions)
+		}
+
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+	}
+}
+
+// attempt sends one delivery and records the outcome
+func (s *WebhookService) attempt(delivery *models.WebhookDelivery, subscriptions map[uuid.UUID]*models.WebhookSubscription) {
+	subscription, ok := subscriptions[delivery.SubscriptionID]
+	if !ok {
+		var err error
+		if subscription, err = s.webhookRepo.GetSubscription(delivery.SubscriptionID); err != nil {
+			s.logger.WithField("delivery_id", delivery.ID).WithError(err).Error("Failed to load webhook subscription")
+			return
+		}
+		subscriptions[delivery.SubscriptionID] = subscription
+	}
+
+	var statusCode int
+	var err error
+	if subscription.IsActive {
+		statusCode, err = s.send(subscription, delivery)
+	} else {
+		err = errors.New("subscription is inactive")
+	}
+
+	if err == nil {
+		if err := s.webhookRepo.MarkDelivered(delivery.ID, statusCode); err != nil {
+			s.logger.WithField("delivery_id", delivery.ID).WithError(err).Error("Failed to record webhook delivery")
+		}
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	status := models.WebhookDeliveryPending
+	if attempts >= webhookMaxAttempts || !subscription.IsActive {
+		status = models.WebhookDeliveryDead
+	}
+	s.logger.WithField("delivery_id", delivery.ID).
+		WithField("subscription_id", subscription.ID).
+		WithField("attempts", attempts).
+		WithField("status", status).
+		WithError(err).
+		Warn("Webhook delivery failed")
+
+	next := time.Now().Add(webhookBackoff(attempts))
+	if err := s.webhookRepo.MarkFailed(delivery.ID, status, next, statusCode, err.Error()); err != nil {
+		s.logger.WithField("delivery_id", delivery.ID).WithError(err).Error("Failed to record webhook failure")
+	}
+}
+
+// send POSTs the signed payload; any non-2xx response is a failure
+func (s *WebhookService) send(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-auth-system-webhooks")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookErrorLimit))
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d: %s", resp.StatusCode, body)
+	}
+	return resp.StatusCode, nil
+}
+
+// ListSubscriptions returns every subscription without its secret
+func (s *WebhookService) ListSubscriptions() ([]models.WebhookSubscription, error) {
+	subscriptions, err := s.webhookRepo.ListSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	return subscriptions, nil
+}
+
+// GetSubscription returns one subscription without its secret
+func (s *WebhookService) GetSubscription(id uuid.UUID) (*models.WebhookSubscription, error) {
+	subscription, err := s.getSubscription(id)
+	if err != nil {
+		return nil, err
+	}
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+// CreateSubscription registers an endpoint with a freshly generated secret.
+// The returned subscription is the only place the secret is shown.
+func (s *WebhookService) CreateSubscription(req *models.CreateWebhookRequest) (*models.WebhookSubscription, error) {
+	events, err := validateWebhook(req.URL, req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:      req.URL,
+		Secret:   hex.EncodeToString(secret),
+		Events:   events,
+		IsActive: true,
+	}
+	if err := s.webhookRepo.CreateSubscription(subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// UpdateSubscription changes the URL, event filter or active flag
+func (s *WebhookService) UpdateSubscription(id uuid.UUID, req *models.UpdateWebhookRequest) (*models.WebhookSubscription, error) {
+	subscription, err := s.getSubscription(id)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := validateWebhook(req.URL, req.Events)
+	if err != nil {
+		return nil, err
+	}
+	subscription.URL = req.URL
+	subscription.Events = events
+	if req.IsActive != nil {
+		subscription.IsActive = *req.IsActive
+	}
+
+	if err := s.webhookRepo.UpdateSubscription(subscription); err != nil {
+		return nil, err
+	}
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+// DeleteSubscription removes the subscription along with its deliveries
+func (s *WebhookService) DeleteSubscription(id uuid.UUID) error {
+	if err := s.webhookRepo.DeleteSubscription(id); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrWebhookNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ListDeliveries returns one page of deliveries matching the filter
+func (s *WebhookService) ListDeliveries(filter *models.WebhookDeliveryFilter) (*models.WebhookDeliveryListResponse, error) {
+	switch filter.Status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+	default:
+		return nil, ErrInvalidDeliveryFilter
+	}
+	subscriptionID := uuid.Nil
+	if filter.SubscriptionID != "" {
+		id, err := uuid.Parse(filter.SubscriptionID)
+		if err != nil {
+			return nil, ErrInvalidDeliveryFilter
+		}
+		subscriptionID = id
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultDeliveriesPerPage
+	}
+	if filter.PerPage > maxDeliveriesPerPage {
+		filter.PerPage = maxDeliveriesPerPage
+	}
+
+	deliveries, err := s.webhookRepo.ListDeliveries(filter.Status, subscriptionID, filter.PerPage, (filter.Page-1)*filter.PerPage)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.webhookRepo.CountDeliveries(filter.Status, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WebhookDeliveryListResponse{
+		Deliveries: deliveries,
+		Total:      total,
+		Page:       filter.Page,
+		PerPage:    filter.PerPage,
+	}, nil
+}
+
+func (s *WebhookService) GetDelivery(id uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery, err := s.webhookRepo.GetDelivery(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrWebhookDeliveryMissing
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ReplayDelivery moves a dead delivery back onto the queue
+func (s *WebhookService) ReplayDelivery(id uuid.UUID) error {
+	if _, err := s.GetDelivery(id); err != nil {
+		return err
+	}
+	replayed, err := s.webhookRepo.Replay(id)
+	if err != nil {
+		return err
+	}
+	if !replayed {
+		return ErrWebhookNotReplayable
+	}
+	return nil
+}
+
+// ReplayDeadDeliveries requeues every dead delivery for a subscription
+func (s *WebhookService) ReplayDeadDeliveries(subscriptionID uuid.UUID) (int64, error) {
+	if _, err := s.getSubscription(subscriptionID); err != nil {
+		return 0, err
+	}
+	return s.webhookRepo.ReplayDead(subscriptionID)
+}
+
+func (s *WebhookService) getSubscription(id uuid.UUID) (*models.WebhookSubscription, error) {
+	subscription, err := s.webhookRepo.GetSubscription(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// validateWebhook checks the endpoint URL and event filter, returning the
+// filter without duplicates
+func validateWebhook(rawURL string, events []string) ([]string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+
+	known := map[string]bool{}
+	for _, e := range models.WebhookEvents {
+		known[e] = true
+	}
+	seen := map[string]bool{}
+	filtered := make([]string, 0, len(events))
+	for _, e := range events {
+		if !known[e] {
+			return nil, ErrUnknownWebhookEvent
+		}
+		if !seen[e] {
+			seen[e] = true
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered, nil
+}
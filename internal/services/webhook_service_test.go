@@ -0,0 +1,388 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Flack74/go-auth-system/internal/models"
+	"github.com/google/uuid"
+)
+
+// stubWebhookRepo is an in-memory delivery queue
+type stubWebhookRepo struct {
+	subscriptions map[uuid.UUID]*models.WebhookSubscription
+	deliveries    []*models.WebhookDelivery
+}
+
+func newStubWebhookRepo() *stubWebhookRepo {
+	return &stubWebhookRepo{subscriptions: map[uuid.UUID]*models.WebhookSubscription{}}
+}
+
+func (r *stubWebhookRepo) CreateSubscription(subscription *models.WebhookSubscription) error {
+	subscription.ID = uuid.New()
+	copied := *subscription
+	r.subscriptions[subscription.ID] = &copied
+	return nil
+}
+
+func (r *stubWebhookRepo) GetSubscription(id uuid.UUID) (*models.WebhookSubscription, error) {
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *subscription
+	return &copied, nil
+}
+
+func (r *stubWebhookRepo) ListSubscriptions() ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	for _, subscription := range r.subscriptions {
+		subscriptions = append(subscriptions, *subscription)
+	}
+	return subscriptions, nil
+}
+
+func (r *stubWebhookRepo) UpdateSubscription(subscription *models.WebhookSubscription) error {
+	copied := *subscription
+	r.subscriptions[subscription.ID] = &copied
+	return nil
+}
+
+func (r *stubWebhookRepo) DeleteSubscription(id uuid.UUID) error {
+	if _, ok := r.subscriptions[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.subscriptions, id)
+	return nil
+}
+
+func (r *stubWebhookRepo) Enqueue(eventID uuid.UUID, eventType string, payload []byte) (int64, error) {
+	var queued int64
+	for _, subscription := range r.subscriptions {
+		wanted := len(subscription.Events) == 0
+		for _, e := range subscription.Events {
+			wanted = wanted || e == eventType
+		}
+		if !subscription.IsActive || !wanted {
+			continue
+		}
+		r.deliveries = append(r.deliveries, &models.WebhookDelivery{
+			ID:             uuid.New(),
+			SubscriptionID: subscription.ID,
+			EventID:        eventID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+		queued++
+	}
+	return queued, nil
+}
+
+func (r *stubWebhookRepo) ClaimDue(leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var due []models.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == models.WebhookDeliveryPending && !d.NextAttemptAt.After(time.Now()) && len(due) < limit {
+			d.NextAttemptAt = leaseUntil
+			due = append(due, *d)
+		}
+	}
+	return due, nil
+}
+
+func (r *stubWebhookRepo) find(id uuid.UUID) *models.WebhookDelivery {
+	for _, d := range r.deliveries {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func (r *stubWebhookRepo) MarkDelivered(id uuid.UUID, statusCode int) error {
+	d := r.find(id)
+	d.Status = models.WebhookDeliveryDelivered
+	d.Attempts++
+	d.LastStatusCode = statusCode
+	return nil
+}
+
+func (r *stubWebhookRepo) MarkFailed(id uuid.UUID, status string, nextAttemptAt time.Time, statusCode int, lastError string) error {
+	d := r.find(id)
+	d.Status = status
+	d.Attempts++
+	d.NextAttemptAt = nextAttemptAt
+	d.LastStatusCode = statusCode
+	d.LastError = lastError
+	return nil
+}
+
+func (r *stubWebhookRepo) GetDelivery(id uuid.UUID) (*models.WebhookDelivery, error) {
+	d := r.find(id)
+	if d == nil {
+		return nil, sql.ErrNoRows
+	}
+	copied := *d
+	return &copied, nil
+}
+
+func (r *stubWebhookRepo) ListDeliveries(status string, subscriptionID uuid.UUID, limit, offset int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	for _, d := range r.deliveries {
+		if (status == "" || d.Status == status) && (subscriptionID == uuid.Nil || d.SubscriptionID == subscriptionID) {
+			deliveries = append(deliveries, *d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *stubWebhookRepo) CountDeliveries(status string, subscriptionID uuid.UUID) (int64, error) {
+	deliveries, _ := r.ListDeliveries(status, subscriptionID, 0, 0)
+	return int64(len(deliveries)), nil
+}
+
+func (r *stubWebhookRepo) Replay(id uuid.UUID) (bool, error) {
+	d := r.find(id)
+	if d == nil || d.Status != models.WebhookDeliveryDead {
+		return false, nil
+	}
+	d.Status, d.Attempts, d.NextAttemptAt = models.WebhookDeliveryPending, 0, time.Now()
+	return true, nil
+}
+
+func (r *stubWebhookRepo) ReplayDead(subscriptionID uuid.UUID) (int64, error) {
+	var replayed int64
+	for _, d := range r.deliveries {
+		if d.SubscriptionID == subscriptionID && d.Status == models.WebhookDeliveryDead {
+			d.Status, d.Attempts, d.NextAttemptAt = models.WebhookDeliveryPending, 0, time.Now()
+			replayed++
+		}
+	}
+	return replayed, nil
+}
+
+func TestWebhookService_DeliversSignedPayload(t *testing.T) {
+	repo := newStubWebhookRepo()
+	service := NewWebhookService(repo)
+
+	var received models.WebhookPayload
+	var signature, timestamp string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		signature, timestamp = r.Header.Get(WebhookSignatureHeader), r.Header.Get(WebhookTimestampHeader)
+		ts, _ := strconv.ParseInt(timestamp, 10, 64)
+		if r.Header.Get(WebhookEventHeader) != models.WebhookUserRegistered {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// Reject anything not signed with the subscription secret
+		for _, s := range repo.subscriptions {
+			if SignWebhookPayload(s.Secret, ts, body) != signature {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	// The test server listens on loopback, which deliveries normally refuse
+	service.httpClient = server.Client()
+
+	subscription, err := service.CreateSubscription(&models.CreateWebhookRequest{
+		URL:    server.URL,
+		Events: []string{models.WebhookUserRegistered, models.WebhookUserRegistered},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(subscription.Secret) != 64 || len(subscription.Events) != 1 {
+		t.Fatalf("expected a generated secret and deduplicated events, got %+v", subscription)
+	}
+
+	userID := uuid.New()
+	if err := service.Publish(models.WebhookUserRegistered, map[string]interface{}{"user_id": userID}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	// Not subscribed, so nothing is queued
+	if err := service.Publish(models.WebhookUserLocked, nil); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(repo.deliveries) != 1 {
+		t.Fatalf("expected one queued delivery, got %d", len(repo.deliveries))
+	}
+
+	service.ProcessDue()
+
+	delivery := repo.deliveries[0]
+	if delivery.Status != models.WebhookDeliveryDelivered || delivery.LastStatusCode != http.StatusNoContent {
+		t.Fatalf("expected a successful delivery, got %+v", delivery)
+	}
+	if received.Type != models.WebhookUserRegistered || received.ID != delivery.EventID || received.Data["user_id"] != userID.String() {
+		t.Fatalf("unexpected payload %+v", received)
+	}
+}
+
+func TestWebhookService_RetriesThenDeadLetters(t *testing.T) {
+	repo := newStubWebhookRepo()
+	service := NewWebhookService(repo)
+
+	failing := true
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	service.httpClient = server.Client()
+
+	subscription, _ := service.CreateSubscription(&models.CreateWebhookRequest{URL: server.URL})
+	service.Publish(models.WebhookUserLocked, nil)
+	delivery := repo.deliveries[0]
+
+	service.ProcessDue()
+	if delivery.Status != models.WebhookDeliveryPending || delivery.Attempts != 1 || delivery.LastStatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected a scheduled retry, got %+v", delivery)
+	}
+	if wait := time.Until(delivery.NextAttemptAt); wait < webhookRetryBase-time.Second {
+		t.Fatalf("expected the retry to back off, next attempt in %v", wait)
+	}
+
+	for delivery.Status == models.WebhookDeliveryPending {
+		delivery.NextAttemptAt = time.Now()
+		service.ProcessDue()
+	}
+	if delivery.Status != models.WebhookDeliveryDead || delivery.Attempts != webhookMaxAttempts {
+		t.Fatalf("expected a dead delivery after %d attempts, got %+v", webhookMaxAttempts, delivery)
+	}
+
+	if err := service.ReplayDelivery(uuid.New()); err != ErrWebhookDeliveryMissing {
+		t.Fatalf("expected ErrWebhookDeliveryMissing, got %v", err)
+	}
+	failing = false
+	if replayed, err := service.ReplayDeadDeliveries(subscription.ID); err != nil || replayed != 1 {
+		t.Fatalf("expected one replayed delivery, got %d (%v)", replayed, err)
+	}
+	if err := service.ReplayDelivery(delivery.ID); err != ErrWebhookNotReplayable {
+		t.Fatalf("expected ErrWebhookNotReplayable for a pending delivery, got %v", err)
+	}
+	service.ProcessDue()
+	if delivery.Status != models.WebhookDeliveryDelivered {
+		t.Fatalf("expected the replay to be delivered, got %+v", delivery)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	if webhookBackoff(1) != webhookRetryBase || webhookBackoff(3) != 4*webhookRetryBase {
+		t.Fatalf("expected exponential backoff, got %v and %v", webhookBackoff(1), webhookBackoff(3))
+	}
+	if webhookBackoff(50) != webhookRetryMax {
+		t.Fatalf("expected backoff to be capped, got %v", webhookBackoff(50))
+	}
+}
+
+func TestWebhookService_Validation(t *testing.T) {
+	service := NewWebhookService(newStubWebhookRepo())
+
+	for _, url := range []string{"ftp://example.com/hook", "http://example.com/hook", "https:///hook"} {
+		if _, err := service.CreateSubscription(&models.CreateWebhookRequest{URL: url}); err != ErrInvalidWebhookURL {
+			t.Fatalf("%s: expected ErrInvalidWebhookURL, got %v", url, err)
+		}
+	}
+	if _, err := service.CreateSubscription(&models.CreateWebhookRequest{URL: "https://example.com/hook", Events: []string{"user.deleted"}}); err != ErrUnknownWebhookEvent {
+		t.Fatalf("expected ErrUnknownWebhookEvent, got %v", err)
+	}
+	if err := service.DeleteSubscription(uuid.New()); err != ErrWebhookNotFound {
+		t.Fatalf("expected ErrWebhookNotFound, got %v", err)
+	}
+	if _, err := service.ListDeliveries(&models.WebhookDeliveryFilter{Status: "lost"}); err != ErrInvalidDeliveryFilter {
+		t.Fatalf("expected ErrInvalidDeliveryFilter, got %v", err)
+	}
+}
+
+func TestWebhookService_RefusesInternalEndpoints(t *testing.T) {
+	repo := newStubWebhookRepo()
+	service := NewWebhookService(repo)
+
+	reached := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	if _, err := service.CreateSubscription(&models.CreateWebhookRequest{URL: server.URL}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	service.Publish(models.WebhookUserLocked, nil)
+	service.ProcessDue()
+
+	delivery := repo.deliveries[0]
+	if reached || delivery.Status != models.WebhookDeliveryPending || !strings.Contains(delivery.LastError, "not a public address") {
+		t.Fatalf("expected the loopback endpoint to be refused, got %+v", delivery)
+	}
+
+	for _, address := range []string{"127.0.0.1:443", "10.1.2.3:443", "192.168.0.1:443", "169.254.169.254:80", "100.100.100.200:80", "[::1]:443", "[fd00:ec2::254]:80", "[::ffff:127.0.0.1]:443", "0.0.0.0:443"} {
+		if err := refuseInternalAddress("tcp", address, nil); !errors.Is(err, errWebhookAddressRefused) {
+			t.Fatalf("%s: expected errWebhookAddressRefused, got %v", address, err)
+		}
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1::1]:443"} {
+		if err := refuseInternalAddress("tcp", address, nil); err != nil {
+			t.Fatalf("%s: expected a public address to be allowed, got %v", address, err)
+		}
+	}
+
+	redirect, _ := http.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+	if err := service.httpClient.CheckRedirect(redirect, []*http.Request{redirect}); err != errWebhookRedirect {
+		t.Fatalf("expected redirects to be refused, got %v", err)
+	}
+}
+
+// stubPublisher records published webhook events
+type stubPublisher struct {
+	events []string
+}
+
+func (p *stubPublisher) Publish(eventType string, data map[string]interface{}) error {
+	p.events = append(p.events, eventType)
+	return nil
+}
+
+func TestOutboxService_PublishesWebhookEvents(t *testing.T) {
+	repo := &stubOutboxRepo{}
+	publisher := &stubPublisher{}
+	service := NewOutboxService(repo, new(MockEmailSvc), publisher)
+
+	userID := uuid.New()
+	for _, eventType := range []string{models.AuditRegister, models.AuditLoginSucceeded, models.AuditAccountLocked} {
+		messages, err := webhookMessages(eventType, userID)
+		if err != nil {
+			t.Fatalf("webhook messages: %v", err)
+		}
+		repo.Enqueue(messages...)
+	}
+	if len(repo.messages) != 2 {
+		t.Fatalf("expected messages for the two webhook events, got %d", len(repo.messages))
+	}
+
+	service.ProcessDue()
+	if len(publisher.events) != 2 || publisher.events[0] != models.WebhookUserRegistered || publisher.events[1] != models.WebhookUserLocked {
+		t.Fatalf("unexpected webhook events %v", publisher.events)
+	}
+	for _, message := range repo.messages {
+		if message.Status != models.OutboxSent {
+			t.Fatalf("expected the webhook messages to be sent, got %+v", message)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+// BackupCodeCount is the number of recovery codes issued per batch
+const BackupCodeCount = 10
+
+// backupCodeAlphabet omits look-alike characters (0/o, 1/l/i)
+const backupCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// GenerateBackupCodes returns n random recovery codes formatted as xxxxx-xxxxx
+func GenerateBackupCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	max := big.NewInt(int64(len(backupCodeAlphabet)))
+
+	for i := 0; i < n; i++ {
+		var b strings.Builder
+		for j := 0; j < 10; j++ {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			idx, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, err
+			}
+			b.WriteByte(backupCodeAlphabet[idx.Int64()])
+		}
+		codes = append(codes, b.String())
+	}
+
+	return codes, nil
+}
+
+// HashBackupCode normalizes user input and returns the SHA-256 digest stored in the database
+func HashBackupCode(code string) string {
+	normalized := strings.ToLower(strings.TrimSpace(code))
+	normalized = strings.ReplaceAll(normalized, "-", "")
+	normalized = strings.ReplaceAll(normalized, " ", "")
+
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestGenerateBackupCodes(t *testing.T) {
+	codes, err := GenerateBackupCodes(BackupCodeCount)
+	if err != nil {
+		t.Fatalf("GenerateBackupCodes failed: %v", err)
+	}
+
+	if len(codes) != BackupCodeCount {
+		t.Fatalf("expected %d codes, got %d", BackupCodeCount, len(codes))
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("unexpected code format: %s", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate code generated: %s", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashBackupCode(t *testing.T) {
+	hash := HashBackupCode("abcde-fghjk")
+
+	if len(hash) != 64 {
+		t.Fatalf("expected hex SHA-256 digest, got %s", hash)
+	}
+
+	// Formatting differences in user input map to the same digest
+	if HashBackupCode(" ABCDEFGHJK ") != hash {
+		t.Fatal("HashBackupCode should normalize case, spaces and dashes")
+	}
+
+	if HashBackupCode("abcde-fghjm") == hash {
+		t.Fatal("different codes should not share a digest")
+	}
+}
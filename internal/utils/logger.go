@@ -3,6 +3,7 @@ package utils
 import (
 	"context"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -17,6 +18,33 @@ type contextKey string
 
 const CorrelationIDKey contextKey = "correlation_id"
 
+// EventSink receives every auth and security event logged through a Logger,
+// so they can be kept somewhere more durable than the log output
+type EventSink interface {
+	RecordLogEvent(source, event, correlationID string, data map[string]interface{})
+}
+
+var (
+	eventSinkMu sync.RWMutex
+	eventSink   EventSink
+)
+
+// SetEventSink installs the sink for auth and security events; nil removes it
+func SetEventSink(sink EventSink) {
+	eventSinkMu.Lock()
+	defer eventSinkMu.Unlock()
+	eventSink = sink
+}
+
+func forwardEvent(source, event, correlationID string, data map[string]interface{}) {
+	eventSinkMu.RLock()
+	sink := eventSink
+	eventSinkMu.RUnlock()
+	if sink != nil {
+		sink.RecordLogEvent(source, event, correlationID, data)
+	}
+}
+
 func NewLogger() *Logger {
 	logger := logrus.New()
 	
@@ -46,16 +74,23 @@ func (l *Logger) WithCorrelationID(ctx context.Context) *logrus.Entry {
 }
 
 func (l *Logger) LogAuthEvent(ctx context.Context, event, email string, success bool) {
-	l.WithCorrelationID(ctx).WithFields(logrus.Fields{
+	correlationID := GetCorrelationID(ctx)
+	l.WithField("correlation_id", correlationID).WithFields(logrus.Fields{
 		"event":   event,
 		"email":   email,
 		"success": success,
 		"type":    "auth",
 	}).Info("Authentication event")
+
+	forwardEvent("auth", event, correlationID, map[string]interface{}{
+		"email":   email,
+		"success": success,
+	})
 }
 
 func (l *Logger) LogSecurityEvent(ctx context.Context, event string, data map[string]interface{}) {
-	entry := l.WithCorrelationID(ctx).WithFields(logrus.Fields{
+	correlationID := GetCorrelationID(ctx)
+	entry := l.WithField("correlation_id", correlationID).WithFields(logrus.Fields{
 		"event": event,
 		"type":  "security",
 	})
@@ -65,6 +100,8 @@ func (l *Logger) LogSecurityEvent(ctx context.Context, event string, data map[st
 	}
 	
 	entry.Warn("Security event")
+
+	forwardEvent("security", event, correlationID, data)
 }
 
 func (l *Logger) LogBusinessEvent(ctx context.Context, event string, data map[string]interface{}) {
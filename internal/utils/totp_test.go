@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B test vectors (SHA1), truncated to 6 digits
+func TestGenerateTOTPCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, test := range tests {
+		code, err := GenerateTOTPCode(secret, time.Unix(test.unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateTOTPCode failed: %v", err)
+		}
+		if code != test.code {
+			t.Fatalf("expected %s at %d, got %s", test.code, test.unix, code)
+		}
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret failed: %v", err)
+	}
+	if len(secret) != 32 {
+		t.Fatalf("expected 32 character secret, got %d", len(secret))
+	}
+
+	now := time.Now()
+	code, _ := GenerateTOTPCode(secret, now)
+
+	// Current step and adjacent steps are accepted
+	if _, err := ValidateTOTPCode(secret, code, now); err != nil {
+		t.Fatalf("ValidateTOTPCode failed for current code: %v", err)
+	}
+	if _, err := ValidateTOTPCode(secret, code, now.Add(TOTPPeriod)); err != nil {
+		t.Fatalf("ValidateTOTPCode should accept previous step: %v", err)
+	}
+
+	// Codes outside the skew window are rejected
+	if _, err := ValidateTOTPCode(secret, code, now.Add(5*TOTPPeriod)); err == nil {
+		t.Fatal("ValidateTOTPCode should reject stale code")
+	}
+	if _, err := ValidateTOTPCode(secret, "12345", now); err == nil {
+		t.Fatal("ValidateTOTPCode should reject malformed code")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Go Auth", "user@example.com", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Go%20Auth:user@example.com?") {
+		t.Fatalf("unexpected URI: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=Go+Auth") {
+		t.Fatalf("URI missing parameters: %s", uri)
+	}
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"regexp"
 	"strings"
@@ -63,6 +65,13 @@ func NormalizeEmail(email string) string {
 	return strings.ToLower(strings.TrimSpace(email))
 }
 
+// HashEmail returns the SHA-256 digest of the normalized address, recorded
+// where an email that may belong to no account must not be kept in clear
+func HashEmail(email string) string {
+	sum := sha256.Sum256([]byte(NormalizeEmail(email)))
+	return hex.EncodeToString(sum[:])
+}
+
 func SanitizeInput(input string) string {
 	// Remove potential XSS characters
 	input = strings.ReplaceAll(input, "<", "")
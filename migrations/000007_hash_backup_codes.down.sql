@@ -0,0 +1,9 @@
+-- Hashed codes cannot be converted back
+DELETE FROM backup_codes;
+
+DROP INDEX IF EXISTS idx_backup_codes_user_code_hash;
+
+ALTER TABLE backup_codes ALTER COLUMN code_hash TYPE VARCHAR(10);
+ALTER TABLE backup_codes RENAME COLUMN code_hash TO code;
+
+CREATE INDEX idx_backup_codes_code ON backup_codes(code);
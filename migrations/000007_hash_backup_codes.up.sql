@@ -0,0 +1,10 @@
+-- Backup codes are stored as SHA-256 digests, never in plaintext.
+-- No code path wrote plaintext codes, but clear the table so none survive.
+DELETE FROM backup_codes;
+
+ALTER TABLE backup_codes RENAME COLUMN code TO code_hash;
+ALTER TABLE backup_codes ALTER COLUMN code_hash TYPE VARCHAR(64);
+
+-- Lookups are always scoped to the owning user
+DROP INDEX IF EXISTS idx_backup_codes_code;
+CREATE UNIQUE INDEX idx_backup_codes_user_code_hash ON backup_codes(user_id, code_hash);
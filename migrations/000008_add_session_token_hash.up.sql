@@ -0,0 +1,4 @@
+-- Session cookies are looked up by the SHA-256 digest of their token
+ALTER TABLE sessions ADD COLUMN token_hash VARCHAR(64);
+
+CREATE UNIQUE INDEX idx_sessions_token_hash ON sessions(token_hash);
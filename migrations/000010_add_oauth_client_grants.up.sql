@@ -0,0 +1,3 @@
+-- Machine clients: which grants a client may use and the scopes it may be issued
+ALTER TABLE oauth_clients ADD COLUMN scopes TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE oauth_clients ADD COLUMN grant_types TEXT[] NOT NULL DEFAULT '{authorization_code,refresh_token}';
@@ -0,0 +1,2 @@
+-- Accounts disabled by an administrator cannot sign in until re-enabled
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
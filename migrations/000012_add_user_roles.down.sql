@@ -0,0 +1,16 @@
+ALTER TABLE roles DROP COLUMN IF EXISTS inherits_from;
+
+ALTER TABLE users ADD COLUMN role_id UUID REFERENCES roles(id) DEFAULT NULL;
+
+-- Keep each user's most privileged role
+UPDATE users u SET role_id = (
+    SELECT ur.role_id FROM user_roles ur
+    JOIN roles r ON r.id = ur.role_id
+    WHERE ur.user_id = u.id
+    ORDER BY CASE r.name WHEN 'admin' THEN 0 WHEN 'moderator' THEN 1 WHEN 'user' THEN 3 ELSE 2 END, r.name
+    LIMIT 1
+);
+
+CREATE INDEX idx_users_role_id ON users(role_id);
+
+DROP TABLE IF EXISTS user_roles;
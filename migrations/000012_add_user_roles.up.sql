@@ -0,0 +1,20 @@
+-- Users may hold several roles at once
+CREATE TABLE user_roles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX idx_user_roles_role_id ON user_roles(role_id);
+
+INSERT INTO user_roles (user_id, role_id)
+SELECT id, role_id FROM users WHERE role_id IS NOT NULL;
+
+ALTER TABLE users DROP COLUMN role_id;
+
+-- A role includes everything granted to the role it inherits from
+ALTER TABLE roles ADD COLUMN inherits_from UUID REFERENCES roles(id) ON DELETE SET NULL DEFAULT NULL;
+
+UPDATE roles SET inherits_from = (SELECT id FROM roles WHERE name = 'user') WHERE name = 'moderator';
+UPDATE roles SET inherits_from = (SELECT id FROM roles WHERE name = 'moderator') WHERE name = 'admin';
@@ -0,0 +1,15 @@
+-- Security history of each account. Rows outlive the user so the trail
+-- stays complete after an account is deleted.
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID,
+    event_type VARCHAR(50) NOT NULL,
+    ip_address INET,
+    user_agent TEXT,
+    correlation_id VARCHAR(100),
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_events_user_id_created_at ON audit_events(user_id, created_at DESC);
+CREATE INDEX idx_audit_events_event_type ON audit_events(event_type);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_audit_events_created_at;
+
+ALTER TABLE audit_events ALTER COLUMN ip_address TYPE INET USING ip_address::inet;
+
+ALTER TABLE audit_events DROP COLUMN IF EXISTS hash;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS prev_hash;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS source;
@@ -0,0 +1,11 @@
+-- Each record stores a hash over its own fields and the previous record's
+-- hash, so editing or deleting a row breaks the chain from that point on.
+-- Rows written before this migration are left unchained.
+ALTER TABLE audit_events ADD COLUMN source VARCHAR(20) NOT NULL DEFAULT 'account';
+ALTER TABLE audit_events ADD COLUMN prev_hash VARCHAR(64);
+ALTER TABLE audit_events ADD COLUMN hash VARCHAR(64);
+
+-- Stored as text so the value read back is exactly the one that was hashed
+ALTER TABLE audit_events ALTER COLUMN ip_address TYPE VARCHAR(45) USING host(ip_address);
+
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
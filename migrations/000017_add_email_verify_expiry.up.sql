@@ -0,0 +1,7 @@
+-- Verification links expire like password reset links. Tokens issued before
+-- this migration get a fresh day so pending sign-ups can still complete.
+ALTER TABLE users ADD COLUMN email_verify_expiry TIMESTAMP WITH TIME ZONE;
+
+UPDATE users
+SET email_verify_expiry = NOW() + INTERVAL '24 hours'
+WHERE email_verify_token IS NOT NULL AND email_verified = false;
@@ -0,0 +1,16 @@
+-- Outstanding tokens cannot be restored; users have to request new links
+ALTER TABLE users
+    ADD COLUMN email_verify_token VARCHAR(255),
+    ADD COLUMN email_verify_expiry TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN password_reset_token VARCHAR(255),
+    ADD COLUMN password_reset_expiry TIMESTAMP;
+
+CREATE INDEX idx_users_email_verify_token ON users(email_verify_token);
+CREATE INDEX idx_users_password_reset_token ON users(password_reset_token);
+CREATE INDEX idx_users_unverified
+ON users(email_verify_token) WHERE email_verified = false AND email_verify_token IS NOT NULL;
+CREATE INDEX idx_users_password_reset
+ON users(password_reset_token, password_reset_expiry)
+WHERE password_reset_token IS NOT NULL;
+
+DROP TABLE IF EXISTS one_time_tokens;
@@ -0,0 +1,34 @@
+-- Single-use tokens sent by email (verification, password reset, email
+-- change). Only the SHA-256 digest is stored, so reading the table does not
+-- yield working links.
+CREATE TABLE one_time_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(20) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    data JSONB NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_one_time_tokens_outstanding ON one_time_tokens(user_id, purpose) WHERE used_at IS NULL;
+CREATE INDEX idx_one_time_tokens_expires_at ON one_time_tokens(expires_at);
+
+-- Carry over outstanding links as digests, then drop the plaintext columns
+INSERT INTO one_time_tokens (user_id, purpose, token_hash, expires_at)
+SELECT id, 'verify_email', encode(sha256(convert_to(email_verify_token, 'UTF8')), 'hex'),
+       COALESCE(email_verify_expiry, NOW() + INTERVAL '24 hours')
+FROM users
+WHERE email_verify_token IS NOT NULL AND email_verified = false;
+
+INSERT INTO one_time_tokens (user_id, purpose, token_hash, expires_at)
+SELECT id, 'password_reset', encode(sha256(convert_to(password_reset_token, 'UTF8')), 'hex'), password_reset_expiry
+FROM users
+WHERE password_reset_token IS NOT NULL AND password_reset_expiry > NOW();
+
+ALTER TABLE users
+    DROP COLUMN email_verify_token,
+    DROP COLUMN email_verify_expiry,
+    DROP COLUMN password_reset_token,
+    DROP COLUMN password_reset_expiry;
@@ -0,0 +1,3 @@
+ALTER TABLE audit_events DROP COLUMN IF EXISTS redacted_at;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
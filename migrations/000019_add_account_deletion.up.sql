@@ -0,0 +1,10 @@
+-- Accounts their owner asked to delete. They can still be restored by
+-- signing in until the grace period ends, after which they are purged.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
+
+CREATE INDEX idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Purging an account clears the personal data from its audit records. They
+-- keep their place in the hash chain, but their contents can no longer be
+-- checked against it.
+ALTER TABLE audit_events ADD COLUMN redacted_at TIMESTAMP WITH TIME ZONE DEFAULT NULL;
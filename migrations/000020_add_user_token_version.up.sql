@@ -0,0 +1,3 @@
+-- Access tokens carry the version current when they were issued. Bumping it
+-- rejects every token the user holds, whichever session it belongs to.
+ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
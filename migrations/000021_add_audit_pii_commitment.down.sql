@@ -0,0 +1,2 @@
+ALTER TABLE audit_events DROP COLUMN IF EXISTS pii_hash;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS pii_nonce;
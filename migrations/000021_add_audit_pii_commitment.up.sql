@@ -0,0 +1,7 @@
+-- Each audit record commits to its personal data, the IP address, user agent
+-- and metadata, with a salted digest that its chain hash covers in their
+-- place. Redaction clears the data and the salt but keeps the digest, so a
+-- redacted record still verifies and the digest can't be used to guess what
+-- was removed. Records from before keep hashing their data directly.
+ALTER TABLE audit_events ADD COLUMN pii_nonce TEXT DEFAULT NULL;
+ALTER TABLE audit_events ADD COLUMN pii_hash TEXT DEFAULT NULL;
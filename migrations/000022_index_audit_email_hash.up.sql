@@ -0,0 +1,3 @@
+-- Failed logins for an unknown address carry only a digest of it. Purging
+-- an account redacts them by that digest.
+CREATE INDEX idx_audit_events_email_hash ON audit_events((metadata->>'email_hash')) WHERE user_id IS NULL;
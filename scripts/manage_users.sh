@@ -1,46 +1,69 @@
 #!/bin/bash
 
+# User management through the admin API. Requires curl and jq, and an access
+# token belonging to an admin account:
+#   export API_URL=http://localhost:8080
+#   export ADMIN_TOKEN=<access token>
+
+API_URL="${API_URL:-http://localhost:8080}"
+
 echo "🔧 User Management Tool"
 echo ""
 
+if [ -z "$ADMIN_TOKEN" ] && [ -n "$1" ]; then
+  echo "❌ ADMIN_TOKEN must be set to an admin access token"
+  exit 1
+fi
+
+api() {
+  local method="$1" path="$2"
+  shift 2
+  curl -sS -X "$method" -H "Authorization: Bearer $ADMIN_TOKEN" "$@" "$API_URL/api/admin$path"
+}
+
+# user_id looks up a user by exact email without ever building SQL from input
+user_id() {
+  api GET "/users" -G --data-urlencode "email=$1" --data-urlencode "per_page=100" \
+    | jq -r --arg email "$(echo "$1" | tr '[:upper:]' '[:lower:]')" '.users[]? | select(.email == $email) | .id'
+}
+
+require_user() {
+  ID="$(user_id "$1")"
+  if [ -z "$ID" ]; then
+    echo "❌ No user with email: $1"
+    exit 1
+  fi
+}
+
 case "$1" in
   "reset")
-    EMAIL="$2"
-    docker-compose -f docker-compose.backend.yml exec -T postgres psql -U authuser -d authdb << EOF
-UPDATE users 
-SET failed_login_attempts = 0, 
-    locked_until = NULL 
-WHERE email = '$EMAIL';
-EOF
-    echo "✅ Reset failed attempts for: $EMAIL"
+    require_user "$2"
+    api POST "/users/$ID/unlock" | jq .
+    echo "✅ Reset failed attempts for: $2"
     echo "   Try logging in again with your password"
     ;;
-    
+
   "delete")
-    EMAIL="$2"
-    docker-compose -f docker-compose.backend.yml exec -T postgres psql -U authuser -d authdb << EOF
-DELETE FROM users WHERE email = '$EMAIL';
-EOF
-    echo "✅ Deleted user: $EMAIL"
+    require_user "$2"
+    api DELETE "/users/$ID" | jq .
+    echo "✅ Deleted user: $2"
     echo "   You can now register again"
     ;;
-    
+
   "list")
-    docker-compose -f docker-compose.backend.yml exec -T postgres psql -U authuser -d authdb << EOF
-SELECT email, email_verified, failed_login_attempts, 
-       CASE WHEN locked_until IS NOT NULL THEN 'LOCKED' ELSE 'ACTIVE' END as status
-FROM users;
-EOF
+    api GET "/users" -G --data-urlencode "per_page=100" \
+      | jq -r '.users[] | [.email, (.roles | join(",")), (if .email_verified then "verified" else "unverified" end), (if .disabled_at then "DISABLED" elif .locked_until then "LOCKED" else "ACTIVE" end)] | @tsv'
     ;;
-    
+
   *)
     echo "Usage:"
     echo "  bash manage_users.sh reset <email>   - Reset failed login attempts"
     echo "  bash manage_users.sh delete <email>  - Delete user (can re-register)"
-    echo "  bash manage_users.sh list            - List all users"
+    echo "  bash manage_users.sh list            - List users"
     echo ""
     echo "Example:"
-    echo "  bash manage_users.sh delete puspendrachawlax@gmail.com"
-    echo "  Then register again at http://localhost:3000/register"
+    echo "  ADMIN_TOKEN=... bash manage_users.sh delete someone@example.com"
+    echo ""
+    echo "More actions (verify, disable, role changes) are available under /api/admin/users"
     ;;
 esac